@@ -12,10 +12,38 @@ import (
 // Организация конфига в main принуждает нас сужать API компонентов, использовать
 // при их конструировании только необходимые параметры, а также уменьшает вероятность циклической зависимости.
 type Config struct {
-	Logger             LoggerConf   `config:"logger"`
-	Database           DatabaseConf `config:"database"`
-	Server             ServerConf   `config:"server"`
-	UseInMemoryStorage bool         `config:"use_in_memory_storage"`
+	Logger    LoggerConf    `config:"logger"`
+	Storage   StorageConf   `config:"storage"`
+	Database  DatabaseConf  `config:"database"`
+	Server    ServerConf    `config:"server"`
+	GRPC      GRPCConf      `config:"grpc"`
+	Trash     TrashConf     `config:"trash"`
+	Webhooks  WebhooksConf  `config:"webhooks"`
+	Reminders RemindersConf `config:"reminders"`
+	// файлы, прикрепленные к событиям
+	Attachments AttachmentsConf `config:"attachments"`
+	// устарело, используйте storage.type = "memory"
+	UseInMemoryStorage bool `config:"use_in_memory_storage"`
+}
+
+const (
+	MemoryStorage = "memory"
+	SQLStorage    = "sql"
+	BoltStorage   = "bolt"
+)
+
+type StorageConf struct {
+	// memory, sql (настройки в database) или bolt (файл в path), пустой - sql
+	Type string `config:"type"`
+	// для memory - директория для WAL и снапшотов, если пустая - данные не сохраняются
+	Path string `config:"path"`
+	// always, interval или never
+	Sync             string        `config:"sync"`
+	SyncInterval     time.Duration `config:"syncinterval"`
+	SnapshotInterval time.Duration `config:"snapshotinterval"`
+	// кэш GetEvent и выборок за период для любого type, 0 записей - без кэша
+	CacheEntries int           `config:"cacheentries"`
+	CacheTTL     time.Duration `config:"cachettl"`
 }
 
 type ServerConf struct {
@@ -26,6 +54,12 @@ type ServerConf struct {
 	ShutDownTimeout time.Duration `config:"shutdowntimeout"`
 }
 
+// GRPCConf - адрес gRPC API, остальные настройки общие с server.
+type GRPCConf struct {
+	Host string `config:"host"`
+	Port string `config:"port"`
+}
+
 type DatabaseConf struct {
 	Host     string `config:"Host"`
 	Port     string `config:"port"`
@@ -35,6 +69,81 @@ type DatabaseConf struct {
 	// почему то со снейк кейсом не работало, оставил уж так
 	ConnectionTimeout time.Duration `config:"connectiontimeout"`
 	OperationTimeout  time.Duration `config:"operationtimeout"`
+	// пул соединений, 0 - значения database/sql по умолчанию
+	MaxOpenConns    int           `config:"maxopenconns"`
+	MaxIdleConns    int           `config:"maxidleconns"`
+	ConnMaxLifetime time.Duration `config:"connmaxlifetime"`
+	ConnMaxIdleTime time.Duration `config:"connmaxidletime"`
+	// disable, require, verify-ca или verify-full
+	SSLMode     string `config:"sslmode"`
+	SSLRootCert string `config:"sslrootcert"`
+	// повторы при обрывах соединения и конфликтах сериализации, 0 - без повторов
+	RetryAttempts   int           `config:"retryattempts"`
+	RetryBackoff    time.Duration `config:"retrybackoff"`
+	RetryMaxBackoff time.Duration `config:"retrymaxbackoff"`
+	// DSN реплик для чтения, упавшая реплика исключается на replicaejectfor
+	Replicas        []string      `config:"replicas"`
+	ReplicaEjectFor time.Duration `config:"replicaejectfor"`
+}
+
+type TrashConf struct {
+	// сколько удаленные события хранятся в корзине, 0 - не очищать корзину
+	Retention     time.Duration `config:"retention"`
+	PurgeInterval time.Duration `config:"purgeinterval"`
+}
+
+type WebhooksConf struct {
+	// 0 воркеров - изменения на вебхуки не отправляются,
+	// для остальных настроек 0 - значения webhook.Default*
+	Workers int `config:"workers"`
+	// после maxattempts неудачных попыток доставка помечается dead
+	MaxAttempts int           `config:"maxattempts"`
+	Backoff     time.Duration `config:"backoff"`
+	// 0 - задержка между попытками не ограничена
+	MaxBackoff time.Duration `config:"maxbackoff"`
+	Timeout    time.Duration `config:"timeout"`
+	// как часто хранилище проверяется на доставки для повтора
+	SweepInterval time.Duration `config:"sweepinterval"`
+	QueueSize     int           `config:"queuesize"`
+	// разрешить отправку на локальные и приватные адреса, только для разработки
+	AllowPrivate bool `config:"allowprivate"`
+}
+
+type AttachmentsConf struct {
+	// директория для содержимого файлов, если пустая - к событиям прикрепляются только ссылки
+	Dir string `config:"dir"`
+	// максимальный размер файла в байтах, 0 - без ограничения
+	MaxSize int64 `config:"maxsize"`
+}
+
+// RemindersConf - планировщик и рассыльщик напоминаний, они работают в процессе календаря
+// и связаны очередью в памяти.
+type RemindersConf struct {
+	// 0 - напоминания не отправляются
+	Interval time.Duration `config:"interval"`
+	// напоминания, пропущенные за lookback (например, при перезапуске), отправляются с опозданием
+	Lookback time.Duration `config:"lookback"`
+	// сколько хранятся записи об отправленных напоминаниях, должно быть больше lookback
+	Retention time.Duration `config:"retention"`
+	// реплики выбирают лидера арендой в хранилище, лидер, не продливший ее за leasettl, ее теряет
+	LeaseTTL time.Duration `config:"leasettl"`
+	// пауза перед повтором напоминания при ошибке хранилища
+	RetryDelay time.Duration `config:"retrydelay"`
+	// каналы пользователей без профиля: email, webhook или stdout
+	DefaultChannels []string `config:"defaultchannels"`
+	// text/template для темы и текста, пустые - шаблоны по умолчанию
+	SubjectTemplate string   `config:"subjecttemplate"`
+	BodyTemplate    string   `config:"bodytemplate"`
+	SMTP            SMTPConf `config:"smtp"`
+}
+
+// SMTPConf - сервер для канала email, пустой addr - канал не настроен.
+type SMTPConf struct {
+	Addr     string        `config:"addr"`
+	Username string        `config:"username"`
+	Password string        `config:"password"`
+	From     string        `config:"from"`
+	Timeout  time.Duration `config:"timeout"`
 }
 
 type LoggerConf struct {
@@ -51,3 +160,16 @@ func NewConfig(ctx context.Context, configPath string) (*Config, error) {
 
 	return &cfg, err
 }
+
+// StorageType returns the type of the storage, configs written before storage.type
+// keep their storage: use_in_memory_storage selects memory, otherwise sql is used.
+func (c *Config) StorageType() string {
+	switch {
+	case c.Storage.Type != "":
+		return c.Storage.Type
+	case c.UseInMemoryStorage:
+		return MemoryStorage
+	default:
+		return SQLStorage
+	}
+}
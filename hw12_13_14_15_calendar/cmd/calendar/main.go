@@ -2,17 +2,29 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // alpine image has no zoneinfo
 
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	localblobstore "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/blobstore/local"
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/notifier"
+	memoryqueue "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/sender"
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	boltstorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/bolt"
+	cachestorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/cache"
 	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/sql"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/webhook"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/common/log"
 )
@@ -45,13 +57,42 @@ func main() {
 	logg.Info().Msg("Successfully initialize config...")
 
 	var st app.Storage
-	if config.UseInMemoryStorage {
-		st = memorystorage.New(logg)
-	} else {
+	switch config.StorageType() {
+	case MemoryStorage:
+		if config.Storage.Path == "" {
+			st = memorystorage.New(logg)
+			break
+		}
+		memorySt := memorystorage.NewPersistent(logg, config.Storage.Path,
+			memorystorage.SyncPolicy(config.Storage.Sync), config.Storage.SyncInterval,
+			config.Storage.SnapshotInterval)
+		if connectionErr := memorySt.Connect(ctx); connectionErr != nil {
+			logg.Fatal().Err(connectionErr).Msg("failed to restore storage")
+		}
+		defer func() {
+			if closeErr := memorySt.Close(ctx); closeErr != nil {
+				logg.Error().Err(closeErr).Msg("failed to close storage")
+			}
+		}()
+		st = memorySt
+	case SQLStorage:
 		sqlSt := sqlstorage.New(
 			logg, config.Database.Host, config.Database.Port, config.Database.User,
 			config.Database.Password, config.Database.DBName, config.Database.ConnectionTimeout,
-			config.Database.OperationTimeout)
+			config.Database.OperationTimeout,
+			sqlstorage.PoolOptions{
+				MaxOpenConns:    config.Database.MaxOpenConns,
+				MaxIdleConns:    config.Database.MaxIdleConns,
+				ConnMaxLifetime: config.Database.ConnMaxLifetime,
+				ConnMaxIdleTime: config.Database.ConnMaxIdleTime,
+			},
+			sqlstorage.TLSOptions{Mode: config.Database.SSLMode, RootCert: config.Database.SSLRootCert},
+			sqlstorage.RetryPolicy{
+				MaxAttempts:    config.Database.RetryAttempts,
+				InitialBackoff: config.Database.RetryBackoff,
+				MaxBackoff:     config.Database.RetryMaxBackoff,
+			},
+			sqlstorage.ReplicaOptions{DSNs: config.Database.Replicas, EjectFor: config.Database.ReplicaEjectFor})
 		if connectionErr := sqlSt.Connect(ctx); connectionErr != nil {
 			logg.Fatal().Err(connectionErr).Msg("failed to connect to database")
 		}
@@ -61,18 +102,85 @@ func main() {
 			}
 		}()
 		st = sqlSt
+	case BoltStorage:
+		boltSt := boltstorage.New(logg, config.Storage.Path, config.Database.ConnectionTimeout)
+		if connectionErr := boltSt.Connect(ctx); connectionErr != nil {
+			logg.Fatal().Err(connectionErr).Msg("failed to open database file")
+		}
+		defer func() {
+			if closeErr := boltSt.Close(ctx); closeErr != nil {
+				logg.Error().Err(closeErr).Msg("failed to close database file")
+			}
+		}()
+		st = boltSt
+	default:
+		logg.Fatal().Msgf("unknown storage type %q", config.StorageType())
+	}
+
+	if config.Storage.CacheEntries > 0 {
+		cachedSt := cachestorage.New(st, config.Storage.CacheEntries, config.Storage.CacheTTL)
+		// counters are served by the http server on /debug/vars
+		expvar.Publish("storage_cache", expvar.Func(func() interface{} {
+			return cachedSt.Stats()
+		}))
+		st = cachedSt
 	}
 
 	calendar := app.New(logg, st)
+	if config.Attachments.Dir != "" {
+		blobs, blobsErr := localblobstore.New(config.Attachments.Dir)
+		if blobsErr != nil {
+			logg.Fatal().Err(blobsErr).Msg("failed to open attachments directory")
+		}
+		calendar.SetBlobStore(blobs, config.Attachments.MaxSize)
+	}
+	var dispatcher *webhook.Dispatcher
+	if config.Webhooks.Workers > 0 {
+		dispatcher = webhook.New(logg, st, webhook.Options{
+			Workers:       config.Webhooks.Workers,
+			MaxAttempts:   config.Webhooks.MaxAttempts,
+			Backoff:       config.Webhooks.Backoff,
+			MaxBackoff:    config.Webhooks.MaxBackoff,
+			Timeout:       config.Webhooks.Timeout,
+			SweepInterval: config.Webhooks.SweepInterval,
+			QueueSize:     config.Webhooks.QueueSize,
+			AllowPrivate:  config.Webhooks.AllowPrivate,
+		})
+		calendar.AddChangeListener(dispatcher)
+	}
 
 	server := internalhttp.NewServer(logg, calendar,
 		config.Server.Host, config.Server.Port, config.Server.ReadTimeout,
 		config.Server.WriteTimeout, config.Server.ShutDownTimeout)
+	grpcServer := internalgrpc.NewServer(logg, calendar,
+		config.GRPC.Host, config.GRPC.Port, config.Server.ShutDownTimeout)
+	// the JSON API of the proto is served by the http server with the same service as grpc
+	gateway, err := internalgrpc.NewGateway(logg, calendar)
+	if err != nil {
+		logg.Fatal().Err(err).Msg("failed to build grpc gateway")
+	}
+	server.HandleDocumented("/v1/", gateway, gateway.WriteInvalidRequest)
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
+	if config.Trash.Retention > 0 && config.Trash.PurgeInterval > 0 {
+		go calendar.RunTrashPurge(ctx, config.Trash.Retention, config.Trash.PurgeInterval)
+	}
+	if dispatcher != nil {
+		go dispatcher.Run(ctx)
+	}
+	if config.Reminders.Interval > 0 {
+		startReminders(ctx, logg, st, config)
+	}
+	go func() {
+		if err := grpcServer.Start(ctx); err != nil {
+			logg.Error().Err(err).Msg("failed to start grpc server")
+			cancel()
+		}
+	}()
+
 	stopChan := make(chan interface{})
 	go func() {
 		<-ctx.Done()
@@ -83,6 +191,9 @@ func main() {
 		if stopError := server.Stop(ctx); stopError != nil {
 			logg.Error().Err(stopError).Msg("failed to stop http server")
 		}
+		if stopError := grpcServer.Stop(ctx); stopError != nil {
+			logg.Error().Err(stopError).Msg("failed to stop grpc server")
+		}
 		defer close(stopChan)
 	}()
 
@@ -101,3 +212,47 @@ func main() {
 		os.Exit(1) //nolint:gocritic
 	}
 }
+
+// startReminders runs the scheduler and the sender of reminders until ctx is done.
+func startReminders(ctx context.Context, logg *logger.Logger, st app.Storage, config *Config) {
+	templates, err := notifier.NewTemplates(config.Reminders.SubjectTemplate, config.Reminders.BodyTemplate)
+	if err != nil {
+		logg.Fatal().Err(err).Msg("invalid reminder templates")
+	}
+	notifiers := map[storage.NotificationChannel]notifier.Notifier{
+		storage.NotificationChannelStdout: notifier.NewStdout(os.Stdout, templates),
+		storage.NotificationChannelWebhook: notifier.NewWebhook(st, templates,
+			config.Webhooks.Timeout, config.Webhooks.AllowPrivate),
+	}
+	if config.Reminders.SMTP.Addr != "" {
+		notifiers[storage.NotificationChannelEmail] = notifier.NewSMTP(notifier.SMTPOptions{
+			Addr:     config.Reminders.SMTP.Addr,
+			Username: config.Reminders.SMTP.Username,
+			Password: config.Reminders.SMTP.Password,
+			From:     config.Reminders.SMTP.From,
+			Timeout:  config.Reminders.SMTP.Timeout,
+		}, templates)
+	}
+	defaults := make([]storage.NotificationChannel, 0, len(config.Reminders.DefaultChannels))
+	for _, channel := range config.Reminders.DefaultChannels {
+		if !storage.NotificationChannel(channel).Valid() {
+			logg.Fatal().Msgf("unknown notification channel %q", channel)
+		}
+		defaults = append(defaults, storage.NotificationChannel(channel))
+	}
+
+	queue := memoryqueue.New()
+	go scheduler.New(logg, st, queue, scheduler.Options{
+		Interval:          config.Reminders.Interval,
+		Lookback:          config.Reminders.Lookback,
+		ReminderRetention: config.Reminders.Retention,
+		LeaseTTL:          config.Reminders.LeaseTTL,
+	}).Run(ctx)
+	reminderSender := sender.New(logg, st, queue, notifier.NewRouter(st, notifiers, defaults),
+		config.Reminders.RetryDelay)
+	go func() {
+		if err := reminderSender.Run(ctx); err != nil {
+			logg.Error().Err(err).Msg("failed to run reminder sender")
+		}
+	}()
+}
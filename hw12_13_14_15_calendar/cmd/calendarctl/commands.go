@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type command struct {
+	help string
+	run  func(ctx context.Context, c *cli, name string, args []string) error
+}
+
+// commandNames keeps the order of commands in the usage.
+var commandNames = []string{
+	"create", "update", "delete", "get", "list", "list-day", "list-week", "list-month", "import", "export",
+}
+
+var commands = map[string]command{
+	"create":     {"create an event", createCommand},
+	"update":     {"change fields of the event given by flags", updateCommand},
+	"delete":     {"move the event to the trash", deleteCommand},
+	"get":        {"print the event", getCommand},
+	"list":       {"print all events", listCommand},
+	"list-day":   {"print events of the day, today by default", listPeriodCommand},
+	"list-week":  {"print events of the week starting on the day, today by default", listPeriodCommand},
+	"list-month": {"print events of the month starting on the day, today by default", listPeriodCommand},
+	"import":     {"create events from a file exported before, all or none of them", importCommand},
+	"export":     {"write all events to a file", exportCommand},
+}
+
+// timeLayouts are accepted by time flags, the ones without the offset are in the zone of -tz.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", dateLayout}
+
+const dateLayout = "2006-01-02"
+
+func (c *cli) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	fs.Usage = func() {
+		fmt.Fprintf(c.errOut, "Usage: calendarctl [flags] %s [command flags] %s\n\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses flags and checks that the number of positional arguments is in [min, max].
+func parse(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		fmt.Fprintf(fs.Output(), "unexpected number of arguments %d\n", fs.NArg())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// eventFlags are fields of events, only the flags given are applied to the event.
+type eventFlags struct {
+	title        string
+	description  string
+	start        string
+	end          string
+	timeZone     string
+	allDay       bool
+	notifyBefore time.Duration
+}
+
+func (f *eventFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.title, "title", "", "title")
+	fs.StringVar(&f.description, "description", "", "description")
+	fs.StringVar(&f.start, "start", "", "start time as RFC 3339 or 2006-01-02 15:04 in the zone of -tz")
+	fs.StringVar(&f.end, "end", "", "end time in the same formats as -start")
+	fs.StringVar(&f.timeZone, "time-zone", "", "IANA time zone the event is planned in")
+	fs.BoolVar(&f.allDay, "all-day", false, "the event lasts whole days")
+	fs.DurationVar(&f.notifyBefore, "notify-before", 0, "how long before the start the reminder is sent")
+}
+
+func (f *eventFlags) apply(c *cli, fs *flag.FlagSet, event *pb.Event) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch fl.Name {
+		case "title":
+			event.Title = f.title
+		case "description":
+			event.Description = f.description
+		case "start":
+			event.StartAt, err = c.parseTime(f.start)
+		case "end":
+			event.EndAt, err = c.parseTime(f.end)
+		case "time-zone":
+			event.TimeZone = f.timeZone
+		case "all-day":
+			event.AllDay = f.allDay
+		case "notify-before":
+			event.NotifyBefore = durationpb.New(f.notifyBefore)
+		}
+	})
+	return err
+}
+
+func (c *cli) parseTime(value string) (*timestamppb.Timestamp, error) {
+	loc, err := c.location()
+	if err != nil {
+		return nil, err
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return timestamppb.New(t), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time %q, expected RFC 3339 or 2006-01-02 15:04", value)
+}
+
+func createCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "")
+	var f eventFlags
+	f.register(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	event := &pb.Event{}
+	if err := f.apply(c, fs, event); err != nil {
+		return err
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	created, err := c.client.CreateEvent(ctx, &pb.CreateEventRequest{Event: event})
+	if err != nil {
+		return err
+	}
+	return c.printEvent(created)
+}
+
+func updateCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "<id>")
+	var f eventFlags
+	f.register(fs)
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	event, err := c.client.GetEvent(ctx, &pb.GetEventRequest{Id: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	if err := f.apply(c, fs, event); err != nil {
+		return err
+	}
+	updated, err := c.client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: event})
+	if err != nil {
+		return err
+	}
+	return c.printEvent(updated)
+}
+
+func deleteCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "<id>")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	_, err := c.client.DeleteEvent(ctx, &pb.DeleteEventRequest{Id: fs.Arg(0)})
+	return err
+}
+
+func getCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "<id>")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	event, err := c.client.GetEvent(ctx, &pb.GetEventRequest{Id: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.printEvent(event)
+}
+
+func listCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	events, err := c.client.ListEvents(ctx, &pb.ListEventsRequest{})
+	if err != nil {
+		return err
+	}
+	return c.printEvents(events)
+}
+
+func listPeriodCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "[2006-01-02]")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+	date := fs.Arg(0)
+	if date == "" {
+		loc, err := c.location()
+		if err != nil {
+			return err
+		}
+		date = time.Now().In(loc).Format(dateLayout)
+	} else if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("invalid date %q, expected 2006-01-02", date)
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	req := &pb.ListPeriodEventsRequest{Date: date, TimeZone: c.timeZone}
+	var (
+		events *pb.ListEventsResponse
+		err    error
+	)
+	switch name {
+	case "list-day":
+		events, err = c.client.ListDayEvents(ctx, req)
+	case "list-week":
+		events, err = c.client.ListWeekEvents(ctx, req)
+	default:
+		events, err = c.client.ListMonthEvents(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printEvents(events)
+}
+
+func exportCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "")
+	file := fs.String("file", "", "file to write, stdout by default")
+	format := fs.String("format", outputJSON, "format of the file: json or yaml")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := validateFileFormat(*format); err != nil {
+		return err
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	events, err := c.client.ListEvents(ctx, &pb.ListEventsRequest{})
+	if err != nil {
+		return err
+	}
+	data, err := marshal(*format, events)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		_, err = c.out.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(*file, data, 0o600)
+}
+
+func importCommand(ctx context.Context, c *cli, name string, args []string) error {
+	fs := c.flagSet(name, "")
+	file := fs.String("file", "", "file to read, stdin by default")
+	format := fs.String("format", outputJSON, "format of the file: json or yaml")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := validateFileFormat(*format); err != nil {
+		return err
+	}
+	var in io.Reader = c.in
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	var events pb.ListEventsResponse
+	if err := unmarshal(*format, data, &events); err != nil {
+		return fmt.Errorf("invalid %s file: %w", *format, err)
+	}
+
+	// events are created anew for the user, so ids and the trash state are not kept
+	operations := make([]*pb.BatchOperation, 0, len(events.GetEvents()))
+	for _, event := range events.GetEvents() {
+		event.Id, event.UserId, event.DeletedAt = "", "", nil
+		operations = append(operations, &pb.BatchOperation{Op: "create", Event: event})
+	}
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	created, err := c.client.ApplyBatch(ctx, &pb.ApplyBatchRequest{Operations: operations})
+	if err != nil {
+		return err
+	}
+	return c.printEvents(created)
+}
@@ -0,0 +1,165 @@
+// calendarctl manages events of the calendar over its gRPC API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Environment variables are defaults of the global flags of the same meaning.
+const (
+	addrEnv     = "CALENDAR_ADDR"
+	userIDEnv   = "CALENDAR_USER_ID"
+	timeZoneEnv = "CALENDAR_TIME_ZONE"
+
+	defaultAddr = "localhost:50051"
+)
+
+// errUsage is returned when arguments are wrong, the flag set has already reported why.
+var errUsage = errors.New("usage")
+
+type cli struct {
+	addr       string
+	userID     string
+	calendarID string
+	timeZone   string
+	output     string
+	timeout    time.Duration
+
+	client pb.EventServiceClient
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command of args and returns the exit code: 1 if the command failed
+// and 2 if it was called wrong.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{in: stdin, out: stdout, errOut: stderr}
+	global := flag.NewFlagSet("calendarctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.StringVar(&c.addr, "addr", envOr(addrEnv, defaultAddr), "address of the gRPC API, $"+addrEnv)
+	global.StringVar(&c.userID, "user", os.Getenv(userIDEnv), "ID of the user, $"+userIDEnv)
+	global.StringVar(&c.calendarID, "calendar", "",
+		"owner of the calendar to work with, it has to be shared with the user, the own one by default")
+	global.StringVar(&c.timeZone, "tz", os.Getenv(timeZoneEnv),
+		"IANA time zone of dates and the table output, the local one by default, $"+timeZoneEnv)
+	global.StringVar(&c.output, "output", outputTable, "output format: table, json or yaml")
+	global.DurationVar(&c.timeout, "timeout", 10*time.Second, "timeout of a call")
+	global.Usage = func() { c.usage(global) }
+	if err := global.Parse(args); err != nil {
+		return exitCode(err)
+	}
+
+	if global.NArg() == 0 {
+		global.Usage()
+		return 2
+	}
+	cmd, ok := commands[global.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", global.Arg(0))
+		global.Usage()
+		return 2
+	}
+	if err := validateOutput(c.output); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if _, err := c.location(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	conn, err := grpc.DialContext(ctx, c.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to connect to %s: %s\n", c.addr, err)
+		return 1
+	}
+	defer conn.Close()
+	c.client = pb.NewEventServiceClient(conn)
+
+	if err := cmd.run(ctx, c, global.Arg(0), global.Args()[1:]); err != nil {
+		if code := exitCode(err); code != 1 {
+			return code
+		}
+		// statuses of the API are printed without the rpc error prefix
+		if st, ok := status.FromError(err); ok {
+			fmt.Fprintf(stderr, "%s: %s: %s\n", global.Arg(0), st.Code(), st.Message())
+			return 1
+		}
+		fmt.Fprintf(stderr, "%s: %s\n", global.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (c *cli) usage(global *flag.FlagSet) {
+	fmt.Fprintf(c.errOut, "Usage: calendarctl [flags] <command> [command flags] [args]\n\nCommands:\n")
+	for _, name := range commandNames {
+		fmt.Fprintf(c.errOut, "  %-11s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintf(c.errOut, "\nFlags:\n")
+	global.PrintDefaults()
+}
+
+// callContext limits the call by the timeout and passes the user, the calendar and the zone as metadata.
+func (c *cli) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	var pairs []string
+	if c.userID != "" {
+		pairs = append(pairs, internalgrpc.UserIDKey, c.userID)
+	}
+	if c.calendarID != "" {
+		pairs = append(pairs, internalgrpc.CalendarIDKey, c.calendarID)
+	}
+	if c.timeZone != "" {
+		pairs = append(pairs, internalgrpc.TimeZoneKey, c.timeZone)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// location is the zone of -tz, the local one if it is not set.
+func (c *cli) location() (*time.Location, error) {
+	if c.timeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", c.timeZone, err)
+	}
+	return loc, nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// startServer serves the gRPC API with memory storage in process and returns its address.
+func startServer(t *testing.T) string {
+	t.Helper()
+	logg := logger.New("error")
+	server := internalgrpc.NewServer(logg, app.New(logg, memorystorage.New(logg)), "", "", time.Second)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Server.Serve(listener)
+	}()
+	t.Cleanup(server.Server.Stop)
+	return listener.Addr().String()
+}
+
+type result struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+func runCLI(t *testing.T, stdin string, args ...string) result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+type jsonEvent struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	StartAt      string `json:"start_at"`      //nolint:tagliatelle
+	EndAt        string `json:"end_at"`        //nolint:tagliatelle
+	UserID       string `json:"user_id"`       //nolint:tagliatelle
+	NotifyBefore string `json:"notify_before"` //nolint:tagliatelle
+}
+
+type jsonEvents struct {
+	Events []jsonEvent `json:"events"`
+}
+
+func decode(t *testing.T, res result, v interface{}) {
+	t.Helper()
+	require.Equal(t, 0, res.code, res.stderr)
+	require.NoError(t, json.Unmarshal([]byte(res.stdout), v), res.stdout)
+}
+
+func TestCommands(t *testing.T) {
+	addr := startServer(t)
+	as := func(userID string, args ...string) []string {
+		return append([]string{"-addr", addr, "-user", userID, "-tz", "Europe/Moscow", "-output", "json"}, args...)
+	}
+
+	var created jsonEvent
+	decode(t, runCLI(t, "", as("alice", "create",
+		"-title", "planning", "-start", "2022-10-24 10:00", "-end", "2022-10-24T11:00:00+03:00")...), &created)
+	require.NotEmpty(t, created.ID)
+	require.Equal(t, "alice", created.UserID)
+	require.Equal(t, "2022-10-24T07:00:00Z", created.StartAt, "time without offset is in the zone of -tz")
+
+	t.Run("get", func(t *testing.T) {
+		var got jsonEvent
+		decode(t, runCLI(t, "", as("alice", "get", created.ID)...), &got)
+		require.Equal(t, created, got)
+	})
+
+	t.Run("update changes only given fields", func(t *testing.T) {
+		var updated jsonEvent
+		decode(t, runCLI(t, "", as("alice", "update", "-title", "weekly planning", "-notify-before", "15m", created.ID)...),
+			&updated)
+		require.Equal(t, "weekly planning", updated.Title)
+		require.Equal(t, "900s", updated.NotifyBefore)
+		require.Equal(t, created.StartAt, updated.StartAt)
+	})
+
+	t.Run("list periods", func(t *testing.T) {
+		for _, tc := range []struct {
+			command, date string
+			count         int
+		}{
+			{"list-day", "2022-10-24", 1},
+			{"list-day", "2022-10-25", 0},
+			{"list-week", "2022-10-24", 1},
+			{"list-month", "2022-10-01", 1},
+		} {
+			var events jsonEvents
+			decode(t, runCLI(t, "", as("alice", tc.command, tc.date)...), &events)
+			require.Len(t, events.Events, tc.count, "%s %s", tc.command, tc.date)
+		}
+
+		var others jsonEvents
+		decode(t, runCLI(t, "", as("bob", "list-week", "2022-10-24")...), &others)
+		require.Empty(t, others.Events)
+	})
+
+	t.Run("calendar of another user has to be shared", func(t *testing.T) {
+		res := runCLI(t, "", as("bob", "-calendar", "alice", "list")...)
+		require.Equal(t, 1, res.code)
+		require.Contains(t, res.stderr, "PermissionDenied")
+	})
+
+	t.Run("table output", func(t *testing.T) {
+		res := runCLI(t, "", "-addr", addr, "-user", "alice", "-tz", "Europe/Moscow", "list")
+		require.Equal(t, 0, res.code, res.stderr)
+		lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+		require.Len(t, lines, 2)
+		require.Regexp(t, `^ID\s+TITLE\s+START\s+END\s+OWNER\s+NOTIFY BEFORE$`, lines[0])
+		require.Regexp(t, created.ID+`\s+weekly planning\s+2022-10-24 10:00\s+2022-10-24 11:00\s+alice\s+15m0s`, lines[1])
+	})
+
+	t.Run("yaml output", func(t *testing.T) {
+		res := runCLI(t, "", "-addr", addr, "-user", "alice", "-output", "yaml", "get", created.ID)
+		require.Equal(t, 0, res.code, res.stderr)
+		var got map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(res.stdout), &got))
+		require.Equal(t, "weekly planning", got["title"])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		res := runCLI(t, "", as("alice", "delete", created.ID)...)
+		require.Equal(t, 0, res.code, res.stderr)
+
+		res = runCLI(t, "", as("alice", "get", created.ID)...)
+		require.Equal(t, 1, res.code)
+		require.Contains(t, res.stderr, "get: NotFound:")
+	})
+}
+
+func TestImportExport(t *testing.T) {
+	addr := startServer(t)
+	for _, title := range []string{"review", "retro"} {
+		res := runCLI(t, "", "-addr", addr, "-user", "alice", "create",
+			"-title", title, "-start", "2022-10-24T10:00:00Z", "-end", "2022-10-24T11:00:00Z")
+		require.Equal(t, 0, res.code, res.stderr)
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "events."+format)
+			res := runCLI(t, "", "-addr", addr, "-user", "alice", "export", "-format", format, "-file", file)
+			require.Equal(t, 0, res.code, res.stderr)
+
+			userID := "bob-" + format
+			res = runCLI(t, "", "-addr", addr, "-user", userID, "-output", "json", "import", "-format", format, "-file", file)
+			var imported jsonEvents
+			decode(t, res, &imported)
+			require.Len(t, imported.Events, 2)
+			for _, event := range imported.Events {
+				require.Equal(t, userID, event.UserID)
+			}
+		})
+	}
+
+	t.Run("stdin and stdout", func(t *testing.T) {
+		exported := runCLI(t, "", "-addr", addr, "-user", "alice", "export")
+		require.Equal(t, 0, exported.code, exported.stderr)
+
+		res := runCLI(t, exported.stdout, "-addr", addr, "-user", "carol", "-output", "json", "import")
+		var imported jsonEvents
+		decode(t, res, &imported)
+		require.Len(t, imported.Events, 2)
+	})
+
+	t.Run("invalid file imports nothing", func(t *testing.T) {
+		res := runCLI(t, `{"events": [{"title": 1}]}`, "-addr", addr, "-user", "dave", "import")
+		require.Equal(t, 1, res.code)
+		require.Contains(t, res.stderr, "invalid json file")
+	})
+}
+
+func TestEnvironment(t *testing.T) {
+	addr := startServer(t)
+	for key, value := range map[string]string{addrEnv: addr, userIDEnv: "alice", timeZoneEnv: "Asia/Tokyo"} {
+		previous, ok := os.LookupEnv(key)
+		require.NoError(t, os.Setenv(key, value))
+		key := key
+		t.Cleanup(func() {
+			if ok {
+				_ = os.Setenv(key, previous)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		})
+	}
+
+	var created jsonEvent
+	decode(t, runCLI(t, "", "-output", "json", "create",
+		"-title", "standup", "-start", "2022-10-24 10:00", "-end", "2022-10-24 10:15"), &created)
+	require.Equal(t, "alice", created.UserID)
+	require.Equal(t, "2022-10-24T01:00:00Z", created.StartAt)
+
+	t.Run("flags take precedence", func(t *testing.T) {
+		var events jsonEvents
+		decode(t, runCLI(t, "", "-user", "bob", "-output", "json", "list"), &events)
+		require.Empty(t, events.Events)
+	})
+}
+
+func TestUsage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{"no command", nil, 2, "Commands:"},
+		{"unknown command", []string{"remove"}, 2, `unknown command "remove"`},
+		{"unknown output", []string{"-output", "xml", "list"}, 2, `unknown output format "xml"`},
+		{"invalid time zone", []string{"-tz", "Mars/Olympus", "list"}, 2, "invalid time zone"},
+		{"missing id", []string{"get"}, 2, "Usage: calendarctl [flags] get"},
+		{"unknown flag", []string{"create", "-color", "red"}, 2, "flag provided but not defined"},
+		{"help", []string{"create", "-h"}, 0, "-notify-before"},
+		{"invalid date", []string{"list-day", "24.10.2022"}, 1, "invalid date"},
+		{"invalid time", []string{"create", "-start", "tomorrow"}, 1, "invalid time"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res := runCLI(t, "", append([]string{"-addr", "127.0.0.1:1"}, tc.args...)...)
+			require.Equal(t, tc.code, res.code, res.stderr)
+			require.Contains(t, res.stderr, tc.stderr)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// the files and the output use names of the proto, as the JSON API does.
+var (
+	marshalOptions   = protojson.MarshalOptions{UseProtoNames: true, Multiline: true, Indent: "  "}
+	unmarshalOptions = protojson.UnmarshalOptions{}
+)
+
+func validateOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, json or yaml", output)
+	}
+}
+
+func validateFileFormat(format string) error {
+	switch format {
+	case outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("unknown file format %q, expected json or yaml", format)
+	}
+}
+
+func (c *cli) printEvent(event *pb.Event) error {
+	if c.output == outputTable {
+		return c.printTable([]*pb.Event{event})
+	}
+	return c.printMessage(event)
+}
+
+func (c *cli) printEvents(events *pb.ListEventsResponse) error {
+	if c.output == outputTable {
+		return c.printTable(events.GetEvents())
+	}
+	return c.printMessage(events)
+}
+
+func (c *cli) printMessage(message proto.Message) error {
+	data, err := marshal(c.output, message)
+	if err != nil {
+		return err
+	}
+	_, err = c.out.Write(data)
+	return err
+}
+
+// printTable prints main fields of events, times are in the zone of -tz.
+func (c *cli) printTable(events []*pb.Event) error {
+	loc, err := c.location()
+	if err != nil {
+		return err
+	}
+	formatTime := func(event *pb.Event, t *timestamppb.Timestamp) string {
+		layout := "2006-01-02 15:04"
+		if event.GetAllDay() {
+			layout = dateLayout
+		}
+		return t.AsTime().In(loc).Format(layout)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tSTART\tEND\tOWNER\tNOTIFY BEFORE")
+	for _, event := range events {
+		var notifyBefore string
+		if event.GetNotifyBefore() != nil {
+			notifyBefore = event.GetNotifyBefore().AsDuration().String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.GetId(), event.GetTitle(),
+			formatTime(event, event.GetStartAt()), formatTime(event, event.GetEndAt()),
+			event.GetUserId(), notifyBefore)
+	}
+	return w.Flush()
+}
+
+// marshal encodes the message as JSON or YAML, YAML is converted from JSON of the message.
+func marshal(format string, message proto.Message) ([]byte, error) {
+	data, err := marshalOptions.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	if format == outputJSON {
+		return append(data, '\n'), nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+func unmarshal(format string, data []byte, message proto.Message) error {
+	if format == outputYAML {
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		var err error
+		if data, err = json.Marshal(jsonValue(value)); err != nil {
+			return err
+		}
+	}
+	return unmarshalOptions.Unmarshal(data, message)
+}
+
+// jsonValue converts maps decoded from YAML to maps with string keys JSON can encode.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			converted[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return converted
+	case []interface{}:
+		for i, v := range value {
+			value[i] = jsonValue(v)
+		}
+		return value
+	default:
+		return value
+	}
+}
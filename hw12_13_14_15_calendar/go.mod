@@ -13,7 +13,10 @@ require (
 	github.com/prometheus/common v0.26.0
 	github.com/rs/xid v1.4.0
 	github.com/rs/zerolog v1.28.0
-	github.com/stretchr/testify v1.8.0
-	golang.org/x/sys v0.0.0-20220907062415-87db552b00fd // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/stretchr/testify v1.8.1
+	go.etcd.io/bbolt v1.3.6
+	google.golang.org/genproto v0.0.0-20230110181048-76db0878b65f
+	google.golang.org/grpc v1.53.0
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v2 v2.4.0
 )
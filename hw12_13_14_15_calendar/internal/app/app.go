@@ -2,7 +2,11 @@ package app
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
 	"github.com/rs/zerolog"
 )
@@ -10,6 +14,21 @@ import (
 type App struct {
 	Logg  Logger
 	Store Storage
+
+	listeners []ChangeListener
+	changes   *changeBroker
+	// blobs keep content of attachments, see SetBlobStore
+	blobs             BlobStore
+	maxAttachmentSize int64
+	// pending is set for the app working in a transaction, changes are collected in it
+	// and listeners are notified after commit
+	pending *[]*storage.AuditRecord
+}
+
+// ChangeListener is notified about every change of an event made through the app.
+// The record describes the change, it is passed even if it was not saved to the audit log.
+type ChangeListener interface {
+	EventChanged(ctx context.Context, record *storage.AuditRecord)
 }
 
 type Logger interface {
@@ -21,24 +40,376 @@ type Logger interface {
 }
 
 type Storage interface {
+	// AddEvent, ModifyEvent and RestoreEvent fail with ErrResourceBusy if a resource of the event
+	// is booked for another event at the same time. The check is atomic with the change.
 	AddEvent(ctx context.Context, event *storage.Event) error
 	ModifyEvent(ctx context.Context, event *storage.Event) error
 	DeleteEvent(ctx context.Context, id string) error
 	GetEvent(ctx context.Context, id string) (*storage.Event, error)
 	ListEvents(ctx context.Context) ([]*storage.Event, error)
+	ListUserEvents(ctx context.Context, userID string) ([]*storage.Event, error)
+	ListEventsInRange(ctx context.Context, from, to time.Time) ([]*storage.Event, error)
+	ListUsersEventsInRange(ctx context.Context, userIDs []string, from, to time.Time) ([]*storage.Event, error)
+
+	AddAttendees(ctx context.Context, eventID string, userIDs []string) error
+	SetAttendeeStatus(ctx context.Context, eventID, userID string, status storage.AttendeeStatus) error
+
+	RestoreEvent(ctx context.Context, id string) error
+	ListDeletedEvents(ctx context.Context) ([]*storage.Event, error)
+	PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) (int, error)
+
+	AddAuditRecord(ctx context.Context, record *storage.AuditRecord) error
+	ListAuditRecords(ctx context.Context, eventID string) ([]*storage.AuditRecord, error)
+
+	AddWebhook(ctx context.Context, webhook *storage.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*storage.Webhook, error)
+	// DeleteWebhook removes the webhook together with its deliveries.
+	DeleteWebhook(ctx context.Context, id string) error
+	ListUsersWebhooks(ctx context.Context, userIDs []string) ([]*storage.Webhook, error)
+	AddWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error
+	UpdateWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error
+	// ListWebhookDeliveries returns deliveries of the webhook from the oldest to the newest.
+	ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error)
+	// ListDueWebhookDeliveries returns up to limit pending deliveries whose next attempt is not after now.
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*storage.WebhookDelivery, error)
+
+	// SaveUserProfile creates or replaces the profile of profile.UserID.
+	SaveUserProfile(ctx context.Context, profile *storage.UserProfile) error
+	GetUserProfile(ctx context.Context, userID string) (*storage.UserProfile, error)
+
+	// SaveTag creates or replaces the tag of tag.UserID.
+	SaveTag(ctx context.Context, tag *storage.Tag) error
+	// ListUserTags returns categories of the user and tags of events owned by the user which are
+	// not in the trash, sorted by name.
+	ListUserTags(ctx context.Context, userID string) ([]*storage.Tag, error)
+
+	// SaveShare creates or replaces the share of the calendar of share.OwnerID with share.UserID.
+	SaveShare(ctx context.Context, share *storage.Share) error
+	// DeleteShare fails with ErrNotFoundShare if the calendar of the owner is not shared with the user.
+	DeleteShare(ctx context.Context, ownerID, userID string) error
+	// ListOwnerShares returns shares of the calendar of the owner sorted by user.
+	ListOwnerShares(ctx context.Context, ownerID string) ([]*storage.Share, error)
+	// ListUserShares returns shares of calendars with the user sorted by owner.
+	ListUserShares(ctx context.Context, userID string) ([]*storage.Share, error)
+
+	AddResource(ctx context.Context, resource *storage.Resource) error
+	// ModifyResource fails with ErrNotFoundResource if the resource does not exist.
+	ModifyResource(ctx context.Context, resource *storage.Resource) error
+	GetResource(ctx context.Context, id string) (*storage.Resource, error)
+	// ListResources returns all resources sorted by name.
+	ListResources(ctx context.Context) ([]*storage.Resource, error)
+	// ListResourcesEventsInRange returns events any of resources is booked for, which overlap with [from, to).
+	ListResourcesEventsInRange(ctx context.Context, resourceIDs []string, from, to time.Time) ([]*storage.Event, error)
+
+	// AddAttachment adds the attachment to the event, it fails with ErrNotFoundEvent if the event
+	// does not exist or is in the trash.
+	AddAttachment(ctx context.Context, attachment *storage.Attachment) error
+	GetAttachment(ctx context.Context, id string) (*storage.Attachment, error)
+	// ListEventAttachments returns attachments of the event from the oldest to the newest.
+	ListEventAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error)
+	DeleteAttachment(ctx context.Context, id string) error
+	// ListOrphanedAttachments returns up to limit attachments of purged events, they are kept
+	// until their content is removed from the blob store.
+	ListOrphanedAttachments(ctx context.Context, limit int) ([]*storage.Attachment, error)
+
+	// ListEventsToNotify returns events whose reminder time, see storage.Event.NotifyAt, is in (from, to].
+	ListEventsToNotify(ctx context.Context, from, to time.Time) ([]*storage.Event, error)
+	// AddReminder records the handled reminder, recording it twice fails with ErrReminderExists.
+	AddReminder(ctx context.Context, reminder *storage.Reminder) error
+	GetReminder(ctx context.Context, id string) (*storage.Reminder, error)
+	// DeleteReminders removes reminders handled before the time and returns their number.
+	DeleteReminders(ctx context.Context, handledBefore time.Time) (int, error)
+
+	// AcquireLease takes the named lease for holder or renews it if holder has it already,
+	// it reports false if another holder has the lease. The lease expires after ttl unless
+	// it is renewed, sql storage keeps it instead until the session holding it ends.
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives the lease up if holder has it.
+	ReleaseLease(ctx context.Context, name, holder string) error
+
+	// WithTx runs fn with storage whose changes are committed all together if fn returns nil
+	// and are discarded otherwise. Calling WithTx on the storage passed to fn reuses the transaction.
+	WithTx(ctx context.Context, fn func(tx Storage) error) error
 }
 
 func New(logger Logger, storage Storage) *App {
+	changes := newChangeBroker()
 	return &App{
-		Logg:  logger,
-		Store: storage,
+		Logg:      logger,
+		Store:     storage,
+		listeners: []ChangeListener{changes},
+		changes:   changes,
+	}
+}
+
+// AddChangeListener subscribes listener to changes of events. It must be called before the app is used.
+func (a *App) AddChangeListener(listener ChangeListener) {
+	a.listeners = append(a.listeners, listener)
+}
+
+// CreateEvent adds event to the calendar the request works with, see ContextWithCalendarID,
+// so it is owned by the user from ctx by default. Attendees of the new event have to respond
+// to the invitation, so their statuses are reset.
+func (a *App) CreateEvent(ctx context.Context, event *storage.Event) error {
+	ownerID, err := a.calendar(ctx, storage.ShareAccessReadWrite)
+	if err != nil {
+		return err
+	}
+	if ownerID != "" {
+		event.UserID = ownerID
 	}
+	attendees := event.Attendees
+	event.Attendees = nil
+	for _, attendee := range attendees {
+		if !event.IsAttendee(attendee.UserID) {
+			event.Attendees = append(event.Attendees, storage.Attendee{
+				UserID: attendee.UserID,
+				Status: storage.AttendeeStatusNeedsAction,
+			})
+		}
+	}
+	if err := normalizeEventTime(event); err != nil {
+		return err
+	}
+	if err := normalizeTags(event); err != nil {
+		return err
+	}
+	if err := a.normalizeResources(ctx, event); err != nil {
+		return err
+	}
+	if err := a.Store.AddEvent(ctx, event); err != nil {
+		return err
+	}
+	return a.audit(ctx, storage.AuditActionCreate, event.ID, nil, event)
 }
 
-func (a *App) CreateEvent(ctx context.Context, id, title string) error {
-	// TODO
+func (a *App) UpdateEvent(ctx context.Context, event *storage.Event) error {
+	// the audit record has to be based on the latest state
+	ctx = ContextWithReadYourWrites(ctx)
+	before, err := a.getEvent(ctx, event.ID, storage.ShareAccessReadWrite)
+	if err != nil {
+		return err
+	}
+	// owner and attendees are not changed by update
+	event.UserID = before.UserID
+	event.Attendees = before.Attendees
+	if err = normalizeEventTime(event); err != nil {
+		return err
+	}
+	if err = normalizeTags(event); err != nil {
+		return err
+	}
+	if err = a.normalizeResources(ctx, event); err != nil {
+		return err
+	}
+	if err = a.Store.ModifyEvent(ctx, event); err != nil {
+		return err
+	}
+	return a.audit(ctx, storage.AuditActionUpdate, event.ID, before, event)
+}
+
+// InviteAttendees adds users to the attendees of the event, already invited users are skipped.
+func (a *App) InviteAttendees(ctx context.Context, eventID string, userIDs []string) error {
+	return a.changeAttendees(ctx, eventID, storage.ShareAccessReadWrite, func() error {
+		return a.Store.AddAttendees(ctx, eventID, userIDs)
+	})
+}
+
+// RespondToInvitation sets the status of the owner of the calendar the request works with
+// in the event attendees, so delegates respond on behalf of the owner.
+func (a *App) RespondToInvitation(ctx context.Context, eventID string, status storage.AttendeeStatus) error {
+	userID, err := a.calendar(ctx, storage.ShareAccessReadWrite)
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return apperrors.ErrUserRequired{}
+	}
+	if !status.Valid() {
+		return apperrors.ErrInvalidAttendeeStatus{Status: string(status)}
+	}
+	return a.changeAttendees(ctx, eventID, storage.ShareAccessRead, func() error {
+		return a.Store.SetAttendeeStatus(ctx, eventID, userID, status)
+	})
+}
+
+func (a *App) changeAttendees(
+	ctx context.Context, eventID string, required storage.ShareAccess, change func() error,
+) error {
+	ctx = ContextWithReadYourWrites(ctx)
+	before, err := a.getEvent(ctx, eventID, required)
+	if err != nil {
+		return err
+	}
+	if err = change(); err != nil {
+		return err
+	}
+	after, err := a.Store.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	return a.audit(ctx, storage.AuditActionUpdate, eventID, before, after)
+}
+
+func (a *App) DeleteEvent(ctx context.Context, id string) error {
+	ctx = ContextWithReadYourWrites(ctx)
+	before, err := a.getEvent(ctx, id, storage.ShareAccessReadWrite)
+	if err != nil {
+		return err
+	}
+	if err = a.Store.DeleteEvent(ctx, id); err != nil {
+		return err
+	}
+	return a.audit(ctx, storage.AuditActionDelete, id, before, nil)
+}
+
+// RestoreEvent moves event back from the trash, it fails with ErrResourceBusy if its resources
+// were booked for other events meanwhile.
+func (a *App) RestoreEvent(ctx context.Context, id string) error {
+	if UserIDFromContext(ctx) != "" {
+		if _, err := a.getDeletedEvent(ctx, id, storage.ShareAccessReadWrite); err != nil {
+			return err
+		}
+	}
+	if err := a.Store.RestoreEvent(ctx, id); err != nil {
+		return err
+	}
+	after, err := a.Store.GetEvent(ContextWithReadYourWrites(ctx), id)
+	if err != nil {
+		return err
+	}
+	return a.audit(ctx, storage.AuditActionRestore, id, nil, after)
+}
+
+// ListDeletedEvents returns events of the calendar the request works with which are in the trash.
+// Without user and calendar in ctx all of them are returned.
+func (a *App) ListDeletedEvents(ctx context.Context) ([]*storage.Event, error) {
+	ownerID, err := a.calendar(ctx, storage.ShareAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	events, err := a.Store.ListDeletedEvents(ctx)
+	if err != nil || ownerID == "" {
+		return events, err
+	}
+	owned := make([]*storage.Event, 0, len(events))
+	for _, event := range events {
+		if event.UserID == ownerID {
+			owned = append(owned, event)
+		}
+	}
+	return owned, nil
+}
+
+// PurgeDeletedEvents removes completely events which are in the trash for longer than retention.
+// Attachments of purged events are removed with their content afterwards, attachments left
+// by a failure are removed by the next purge.
+func (a *App) PurgeDeletedEvents(ctx context.Context, retention time.Duration) (int, error) {
+	purged, err := a.Store.PurgeDeletedEvents(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if err = a.purgeAttachments(ctx); err != nil {
+		return purged, err
+	}
+	return purged, nil
+}
+
+// RunTrashPurge purges the trash every interval until ctx is done.
+func (a *App) RunTrashPurge(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := a.PurgeDeletedEvents(ctx, retention)
+			if err != nil {
+				a.Logg.Error().Err(err).Msg("Failed to purge trash")
+				continue
+			}
+			a.Logg.Info().Msgf("Purged %d events from trash", purged)
+		}
+	}
+}
+
+// GetEvent returns the event if the user from ctx may read it, other events are not found.
+func (a *App) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	return a.getEvent(ctx, id, storage.ShareAccessRead)
+}
+
+// ListEvents returns events owned by the owner of the calendar the request works with and events
+// the owner is invited to. Without user and calendar in ctx all events are returned. Given tags
+// leave events labeled with all of them.
+func (a *App) ListEvents(ctx context.Context, tags ...string) ([]*storage.Event, error) {
+	ownerID, err := a.calendar(ctx, storage.ShareAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	var events []*storage.Event
+	if ownerID != "" {
+		events, err = a.Store.ListUserEvents(ctx, ownerID)
+	} else {
+		events, err = a.Store.ListEvents(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filterByTags(events, tags), nil
+}
+
+// GetEventHistory returns audit records of the event from the oldest to the newest. Users see
+// the history of events they may read, including events in the trash.
+func (a *App) GetEventHistory(ctx context.Context, id string) ([]*storage.AuditRecord, error) {
+	if UserIDFromContext(ctx) != "" {
+		if _, err := a.getEvent(ctx, id, storage.ShareAccessRead); err != nil {
+			var notFoundErr errs.ErrNotFoundEvent
+			if !errors.As(err, &notFoundErr) {
+				return nil, err
+			}
+			if _, err = a.getDeletedEvent(ctx, id, storage.ShareAccessRead); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return a.Store.ListAuditRecords(ctx, id)
+}
+
+// audit appends a record about the change. The change itself is already applied,
+// so a failure here is only logged and not returned to the caller. In a transaction
+// it is returned, the failed statement may have broken the transaction, e.g. in Postgres.
+func (a *App) audit(
+	ctx context.Context, action storage.AuditAction, eventID string, before, after *storage.Event,
+) error {
+	record := &storage.AuditRecord{
+		EventID:   eventID,
+		Actor:     UserIDFromContext(ctx),
+		Action:    action,
+		CreatedAt: time.Now().UTC(),
+	}
+	if before != nil {
+		record.Before = before.Clone()
+	}
+	if after != nil {
+		record.After = after.Clone()
+	}
+	if err := a.Store.AddAuditRecord(ctx, record); err != nil {
+		if a.pending != nil {
+			return err
+		}
+		a.Logg.Error().Err(err).Msgf("Failed to write audit record for event %s", eventID)
+	}
+	if a.pending != nil {
+		*a.pending = append(*a.pending, record)
+		return nil
+	}
+	a.notify(ctx, record)
 	return nil
-	// return a.storage.CreateEvent(storage.Event{ID: id, Title: title})
 }
 
-// TODO
+func (a *App) notify(ctx context.Context, record *storage.AuditRecord) {
+	for _, listener := range a.listeners {
+		listener.EventChanged(ctx, record)
+	}
+}
@@ -0,0 +1,139 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// newEvent returns an hour long event, its time doesn't matter for the test.
+func newEvent(title string) *storage.Event {
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+	return &storage.Event{Title: title, StartAt: start, EndAt: start.Add(time.Hour)}
+}
+
+// updatedEvent returns the new state of the event for UpdateEvent.
+func updatedEvent(id, title string) *storage.Event {
+	event := newEvent(title)
+	event.ID = id
+	return event
+}
+
+func TestEventHistory(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+
+	event := newEvent("meeting")
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+	require.NoError(t, calendar.UpdateEvent(ctx, updatedEvent(event.ID, "important meeting")))
+	_, err := calendar.ShareCalendar(ctx, "bob", storage.ShareAccessReadWrite)
+	require.NoError(t, err)
+	require.NoError(t, calendar.DeleteEvent(app.ContextWithUserID(ctx, "bob"), event.ID))
+
+	records, err := calendar.GetEventHistory(ctx, event.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	require.Equal(t, storage.AuditActionCreate, records[0].Action)
+	require.Equal(t, "alice", records[0].Actor)
+	require.Nil(t, records[0].Before)
+	require.Equal(t, "meeting", records[0].After.Title)
+
+	require.Equal(t, storage.AuditActionUpdate, records[1].Action)
+	require.Equal(t, "meeting", records[1].Before.Title)
+	require.Equal(t, "important meeting", records[1].After.Title)
+
+	require.Equal(t, storage.AuditActionDelete, records[2].Action)
+	require.Equal(t, "bob", records[2].Actor)
+	require.Equal(t, "important meeting", records[2].Before.Title)
+	require.Nil(t, records[2].After)
+}
+
+func TestChangeOfMissingEventIsNotAudited(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := context.Background()
+
+	err := calendar.UpdateEvent(ctx, &storage.Event{ID: "missing", Title: "title"})
+	require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+	require.ErrorAs(t, calendar.DeleteEvent(ctx, "missing"), &errs.ErrNotFoundEvent{})
+
+	records, err := calendar.GetEventHistory(ctx, "missing")
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+func TestRestoreEvent(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+
+	event := newEvent("meeting")
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+	require.NoError(t, calendar.DeleteEvent(ctx, event.ID))
+
+	events, err := calendar.ListEvents(ctx)
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	require.NoError(t, calendar.RestoreEvent(ctx, event.ID))
+	got, err := calendar.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event, got)
+
+	records, err := calendar.GetEventHistory(ctx, event.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, storage.AuditActionRestore, records[2].Action)
+	require.Equal(t, "meeting", records[2].After.Title)
+
+	purged, err := calendar.PurgeDeletedEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Zero(t, purged)
+}
+
+func TestInvitations(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	alice := app.ContextWithUserID(context.Background(), "alice")
+	bob := app.ContextWithUserID(context.Background(), "bob")
+
+	event := newEvent("meeting")
+	event.Attendees = []storage.Attendee{{UserID: "bob", Status: storage.AttendeeStatusAccepted}}
+	require.NoError(t, calendar.CreateEvent(alice, event))
+	require.Equal(t, "alice", event.UserID)
+	require.Equal(t, storage.AttendeeStatusNeedsAction, event.Attendees[0].Status)
+
+	require.NoError(t, calendar.InviteAttendees(alice, event.ID, []string{"carol"}))
+	require.NoError(t, calendar.RespondToInvitation(bob, event.ID, storage.AttendeeStatusAccepted))
+	require.ErrorAs(t, calendar.RespondToInvitation(bob, event.ID, "maybe"), &apperrors.ErrInvalidAttendeeStatus{})
+	require.ErrorAs(t, calendar.RespondToInvitation(context.Background(), event.ID, storage.AttendeeStatusAccepted),
+		&apperrors.ErrUserRequired{})
+	// events are not found for users who can't see them
+	require.ErrorAs(t, calendar.RespondToInvitation(app.ContextWithUserID(context.Background(), "dave"),
+		event.ID, storage.AttendeeStatusAccepted), &errs.ErrNotFoundEvent{})
+
+	events, err := calendar.ListEvents(bob)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, []string{"alice", "bob"}, events[0].NotificationRecipients())
+
+	events, err = calendar.ListEvents(app.ContextWithUserID(context.Background(), "dave"))
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	records, err := calendar.GetEventHistory(alice, event.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, "bob", records[2].Actor)
+	require.Equal(t, storage.AttendeeStatusAccepted, records[2].After.Attendees[0].Status)
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+)
+
+// BlobStore keeps content of attachments under keys chosen by the app.
+type BlobStore interface {
+	// Put saves content read from r under the key. Nothing is saved if reading r fails.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get returns content of the key or ErrNotFoundBlob, the caller has to close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes content of the key, a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	maxAttachmentNameLength = 255
+	// sniffLength is how much content http.DetectContentType considers.
+	sniffLength = 512
+	// orphanBatchSize is how many attachments of purged events are removed at once.
+	orphanBatchSize = 100
+)
+
+// SetBlobStore enables uploads of files up to maxSize bytes kept in blobs, only links can
+// be attached without it. Files of any size are accepted if maxSize is not positive.
+// It must be called before the app is used.
+func (a *App) SetBlobStore(blobs BlobStore, maxSize int64) {
+	a.blobs = blobs
+	a.maxAttachmentSize = maxSize
+}
+
+// sizeLimitReader counts bytes read and fails once more than max bytes are read, so the blob store
+// drops the content. Not positive max is no limit.
+type sizeLimitReader struct {
+	r    io.Reader
+	size int64
+	max  int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.size += int64(n)
+	if l.max > 0 && l.size > l.max {
+		return n, apperrors.ErrAttachmentTooLarge{MaxSize: l.max}
+	}
+	return n, err
+}
+
+func normalizeAttachmentName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", apperrors.ErrInvalidAttachment{Reason: "name must not be empty"}
+	}
+	if utf8.RuneCountInString(name) > maxAttachmentNameLength {
+		return "", apperrors.ErrInvalidAttachment{
+			Reason: fmt.Sprintf("name is longer than %d", maxAttachmentNameLength),
+		}
+	}
+	return name, nil
+}
+
+// UploadAttachment saves content as a file attached to the event by the user from ctx.
+// The content type is sniffed from the content, types declared by clients are not trusted.
+func (a *App) UploadAttachment(
+	ctx context.Context, eventID, name string, content io.Reader,
+) (*storage.Attachment, error) {
+	if a.blobs == nil {
+		return nil, apperrors.ErrInvalidAttachment{Reason: "uploads are not enabled"}
+	}
+	name, err := normalizeAttachmentName(name)
+	if err != nil {
+		return nil, err
+	}
+	// the content is not saved for events which do not exist
+	if _, err = a.getEvent(ContextWithReadYourWrites(ctx), eventID, storage.ShareAccessReadWrite); err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReaderSize(content, sniffLength)
+	head, err := buffered.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	limited := &sizeLimitReader{r: buffered, max: a.maxAttachmentSize}
+	key := xid.New().String()
+	if err = a.blobs.Put(ctx, key, limited); err != nil {
+		return nil, err
+	}
+	attachment := &storage.Attachment{
+		EventID:     eventID,
+		UserID:      UserIDFromContext(ctx),
+		Name:        name,
+		ContentType: http.DetectContentType(head),
+		Size:        limited.size,
+		BlobKey:     key,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err = a.Store.AddAttachment(ctx, attachment); err != nil {
+		if deleteErr := a.blobs.Delete(ctx, key); deleteErr != nil {
+			a.Logg.Error().Err(deleteErr).Msgf("Failed to delete content of attachment to event %s", eventID)
+		}
+		return nil, err
+	}
+	attachment.BlobKey = ""
+	return attachment, nil
+}
+
+// AddLink attaches the link to the event on behalf of the user from ctx, the link is its name
+// if the name is empty.
+func (a *App) AddLink(ctx context.Context, eventID, name, link string) (*storage.Attachment, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return nil, apperrors.ErrInvalidAttachment{Reason: err.Error()}
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, apperrors.ErrInvalidAttachment{Reason: "url must be absolute http or https url"}
+	}
+	if strings.TrimSpace(name) == "" {
+		name = link
+	}
+	if name, err = normalizeAttachmentName(name); err != nil {
+		return nil, err
+	}
+	if _, err = a.getEvent(ContextWithReadYourWrites(ctx), eventID, storage.ShareAccessReadWrite); err != nil {
+		return nil, err
+	}
+	attachment := &storage.Attachment{
+		EventID:   eventID,
+		UserID:    UserIDFromContext(ctx),
+		Name:      name,
+		URL:       link,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = a.Store.AddAttachment(ctx, attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// ListAttachments returns files and links attached to the event from the oldest to the newest.
+func (a *App) ListAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error) {
+	if _, err := a.getEvent(ctx, eventID, storage.ShareAccessRead); err != nil {
+		return nil, err
+	}
+	attachments, err := a.Store.ListEventAttachments(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		attachment.BlobKey = ""
+	}
+	return attachments, nil
+}
+
+// OpenAttachment returns the file attached to the event with its content, the caller has to
+// close the content. Attachments of events in the trash are not found.
+func (a *App) OpenAttachment(ctx context.Context, id string) (*storage.Attachment, io.ReadCloser, error) {
+	attachment, err := a.activeAttachment(ctx, id, storage.ShareAccessRead)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.IsLink() {
+		return nil, nil, apperrors.ErrInvalidAttachment{Reason: "link has no content"}
+	}
+	if a.blobs == nil {
+		return nil, nil, apperrors.ErrInvalidAttachment{Reason: "uploads are not enabled"}
+	}
+	content, err := a.blobs.Get(ctx, attachment.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment.BlobKey = ""
+	return attachment, content, nil
+}
+
+// DeleteAttachment removes the file or the link from the event together with its content.
+func (a *App) DeleteAttachment(ctx context.Context, id string) error {
+	attachment, err := a.activeAttachment(ContextWithReadYourWrites(ctx), id, storage.ShareAccessReadWrite)
+	if err != nil {
+		return err
+	}
+	// the content goes first, a failure leaves the attachment to be deleted again
+	if attachment.BlobKey != "" && a.blobs != nil {
+		if err = a.blobs.Delete(ctx, attachment.BlobKey); err != nil {
+			return err
+		}
+	}
+	return a.Store.DeleteAttachment(ctx, id)
+}
+
+// activeAttachment returns the attachment if its event is not in the trash and the user from ctx
+// has the required access to the event.
+func (a *App) activeAttachment(
+	ctx context.Context, id string, required storage.ShareAccess,
+) (*storage.Attachment, error) {
+	attachment, err := a.Store.GetAttachment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = a.getEvent(ctx, attachment.EventID, required); err != nil {
+		var notFoundErr errs.ErrNotFoundEvent
+		if errors.As(err, &notFoundErr) {
+			return nil, errs.ErrNotFoundAttachment{ID: id}
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// purgeAttachments removes attachments of purged events with their content. Without the blob
+// store they are kept, so their content can be removed once the store is configured again.
+func (a *App) purgeAttachments(ctx context.Context) error {
+	if a.blobs == nil {
+		return nil
+	}
+	for {
+		attachments, err := a.Store.ListOrphanedAttachments(ctx, orphanBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			if attachment.BlobKey != "" {
+				if err = a.blobs.Delete(ctx, attachment.BlobKey); err != nil {
+					return err
+				}
+			}
+			var notFoundErr errs.ErrNotFoundAttachment
+			if err = a.Store.DeleteAttachment(ctx, attachment.ID); err != nil && !errors.As(err, &notFoundErr) {
+				return err
+			}
+		}
+		if len(attachments) < orphanBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	localblobstore "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/blobstore/local"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough for the content type of a PNG image to be sniffed.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func TestAttachments(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	dir := t.TempDir()
+	blobs, err := localblobstore.New(dir)
+	require.NoError(t, err)
+	calendar.SetBlobStore(blobs, 1024)
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+
+	event := &storage.Event{Title: "planning", StartAt: start, EndAt: start.Add(time.Hour)}
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+
+	agenda, err := calendar.UploadAttachment(ctx, event.ID, " agenda.txt ", strings.NewReader("1. budget"))
+	require.NoError(t, err)
+	require.Equal(t, "agenda.txt", agenda.Name)
+	require.Equal(t, "alice", agenda.UserID)
+	require.Equal(t, "text/plain; charset=utf-8", agenda.ContentType)
+	require.Equal(t, int64(9), agenda.Size)
+	require.Empty(t, agenda.BlobKey)
+
+	// the declared extension does not matter, the type is sniffed
+	image, err := calendar.UploadAttachment(ctx, event.ID, "board.txt", bytes.NewReader(pngHeader))
+	require.NoError(t, err)
+	require.Equal(t, "image/png", image.ContentType)
+
+	link, err := calendar.AddLink(ctx, event.ID, "", "https://example.com/notes")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/notes", link.Name)
+
+	attachments, err := calendar.ListAttachments(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Attachment{agenda, image, link}, attachments)
+
+	t.Run("download", func(t *testing.T) {
+		got, content, err := calendar.OpenAttachment(ctx, agenda.ID)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(content)
+		require.NoError(t, err)
+		require.NoError(t, content.Close())
+		require.Equal(t, "1. budget", string(data))
+		require.Equal(t, agenda, got)
+
+		_, _, err = calendar.OpenAttachment(ctx, link.ID)
+		require.ErrorAs(t, err, &apperrors.ErrInvalidAttachment{})
+	})
+
+	t.Run("size is not limited without max size", func(t *testing.T) {
+		unlimited := app.New(logg, memorystorage.New(logg))
+		unlimitedBlobs, err := localblobstore.New(t.TempDir())
+		require.NoError(t, err)
+		unlimited.SetBlobStore(unlimitedBlobs, 0)
+		event := &storage.Event{Title: "planning", StartAt: start, EndAt: start.Add(time.Hour)}
+		require.NoError(t, unlimited.CreateEvent(ctx, event))
+
+		large, err := unlimited.UploadAttachment(ctx, event.ID, "large.bin", bytes.NewReader(make([]byte, 4096)))
+		require.NoError(t, err)
+		require.Equal(t, int64(4096), large.Size)
+	})
+
+	t.Run("invalid attachments", func(t *testing.T) {
+		_, err := calendar.UploadAttachment(ctx, event.ID, "large.bin", bytes.NewReader(make([]byte, 1025)))
+		require.ErrorAs(t, err, &apperrors.ErrAttachmentTooLarge{})
+		_, err = calendar.UploadAttachment(ctx, event.ID, " ", strings.NewReader("data"))
+		require.ErrorAs(t, err, &apperrors.ErrInvalidAttachment{})
+		_, err = calendar.UploadAttachment(ctx, "missing", "agenda.txt", strings.NewReader("data"))
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+		for _, invalid := range []string{"ftp://example.com/notes", "/notes", "https://"} {
+			_, err = calendar.AddLink(ctx, event.ID, "notes", invalid)
+			require.ErrorAs(t, err, &apperrors.ErrInvalidAttachment{}, invalid)
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 2, "content of rejected files is kept")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, calendar.DeleteAttachment(ctx, image.ID))
+		_, _, err := calendar.OpenAttachment(ctx, image.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundAttachment{})
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+	})
+
+	t.Run("attachments of purged events are removed with content", func(t *testing.T) {
+		require.NoError(t, calendar.DeleteEvent(ctx, event.ID))
+		_, _, err := calendar.OpenAttachment(ctx, agenda.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundAttachment{}, "event is in the trash")
+
+		_, err = calendar.PurgeDeletedEvents(ctx, 0)
+		require.NoError(t, err)
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+		_, err = calendar.Store.GetAttachment(ctx, link.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundAttachment{})
+	})
+}
+
+func TestUploadsAreDisabledWithoutBlobStore(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := context.Background()
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+	event := &storage.Event{Title: "planning", StartAt: start, EndAt: start.Add(time.Hour)}
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+
+	_, err := calendar.UploadAttachment(ctx, event.ID, "agenda.txt", strings.NewReader("data"))
+	require.ErrorAs(t, err, &apperrors.ErrInvalidAttachment{})
+	_, err = calendar.AddLink(ctx, event.ID, "notes", "https://example.com/notes")
+	require.NoError(t, err)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+type BatchOp string
+
+const (
+	BatchOpCreate BatchOp = "create"
+	BatchOpUpdate BatchOp = "update"
+	BatchOpDelete BatchOp = "delete"
+)
+
+// BatchOperation is a single change of the batch. Create and update take Event,
+// delete takes ID.
+type BatchOperation struct {
+	Op    BatchOp        `json:"op"`
+	Event *storage.Event `json:"event,omitempty"`
+	ID    string         `json:"id,omitempty"`
+}
+
+func (o BatchOperation) validate(index int) error {
+	switch o.Op {
+	case BatchOpCreate:
+		if o.Event == nil {
+			return apperrors.ErrInvalidBatchOperation{Index: index, Reason: "event is required"}
+		}
+	case BatchOpUpdate:
+		if o.Event == nil || o.Event.ID == "" {
+			return apperrors.ErrInvalidBatchOperation{Index: index, Reason: "event with id is required"}
+		}
+	case BatchOpDelete:
+		if o.ID == "" {
+			return apperrors.ErrInvalidBatchOperation{Index: index, Reason: "id is required"}
+		}
+	default:
+		return apperrors.ErrInvalidBatchOperation{Index: index, Reason: "unknown operation '" + string(o.Op) + "'"}
+	}
+	return nil
+}
+
+// ApplyBatch applies all operations in one transaction, so either all of them succeed
+// or none is applied. It returns resulting events in order of operations, nil for delete.
+func (a *App) ApplyBatch(ctx context.Context, operations []BatchOperation) ([]*storage.Event, error) {
+	for i, operation := range operations {
+		if err := operation.validate(i); err != nil {
+			return nil, err
+		}
+	}
+	results := make([]*storage.Event, len(operations))
+	var changes []*storage.AuditRecord
+	err := a.Store.WithTx(ctx, func(tx Storage) error {
+		// the transaction may be retried, changes of failed attempts are dropped
+		changes = changes[:0]
+		txApp := &App{Logg: a.Logg, Store: tx, pending: &changes}
+		for i, operation := range operations {
+			var err error
+			switch operation.Op {
+			case BatchOpCreate:
+				err = txApp.CreateEvent(ctx, operation.Event)
+				results[i] = operation.Event
+			case BatchOpUpdate:
+				err = txApp.UpdateEvent(ctx, operation.Event)
+				results[i] = operation.Event
+			case BatchOpDelete:
+				err = txApp.DeleteEvent(ctx, operation.ID)
+			}
+			if err != nil {
+				return apperrors.ErrBatchOperationFailed{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range changes {
+		a.notify(ctx, record)
+	}
+	return results, nil
+}
@@ -0,0 +1,126 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBatch(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+
+	existing := newEvent("existing")
+	removed := newEvent("removed")
+	require.NoError(t, calendar.CreateEvent(ctx, existing))
+	require.NoError(t, calendar.CreateEvent(ctx, removed))
+
+	t.Run("all operations are applied", func(t *testing.T) {
+		results, err := calendar.ApplyBatch(ctx, []app.BatchOperation{
+			{Op: app.BatchOpCreate, Event: newEvent("imported")},
+			{Op: app.BatchOpUpdate, Event: updatedEvent(existing.ID, "updated")},
+			{Op: app.BatchOpDelete, ID: removed.ID},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.Nil(t, results[2])
+
+		created, err := calendar.GetEvent(ctx, results[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, "alice", created.UserID)
+		updated, err := calendar.GetEvent(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Equal(t, "updated", updated.Title)
+		_, err = calendar.GetEvent(ctx, removed.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+
+		records, err := calendar.GetEventHistory(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+	})
+
+	t.Run("failed operation rolls back the batch", func(t *testing.T) {
+		imported := newEvent("not imported")
+		_, err := calendar.ApplyBatch(ctx, []app.BatchOperation{
+			{Op: app.BatchOpCreate, Event: imported},
+			{Op: app.BatchOpUpdate, Event: updatedEvent(existing.ID, "not updated")},
+			{Op: app.BatchOpDelete, ID: "missing"},
+		})
+		var failedErr apperrors.ErrBatchOperationFailed
+		require.ErrorAs(t, err, &failedErr)
+		require.Equal(t, 2, failedErr.Index)
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+
+		_, err = calendar.GetEvent(ctx, imported.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+		got, err := calendar.GetEvent(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Equal(t, "updated", got.Title)
+		records, err := calendar.GetEventHistory(ctx, existing.ID)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		_, err := calendar.ApplyBatch(ctx, []app.BatchOperation{
+			{Op: app.BatchOpCreate, Event: newEvent("title")},
+			{Op: "rename", ID: existing.ID},
+		})
+		var invalidErr apperrors.ErrInvalidBatchOperation
+		require.ErrorAs(t, err, &invalidErr)
+		require.Equal(t, 1, invalidErr.Index)
+	})
+}
+
+var errAuditFailed = errors.New("audit failed")
+
+// failingAuditStorage fails to write audit records in transactions.
+type failingAuditStorage struct {
+	app.Storage
+	inTx bool
+}
+
+func (s failingAuditStorage) AddAuditRecord(ctx context.Context, record *storage.AuditRecord) error {
+	if s.inTx {
+		return errAuditFailed
+	}
+	return s.Storage.AddAuditRecord(ctx, record)
+}
+
+func (s failingAuditStorage) WithTx(ctx context.Context, fn func(tx app.Storage) error) error {
+	return s.Storage.WithTx(ctx, func(tx app.Storage) error {
+		return fn(failingAuditStorage{Storage: tx, inTx: true})
+	})
+}
+
+func TestApplyBatchFailsOnAuditError(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, failingAuditStorage{Storage: memorystorage.New(logg)})
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+
+	// out of transactions the change is kept
+	existing := newEvent("existing")
+	require.NoError(t, calendar.CreateEvent(ctx, existing))
+
+	_, err := calendar.ApplyBatch(ctx, []app.BatchOperation{
+		{Op: app.BatchOpUpdate, Event: updatedEvent(existing.ID, "updated")},
+		{Op: app.BatchOpCreate, Event: newEvent("imported")},
+	})
+	var failedErr apperrors.ErrBatchOperationFailed
+	require.ErrorAs(t, err, &failedErr)
+	require.Equal(t, 0, failedErr.Index)
+	require.ErrorIs(t, err, errAuditFailed)
+
+	events, err := calendar.ListEvents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"existing"}, titles(events))
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	// changesHistorySize is how many latest changes are kept for subscribers resuming after reconnect.
+	changesHistorySize = 1024
+	// changesBufferSize is how many changes a subscriber may lag behind before it is dropped.
+	changesBufferSize = 64
+)
+
+// Change is a change of an event published to subscribers. IDs grow with every change
+// and keep growing after restart, so a subscriber can resume after the last received one.
+type Change struct {
+	ID     string
+	Record *storage.AuditRecord
+}
+
+// Subscription receives changes of events visible to the user.
+type Subscription struct {
+	// Changes is closed when ctx of the subscription is done, the subscriber lags behind too much
+	// or loses read access to the shared calendar it is subscribed to. In the latter cases it should
+	// resubscribe from the last change, access is checked again then.
+	Changes <-chan Change
+	// Reset is set when changes after the requested one are not kept anymore,
+	// so the subscriber has to reload events instead of resuming.
+	Reset bool
+}
+
+type publishedChange struct {
+	id     uint64
+	record *storage.AuditRecord
+	users  []string
+}
+
+type subscriber struct {
+	// userID is the owner of the calendar, viewerID is the user who subscribed to it
+	userID   string
+	viewerID string
+	changes  chan Change
+}
+
+// changeBroker publishes changes to subscribers and keeps the latest of them for resuming.
+type changeBroker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []publishedChange
+	subscribers map[*subscriber]struct{}
+}
+
+func newChangeBroker() *changeBroker {
+	return &changeBroker{
+		// IDs start from the current time, so IDs of the previous run are older than the history
+		lastID:      uint64(time.Now().UnixNano()),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *changeBroker) EventChanged(_ context.Context, record *storage.AuditRecord) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	change := publishedChange{id: b.lastID, record: record.Clone(), users: record.Users()}
+	b.history = append(b.history, change)
+	if len(b.history) > changesHistorySize {
+		b.history = b.history[1:]
+	}
+	for s := range b.subscribers {
+		if !change.visibleTo(s.userID) {
+			continue
+		}
+		select {
+		case s.changes <- change.toChange():
+		default:
+			b.removeLocked(s)
+		}
+	}
+}
+
+func (b *changeBroker) subscribe(ctx context.Context, userID, viewerID, lastID string) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var replay []Change
+	reset := false
+	if lastID != "" {
+		replay, reset = b.replayLocked(userID, lastID)
+	}
+	s := &subscriber{userID: userID, viewerID: viewerID, changes: make(chan Change, changesBufferSize+len(replay))}
+	for _, change := range replay {
+		s.changes <- change
+	}
+	b.subscribers[s] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.removeLocked(s)
+	}()
+	return &Subscription{Changes: s.changes, Reset: reset}
+}
+
+// replayLocked returns changes visible to the user after lastID, or reset if some of them are not kept.
+func (b *changeBroker) replayLocked(userID, lastID string) (replay []Change, reset bool) {
+	id, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil || id > b.lastID {
+		return nil, true
+	}
+	oldest := b.lastID + 1
+	if len(b.history) > 0 {
+		oldest = b.history[0].id
+	}
+	if id+1 < oldest {
+		return nil, true
+	}
+	for _, change := range b.history {
+		if change.id > id && change.visibleTo(userID) {
+			replay = append(replay, change.toChange())
+		}
+	}
+	return replay, false
+}
+
+// revoke drops subscribers of the viewer to the calendar of the owner, its share is revoked.
+func (b *changeBroker) revoke(ownerID, viewerID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subscribers {
+		if s.userID == ownerID && s.viewerID == viewerID {
+			b.removeLocked(s)
+		}
+	}
+}
+
+func (b *changeBroker) removeLocked(s *subscriber) {
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.changes)
+	}
+}
+
+func (c publishedChange) visibleTo(userID string) bool {
+	for _, user := range c.users {
+		if user == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c publishedChange) toChange() Change {
+	return Change{ID: strconv.FormatUint(c.id, 10), Record: c.record}
+}
+
+// SubscribeChanges subscribes the user from ctx to changes of events the owner of the calendar
+// the request works with owns or attends until ctx is done. With lastID set the subscription starts
+// with changes made after it.
+func (a *App) SubscribeChanges(ctx context.Context, lastID string) (*Subscription, error) {
+	userID, err := a.calendar(ctx, storage.ShareAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	return a.changes.subscribe(ctx, userID, UserIDFromContext(ctx), lastID), nil
+}
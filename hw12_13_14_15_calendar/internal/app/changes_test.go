@@ -0,0 +1,168 @@
+package app_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, subscription *app.Subscription) app.Change {
+	t.Helper()
+	select {
+	case change, ok := <-subscription.Changes:
+		require.True(t, ok, "subscription is closed")
+		return change
+	case <-time.After(time.Second):
+		require.FailNow(t, "no change received")
+	}
+	return app.Change{}
+}
+
+func requireNoChanges(t *testing.T, subscription *app.Subscription) {
+	t.Helper()
+	select {
+	case change := <-subscription.Changes:
+		require.FailNowf(t, "unexpected change", "%+v", change.Record)
+	default:
+	}
+}
+
+func TestSubscribeChanges(t *testing.T) {
+	newCalendar := func() *app.App {
+		logg := logger.New("error")
+		return app.New(logg, memorystorage.New(logg))
+	}
+	alice := app.ContextWithUserID(context.Background(), "alice")
+
+	t.Run("changes of owned and attended events", func(t *testing.T) {
+		calendar := newCalendar()
+		ctx, cancel := context.WithCancel(app.ContextWithUserID(context.Background(), "bob"))
+		defer cancel()
+		subscription, err := calendar.SubscribeChanges(ctx, "")
+		require.NoError(t, err)
+		require.False(t, subscription.Reset)
+
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("private")))
+		event := newEvent("meeting")
+		require.NoError(t, calendar.CreateEvent(alice, event))
+		require.NoError(t, calendar.InviteAttendees(alice, event.ID, []string{"bob"}))
+		require.NoError(t, calendar.DeleteEvent(alice, event.ID))
+
+		invited := receive(t, subscription)
+		require.Equal(t, storage.AuditActionUpdate, invited.Record.Action)
+		require.Equal(t, event.ID, invited.Record.EventID)
+		deleted := receive(t, subscription)
+		require.Equal(t, storage.AuditActionDelete, deleted.Record.Action)
+		require.NotEqual(t, invited.ID, deleted.ID)
+		requireNoChanges(t, subscription)
+
+		cancel()
+		require.Eventually(t, func() bool {
+			_, ok := <-subscription.Changes
+			return !ok
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("resume after the last change", func(t *testing.T) {
+		calendar := newCalendar()
+		ctx, cancel := context.WithCancel(alice)
+		subscription, err := calendar.SubscribeChanges(ctx, "")
+		require.NoError(t, err)
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("first")))
+		last := receive(t, subscription)
+		cancel()
+
+		second, third := newEvent("second"), newEvent("third")
+		require.NoError(t, calendar.CreateEvent(alice, second))
+		require.NoError(t, calendar.CreateEvent(alice, third))
+
+		ctx, cancel = context.WithCancel(alice)
+		defer cancel()
+		subscription, err = calendar.SubscribeChanges(ctx, last.ID)
+		require.NoError(t, err)
+		require.False(t, subscription.Reset)
+		require.Equal(t, second.ID, receive(t, subscription).Record.EventID)
+		require.Equal(t, third.ID, receive(t, subscription).Record.EventID)
+		requireNoChanges(t, subscription)
+	})
+
+	t.Run("reset for unknown change", func(t *testing.T) {
+		calendar := newCalendar()
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("first")))
+		for _, lastID := range []string{"1", "invalid", strconv.FormatUint(uint64(time.Now().Add(time.Hour).UnixNano()), 10)} {
+			ctx, cancel := context.WithCancel(alice)
+			subscription, err := calendar.SubscribeChanges(ctx, lastID)
+			require.NoError(t, err)
+			require.True(t, subscription.Reset, lastID)
+			requireNoChanges(t, subscription)
+			cancel()
+		}
+	})
+
+	t.Run("lagging subscriber is dropped", func(t *testing.T) {
+		calendar := newCalendar()
+		ctx, cancel := context.WithCancel(alice)
+		defer cancel()
+		subscription, err := calendar.SubscribeChanges(ctx, "")
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			require.NoError(t, calendar.CreateEvent(alice, newEvent("event")))
+		}
+		received := 0
+		for range subscription.Changes {
+			received++
+		}
+		require.Less(t, received, 100)
+	})
+
+	t.Run("subscription to shared calendar is closed when the share is revoked", func(t *testing.T) {
+		calendar := newCalendar()
+		_, err := calendar.ShareCalendar(alice, "bob", storage.ShareAccessRead)
+		require.NoError(t, err)
+		_, err = calendar.ShareCalendar(alice, "carol", storage.ShareAccessRead)
+		require.NoError(t, err)
+		sharedWith := func(userID string) context.Context {
+			return app.ContextWithCalendarID(app.ContextWithUserID(context.Background(), userID), "alice")
+		}
+		subscribe := func(userID string) *app.Subscription {
+			ctx, cancel := context.WithCancel(sharedWith(userID))
+			t.Cleanup(cancel)
+			subscription, err := calendar.SubscribeChanges(ctx, "")
+			require.NoError(t, err)
+			return subscription
+		}
+		bob, carol := subscribe("bob"), subscribe("carol")
+		owner := subscribe("alice")
+
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("shared")))
+		receive(t, bob)
+		receive(t, carol)
+		receive(t, owner)
+
+		require.NoError(t, calendar.RevokeShare(alice, "bob"))
+		_, err = calendar.ShareCalendar(alice, "carol", storage.ShareAccessFreeBusy)
+		require.NoError(t, err)
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("private")))
+		for _, subscription := range []*app.Subscription{bob, carol} {
+			_, ok := <-subscription.Changes
+			require.False(t, ok, "subscription is not closed")
+		}
+		receive(t, owner)
+
+		_, err = calendar.SubscribeChanges(sharedWith("bob"), "")
+		require.ErrorAs(t, err, &apperrors.ErrAccessDenied{})
+	})
+
+	t.Run("user is required", func(t *testing.T) {
+		_, err := newCalendar().SubscribeChanges(context.Background(), "")
+		require.ErrorAs(t, err, &apperrors.ErrUserRequired{})
+	})
+}
@@ -0,0 +1,47 @@
+package app
+
+import "context"
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	readYourWritesKey
+	calendarIDKey
+)
+
+// ContextWithUserID returns a copy of ctx carrying the ID of the user who makes the request.
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the user ID stored by ContextWithUserID or empty string.
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// ContextWithReadYourWrites returns a copy of ctx requiring reads to see all preceding writes,
+// so storages with read replicas serve them from the primary.
+func ContextWithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey, true)
+}
+
+// ReadYourWritesFromContext reports whether ctx was returned by ContextWithReadYourWrites.
+func ReadYourWritesFromContext(ctx context.Context) bool {
+	required, _ := ctx.Value(readYourWritesKey).(bool)
+	return required
+}
+
+// ContextWithCalendarID returns a copy of ctx working with the calendar of the user calendarID,
+// e.g. an assistant managing the calendar of a manager. The calendar has to be shared with the user
+// who makes the request.
+func ContextWithCalendarID(ctx context.Context, calendarID string) context.Context {
+	return context.WithValue(ctx, calendarIDKey, calendarID)
+}
+
+// CalendarIDFromContext returns the calendar stored by ContextWithCalendarID or empty string.
+func CalendarIDFromContext(ctx context.Context) string {
+	calendarID, _ := ctx.Value(calendarIDKey).(string)
+	return calendarID
+}
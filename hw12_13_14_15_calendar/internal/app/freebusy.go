@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+type TimeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeSlots returns slots in [from, to) at least minDuration long, when none of users is busy.
+// User is busy during events they own and events they are invited to, unless they declined.
+// Calendars of other users have to be shared with the user from ctx, at least as free/busy.
+func (a *App) FreeSlots(
+	ctx context.Context, userIDs []string, from, to time.Time, minDuration time.Duration,
+) ([]TimeSlot, error) {
+	switch {
+	case len(userIDs) == 0:
+		return nil, apperrors.ErrInvalidFreeBusyQuery{Reason: "at least one user is required"}
+	case !from.Before(to):
+		return nil, apperrors.ErrInvalidFreeBusyQuery{Reason: "range start must be before its end"}
+	case minDuration < 0:
+		return nil, apperrors.ErrInvalidFreeBusyQuery{Reason: "duration must not be negative"}
+	}
+	if err := a.checkCalendarAccess(ctx, storage.ShareAccessFreeBusy, userIDs...); err != nil {
+		return nil, err
+	}
+	events, err := a.Store.ListUsersEventsInRange(ctx, userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make([]TimeSlot, 0, len(events))
+	for _, event := range events {
+		if isBusy(event, userIDs) {
+			busy = append(busy, TimeSlot{Start: maxTime(event.StartAt, from), End: minTime(event.EndAt, to)})
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool {
+		return busy[i].Start.Before(busy[j].Start)
+	})
+
+	free := make([]TimeSlot, 0)
+	cursor := from
+	for _, slot := range busy {
+		if slot.Start.Sub(cursor) >= minDuration && slot.Start.After(cursor) {
+			free = append(free, TimeSlot{Start: cursor, End: slot.Start})
+		}
+		cursor = maxTime(cursor, slot.End)
+	}
+	if to.Sub(cursor) >= minDuration && to.After(cursor) {
+		free = append(free, TimeSlot{Start: cursor, End: to})
+	}
+	return free, nil
+}
+
+func isBusy(event *storage.Event, userIDs []string) bool {
+	for _, userID := range userIDs {
+		if event.UserID == userID {
+			return true
+		}
+		for _, attendee := range event.Attendees {
+			if attendee.UserID == userID && attendee.Status != storage.AttendeeStatusDeclined {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
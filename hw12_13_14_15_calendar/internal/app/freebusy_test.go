@@ -0,0 +1,97 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeSlots(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	day := time.Date(2022, time.October, 24, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	create := func(owner string, start, end time.Time, attendees ...string) *storage.Event {
+		event := &storage.Event{Title: "event", StartAt: start, EndAt: end}
+		for _, attendee := range attendees {
+			event.Attendees = append(event.Attendees, storage.Attendee{UserID: attendee})
+		}
+		require.NoError(t, calendar.CreateEvent(app.ContextWithUserID(context.Background(), owner), event))
+		return event
+	}
+
+	create("alice", at(9, 0), at(10, 0))
+	create("bob", at(9, 30), at(11, 0))
+	create("carol", at(13, 0), at(13, 30))
+	create("dave", at(15, 0), at(16, 0), "carol")
+	declined := create("dave", at(16, 0), at(17, 0), "carol")
+	require.NoError(t, calendar.RespondToInvitation(
+		app.ContextWithUserID(context.Background(), "carol"), declined.ID, storage.AttendeeStatusDeclined))
+	create("alice", at(7, 0), at(8, 30))
+	create("alice", at(17, 30), at(19, 0))
+
+	tests := []struct {
+		name     string
+		users    []string
+		duration time.Duration
+		expected []app.TimeSlot
+	}{
+		{
+			name:  "all users",
+			users: []string{"alice", "bob", "carol"},
+			expected: []app.TimeSlot{
+				{Start: at(8, 30), End: at(9, 0)},
+				{Start: at(11, 0), End: at(13, 0)},
+				{Start: at(13, 30), End: at(15, 0)},
+				{Start: at(16, 0), End: at(17, 30)},
+			},
+		},
+		{
+			name:     "long enough slots",
+			users:    []string{"alice", "bob", "carol"},
+			duration: 90 * time.Minute,
+			expected: []app.TimeSlot{
+				{Start: at(11, 0), End: at(13, 0)},
+				{Start: at(13, 30), End: at(15, 0)},
+				{Start: at(16, 0), End: at(17, 30)},
+			},
+		},
+		{
+			name:     "too long duration",
+			users:    []string{"alice", "bob", "carol"},
+			duration: 3 * time.Hour,
+			expected: []app.TimeSlot{},
+		},
+		{
+			name:  "user without events",
+			users: []string{"eve"},
+			expected: []app.TimeSlot{
+				{Start: at(8, 0), End: at(18, 0)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			slots, err := calendar.FreeSlots(context.Background(), tc.users, at(8, 0), at(18, 0), tc.duration)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, slots)
+		})
+	}
+
+	t.Run("invalid query", func(t *testing.T) {
+		_, err := calendar.FreeSlots(context.Background(), nil, at(8, 0), at(18, 0), 0)
+		require.ErrorAs(t, err, &apperrors.ErrInvalidFreeBusyQuery{})
+		_, err = calendar.FreeSlots(context.Background(), []string{"alice"}, at(18, 0), at(8, 0), 0)
+		require.ErrorAs(t, err, &apperrors.ErrInvalidFreeBusyQuery{})
+	})
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+const defaultTimeZone = "UTC"
+
+// normalizeEventTime checks the time zone, the time and the reminder of the event, stretches all-day events
+// to whole days in it and converts the event time to UTC.
+func normalizeEventTime(event *storage.Event) error {
+	if event.NotifyBefore < 0 {
+		return apperrors.ErrInvalidEvent{Reason: "notify_before must not be negative"}
+	}
+	if event.TimeZone == "" {
+		event.TimeZone = defaultTimeZone
+	}
+	loc, err := time.LoadLocation(event.TimeZone)
+	if err != nil {
+		return apperrors.ErrInvalidTimeZone{Name: event.TimeZone}
+	}
+	if event.AllDay {
+		// dates of all-day events are taken as they are written by the client,
+		// midnight is looked up in the zone of the event
+		start := startOfDay(event.StartAt, loc)
+		end := startOfDay(event.EndAt, loc)
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+		event.StartAt, event.EndAt = start, end
+	}
+	// events without time never overlap a period, so they would be lost for listings and free/busy
+	if !event.EndAt.After(event.StartAt) {
+		return apperrors.ErrInvalidEvent{Reason: "end_at must be after start_at"}
+	}
+	event.StartAt, event.EndAt = event.StartAt.UTC(), event.EndAt.UTC()
+	return nil
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// ListDayEvents returns events of the day. The day is taken in the location of day,
+// event time in the result is in the same location. Given tags filter events as in ListEvents.
+func (a *App) ListDayEvents(ctx context.Context, day time.Time, tags ...string) ([]*storage.Event, error) {
+	start := startOfDay(day, day.Location())
+	return a.listEventsInRange(ctx, start, start.AddDate(0, 0, 1), tags)
+}
+
+// ListWeekEvents returns events of 7 days starting from weekStart, see ListDayEvents.
+func (a *App) ListWeekEvents(ctx context.Context, weekStart time.Time, tags ...string) ([]*storage.Event, error) {
+	start := startOfDay(weekStart, weekStart.Location())
+	return a.listEventsInRange(ctx, start, start.AddDate(0, 0, 7), tags)
+}
+
+// ListMonthEvents returns events of the month starting from monthStart, see ListDayEvents.
+func (a *App) ListMonthEvents(ctx context.Context, monthStart time.Time, tags ...string) ([]*storage.Event, error) {
+	start := startOfDay(monthStart, monthStart.Location())
+	return a.listEventsInRange(ctx, start, start.AddDate(0, 1, 0), tags)
+}
+
+func (a *App) listEventsInRange(ctx context.Context, from, to time.Time, tags []string) ([]*storage.Event, error) {
+	ownerID, err := a.calendar(ctx, storage.ShareAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	var events []*storage.Event
+	if ownerID != "" {
+		events, err = a.Store.ListUsersEventsInRange(ctx, []string{ownerID}, from, to)
+	} else {
+		events, err = a.Store.ListEventsInRange(ctx, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	events = filterByTags(events, tags)
+	for _, event := range events {
+		event.StartAt, event.EndAt = event.StartAt.In(from.Location()), event.EndAt.In(from.Location())
+	}
+	return events, nil
+}
@@ -0,0 +1,175 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata" // tests must not depend on tzdata of the host
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func titles(events []*storage.Event) []string {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Title)
+	}
+	return result
+}
+
+func TestEventTimeIsStoredInUTC(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := context.Background()
+	moscow := loadLocation(t, "Europe/Moscow")
+
+	event := &storage.Event{
+		Title:    "meeting",
+		StartAt:  time.Date(2022, time.October, 24, 10, 0, 0, 0, moscow),
+		EndAt:    time.Date(2022, time.October, 24, 11, 0, 0, 0, moscow),
+		TimeZone: "Europe/Moscow",
+	}
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+
+	got, err := calendar.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2022, time.October, 24, 7, 0, 0, 0, time.UTC), got.StartAt)
+	require.Equal(t, time.UTC, got.StartAt.Location())
+	require.Equal(t, "Europe/Moscow", got.TimeZone)
+
+	require.ErrorAs(t, calendar.CreateEvent(ctx, &storage.Event{Title: "bad", TimeZone: "Mars/Olympus"}),
+		&apperrors.ErrInvalidTimeZone{})
+	require.NoError(t, calendar.CreateEvent(ctx, newEvent("no zone")))
+	require.ErrorAs(t, calendar.CreateEvent(ctx, &storage.Event{Title: "bad", NotifyBefore: -time.Minute}),
+		&apperrors.ErrInvalidEvent{})
+}
+
+func TestEventMustEndAfterStart(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+
+	for name, event := range map[string]*storage.Event{
+		"without time":        {Title: "no time"},
+		"ending at the start": {Title: "empty", StartAt: start, EndAt: start},
+		"ending before start": {Title: "backwards", StartAt: start, EndAt: start.Add(-time.Hour)},
+	} {
+		require.ErrorAs(t, calendar.CreateEvent(ctx, event), &apperrors.ErrInvalidEvent{}, name)
+	}
+
+	event := newEvent("meeting")
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+	update := *event
+	update.EndAt = update.StartAt.Add(-time.Minute)
+	require.ErrorAs(t, calendar.UpdateEvent(ctx, &update), &apperrors.ErrInvalidEvent{})
+	got, err := calendar.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event.EndAt, got.EndAt)
+
+	// all-day events without an end last a day
+	allDay := &storage.Event{Title: "holiday", StartAt: start, AllDay: true}
+	require.NoError(t, calendar.CreateEvent(ctx, allDay))
+	require.Equal(t, start.Add(-10*time.Hour).AddDate(0, 0, 1), allDay.EndAt)
+}
+
+func TestAllDayEventsAcrossDST(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		zone          string
+		date          time.Time
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{
+			name:          "spring forward day lasts 23 hours",
+			zone:          "America/New_York",
+			date:          time.Date(2022, time.March, 13, 0, 0, 0, 0, time.UTC),
+			expectedStart: time.Date(2022, time.March, 13, 5, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2022, time.March, 14, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "fall back day lasts 25 hours",
+			zone:          "Europe/Berlin",
+			date:          time.Date(2022, time.October, 30, 0, 0, 0, 0, time.UTC),
+			expectedStart: time.Date(2022, time.October, 29, 22, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2022, time.October, 30, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "zone without DST",
+			zone:          "Europe/Moscow",
+			date:          time.Date(2022, time.October, 30, 0, 0, 0, 0, time.UTC),
+			expectedStart: time.Date(2022, time.October, 29, 21, 0, 0, 0, time.UTC),
+			expectedEnd:   time.Date(2022, time.October, 30, 21, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			event := &storage.Event{Title: tc.name, StartAt: tc.date, TimeZone: tc.zone, AllDay: true}
+			require.NoError(t, calendar.CreateEvent(ctx, event))
+			require.Equal(t, tc.expectedStart, event.StartAt)
+			require.Equal(t, tc.expectedEnd, event.EndAt)
+		})
+	}
+}
+
+func TestListingsInCallerZone(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := context.Background()
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	create := func(title string, start time.Time, duration time.Duration) {
+		require.NoError(t, calendar.CreateEvent(ctx, &storage.Event{
+			Title: title, StartAt: start, EndAt: start.Add(duration), TimeZone: "Europe/Berlin",
+		}))
+	}
+	// 27.03.2022 clocks in Berlin jump from 02:00 to 03:00, 30.10.2022 - from 03:00 to 02:00
+	create("before spring day", time.Date(2022, time.March, 26, 23, 0, 0, 0, berlin), 30*time.Minute)
+	create("spring day start", time.Date(2022, time.March, 27, 0, 30, 0, 0, berlin), 30*time.Minute)
+	create("spring day end", time.Date(2022, time.March, 27, 23, 30, 0, 0, berlin), 15*time.Minute)
+	create("after spring day", time.Date(2022, time.March, 28, 0, 15, 0, 0, berlin), 30*time.Minute)
+	create("fall day end", time.Date(2022, time.October, 30, 23, 30, 0, 0, berlin), 15*time.Minute)
+	create("after fall day", time.Date(2022, time.October, 31, 0, 15, 0, 0, berlin), 30*time.Minute)
+	create("next month", time.Date(2022, time.November, 1, 0, 15, 0, 0, berlin), 30*time.Minute)
+
+	events, err := calendar.ListDayEvents(ctx, time.Date(2022, time.March, 27, 0, 0, 0, 0, berlin))
+	require.NoError(t, err)
+	require.Equal(t, []string{"spring day start", "spring day end"}, titles(events))
+	require.Equal(t, berlin, events[0].StartAt.Location())
+	require.Equal(t, 0, events[0].StartAt.Hour())
+
+	// the same day in UTC contains other events
+	events, err = calendar.ListDayEvents(ctx, time.Date(2022, time.March, 27, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []string{"spring day end", "after spring day"}, titles(events))
+
+	events, err = calendar.ListDayEvents(ctx, time.Date(2022, time.October, 30, 0, 0, 0, 0, berlin))
+	require.NoError(t, err)
+	require.Equal(t, []string{"fall day end"}, titles(events))
+
+	events, err = calendar.ListWeekEvents(ctx, time.Date(2022, time.March, 21, 0, 0, 0, 0, berlin))
+	require.NoError(t, err)
+	require.Equal(t, []string{"before spring day", "spring day start", "spring day end"}, titles(events))
+
+	events, err = calendar.ListMonthEvents(ctx, time.Date(2022, time.October, 1, 0, 0, 0, 0, berlin))
+	require.NoError(t, err)
+	require.Equal(t, []string{"fall day end", "after fall day"}, titles(events))
+}
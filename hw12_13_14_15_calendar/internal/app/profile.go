@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"net/mail"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// GetProfile returns the profile of the user from ctx.
+func (a *App) GetProfile(ctx context.Context) (*storage.UserProfile, error) {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	return a.Store.GetUserProfile(ctx, userID)
+}
+
+// UpdateProfile replaces the profile of the user from ctx. Repeated channels are dropped,
+// the email channel requires an email.
+func (a *App) UpdateProfile(ctx context.Context, profile *storage.UserProfile) error {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return apperrors.ErrUserRequired{}
+	}
+	if profile.Email != "" {
+		address, err := mail.ParseAddress(profile.Email)
+		if err != nil {
+			return apperrors.ErrInvalidProfile{Reason: "invalid email: " + err.Error()}
+		}
+		profile.Email = address.Address
+	}
+	channels := make([]storage.NotificationChannel, 0, len(profile.Channels))
+	for _, channel := range profile.Channels {
+		if !channel.Valid() {
+			return apperrors.ErrInvalidProfile{Reason: "unknown channel '" + string(channel) + "'"}
+		}
+		if channel == storage.NotificationChannelEmail && profile.Email == "" {
+			return apperrors.ErrInvalidProfile{Reason: "email channel requires email"}
+		}
+		if !containsChannel(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	profile.Channels = channels
+	profile.UserID = userID
+	profile.UpdatedAt = time.Now().UTC()
+	return a.Store.SaveUserProfile(ctx, profile)
+}
+
+func containsChannel(channels []storage.NotificationChannel, channel storage.NotificationChannel) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateProfile(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+
+	_, err := calendar.GetProfile(ctx)
+	require.ErrorAs(t, err, &errs.ErrNotFoundUserProfile{})
+
+	require.NoError(t, calendar.UpdateProfile(ctx, &storage.UserProfile{
+		UserID: "mallory",
+		Email:  "Alice <alice@example.com>",
+		Channels: []storage.NotificationChannel{
+			storage.NotificationChannelEmail, storage.NotificationChannelStdout, storage.NotificationChannelEmail,
+		},
+	}))
+	profile, err := calendar.GetProfile(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "alice", profile.UserID)
+	require.Equal(t, "alice@example.com", profile.Email)
+	require.Equal(t, []storage.NotificationChannel{
+		storage.NotificationChannelEmail, storage.NotificationChannelStdout,
+	}, profile.Channels)
+
+	for name, invalid := range map[string]*storage.UserProfile{
+		"unknown channel":       {Channels: []storage.NotificationChannel{"pigeon"}},
+		"email without address": {Channels: []storage.NotificationChannel{storage.NotificationChannelEmail}},
+		"invalid email":         {Email: "alice"},
+	} {
+		err = calendar.UpdateProfile(ctx, invalid)
+		require.ErrorAs(t, err, &apperrors.ErrInvalidProfile{}, name)
+	}
+
+	err = calendar.UpdateProfile(context.Background(), &storage.UserProfile{})
+	require.ErrorAs(t, err, &apperrors.ErrUserRequired{})
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/quickadd"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// QuickAddEvent creates the event described by text, e.g. "Lunch with Sam tomorrow 1pm for 1h",
+// see quickadd.Parse. Relative dates and times of the text are taken in loc, the zone of the caller,
+// which becomes the zone of the event.
+func (a *App) QuickAddEvent(ctx context.Context, text string, loc *time.Location) (*storage.Event, error) {
+	event, err := quickadd.Parse(text, time.Now().In(loc))
+	if err != nil {
+		return nil, apperrors.ErrInvalidQuickAdd{Reason: err.Error()}
+	}
+	if err = a.CreateEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
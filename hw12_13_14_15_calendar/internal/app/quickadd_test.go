@@ -0,0 +1,34 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickAddEvent(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+	moscow := loadLocation(t, "Europe/Moscow")
+
+	event, err := calendar.QuickAddEvent(ctx, "Lunch with Sam 2030-01-15 13:00 for 30m", moscow)
+	require.NoError(t, err)
+	require.Equal(t, "Lunch with Sam", event.Title)
+	require.Equal(t, "alice", event.UserID)
+	require.Equal(t, "Europe/Moscow", event.TimeZone)
+	require.Equal(t, time.Date(2030, time.January, 15, 10, 0, 0, 0, time.UTC), event.StartAt)
+	require.Equal(t, 30*time.Minute, event.EndAt.Sub(event.StartAt))
+	got, err := calendar.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event.Title, got.Title)
+
+	_, err = calendar.QuickAddEvent(ctx, "Lunch with Sam", moscow)
+	require.ErrorAs(t, err, &apperrors.ErrInvalidQuickAdd{})
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+const maxResourceNameLength = 255
+
+func validateResource(resource *storage.Resource) error {
+	resource.Name = strings.TrimSpace(resource.Name)
+	switch {
+	case resource.Name == "":
+		return apperrors.ErrInvalidResource{Reason: "name must not be empty"}
+	case utf8.RuneCountInString(resource.Name) > maxResourceNameLength:
+		return apperrors.ErrInvalidResource{Reason: fmt.Sprintf("name is longer than %d", maxResourceNameLength)}
+	case !resource.Kind.Valid():
+		return apperrors.ErrInvalidResource{Reason: fmt.Sprintf("unknown kind '%s'", resource.Kind)}
+	case resource.Capacity < 0:
+		return apperrors.ErrInvalidResource{Reason: "capacity must not be negative"}
+	}
+	return nil
+}
+
+// normalizeResources drops repeated resources of the event and sorts them. Resources have
+// to exist, whether they are free is checked by the storage together with the change.
+func (a *App) normalizeResources(ctx context.Context, event *storage.Event) error {
+	if len(event.Resources) == 0 {
+		event.Resources = nil
+		return nil
+	}
+	resources := make([]string, 0, len(event.Resources))
+	seen := make(map[string]bool, len(event.Resources))
+	for _, id := range event.Resources {
+		if seen[id] {
+			continue
+		}
+		if _, err := a.Store.GetResource(ctx, id); err != nil {
+			return err
+		}
+		seen[id] = true
+		resources = append(resources, id)
+	}
+	sort.Strings(resources)
+	event.Resources = resources
+	return nil
+}
+
+// CreateResource adds a room or a piece of equipment which can be booked for events.
+func (a *App) CreateResource(ctx context.Context, resource *storage.Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+	return a.Store.AddResource(ctx, resource)
+}
+
+// UpdateResource replaces the name, the kind and the capacity of the resource, its bookings are kept.
+func (a *App) UpdateResource(ctx context.Context, resource *storage.Resource) error {
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+	return a.Store.ModifyResource(ctx, resource)
+}
+
+func (a *App) GetResource(ctx context.Context, id string) (*storage.Resource, error) {
+	return a.Store.GetResource(ctx, id)
+}
+
+// ListResources returns all resources sorted by name.
+func (a *App) ListResources(ctx context.Context) ([]*storage.Resource, error) {
+	return a.Store.ListResources(ctx)
+}
+
+// ListResourceEvents returns the calendar of the resource: events it is booked for which
+// overlap with [from, to), sorted by start. Only time is shown of events the user from ctx can't read.
+func (a *App) ListResourceEvents(ctx context.Context, id string, from, to time.Time) ([]*storage.Event, error) {
+	if !from.Before(to) {
+		return nil, apperrors.ErrInvalidFreeBusyQuery{Reason: "range start must be before its end"}
+	}
+	if _, err := a.Store.GetResource(ctx, id); err != nil {
+		return nil, err
+	}
+	events, err := a.Store.ListResourcesEventsInRange(ctx, []string{id}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return a.busyOnly(ctx, events)
+}
+
+// ListAvailableResources returns resources which are not booked for any event within [from, to),
+// sorted by name. Only resources of the kind, if it is not empty, seating at least minCapacity
+// people are returned.
+func (a *App) ListAvailableResources(
+	ctx context.Context, from, to time.Time, kind storage.ResourceKind, minCapacity int,
+) ([]*storage.Resource, error) {
+	switch {
+	case !from.Before(to):
+		return nil, apperrors.ErrInvalidFreeBusyQuery{Reason: "range start must be before its end"}
+	case kind != "" && !kind.Valid():
+		return nil, apperrors.ErrInvalidFreeBusyQuery{Reason: fmt.Sprintf("unknown resource kind '%s'", kind)}
+	}
+	resources, err := a.Store.ListResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]*storage.Resource, 0, len(resources))
+	ids := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		if (kind == "" || resource.Kind == kind) && resource.Capacity >= minCapacity {
+			candidates = append(candidates, resource)
+			ids = append(ids, resource.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+	events, err := a.Store.ListResourcesEventsInRange(ctx, ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+	available := make([]*storage.Resource, 0, len(candidates))
+	for _, resource := range candidates {
+		booked := false
+		for _, event := range events {
+			if event.UsesResource(resource.ID) {
+				booked = true
+				break
+			}
+		}
+		if !booked {
+			available = append(available, resource)
+		}
+	}
+	return available, nil
+}
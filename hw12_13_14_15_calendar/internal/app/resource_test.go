@@ -0,0 +1,124 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceBooking(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	alice := app.ContextWithUserID(context.Background(), "alice")
+	bob := app.ContextWithUserID(context.Background(), "bob")
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+
+	everest := &storage.Resource{Name: " Everest ", Kind: storage.ResourceKindRoom, Capacity: 10}
+	elbrus := &storage.Resource{Name: "Elbrus", Kind: storage.ResourceKindRoom, Capacity: 4}
+	projector := &storage.Resource{Name: "Projector", Kind: storage.ResourceKindEquipment}
+	for _, resource := range []*storage.Resource{everest, elbrus, projector} {
+		require.NoError(t, calendar.CreateResource(alice, resource))
+	}
+	require.Equal(t, "Everest", everest.Name)
+	resources, err := calendar.ListResources(alice)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Resource{elbrus, everest, projector}, resources)
+
+	planning := &storage.Event{
+		Title: "planning", StartAt: start, EndAt: start.Add(time.Hour),
+		Resources: []string{everest.ID, projector.ID, everest.ID},
+	}
+	require.NoError(t, calendar.CreateEvent(alice, planning))
+	require.Len(t, planning.Resources, 2)
+
+	t.Run("double booking is rejected", func(t *testing.T) {
+		overlapping := &storage.Event{
+			Title: "retro", StartAt: start.Add(30 * time.Minute), EndAt: start.Add(90 * time.Minute),
+			Resources: []string{everest.ID},
+		}
+		var busyErr errs.ErrResourceBusy
+		require.ErrorAs(t, calendar.CreateEvent(bob, overlapping), &busyErr)
+		require.Equal(t, everest.ID, busyErr.ResourceID)
+		require.Equal(t, planning.ID, busyErr.EventID)
+
+		overlapping.Resources = []string{elbrus.ID}
+		require.NoError(t, calendar.CreateEvent(bob, overlapping))
+		overlapping.Resources = []string{elbrus.ID, projector.ID}
+		require.ErrorAs(t, calendar.UpdateEvent(bob, overlapping), &errs.ErrResourceBusy{})
+		history, err := calendar.GetEventHistory(bob, overlapping.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1, "rejected update is not audited")
+	})
+
+	t.Run("calendar of the resource", func(t *testing.T) {
+		events, err := calendar.ListResourceEvents(alice, everest.ID, start.Add(-time.Hour), start.Add(time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, []string{"planning"}, titles(events))
+		// others see only when the resource is booked
+		events, err = calendar.ListResourceEvents(bob, everest.ID, start.Add(-time.Hour), start.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, planning.ID, events[0].ID)
+		require.Empty(t, events[0].Title)
+		require.Equal(t, planning.StartAt, events[0].StartAt)
+		events, err = calendar.ListResourceEvents(bob, everest.ID, start.Add(time.Hour), start.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		_, err = calendar.ListResourceEvents(bob, "missing", start, start.Add(time.Hour))
+		require.ErrorAs(t, err, &errs.ErrNotFoundResource{})
+		_, err = calendar.ListResourceEvents(bob, everest.ID, start, start)
+		require.ErrorAs(t, err, &apperrors.ErrInvalidFreeBusyQuery{})
+	})
+
+	t.Run("available resources", func(t *testing.T) {
+		available, err := calendar.ListAvailableResources(bob, start, start.Add(time.Hour), "", 0)
+		require.NoError(t, err)
+		require.Empty(t, available)
+
+		later := start.Add(90 * time.Minute)
+		available, err = calendar.ListAvailableResources(bob, later, later.Add(time.Hour), "", 0)
+		require.NoError(t, err)
+		require.Equal(t, []*storage.Resource{elbrus, everest, projector}, available)
+		available, err = calendar.ListAvailableResources(bob, later, later.Add(time.Hour), storage.ResourceKindRoom, 5)
+		require.NoError(t, err)
+		require.Equal(t, []*storage.Resource{everest}, available)
+
+		_, err = calendar.ListAvailableResources(bob, later, later.Add(time.Hour), "boat", 0)
+		require.ErrorAs(t, err, &apperrors.ErrInvalidFreeBusyQuery{})
+	})
+
+	t.Run("invalid resources", func(t *testing.T) {
+		for name, resource := range map[string]*storage.Resource{
+			"empty name":        {Name: " ", Kind: storage.ResourceKindRoom},
+			"unknown kind":      {Name: "Boat", Kind: "boat"},
+			"negative capacity": {Name: "Closet", Kind: storage.ResourceKindRoom, Capacity: -1},
+		} {
+			require.ErrorAs(t, calendar.CreateResource(alice, resource), &apperrors.ErrInvalidResource{}, name)
+		}
+		event := &storage.Event{
+			Title: "offsite", StartAt: start, EndAt: start.Add(time.Hour), Resources: []string{"missing"},
+		}
+		require.ErrorAs(t, calendar.CreateEvent(alice, event), &errs.ErrNotFoundResource{})
+	})
+
+	t.Run("restored event needs its resources free", func(t *testing.T) {
+		require.NoError(t, calendar.DeleteEvent(alice, planning.ID))
+		standup := &storage.Event{
+			Title: "standup", StartAt: start, EndAt: start.Add(15 * time.Minute), Resources: []string{everest.ID},
+		}
+		require.NoError(t, calendar.CreateEvent(bob, standup))
+		require.ErrorAs(t, calendar.RestoreEvent(alice, planning.ID), &errs.ErrResourceBusy{})
+
+		require.NoError(t, calendar.DeleteEvent(bob, standup.ID))
+		require.NoError(t, calendar.RestoreEvent(alice, planning.ID))
+	})
+}
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// ownerAccess is the access of users to their own calendars and of requests without user,
+// which are made by the service itself.
+const ownerAccess storage.ShareAccess = storage.ShareAccessReadWrite
+
+// grants returns access of the user from ctx to calendars of other users by their owners.
+func (a *App) grants(ctx context.Context) (map[string]storage.ShareAccess, error) {
+	shares, err := a.Store.ListUserShares(ctx, UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	grants := make(map[string]storage.ShareAccess, len(shares))
+	for _, share := range shares {
+		grants[share.OwnerID] = share.Access
+	}
+	return grants, nil
+}
+
+// checkCalendarAccess fails with ErrAccessDenied unless the user from ctx has the required access
+// to calendars of all of the owners.
+func (a *App) checkCalendarAccess(ctx context.Context, required storage.ShareAccess, ownerIDs ...string) error {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return nil
+	}
+	var grants map[string]storage.ShareAccess
+	for _, ownerID := range ownerIDs {
+		if ownerID == userID {
+			continue
+		}
+		if grants == nil {
+			var err error
+			if grants, err = a.grants(ctx); err != nil {
+				return err
+			}
+		}
+		if !grants[ownerID].Allows(required) {
+			return apperrors.ErrAccessDenied{UserID: userID, OwnerID: ownerID, Access: string(required)}
+		}
+	}
+	return nil
+}
+
+// calendar returns the owner of the calendar the request works with, see ContextWithCalendarID.
+// It is the user from ctx by default, empty requests without user and calendar work with all events.
+func (a *App) calendar(ctx context.Context, required storage.ShareAccess) (string, error) {
+	calendarID := CalendarIDFromContext(ctx)
+	if calendarID == "" {
+		return UserIDFromContext(ctx), nil
+	}
+	if err := a.checkCalendarAccess(ctx, required, calendarID); err != nil {
+		return "", err
+	}
+	return calendarID, nil
+}
+
+// eventAccess returns access of the user from ctx to the event. Attendees and users the calendar
+// of an attendee is shared with may read the event, the owner and users the calendar of the owner
+// is shared with have access given by their share.
+func (a *App) eventAccess(ctx context.Context, event *storage.Event) (storage.ShareAccess, error) {
+	userID := UserIDFromContext(ctx)
+	if userID == "" || userID == event.UserID {
+		return ownerAccess, nil
+	}
+	grants, err := a.grants(ctx)
+	if err != nil {
+		return "", err
+	}
+	return accessToEvent(userID, grants, event), nil
+}
+
+func accessToEvent(userID string, grants map[string]storage.ShareAccess, event *storage.Event) storage.ShareAccess {
+	if userID == event.UserID {
+		return ownerAccess
+	}
+	access := grants[event.UserID]
+	if access.Allows(storage.ShareAccessRead) {
+		return access
+	}
+	for _, attendee := range event.Attendees {
+		if attendee.UserID == userID || grants[attendee.UserID].Allows(storage.ShareAccessRead) {
+			return storage.ShareAccessRead
+		}
+	}
+	return access
+}
+
+// checkEventAccess fails unless the user from ctx has the required access to the event.
+// Events the user can't read are reported as not found, as if they did not exist.
+func (a *App) checkEventAccess(ctx context.Context, event *storage.Event, required storage.ShareAccess) error {
+	access, err := a.eventAccess(ctx, event)
+	if err != nil {
+		return err
+	}
+	if !access.Allows(storage.ShareAccessRead) {
+		return errs.ErrNotFoundEvent{ID: event.ID}
+	}
+	if !access.Allows(required) {
+		return apperrors.ErrAccessDenied{
+			UserID: UserIDFromContext(ctx), OwnerID: event.UserID, Access: string(required),
+		}
+	}
+	return nil
+}
+
+// getEvent returns the event if the user from ctx has the required access to it.
+func (a *App) getEvent(ctx context.Context, id string, required storage.ShareAccess) (*storage.Event, error) {
+	event, err := a.Store.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.checkEventAccess(ctx, event, required); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// getDeletedEvent returns the event from the trash if the user from ctx has the required access to it.
+func (a *App) getDeletedEvent(ctx context.Context, id string, required storage.ShareAccess) (*storage.Event, error) {
+	deleted, err := a.Store.ListDeletedEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range deleted {
+		if event.ID == id {
+			if err = a.checkEventAccess(ctx, event, required); err != nil {
+				return nil, err
+			}
+			return event, nil
+		}
+	}
+	return nil, errs.ErrNotFoundEvent{ID: id}
+}
+
+// ShareCalendar shares the calendar of the user from ctx with the user, sharing it again changes the access.
+func (a *App) ShareCalendar(ctx context.Context, userID string, access storage.ShareAccess) (*storage.Share, error) {
+	ownerID := UserIDFromContext(ctx)
+	switch {
+	case ownerID == "":
+		return nil, apperrors.ErrUserRequired{}
+	case userID == "":
+		return nil, apperrors.ErrInvalidShare{Reason: "user must not be empty"}
+	case userID == ownerID:
+		return nil, apperrors.ErrInvalidShare{Reason: "calendar can't be shared with its owner"}
+	case !access.Valid():
+		return nil, apperrors.ErrInvalidShare{Reason: fmt.Sprintf("unknown access '%s'", access)}
+	}
+	share := &storage.Share{OwnerID: ownerID, UserID: userID, Access: access, CreatedAt: time.Now().UTC()}
+	if err := a.Store.SaveShare(ctx, share); err != nil {
+		return nil, err
+	}
+	if !access.Allows(storage.ShareAccessRead) {
+		// subscriptions of the user to the calendar are checked only when they start
+		a.changes.revoke(ownerID, userID)
+	}
+	return share, nil
+}
+
+// RevokeShare stops sharing the calendar of the user from ctx with the user.
+func (a *App) RevokeShare(ctx context.Context, userID string) error {
+	ownerID := UserIDFromContext(ctx)
+	if ownerID == "" {
+		return apperrors.ErrUserRequired{}
+	}
+	if err := a.Store.DeleteShare(ctx, ownerID, userID); err != nil {
+		return err
+	}
+	a.changes.revoke(ownerID, userID)
+	return nil
+}
+
+// ListShares returns shares of the calendar of the user from ctx sorted by user.
+func (a *App) ListShares(ctx context.Context) ([]*storage.Share, error) {
+	ownerID := UserIDFromContext(ctx)
+	if ownerID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	return a.Store.ListOwnerShares(ctx, ownerID)
+}
+
+// ListSharedCalendars returns shares of calendars of other users with the user from ctx sorted by owner.
+func (a *App) ListSharedCalendars(ctx context.Context) ([]*storage.Share, error) {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	return a.Store.ListUserShares(ctx, userID)
+}
+
+// busyOnly hides details of events the user from ctx can't read, only their time is left.
+func (a *App) busyOnly(ctx context.Context, events []*storage.Event) ([]*storage.Event, error) {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return events, nil
+	}
+	grants, err := a.grants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, event := range events {
+		if !accessToEvent(userID, grants, event).Allows(storage.ShareAccessRead) {
+			events[i] = &storage.Event{
+				ID: event.ID, StartAt: event.StartAt, EndAt: event.EndAt, TimeZone: event.TimeZone,
+				AllDay: event.AllDay, Resources: event.Resources,
+			}
+		}
+	}
+	return events, nil
+}
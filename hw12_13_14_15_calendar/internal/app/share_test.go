@@ -0,0 +1,133 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShares(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	alice := app.ContextWithUserID(context.Background(), "alice")
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+
+	planning := &storage.Event{Title: "planning", StartAt: start, EndAt: start.Add(time.Hour)}
+	require.NoError(t, calendar.CreateEvent(alice, planning))
+
+	t.Run("grant, list and revoke", func(t *testing.T) {
+		share, err := calendar.ShareCalendar(alice, "bob", storage.ShareAccessRead)
+		require.NoError(t, err)
+		require.Equal(t, "alice", share.OwnerID)
+		_, err = calendar.ShareCalendar(alice, "carol", storage.ShareAccessFreeBusy)
+		require.NoError(t, err)
+		// sharing again changes the access
+		_, err = calendar.ShareCalendar(alice, "carol", storage.ShareAccessReadWrite)
+		require.NoError(t, err)
+
+		shares, err := calendar.ListShares(alice)
+		require.NoError(t, err)
+		require.Len(t, shares, 2)
+		require.Equal(t, "bob", shares[0].UserID)
+		require.Equal(t, storage.ShareAccessReadWrite, shares[1].Access)
+		shared, err := calendar.ListSharedCalendars(app.ContextWithUserID(context.Background(), "carol"))
+		require.NoError(t, err)
+		require.Len(t, shared, 1)
+		require.Equal(t, "alice", shared[0].OwnerID)
+
+		require.NoError(t, calendar.RevokeShare(alice, "carol"))
+		require.ErrorAs(t, calendar.RevokeShare(alice, "carol"), &errs.ErrNotFoundShare{})
+		shares, err = calendar.ListShares(alice)
+		require.NoError(t, err)
+		require.Len(t, shares, 1)
+	})
+
+	t.Run("invalid shares", func(t *testing.T) {
+		for name, share := range map[string]*storage.Share{
+			"empty user":     {UserID: "", Access: storage.ShareAccessRead},
+			"owner":          {UserID: "alice", Access: storage.ShareAccessRead},
+			"unknown access": {UserID: "bob", Access: "admin"},
+		} {
+			_, err := calendar.ShareCalendar(alice, share.UserID, share.Access)
+			require.ErrorAs(t, err, &apperrors.ErrInvalidShare{}, name)
+		}
+		_, err := calendar.ShareCalendar(context.Background(), "bob", storage.ShareAccessRead)
+		require.ErrorAs(t, err, &apperrors.ErrUserRequired{})
+	})
+
+	t.Run("free/busy access shows only when the owner is busy", func(t *testing.T) {
+		dave := app.ContextWithUserID(context.Background(), "dave")
+		_, err := calendar.FreeSlots(dave, []string{"alice"}, start, start.Add(2*time.Hour), 0)
+		require.ErrorAs(t, err, &apperrors.ErrAccessDenied{})
+
+		_, err = calendar.ShareCalendar(alice, "dave", storage.ShareAccessFreeBusy)
+		require.NoError(t, err)
+		slots, err := calendar.FreeSlots(dave, []string{"alice", "dave"}, start, start.Add(2*time.Hour), 0)
+		require.NoError(t, err)
+		require.Equal(t, []app.TimeSlot{{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)}}, slots)
+
+		_, err = calendar.GetEvent(dave, planning.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+		_, err = calendar.ListEvents(app.ContextWithCalendarID(dave, "alice"))
+		require.ErrorAs(t, err, &apperrors.ErrAccessDenied{})
+	})
+
+	t.Run("read access", func(t *testing.T) {
+		bob := app.ContextWithUserID(context.Background(), "bob")
+		got, err := calendar.GetEvent(bob, planning.ID)
+		require.NoError(t, err)
+		require.Equal(t, "planning", got.Title)
+		events, err := calendar.ListEvents(app.ContextWithCalendarID(bob, "alice"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"planning"}, titles(events))
+		own, err := calendar.ListEvents(bob)
+		require.NoError(t, err)
+		require.Empty(t, own)
+
+		update := *planning
+		update.Title = "hijacked"
+		require.ErrorAs(t, calendar.UpdateEvent(bob, &update), &apperrors.ErrAccessDenied{})
+		require.ErrorAs(t, calendar.DeleteEvent(bob, planning.ID), &apperrors.ErrAccessDenied{})
+		event := &storage.Event{Title: "sneaky", StartAt: start, EndAt: start.Add(time.Hour)}
+		require.ErrorAs(t, calendar.CreateEvent(app.ContextWithCalendarID(bob, "alice"), event),
+			&apperrors.ErrAccessDenied{})
+	})
+
+	t.Run("read-write access manages the calendar on behalf of the owner", func(t *testing.T) {
+		_, err := calendar.ShareCalendar(alice, "erin", storage.ShareAccessReadWrite)
+		require.NoError(t, err)
+		erin := app.ContextWithCalendarID(app.ContextWithUserID(context.Background(), "erin"), "alice")
+
+		review := &storage.Event{Title: "review", StartAt: start.Add(2 * time.Hour), EndAt: start.Add(3 * time.Hour)}
+		require.NoError(t, calendar.CreateEvent(erin, review))
+		require.Equal(t, "alice", review.UserID)
+		review.Title = "design review"
+		require.NoError(t, calendar.UpdateEvent(erin, review))
+		events, err := calendar.ListEvents(alice)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"planning", "design review"}, titles(events))
+
+		require.NoError(t, calendar.DeleteEvent(erin, review.ID))
+		deleted, err := calendar.ListDeletedEvents(erin)
+		require.NoError(t, err)
+		require.Equal(t, []string{"design review"}, titles(deleted))
+		deleted, err = calendar.ListDeletedEvents(app.ContextWithUserID(context.Background(), "erin"))
+		require.NoError(t, err)
+		require.Empty(t, deleted, "trash of erin's own calendar")
+		require.NoError(t, calendar.RestoreEvent(erin, review.ID))
+	})
+
+	t.Run("revoked access", func(t *testing.T) {
+		require.NoError(t, calendar.RevokeShare(alice, "bob"))
+		_, err := calendar.GetEvent(app.ContextWithUserID(context.Background(), "bob"), planning.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+	})
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+const maxTagLength = 64
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// normalizeTag trims the tag name, names are compared as they are written otherwise.
+func normalizeTag(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", apperrors.ErrInvalidTag{Reason: "name must not be empty"}
+	}
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return "", apperrors.ErrInvalidTag{Reason: fmt.Sprintf("name '%s' is longer than %d", name, maxTagLength)}
+	}
+	return name, nil
+}
+
+// normalizeTags checks tags of the event, drops repeated ones and sorts them.
+func normalizeTags(event *storage.Event) error {
+	if len(event.Tags) == 0 {
+		event.Tags = nil
+		return nil
+	}
+	tags := make([]string, 0, len(event.Tags))
+	seen := make(map[string]bool, len(event.Tags))
+	for _, tag := range event.Tags {
+		name, err := normalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		if !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	sort.Strings(tags)
+	event.Tags = tags
+	return nil
+}
+
+// filterByTags keeps events labeled with all of the tags.
+func filterByTags(events []*storage.Event, tags []string) []*storage.Event {
+	if len(tags) == 0 {
+		return events
+	}
+	filtered := make([]*storage.Event, 0, len(events))
+	for _, event := range events {
+		if event.HasTags(tags) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// ListTags returns tags of the owner of the calendar the request works with, see Storage.ListUserTags.
+func (a *App) ListTags(ctx context.Context) ([]*storage.Tag, error) {
+	userID, err := a.calendar(ctx, storage.ShareAccessRead)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	return a.Store.ListUserTags(ctx, userID)
+}
+
+// UpdateTag sets the color of the tag of the owner of the calendar the request works with, making
+// the tag a category, or takes it away if the color is empty. The tag does not have to label any events yet.
+func (a *App) UpdateTag(ctx context.Context, tag *storage.Tag) error {
+	userID, err := a.calendar(ctx, storage.ShareAccessReadWrite)
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return apperrors.ErrUserRequired{}
+	}
+	name, err := normalizeTag(tag.Name)
+	if err != nil {
+		return err
+	}
+	color := strings.ToLower(tag.Color)
+	if color != "" && !colorPattern.MatchString(color) {
+		return apperrors.ErrInvalidTag{Reason: fmt.Sprintf("color '%s' is not #rrggbb", tag.Color)}
+	}
+	tag.UserID, tag.Name, tag.Color = userID, name, color
+	return a.Store.SaveTag(ctx, tag)
+}
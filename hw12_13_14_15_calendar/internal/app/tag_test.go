@@ -0,0 +1,88 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventTags(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+
+	standup := &storage.Event{
+		Title: "standup", StartAt: start, EndAt: start.Add(time.Hour),
+		Tags: []string{" work", "oncall ", "work"},
+	}
+	require.NoError(t, calendar.CreateEvent(ctx, standup))
+	require.Equal(t, []string{"oncall", "work"}, standup.Tags)
+	flight := &storage.Event{
+		Title: "flight", StartAt: start.Add(2 * time.Hour), EndAt: start.Add(5 * time.Hour),
+		Tags: []string{"travel", "work"},
+	}
+	require.NoError(t, calendar.CreateEvent(ctx, flight))
+
+	events, err := calendar.ListEvents(ctx, "work")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"standup", "flight"}, titles(events))
+	events, err = calendar.ListDayEvents(ctx, start, "work", "travel")
+	require.NoError(t, err)
+	require.Equal(t, []string{"flight"}, titles(events))
+	events, err = calendar.ListWeekEvents(ctx, start, "family")
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	for name, tags := range map[string][]string{
+		"empty name":    {" "},
+		"too long name": {strings.Repeat("a", 65)},
+	} {
+		invalid := &storage.Event{Title: "invalid", StartAt: start, EndAt: start.Add(time.Hour), Tags: tags}
+		require.ErrorAs(t, calendar.CreateEvent(ctx, invalid), &apperrors.ErrInvalidTag{}, name)
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	logg := logger.New("error")
+	calendar := app.New(logg, memorystorage.New(logg))
+	ctx := app.ContextWithUserID(context.Background(), "alice")
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, calendar.CreateEvent(ctx, &storage.Event{
+		Title: "standup", StartAt: start, EndAt: start.Add(time.Hour), Tags: []string{"oncall"},
+	}))
+	tag := &storage.Tag{UserID: "mallory", Name: " work ", Color: "#FF8800"}
+	require.NoError(t, calendar.UpdateTag(ctx, tag))
+	require.Equal(t, &storage.Tag{UserID: "alice", Name: "work", Color: "#ff8800"}, tag)
+
+	tags, err := calendar.ListTags(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Tag{
+		{UserID: "alice", Name: "oncall"},
+		{UserID: "alice", Name: "work", Color: "#ff8800"},
+	}, tags)
+
+	for name, invalid := range map[string]*storage.Tag{
+		"empty name":    {Color: "#ff8800"},
+		"named color":   {Name: "work", Color: "orange"},
+		"short color":   {Name: "work", Color: "#f80"},
+		"missing hash":  {Name: "work", Color: "ff8800"},
+		"too long name": {Name: strings.Repeat("a", 65)},
+	} {
+		require.ErrorAs(t, calendar.UpdateTag(ctx, invalid), &apperrors.ErrInvalidTag{}, name)
+	}
+
+	require.ErrorAs(t, calendar.UpdateTag(context.Background(), &storage.Tag{Name: "work"}),
+		&apperrors.ErrUserRequired{})
+	_, err = calendar.ListTags(context.Background())
+	require.ErrorAs(t, err, &apperrors.ErrUserRequired{})
+}
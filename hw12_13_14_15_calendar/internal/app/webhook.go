@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+const webhookSecretSize = 32
+
+// RegisterWebhook adds webhook of the user from ctx. A random secret is generated
+// if the webhook has none, the secret is returned only here.
+func (a *App) RegisterWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return apperrors.ErrUserRequired{}
+	}
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil {
+		return apperrors.ErrInvalidWebhook{Reason: err.Error()}
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return apperrors.ErrInvalidWebhook{Reason: "url must be absolute http or https url"}
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, webhookSecretSize)
+		if _, err = rand.Read(secret); err != nil {
+			return err
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	webhook.UserID = userID
+	webhook.CreatedAt = time.Now().UTC()
+	return a.Store.AddWebhook(ctx, webhook)
+}
+
+// ListWebhooks returns webhooks of the user from ctx without their secrets.
+func (a *App) ListWebhooks(ctx context.Context) ([]*storage.Webhook, error) {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	webhooks, err := a.Store.ListUsersWebhooks(ctx, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes webhook of the user from ctx together with its delivery log.
+func (a *App) DeleteWebhook(ctx context.Context, id string) error {
+	ctx = ContextWithReadYourWrites(ctx)
+	if _, err := a.userWebhook(ctx, id); err != nil {
+		return err
+	}
+	return a.Store.DeleteWebhook(ctx, id)
+}
+
+// ListWebhookDeliveries returns the delivery log of webhook of the user from ctx.
+func (a *App) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error) {
+	if _, err := a.userWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return a.Store.ListWebhookDeliveries(ctx, webhookID)
+}
+
+// userWebhook returns webhook of the user from ctx, webhooks of other users are reported as not found.
+func (a *App) userWebhook(ctx context.Context, id string) (*storage.Webhook, error) {
+	userID := UserIDFromContext(ctx)
+	if userID == "" {
+		return nil, apperrors.ErrUserRequired{}
+	}
+	webhook, err := a.Store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.UserID != userID {
+		return nil, errs.ErrNotFoundWebhook{ID: id}
+	}
+	return webhook, nil
+}
@@ -0,0 +1,94 @@
+// Package localblobstore keeps blobs as files in a local directory, so it serves a single
+// instance of the calendar or instances sharing the directory.
+package localblobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+)
+
+// tmpPrefix marks files being written, they are never read as blobs.
+const tmpPrefix = ".tmp-"
+
+type Store struct {
+	dir string
+}
+
+// New returns the store of blobs in dir, the directory is created if there is none.
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errs.ErrConnectionFailed{Err: err}
+	}
+	return &Store{dir: dir}, nil
+}
+
+// path returns the file of the key, keys are single path elements.
+func (s *Store) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.HasPrefix(key, tmpPrefix) ||
+		strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Put writes the content to a temporary file, which is renamed to the file of the key once
+// the content is read completely.
+func (s *Store) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return errs.ErrSaveBlob{Err: err}
+	}
+	tmp, err := os.CreateTemp(s.dir, tmpPrefix+"*")
+	if err != nil {
+		return errs.ErrSaveBlob{Err: err}
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return errs.ErrSaveBlob{Err: err}
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return errs.ErrSaveBlob{Err: err}
+	}
+	if err = tmp.Close(); err != nil {
+		return errs.ErrSaveBlob{Err: err}
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errs.ErrSaveBlob{Err: err}
+	}
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, errs.ErrReadBlob{Err: err}
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errs.ErrNotFoundBlob{Key: key}
+	}
+	if err != nil {
+		return nil, errs.ErrReadBlob{Err: err}
+	}
+	return file, nil
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return errs.ErrDeleteBlob{Err: err}
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errs.ErrDeleteBlob{Err: err}
+	}
+	return nil
+}
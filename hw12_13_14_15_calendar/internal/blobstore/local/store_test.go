@@ -0,0 +1,63 @@
+package localblobstore_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	localblobstore "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/blobstore/local"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := localblobstore.New(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "agenda", strings.NewReader("1. budget")))
+	content, err := store.Get(ctx, "agenda")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+	require.Equal(t, "1. budget", string(data))
+
+	t.Run("failed put keeps the old content", func(t *testing.T) {
+		readErr := errors.New("connection reset")
+		err := store.Put(ctx, "agenda", io.MultiReader(strings.NewReader("2. hiring"), iotest.ErrReader(readErr)))
+		require.ErrorIs(t, err, readErr)
+
+		content, err := store.Get(ctx, "agenda")
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(content)
+		require.NoError(t, err)
+		require.NoError(t, content.Close())
+		require.Equal(t, "1. budget", string(data))
+
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1, "temporary file is left")
+	})
+
+	t.Run("keys are not paths", func(t *testing.T) {
+		for _, key := range []string{"", "..", "../agenda", "a/b", `a\b`, ".tmp-1"} {
+			require.Error(t, store.Put(ctx, key, strings.NewReader("data")), key)
+			_, err := store.Get(ctx, key)
+			require.Error(t, err, key)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, store.Delete(ctx, "agenda"))
+		_, err := store.Get(ctx, "agenda")
+		require.ErrorAs(t, err, &errs.ErrNotFoundBlob{})
+		require.NoError(t, store.Delete(ctx, "agenda"), "missing blob")
+	})
+}
@@ -0,0 +1,118 @@
+// Package notifier delivers reminders about events to users by channels they choose in their profiles.
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Notification is a reminder about the event for one user. ID is the same for every attempt
+// to deliver the reminder, so receivers can deduplicate them.
+type Notification struct {
+	ID          string    `json:"id"`
+	EventID     string    `json:"event_id"`
+	UserID      string    `json:"user_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	TimeZone    string    `json:"time_zone"`
+	AllDay      bool      `json:"all_day,omitempty"`
+	NotifyAt    time.Time `json:"notify_at"`
+}
+
+// NewNotification returns the reminder for userID about the occurrence of the event,
+// its ID is storage.ReminderID of the occurrence followed by userID.
+func NewNotification(event *storage.Event, userID string) *Notification {
+	notifyAt := event.NotifyAt()
+	return &Notification{
+		ID:          storage.ReminderID(event.ID, event.StartAt, notifyAt) + "/" + userID,
+		EventID:     event.ID,
+		UserID:      userID,
+		Title:       event.Title,
+		Description: event.Description,
+		StartAt:     event.StartAt,
+		EndAt:       event.EndAt,
+		TimeZone:    event.TimeZone,
+		AllDay:      event.AllDay,
+		NotifyAt:    notifyAt,
+	}
+}
+
+// Notifier delivers the notification to the user by one channel.
+type Notifier interface {
+	Notify(ctx context.Context, profile *storage.UserProfile, notification *Notification) error
+}
+
+type ProfileStorage interface {
+	GetUserProfile(ctx context.Context, userID string) (*storage.UserProfile, error)
+}
+
+var errChannelNotConfigured = errors.New("channel is not configured")
+
+// Router sends notifications by the channels from the profile of the user.
+// Users without a profile get them by the default channels.
+type Router struct {
+	profiles  ProfileStorage
+	notifiers map[storage.NotificationChannel]Notifier
+	defaults  []storage.NotificationChannel
+}
+
+func NewRouter(profiles ProfileStorage, notifiers map[storage.NotificationChannel]Notifier,
+	defaults []storage.NotificationChannel,
+) *Router {
+	return &Router{
+		profiles:  profiles,
+		notifiers: notifiers,
+		defaults:  defaults,
+	}
+}
+
+// Send delivers the notification by every channel of the user. The error lists failed channels,
+// the notification is delivered by the others.
+func (r *Router) Send(ctx context.Context, notification *Notification) error {
+	profile, err := r.profiles.GetUserProfile(ctx, notification.UserID)
+	if err != nil {
+		if !errors.As(err, &errs.ErrNotFoundUserProfile{}) {
+			return fmt.Errorf("failed to get profile of user '%s': %w", notification.UserID, err)
+		}
+		profile = &storage.UserProfile{UserID: notification.UserID, Channels: r.defaults}
+	}
+
+	failed := make(map[storage.NotificationChannel]error)
+	for _, channel := range profile.Channels {
+		notifier, ok := r.notifiers[channel]
+		if !ok {
+			failed[channel] = errChannelNotConfigured
+			continue
+		}
+		if err := notifier.Notify(ctx, profile, notification); err != nil {
+			failed[channel] = err
+		}
+	}
+	if len(failed) > 0 {
+		return ErrSend{Failed: failed}
+	}
+	return nil
+}
+
+// ErrSend is returned when the notification is not delivered by some channels.
+type ErrSend struct {
+	Failed map[storage.NotificationChannel]error
+}
+
+func (e ErrSend) Error() string {
+	messages := make([]string, 0, len(e.Failed))
+	for channel, err := range e.Failed {
+		messages = append(messages, fmt.Sprintf("%s: %s", channel, err))
+	}
+	sort.Strings(messages)
+	return fmt.Sprintf("failed to send notification by %s", strings.Join(messages, "; "))
+}
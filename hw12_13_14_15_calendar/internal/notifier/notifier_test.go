@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func testNotification() *Notification {
+	return NewNotification(&storage.Event{
+		ID:          "event",
+		Title:       "planning",
+		Description: "agenda",
+		StartAt:     time.Date(2022, 11, 14, 7, 0, 0, 0, time.UTC),
+		EndAt:       time.Date(2022, 11, 14, 8, 30, 0, 0, time.UTC),
+		TimeZone:    "Europe/Moscow",
+	}, "alice")
+}
+
+// recorder is a Notifier remembering notified users.
+type recorder struct {
+	mu    sync.Mutex
+	users []string
+	err   error
+}
+
+func (r *recorder) Notify(_ context.Context, profile *storage.UserProfile, _ *Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = append(r.users, profile.UserID)
+	return r.err
+}
+
+func TestTemplates(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		subject, body, err := DefaultTemplates().Render(testNotification())
+		require.NoError(t, err)
+		require.Equal(t, "Reminder: planning", subject)
+		require.Equal(t, "planning\nMonday, 14 November 2022 10:00 - 11:30 Europe/Moscow\n\nagenda\n", body)
+	})
+
+	t.Run("all day", func(t *testing.T) {
+		notification := testNotification()
+		notification.AllDay = true
+		notification.Description = ""
+		_, body, err := DefaultTemplates().Render(notification)
+		require.NoError(t, err)
+		require.Equal(t, "planning\nMonday, 14 November 2022, all day\n", body)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		templates, err := NewTemplates("{{.Title}}\r\nBcc: eve@example.com", "{{.Start.Format \"15:04\"}}")
+		require.NoError(t, err)
+		subject, body, err := templates.Render(testNotification())
+		require.NoError(t, err)
+		require.Equal(t, "planning Bcc: eve@example.com", subject)
+		require.Equal(t, "10:00", body)
+
+		_, err = NewTemplates("{{.Title", "")
+		require.Error(t, err)
+	})
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	store := memorystorage.New(logger.New("error"))
+	require.NoError(t, store.SaveUserProfile(ctx, &storage.UserProfile{
+		UserID:   "alice",
+		Channels: []storage.NotificationChannel{storage.NotificationChannelEmail, storage.NotificationChannelStdout},
+	}))
+	require.NoError(t, store.SaveUserProfile(ctx, &storage.UserProfile{
+		UserID:   "bob",
+		Channels: []storage.NotificationChannel{storage.NotificationChannelWebhook},
+	}))
+	email, stdout := &recorder{}, &recorder{}
+	router := NewRouter(store, map[storage.NotificationChannel]Notifier{
+		storage.NotificationChannelEmail:  email,
+		storage.NotificationChannelStdout: stdout,
+	}, []storage.NotificationChannel{storage.NotificationChannelStdout})
+
+	t.Run("channels of the profile", func(t *testing.T) {
+		require.NoError(t, router.Send(ctx, testNotification()))
+		require.Equal(t, []string{"alice"}, email.users)
+		require.Equal(t, []string{"alice"}, stdout.users)
+	})
+
+	t.Run("default channels without profile", func(t *testing.T) {
+		notification := testNotification()
+		notification.UserID = "carol"
+		require.NoError(t, router.Send(ctx, notification))
+		require.Equal(t, []string{"alice"}, email.users)
+		require.Equal(t, []string{"alice", "carol"}, stdout.users)
+	})
+
+	t.Run("failed channels", func(t *testing.T) {
+		notification := testNotification()
+		notification.UserID = "bob"
+		err := router.Send(ctx, notification)
+		var sendErr ErrSend
+		require.ErrorAs(t, err, &sendErr)
+		require.ErrorIs(t, sendErr.Failed[storage.NotificationChannelWebhook], errChannelNotConfigured)
+
+		email.err = errors.New("connection refused")
+		err = router.Send(ctx, testNotification())
+		require.ErrorAs(t, err, &sendErr)
+		require.Len(t, sendErr.Failed, 1)
+		require.Contains(t, err.Error(), "email: connection refused")
+		require.Equal(t, []string{"alice", "carol", "alice"}, stdout.users)
+	})
+}
+
+func TestStdout(t *testing.T) {
+	var out bytes.Buffer
+	stdout := NewStdout(&out, DefaultTemplates())
+	require.NoError(t, stdout.Notify(context.Background(), &storage.UserProfile{UserID: "alice"}, testNotification()))
+	require.Equal(t, "To: alice\nSubject: Reminder: planning\n\n"+
+		"planning\nMonday, 14 November 2022 10:00 - 11:30 Europe/Moscow\n\nagenda\n\n", out.String())
+}
+
+func TestWebhook(t *testing.T) {
+	ctx := context.Background()
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan request, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests <- request{header: r.Header.Clone(), body: body}
+	}))
+	defer receiver.Close()
+
+	store := memorystorage.New(logger.New("error"))
+	require.NoError(t, store.AddWebhook(ctx, &storage.Webhook{UserID: "alice", URL: receiver.URL, Secret: "secret"}))
+	notification := testNotification()
+
+	t.Run("posts signed reminder", func(t *testing.T) {
+		hook := NewWebhook(store, DefaultTemplates(), time.Second, true)
+		require.NoError(t, hook.Notify(ctx, &storage.UserProfile{UserID: "alice"}, notification))
+
+		received := <-requests
+		require.Equal(t, ReminderType, received.header.Get(webhook.TypeHeader))
+		require.Equal(t, notification.ID, received.header.Get(webhook.DeliveryHeader))
+		require.True(t, webhook.Verify("secret", received.header.Get(webhook.SignatureHeader), received.body,
+			time.Now(), time.Minute))
+		var payload ReminderPayload
+		require.NoError(t, json.Unmarshal(received.body, &payload))
+		require.Equal(t, notification.ID, payload.ID)
+		require.Equal(t, "Reminder: planning", payload.Subject)
+		require.Equal(t, "agenda", payload.Notification.Description)
+	})
+
+	t.Run("user without webhooks", func(t *testing.T) {
+		hook := NewWebhook(store, DefaultTemplates(), time.Second, true)
+		require.ErrorIs(t, hook.Notify(ctx, &storage.UserProfile{UserID: "bob"}, notification), errNoWebhooks)
+	})
+
+	t.Run("private addresses are refused", func(t *testing.T) {
+		hook := NewWebhook(store, DefaultTemplates(), time.Second, false)
+		require.Error(t, hook.Notify(ctx, &storage.UserProfile{UserID: "alice"}, notification))
+		require.Empty(t, requests)
+	})
+}
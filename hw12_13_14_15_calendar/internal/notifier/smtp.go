@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// SMTPOptions of the SMTP notifier. Username and Password are used for PLAIN authentication,
+// which is only done over TLS, so the server must support STARTTLS unless it is on localhost.
+type SMTPOptions struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+	// TLSConfig is used for STARTTLS, nil means the default config for the host of Addr.
+	TLSConfig *tls.Config
+}
+
+var errNoEmail = errors.New("user has no email")
+
+// SMTP sends notifications as emails to addresses from profiles of users.
+type SMTP struct {
+	options   SMTPOptions
+	templates *Templates
+	now       func() time.Time
+}
+
+func NewSMTP(options SMTPOptions, templates *Templates) *SMTP {
+	return &SMTP{options: options, templates: templates, now: time.Now}
+}
+
+func (s *SMTP) Notify(ctx context.Context, profile *storage.UserProfile, notification *Notification) error {
+	if profile.Email == "" {
+		return errNoEmail
+	}
+	subject, body, err := s.templates.Render(notification)
+	if err != nil {
+		return err
+	}
+	message, err := s.message(profile.Email, subject, body, notification.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.send(ctx, profile.Email, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTP) message(to, subject, body, id string) ([]byte, error) {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.options.From)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	if id != "" {
+		// the same reminder gets the same Message-ID, so mail clients can drop duplicates
+		fmt.Fprintf(&message, "Message-ID: <%s@calendar>\r\n", id)
+	}
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&message)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	return message.Bytes(), nil
+}
+
+func (s *SMTP) send(ctx context.Context, to string, message []byte) error {
+	host, _, err := net.SplitHostPort(s.options.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s': %w", s.options.Addr, err)
+	}
+	dialer := net.Dialer{Timeout: s.options.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.options.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if s.options.Timeout > 0 && (!ok || time.Now().Add(s.options.Timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(s.options.Timeout), true
+	}
+	if ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		config := s.options.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		}
+		if err := client.StartTLS(config); err != nil {
+			return err
+		}
+	}
+	if s.options.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.options.Username, s.options.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.options.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
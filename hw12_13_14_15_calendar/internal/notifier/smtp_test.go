@@ -0,0 +1,175 @@
+package notifier
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedMail struct {
+	auth string
+	from string
+	to   []string
+	data []byte
+}
+
+// smtpServer is an SMTP server accepting every mail, it supports only commands sent by net/smtp.
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	mails    []receivedMail
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var mail receivedMail
+	reply := func(format string, args ...interface{}) bool {
+		return text.PrintfLine(format, args...) == nil
+	}
+	if !reply("220 localhost ready") {
+		return
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			reply("250-localhost\r\n250-8BITMIME\r\n250 AUTH PLAIN")
+		case "AUTH":
+			mail.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+			reply("235 accepted")
+		case "MAIL":
+			mail.from = address(line)
+			reply("250 ok")
+		case "RCPT":
+			mail.to = append(mail.to, address(line))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			mail.data, err = text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			mail = receivedMail{}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// address returns the path from MAIL and RCPT commands.
+func address(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func (s *smtpServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mails...)
+}
+
+func TestSMTP(t *testing.T) {
+	server := newSMTPServer(t)
+	notifier := NewSMTP(SMTPOptions{
+		Addr:     server.listener.Addr().String(),
+		Username: "calendar",
+		Password: "password",
+		From:     "calendar@example.com",
+		Timeout:  time.Second,
+	}, DefaultTemplates())
+	notifier.now = func() time.Time { return time.Date(2022, 11, 14, 6, 45, 0, 0, time.UTC) }
+	notification := testNotification()
+	notification.Title = "планёрка"
+	notification.Description = "agenda\n.\nend"
+
+	t.Run("sends email", func(t *testing.T) {
+		profile := &storage.UserProfile{UserID: "alice", Email: "alice@example.com"}
+		require.NoError(t, notifier.Notify(context.Background(), profile, notification))
+
+		mails := server.received()
+		require.Len(t, mails, 1)
+		require.Equal(t, "calendar@example.com", mails[0].from)
+		require.Equal(t, []string{"alice@example.com"}, mails[0].to)
+		auth, err := base64.StdEncoding.DecodeString(mails[0].auth)
+		require.NoError(t, err)
+		require.Equal(t, "\x00calendar\x00password", string(auth))
+
+		message, err := mail.ReadMessage(strings.NewReader(string(mails[0].data)))
+		require.NoError(t, err)
+		subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+		require.NoError(t, err)
+		require.Equal(t, "Reminder: планёрка", subject)
+		require.Equal(t, "alice@example.com", message.Header.Get("To"))
+		require.Equal(t, "<"+notification.ID+"@calendar>", message.Header.Get("Message-ID"))
+		date, err := message.Header.Date()
+		require.NoError(t, err)
+		require.True(t, date.Equal(notifier.now()))
+		body, err := ioutil.ReadAll(quotedprintable.NewReader(message.Body))
+		require.NoError(t, err)
+		require.Equal(t, "планёрка\nMonday, 14 November 2022 10:00 - 11:30 Europe/Moscow\n\nagenda\n.\nend\n",
+			strings.ReplaceAll(string(body), "\r\n", "\n"))
+	})
+
+	t.Run("user without email", func(t *testing.T) {
+		err := notifier.Notify(context.Background(), &storage.UserProfile{UserID: "bob"}, notification)
+		require.ErrorIs(t, err, errNoEmail)
+		require.Len(t, server.received(), 1)
+	})
+
+	t.Run("server is not available", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		listener.Close()
+		unavailable := NewSMTP(SMTPOptions{Addr: addr, From: "calendar@example.com", Timeout: time.Second},
+			DefaultTemplates())
+		err = unavailable.Notify(context.Background(), &storage.UserProfile{UserID: "alice", Email: "alice@example.com"},
+			notification)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,34 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Stdout writes notifications to w, it is useful for development.
+type Stdout struct {
+	mu        sync.Mutex
+	w         io.Writer
+	templates *Templates
+}
+
+func NewStdout(w io.Writer, templates *Templates) *Stdout {
+	return &Stdout{w: w, templates: templates}
+}
+
+func (s *Stdout) Notify(_ context.Context, profile *storage.UserProfile, notification *Notification) error {
+	subject, body, err := s.templates.Render(notification)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.w, "To: %s\nSubject: %s\n\n%s\n", profile.UserID, subject, body); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	DefaultSubjectTemplate = `Reminder: {{.Title}}`
+	DefaultBodyTemplate    = `{{.Title}}
+{{if .AllDay}}{{.Start.Format "Monday, 2 January 2006"}}, all day{{else -}}
+{{.Start.Format "Monday, 2 January 2006 15:04"}} - {{.End.Format "15:04"}} {{.Start.Location}}{{end}}
+{{with .Description}}
+{{.}}
+{{end}}`
+)
+
+// Templates render messages of text channels. Templates get the Notification
+// together with Start and End converted to the time zone of the event.
+type Templates struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+type templateData struct {
+	*Notification
+	Start time.Time
+	End   time.Time
+}
+
+// NewTemplates parses templates of the subject and the body, empty ones are replaced with defaults.
+func NewTemplates(subject, body string) (*Templates, error) {
+	if subject == "" {
+		subject = DefaultSubjectTemplate
+	}
+	if body == "" {
+		body = DefaultBodyTemplate
+	}
+	subjectTemplate, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subject template: %w", err)
+	}
+	bodyTemplate, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse body template: %w", err)
+	}
+	return &Templates{subject: subjectTemplate, body: bodyTemplate}, nil
+}
+
+// DefaultTemplates returns templates made of DefaultSubjectTemplate and DefaultBodyTemplate.
+func DefaultTemplates() *Templates {
+	return &Templates{
+		subject: template.Must(template.New("subject").Parse(DefaultSubjectTemplate)),
+		body:    template.Must(template.New("body").Parse(DefaultBodyTemplate)),
+	}
+}
+
+// Render returns the subject and the body of the message about the notification.
+// The subject is a single line.
+func (t *Templates) Render(notification *Notification) (subject, body string, err error) {
+	location, err := time.LoadLocation(notification.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	data := templateData{
+		Notification: notification,
+		Start:        notification.StartAt.In(location),
+		End:          notification.EndAt.In(location),
+	}
+
+	var builder strings.Builder
+	if err := t.subject.Execute(&builder, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	// line breaks in the subject would inject headers of emails
+	subject = strings.Join(strings.Fields(builder.String()), " ")
+	builder.Reset()
+	if err := t.body.Execute(&builder, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return subject, builder.String(), nil
+}
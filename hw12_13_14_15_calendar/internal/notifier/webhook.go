@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/webhook"
+)
+
+// ReminderType is the value of webhook.TypeHeader of reminders.
+const ReminderType = "event.reminder"
+
+// ReminderPayload is the body of reminders posted to webhooks.
+type ReminderPayload struct {
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Subject      string        `json:"subject"`
+	Body         string        `json:"body"`
+	Notification *Notification `json:"notification"`
+}
+
+type WebhookStorage interface {
+	ListUsersWebhooks(ctx context.Context, userIDs []string) ([]*storage.Webhook, error)
+}
+
+var errNoWebhooks = errors.New("user has no webhooks")
+
+// Webhook posts notifications to webhooks of the user, signed the same way as changes of events.
+// Unlike changes, reminders are not retried later, the caller decides whether to send them again.
+type Webhook struct {
+	store     WebhookStorage
+	client    *http.Client
+	templates *Templates
+	now       func() time.Time
+}
+
+func NewWebhook(store WebhookStorage, templates *Templates, timeout time.Duration, allowPrivate bool) *Webhook {
+	return &Webhook{
+		store:     store,
+		client:    webhook.NewClient(timeout, allowPrivate),
+		templates: templates,
+		now:       time.Now,
+	}
+}
+
+func (w *Webhook) Notify(ctx context.Context, profile *storage.UserProfile, notification *Notification) error {
+	webhooks, err := w.store.ListUsersWebhooks(ctx, []string{profile.UserID})
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return errNoWebhooks
+	}
+	subject, body, err := w.templates.Render(notification)
+	if err != nil {
+		return err
+	}
+	now := w.now()
+	payload, err := json.Marshal(ReminderPayload{
+		ID:           notification.ID,
+		Type:         ReminderType,
+		CreatedAt:    now.UTC(),
+		Subject:      subject,
+		Body:         body,
+		Notification: notification,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	for _, hook := range webhooks {
+		if err := w.post(ctx, hook, notification.ID, now, payload); err != nil {
+			return fmt.Errorf("failed to post to webhook %s: %w", hook.ID, err)
+		}
+	}
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, hook *storage.Webhook, id string, now time.Time, payload []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhook.DeliveryHeader, id)
+	request.Header.Set(webhook.TypeHeader, ReminderType)
+	request.Header.Set(webhook.SignatureHeader, webhook.Sign(hook.Secret, now, payload))
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// the body is drained, so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 1<<16))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return nil
+}
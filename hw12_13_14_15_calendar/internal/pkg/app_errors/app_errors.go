@@ -0,0 +1,140 @@
+package apperrors
+
+import "fmt"
+
+type ErrUserRequired struct{}
+
+func (e ErrUserRequired) Error() string {
+	return "user id is required for the operation"
+}
+
+type ErrInvalidAttendeeStatus struct {
+	Status string
+}
+
+func (e ErrInvalidAttendeeStatus) Error() string {
+	return fmt.Sprintf("invalid attendee status '%s'", e.Status)
+}
+
+type ErrInvalidFreeBusyQuery struct {
+	Reason string
+}
+
+func (e ErrInvalidFreeBusyQuery) Error() string {
+	return fmt.Sprintf("invalid free/busy query: %s", e.Reason)
+}
+
+type ErrInvalidTimeZone struct {
+	Name string
+}
+
+func (e ErrInvalidTimeZone) Error() string {
+	return fmt.Sprintf("unknown time zone '%s'", e.Name)
+}
+
+type ErrInvalidBatchOperation struct {
+	Index  int
+	Reason string
+}
+
+func (e ErrInvalidBatchOperation) Error() string {
+	return fmt.Sprintf("invalid batch operation #%d: %s", e.Index, e.Reason)
+}
+
+// ErrBatchOperationFailed tells which operation of the batch failed, the batch is rolled back.
+type ErrBatchOperationFailed struct {
+	Index int
+	Err   error
+}
+
+func (e ErrBatchOperationFailed) Error() string {
+	return fmt.Sprintf("batch operation #%d failed: %s", e.Index, e.Err.Error())
+}
+
+func (e ErrBatchOperationFailed) Unwrap() error {
+	return e.Err
+}
+
+type ErrInvalidWebhook struct {
+	Reason string
+}
+
+func (e ErrInvalidWebhook) Error() string {
+	return fmt.Sprintf("invalid webhook: %s", e.Reason)
+}
+
+type ErrInvalidProfile struct {
+	Reason string
+}
+
+func (e ErrInvalidProfile) Error() string {
+	return fmt.Sprintf("invalid profile: %s", e.Reason)
+}
+
+type ErrInvalidEvent struct {
+	Reason string
+}
+
+func (e ErrInvalidEvent) Error() string {
+	return fmt.Sprintf("invalid event: %s", e.Reason)
+}
+
+type ErrInvalidTag struct {
+	Reason string
+}
+
+func (e ErrInvalidTag) Error() string {
+	return fmt.Sprintf("invalid tag: %s", e.Reason)
+}
+
+type ErrInvalidAttachment struct {
+	Reason string
+}
+
+func (e ErrInvalidAttachment) Error() string {
+	return fmt.Sprintf("invalid attachment: %s", e.Reason)
+}
+
+type ErrAttachmentTooLarge struct {
+	MaxSize int64
+}
+
+func (e ErrAttachmentTooLarge) Error() string {
+	return fmt.Sprintf("attachment is larger than %d bytes", e.MaxSize)
+}
+
+type ErrInvalidResource struct {
+	Reason string
+}
+
+func (e ErrInvalidResource) Error() string {
+	return fmt.Sprintf("invalid resource: %s", e.Reason)
+}
+
+type ErrInvalidShare struct {
+	Reason string
+}
+
+func (e ErrInvalidShare) Error() string {
+	return fmt.Sprintf("invalid share: %s", e.Reason)
+}
+
+// ErrAccessDenied is returned when the user is not allowed to do the operation in the calendar of the owner.
+type ErrAccessDenied struct {
+	UserID  string
+	OwnerID string
+	Access  string
+}
+
+func (e ErrAccessDenied) Error() string {
+	return fmt.Sprintf("user '%s' has no %s access to the calendar of '%s'", e.UserID, e.Access, e.OwnerID)
+}
+
+// ErrInvalidQuickAdd is returned when the event is not found in the text of a quick add.
+type ErrInvalidQuickAdd struct {
+	Reason string
+}
+
+func (e ErrInvalidQuickAdd) Error() string {
+	return fmt.Sprintf("invalid quick add: %s", e.Reason)
+}
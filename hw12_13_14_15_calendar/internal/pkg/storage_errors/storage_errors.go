@@ -18,6 +18,10 @@ func (e ErrConnectionFailed) Error() string {
 	return fmt.Sprintf("Failed to connect to database: %s", e.Err.Error())
 }
 
+func (e ErrConnectionFailed) Unwrap() error {
+	return e.Err
+}
+
 type ErrPingFailed struct {
 	Err error
 }
@@ -26,6 +30,10 @@ func (e ErrPingFailed) Error() string {
 	return fmt.Sprintf("Failed to ping database: %s", e.Err.Error())
 }
 
+func (e ErrPingFailed) Unwrap() error {
+	return e.Err
+}
+
 type ErrCloseConnectionFailed struct {
 	Err error
 }
@@ -34,6 +42,10 @@ func (e ErrCloseConnectionFailed) Error() string {
 	return fmt.Sprintf("Failed to close connection to database: %s", e.Err.Error())
 }
 
+func (e ErrCloseConnectionFailed) Unwrap() error {
+	return e.Err
+}
+
 type ErrAddEvent struct {
 	Err error
 }
@@ -42,6 +54,10 @@ func (e ErrAddEvent) Error() string {
 	return fmt.Sprintf("Failed to add event to database: %s", e.Err.Error())
 }
 
+func (e ErrAddEvent) Unwrap() error {
+	return e.Err
+}
+
 type ErrUpdateEvent struct {
 	Err error
 }
@@ -50,6 +66,10 @@ func (e ErrUpdateEvent) Error() string {
 	return fmt.Sprintf("Failed to update event in database: %s", e.Err.Error())
 }
 
+func (e ErrUpdateEvent) Unwrap() error {
+	return e.Err
+}
+
 type ErrDeleteEvent struct {
 	Err error
 }
@@ -58,6 +78,10 @@ func (e ErrDeleteEvent) Error() string {
 	return fmt.Sprintf("Failed to delete event from database: %s", e.Err.Error())
 }
 
+func (e ErrDeleteEvent) Unwrap() error {
+	return e.Err
+}
+
 type ErrGetEvent struct {
 	Err error
 }
@@ -66,6 +90,10 @@ func (e ErrGetEvent) Error() string {
 	return fmt.Sprintf("Failed to get event from database: %s", e.Err.Error())
 }
 
+func (e ErrGetEvent) Unwrap() error {
+	return e.Err
+}
+
 type ErrListEvents struct {
 	Err error
 }
@@ -73,3 +101,547 @@ type ErrListEvents struct {
 func (e ErrListEvents) Error() string {
 	return fmt.Sprintf("Failed to list events from database: %s", e.Err.Error())
 }
+
+func (e ErrListEvents) Unwrap() error {
+	return e.Err
+}
+
+type ErrAddAuditRecord struct {
+	Err error
+}
+
+func (e ErrAddAuditRecord) Error() string {
+	return fmt.Sprintf("Failed to add audit record to database: %s", e.Err.Error())
+}
+
+func (e ErrAddAuditRecord) Unwrap() error {
+	return e.Err
+}
+
+type ErrListAuditRecords struct {
+	Err error
+}
+
+func (e ErrListAuditRecords) Error() string {
+	return fmt.Sprintf("Failed to list audit records from database: %s", e.Err.Error())
+}
+
+func (e ErrListAuditRecords) Unwrap() error {
+	return e.Err
+}
+
+type ErrRestoreEvent struct {
+	Err error
+}
+
+func (e ErrRestoreEvent) Error() string {
+	return fmt.Sprintf("Failed to restore event in database: %s", e.Err.Error())
+}
+
+func (e ErrRestoreEvent) Unwrap() error {
+	return e.Err
+}
+
+type ErrPurgeEvents struct {
+	Err error
+}
+
+func (e ErrPurgeEvents) Error() string {
+	return fmt.Sprintf("Failed to purge deleted events from database: %s", e.Err.Error())
+}
+
+func (e ErrPurgeEvents) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundAttendee struct {
+	EventID string
+	UserID  string
+}
+
+func (e ErrNotFoundAttendee) Error() string {
+	return fmt.Sprintf("user '%s' is not invited to event '%s'", e.UserID, e.EventID)
+}
+
+type ErrAddAttendees struct {
+	Err error
+}
+
+func (e ErrAddAttendees) Error() string {
+	return fmt.Sprintf("Failed to add attendees to database: %s", e.Err.Error())
+}
+
+func (e ErrAddAttendees) Unwrap() error {
+	return e.Err
+}
+
+type ErrUpdateAttendee struct {
+	Err error
+}
+
+func (e ErrUpdateAttendee) Error() string {
+	return fmt.Sprintf("Failed to update attendee in database: %s", e.Err.Error())
+}
+
+func (e ErrUpdateAttendee) Unwrap() error {
+	return e.Err
+}
+
+type ErrPersistence struct {
+	Err error
+}
+
+func (e ErrPersistence) Error() string {
+	return fmt.Sprintf("Failed to persist storage state: %s", e.Err.Error())
+}
+
+func (e ErrPersistence) Unwrap() error {
+	return e.Err
+}
+
+type ErrTransaction struct {
+	Err error
+}
+
+func (e ErrTransaction) Error() string {
+	return fmt.Sprintf("Failed to commit transaction: %s", e.Err.Error())
+}
+
+func (e ErrTransaction) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundWebhook struct {
+	ID string
+}
+
+func (e ErrNotFoundWebhook) Error() string {
+	return fmt.Sprintf("webhook '%s' is not found in storage", e.ID)
+}
+
+type ErrAddWebhook struct {
+	Err error
+}
+
+func (e ErrAddWebhook) Error() string {
+	return fmt.Sprintf("Failed to add webhook to database: %s", e.Err.Error())
+}
+
+func (e ErrAddWebhook) Unwrap() error {
+	return e.Err
+}
+
+type ErrGetWebhook struct {
+	Err error
+}
+
+func (e ErrGetWebhook) Error() string {
+	return fmt.Sprintf("Failed to get webhook from database: %s", e.Err.Error())
+}
+
+func (e ErrGetWebhook) Unwrap() error {
+	return e.Err
+}
+
+type ErrDeleteWebhook struct {
+	Err error
+}
+
+func (e ErrDeleteWebhook) Error() string {
+	return fmt.Sprintf("Failed to delete webhook from database: %s", e.Err.Error())
+}
+
+func (e ErrDeleteWebhook) Unwrap() error {
+	return e.Err
+}
+
+type ErrListWebhooks struct {
+	Err error
+}
+
+func (e ErrListWebhooks) Error() string {
+	return fmt.Sprintf("Failed to list webhooks from database: %s", e.Err.Error())
+}
+
+func (e ErrListWebhooks) Unwrap() error {
+	return e.Err
+}
+
+type ErrSaveWebhookDelivery struct {
+	Err error
+}
+
+func (e ErrSaveWebhookDelivery) Error() string {
+	return fmt.Sprintf("Failed to save webhook delivery to database: %s", e.Err.Error())
+}
+
+func (e ErrSaveWebhookDelivery) Unwrap() error {
+	return e.Err
+}
+
+type ErrListWebhookDeliveries struct {
+	Err error
+}
+
+func (e ErrListWebhookDeliveries) Error() string {
+	return fmt.Sprintf("Failed to list webhook deliveries from database: %s", e.Err.Error())
+}
+
+func (e ErrListWebhookDeliveries) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundUserProfile struct {
+	UserID string
+}
+
+func (e ErrNotFoundUserProfile) Error() string {
+	return fmt.Sprintf("profile of user '%s' is not found in storage", e.UserID)
+}
+
+type ErrSaveUserProfile struct {
+	Err error
+}
+
+func (e ErrSaveUserProfile) Error() string {
+	return fmt.Sprintf("Failed to save user profile to database: %s", e.Err.Error())
+}
+
+func (e ErrSaveUserProfile) Unwrap() error {
+	return e.Err
+}
+
+type ErrGetUserProfile struct {
+	Err error
+}
+
+func (e ErrGetUserProfile) Error() string {
+	return fmt.Sprintf("Failed to get user profile from database: %s", e.Err.Error())
+}
+
+func (e ErrGetUserProfile) Unwrap() error {
+	return e.Err
+}
+
+type ErrSaveTag struct {
+	Err error
+}
+
+func (e ErrSaveTag) Error() string {
+	return fmt.Sprintf("Failed to save tag to database: %s", e.Err.Error())
+}
+
+func (e ErrSaveTag) Unwrap() error {
+	return e.Err
+}
+
+type ErrListTags struct {
+	Err error
+}
+
+func (e ErrListTags) Error() string {
+	return fmt.Sprintf("Failed to list tags from database: %s", e.Err.Error())
+}
+
+func (e ErrListTags) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundAttachment struct {
+	ID string
+}
+
+func (e ErrNotFoundAttachment) Error() string {
+	return fmt.Sprintf("attachment '%s' is not found in storage", e.ID)
+}
+
+type ErrAddAttachment struct {
+	Err error
+}
+
+func (e ErrAddAttachment) Error() string {
+	return fmt.Sprintf("Failed to add attachment to database: %s", e.Err.Error())
+}
+
+func (e ErrAddAttachment) Unwrap() error {
+	return e.Err
+}
+
+type ErrGetAttachment struct {
+	Err error
+}
+
+func (e ErrGetAttachment) Error() string {
+	return fmt.Sprintf("Failed to get attachment from database: %s", e.Err.Error())
+}
+
+func (e ErrGetAttachment) Unwrap() error {
+	return e.Err
+}
+
+type ErrListAttachments struct {
+	Err error
+}
+
+func (e ErrListAttachments) Error() string {
+	return fmt.Sprintf("Failed to list attachments from database: %s", e.Err.Error())
+}
+
+func (e ErrListAttachments) Unwrap() error {
+	return e.Err
+}
+
+type ErrDeleteAttachment struct {
+	Err error
+}
+
+func (e ErrDeleteAttachment) Error() string {
+	return fmt.Sprintf("Failed to delete attachment from database: %s", e.Err.Error())
+}
+
+func (e ErrDeleteAttachment) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundBlob struct {
+	Key string
+}
+
+func (e ErrNotFoundBlob) Error() string {
+	return fmt.Sprintf("blob '%s' is not found in blob store", e.Key)
+}
+
+type ErrSaveBlob struct {
+	Err error
+}
+
+func (e ErrSaveBlob) Error() string {
+	return fmt.Sprintf("Failed to save blob: %s", e.Err.Error())
+}
+
+func (e ErrSaveBlob) Unwrap() error {
+	return e.Err
+}
+
+type ErrReadBlob struct {
+	Err error
+}
+
+func (e ErrReadBlob) Error() string {
+	return fmt.Sprintf("Failed to read blob: %s", e.Err.Error())
+}
+
+func (e ErrReadBlob) Unwrap() error {
+	return e.Err
+}
+
+type ErrDeleteBlob struct {
+	Err error
+}
+
+func (e ErrDeleteBlob) Error() string {
+	return fmt.Sprintf("Failed to delete blob: %s", e.Err.Error())
+}
+
+func (e ErrDeleteBlob) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundResource struct {
+	ID string
+}
+
+func (e ErrNotFoundResource) Error() string {
+	return fmt.Sprintf("resource '%s' is not found in storage", e.ID)
+}
+
+// ErrResourceBusy is returned when the resource is booked for another event at the same time.
+type ErrResourceBusy struct {
+	ResourceID string
+	EventID    string
+}
+
+func (e ErrResourceBusy) Error() string {
+	return fmt.Sprintf("resource '%s' is booked for event '%s' at the same time", e.ResourceID, e.EventID)
+}
+
+// ErrInvalidBookingPeriod is returned when resources are booked for an event which does not end
+// after it starts, such a booking would never conflict with others.
+type ErrInvalidBookingPeriod struct {
+	EventID string
+}
+
+func (e ErrInvalidBookingPeriod) Error() string {
+	return fmt.Sprintf("event '%s' must end after it starts to book resources", e.EventID)
+}
+
+type ErrAddResource struct {
+	Err error
+}
+
+func (e ErrAddResource) Error() string {
+	return fmt.Sprintf("Failed to add resource to database: %s", e.Err.Error())
+}
+
+func (e ErrAddResource) Unwrap() error {
+	return e.Err
+}
+
+type ErrUpdateResource struct {
+	Err error
+}
+
+func (e ErrUpdateResource) Error() string {
+	return fmt.Sprintf("Failed to update resource in database: %s", e.Err.Error())
+}
+
+func (e ErrUpdateResource) Unwrap() error {
+	return e.Err
+}
+
+type ErrGetResource struct {
+	Err error
+}
+
+func (e ErrGetResource) Error() string {
+	return fmt.Sprintf("Failed to get resource from database: %s", e.Err.Error())
+}
+
+func (e ErrGetResource) Unwrap() error {
+	return e.Err
+}
+
+type ErrListResources struct {
+	Err error
+}
+
+func (e ErrListResources) Error() string {
+	return fmt.Sprintf("Failed to list resources from database: %s", e.Err.Error())
+}
+
+func (e ErrListResources) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundShare struct {
+	OwnerID string
+	UserID  string
+}
+
+func (e ErrNotFoundShare) Error() string {
+	return fmt.Sprintf("calendar of '%s' is not shared with '%s'", e.OwnerID, e.UserID)
+}
+
+type ErrSaveShare struct {
+	Err error
+}
+
+func (e ErrSaveShare) Error() string {
+	return fmt.Sprintf("Failed to save share to database: %s", e.Err.Error())
+}
+
+func (e ErrSaveShare) Unwrap() error {
+	return e.Err
+}
+
+type ErrDeleteShare struct {
+	Err error
+}
+
+func (e ErrDeleteShare) Error() string {
+	return fmt.Sprintf("Failed to delete share from database: %s", e.Err.Error())
+}
+
+func (e ErrDeleteShare) Unwrap() error {
+	return e.Err
+}
+
+type ErrListShares struct {
+	Err error
+}
+
+func (e ErrListShares) Error() string {
+	return fmt.Sprintf("Failed to list shares from database: %s", e.Err.Error())
+}
+
+func (e ErrListShares) Unwrap() error {
+	return e.Err
+}
+
+type ErrNotFoundReminder struct {
+	ID string
+}
+
+func (e ErrNotFoundReminder) Error() string {
+	return fmt.Sprintf("reminder '%s' is not found in storage", e.ID)
+}
+
+type ErrReminderExists struct {
+	ID string
+}
+
+func (e ErrReminderExists) Error() string {
+	return fmt.Sprintf("reminder '%s' is already recorded", e.ID)
+}
+
+type ErrAddReminder struct {
+	Err error
+}
+
+func (e ErrAddReminder) Error() string {
+	return fmt.Sprintf("Failed to add reminder to database: %s", e.Err.Error())
+}
+
+func (e ErrAddReminder) Unwrap() error {
+	return e.Err
+}
+
+type ErrGetReminder struct {
+	Err error
+}
+
+func (e ErrGetReminder) Error() string {
+	return fmt.Sprintf("Failed to get reminder from database: %s", e.Err.Error())
+}
+
+func (e ErrGetReminder) Unwrap() error {
+	return e.Err
+}
+
+type ErrDeleteReminders struct {
+	Err error
+}
+
+func (e ErrDeleteReminders) Error() string {
+	return fmt.Sprintf("Failed to delete reminders from database: %s", e.Err.Error())
+}
+
+func (e ErrDeleteReminders) Unwrap() error {
+	return e.Err
+}
+
+type ErrAcquireLease struct {
+	Name string
+	Err  error
+}
+
+func (e ErrAcquireLease) Error() string {
+	return fmt.Sprintf("Failed to acquire lease %s: %s", e.Name, e.Err.Error())
+}
+
+func (e ErrAcquireLease) Unwrap() error {
+	return e.Err
+}
+
+type ErrReleaseLease struct {
+	Name string
+	Err  error
+}
+
+func (e ErrReleaseLease) Error() string {
+	return fmt.Sprintf("Failed to release lease %s: %s", e.Name, e.Err.Error())
+}
+
+func (e ErrReleaseLease) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,142 @@
+package memoryqueue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue"
+)
+
+type unacked struct {
+	message  queue.Message
+	consumer uint64
+}
+
+// Queue keeps messages in memory, so it connects the scheduler and the sender
+// only when they run in the same process. Every message is delivered to one of consumers.
+type Queue struct {
+	mu        sync.Mutex
+	ready     []queue.Message
+	unacked   map[uint64]unacked
+	tag       uint64
+	consumers uint64
+	// wake is closed and replaced when messages become ready
+	wake chan struct{}
+}
+
+func New() *Queue {
+	return &Queue{
+		unacked: make(map[uint64]unacked),
+		wake:    make(chan struct{}),
+	}
+}
+
+func (q *Queue) Publish(ctx context.Context, message queue.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ready = append(q.ready, message)
+	q.wakeUp()
+	return nil
+}
+
+// wakeUp notifies waiting consumers, callers must hold the lock.
+func (q *Queue) wakeUp() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// Len returns the number of messages which are not acknowledged yet.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready) + len(q.unacked)
+}
+
+// Consume delivers messages one by one, the next message is delivered after the previous is received.
+func (q *Queue) Consume(ctx context.Context) (<-chan queue.Delivery, error) {
+	q.mu.Lock()
+	q.consumers++
+	consumer := q.consumers
+	q.mu.Unlock()
+	deliveries := make(chan queue.Delivery)
+	go func() {
+		defer close(deliveries)
+		defer q.requeue(consumer)
+		for {
+			q.mu.Lock()
+			if len(q.ready) == 0 {
+				wake := q.wake
+				q.mu.Unlock()
+				select {
+				case <-ctx.Done():
+					return
+				case <-wake:
+					continue
+				}
+			}
+			message := q.ready[0]
+			q.ready = q.ready[1:]
+			q.tag++
+			tag := q.tag
+			q.unacked[tag] = unacked{message: message, consumer: consumer}
+			q.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case deliveries <- queue.Delivery{Message: message, Tag: tag, Acknowledger: q}:
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// requeue returns messages delivered to the consumer and not acknowledged to the head of the queue,
+// as a broker does when the consumer disconnects.
+func (q *Queue) requeue(consumer uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tags := make([]uint64, 0)
+	for tag, delivery := range q.unacked {
+		if delivery.consumer == consumer {
+			tags = append(tags, tag)
+		}
+	}
+	// messages keep the order they were delivered in
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	returned := make([]queue.Message, 0, len(tags))
+	for _, tag := range tags {
+		returned = append(returned, q.unacked[tag].message)
+		delete(q.unacked, tag)
+	}
+	if len(returned) > 0 {
+		q.ready = append(returned, q.ready...)
+		q.wakeUp()
+	}
+}
+
+func (q *Queue) Ack(tag uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.unacked[tag]; !ok {
+		return fmt.Errorf("unknown delivery tag %d", tag)
+	}
+	delete(q.unacked, tag)
+	return nil
+}
+
+func (q *Queue) Nack(tag uint64, requeue bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delivery, ok := q.unacked[tag]
+	if !ok {
+		return fmt.Errorf("unknown delivery tag %d", tag)
+	}
+	delete(q.unacked, tag)
+	if requeue {
+		q.ready = append(q.ready, delivery.message)
+		q.wakeUp()
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package memoryqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, deliveries <-chan queue.Delivery) queue.Delivery {
+	t.Helper()
+	select {
+	case delivery, ok := <-deliveries:
+		require.True(t, ok, "deliveries are closed")
+		return delivery
+	case <-time.After(time.Second):
+		require.FailNow(t, "no delivery received")
+	}
+	return queue.Delivery{}
+}
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ack and nack", func(t *testing.T) {
+		q := New()
+		consumeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		deliveries, err := q.Consume(consumeCtx)
+		require.NoError(t, err)
+		for _, id := range []string{"first", "second", "third"} {
+			require.NoError(t, q.Publish(ctx, queue.Message{ID: id, Body: []byte(id)}))
+		}
+
+		first := receive(t, deliveries)
+		require.Equal(t, "first", first.ID)
+		require.Equal(t, []byte("first"), first.Body)
+		require.NoError(t, first.Ack())
+		require.Error(t, first.Ack())
+
+		second := receive(t, deliveries)
+		require.NoError(t, second.Nack(true))
+		third := receive(t, deliveries)
+		require.Equal(t, "third", third.ID)
+		require.NoError(t, third.Nack(false))
+
+		require.Equal(t, "second", receive(t, deliveries).ID)
+	})
+
+	t.Run("unacknowledged messages are redelivered to other consumers", func(t *testing.T) {
+		q := New()
+		require.NoError(t, q.Publish(ctx, queue.Message{ID: "first"}))
+		require.NoError(t, q.Publish(ctx, queue.Message{ID: "second"}))
+
+		consumeCtx, cancel := context.WithCancel(ctx)
+		deliveries, err := q.Consume(consumeCtx)
+		require.NoError(t, err)
+		require.Equal(t, "first", receive(t, deliveries).ID)
+		cancel()
+		for range deliveries {
+		}
+		require.Equal(t, 2, q.Len())
+
+		consumeCtx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		deliveries, err = q.Consume(consumeCtx)
+		require.NoError(t, err)
+		first := receive(t, deliveries)
+		require.Equal(t, "first", first.ID)
+		require.NoError(t, first.Ack())
+		require.Equal(t, "second", receive(t, deliveries).ID)
+	})
+}
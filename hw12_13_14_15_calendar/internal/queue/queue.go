@@ -0,0 +1,45 @@
+// Package queue describes the message queue between the scheduler and the sender,
+// so they do not depend on a particular broker.
+package queue
+
+import "context"
+
+// Message is published to the queue. ID identifies the message, copies of the message
+// published again or redelivered have the same ID, so consumers can deduplicate them.
+type Message struct {
+	ID   string
+	Body []byte
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+type Consumer interface {
+	// Consume returns deliveries of messages until ctx is done, then the channel is closed and
+	// deliveries which are not acknowledged are returned to the queue.
+	Consume(ctx context.Context) (<-chan Delivery, error)
+}
+
+// Acknowledger settles deliveries by their tags.
+type Acknowledger interface {
+	Ack(tag uint64) error
+	Nack(tag uint64, requeue bool) error
+}
+
+// Delivery is a received message, it has to be settled with Ack or Nack.
+type Delivery struct {
+	Message
+	Tag          uint64
+	Acknowledger Acknowledger
+}
+
+// Ack removes the message from the queue.
+func (d Delivery) Ack() error {
+	return d.Acknowledger.Ack(d.Tag)
+}
+
+// Nack returns the message to the queue if requeue is set and drops it otherwise.
+func (d Delivery) Nack(requeue bool) error {
+	return d.Acknowledger.Nack(d.Tag, requeue)
+}
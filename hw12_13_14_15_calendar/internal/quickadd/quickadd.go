@@ -0,0 +1,557 @@
+// Package quickadd makes events of short texts such as "Lunch with Sam tomorrow 1pm for 1h"
+// or "Обед с Сэмом завтра в 13:00 на час".
+//
+// A text consists of the title and phrases of the date, the time and the duration in any order:
+//
+//   - dates: today, tomorrow, the day after tomorrow, weekdays ("friday", "next monday"),
+//     "in 3 days", "24 oct", "october 24th", "24.10", "24.10.2022", "2022-10-24";
+//   - times: "1pm", "1:30 pm", "13:00", "at 13", "noon", ranges "10-11am", "from 10 to 11:30";
+//   - durations: "for 1h", "for 90 minutes", "for an hour", "1h30m", "for 2 days";
+//   - "all day".
+//
+// Russian phrases are understood as well: "завтра", "в пятницу", "через неделю", "24 октября",
+// "в 13:00", "в 8 вечера", "с 10 до 11", "на полчаса", "на 2 дня", "весь день". Words which are
+// not a part of any phrase make the title. Bare numbers are times only after a preposition
+// ("at 3", "в 3"), so "Buy 2 tickets" keeps its number.
+package quickadd
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// DefaultDuration is the duration of events with the start time only.
+const DefaultDuration = time.Hour
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+
+	am    = "am"
+	pm    = "pm"
+	night = "night"
+)
+
+var (
+	ErrNoTitle    = errors.New("title is not found")
+	ErrNoTime     = errors.New("date or time is not found")
+	ErrNoStart    = errors.New("start time is required for a duration shorter than a day")
+	ErrAllDayTime = errors.New("all-day event can't have time")
+)
+
+var (
+	clockRe     = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a\.m\.|p\.m\.)?$`)
+	rangeRe     = regexp.MustCompile(`^(\d{1,2}(?::\d{2})?(?:am|pm)?)[-–—](\d{1,2}(?::\d{2})?(?:am|pm)?)$`)
+	dottedRe    = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{4}))?$`)
+	dayNumberRe = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	yearRe      = regexp.MustCompile(`^\d{4}$`)
+	compactRe   = regexp.MustCompile(`(\d+(?:[.,]\d+)?)([a-zа-яё]+)`)
+	numberRe    = regexp.MustCompile(`^\d+(?:[.,]\d+)?$`)
+)
+
+type token struct {
+	// text is the token as it is written, it goes to the title unless the token is used.
+	text string
+	// word is the text in lower case without trailing punctuation.
+	word string
+	used bool
+}
+
+// clock is a time of a day as it is written, the half of the day is resolved by hourOfDay.
+type clock struct {
+	hour, minute int
+	meridiem     string
+	// exact clocks have minutes, a meridiem or an hour word, bare numbers are times only
+	// after a preposition.
+	exact bool
+}
+
+func (c clock) hourOfDay() int {
+	switch {
+	case c.meridiem == pm && c.hour < 12:
+		return c.hour + 12
+	case (c.meridiem == am || c.meridiem == night) && c.hour == 12:
+		return 0
+	case c.meridiem == night && c.hour >= 9:
+		// в 11 ночи
+		return c.hour + 12
+	default:
+		return c.hour
+	}
+}
+
+func (c clock) valid() bool {
+	if c.minute > 59 {
+		return false
+	}
+	if c.meridiem == "" {
+		return c.hour <= 23
+	}
+	return c.hour >= 1 && c.hour <= 12
+}
+
+type parser struct {
+	tokens []token
+	now    time.Time
+
+	date    time.Time
+	hasDate bool
+
+	start, end       clock
+	hasStart, hasEnd bool
+
+	duration time.Duration
+	allDay   bool
+}
+
+// Parse makes the event of text. Relative dates and times are taken from now, in its location,
+// which becomes the zone of the event. Events with a date only are all-day, events with a time
+// only take place today or tomorrow if the time has passed.
+func Parse(text string, now time.Time) (*storage.Event, error) {
+	p := &parser{tokens: tokenize(text), now: now}
+	for i := 0; i < len(p.tokens); {
+		if n := p.match(i); n > 0 {
+			for j := i; j < i+n; j++ {
+				p.tokens[j].used = true
+			}
+			i += n
+			continue
+		}
+		i++
+	}
+	return p.event()
+}
+
+func tokenize(text string) []token {
+	fields := strings.Fields(text)
+	tokens := make([]token, 0, len(fields))
+	for _, field := range fields {
+		word := strings.ToLower(strings.TrimRight(field, ",;!?"))
+		if !strings.HasSuffix(word, "a.m.") && !strings.HasSuffix(word, "p.m.") {
+			word = strings.TrimSuffix(word, ".")
+		}
+		// ranges written without spaces are split, so they are parsed as ones with spaces
+		if m := rangeRe.FindStringSubmatch(word); m != nil {
+			tokens = append(tokens, token{text: field, word: m[1]}, token{word: "-"}, token{word: m[2]})
+			continue
+		}
+		tokens = append(tokens, token{text: field, word: word})
+	}
+	return tokens
+}
+
+// word returns the word of the token i or empty string if it is out of range or used already.
+func (p *parser) word(i int) string {
+	if i >= len(p.tokens) || p.tokens[i].used {
+		return ""
+	}
+	return p.tokens[i].word
+}
+
+// match parses a phrase starting at the token i and returns the number of its tokens.
+// Phrases of the same kind are taken once, repeated ones stay in the title.
+func (p *parser) match(i int) int {
+	if !p.hasDate {
+		if n, date := p.matchDate(i); n > 0 {
+			p.date, p.hasDate = date, true
+			return n
+		}
+	}
+	if !p.hasStart {
+		if n := p.matchTime(i); n > 0 {
+			return n
+		}
+	}
+	if p.duration == 0 {
+		if n, duration := p.matchDuration(i); n > 0 {
+			p.duration = duration
+			return n
+		}
+	}
+	if !p.allDay {
+		if n := p.matchAllDay(i); n > 0 {
+			p.allDay = true
+			return n
+		}
+	}
+	return 0
+}
+
+func (p *parser) today() time.Time {
+	year, month, d := p.now.Date()
+	return time.Date(year, month, d, 0, 0, 0, 0, p.now.Location())
+}
+
+func (p *parser) matchDate(i int) (int, time.Time) {
+	word := p.word(i)
+	if offset, ok := relativeDays[word]; ok {
+		return 1, p.today().AddDate(0, 0, offset)
+	}
+	if word == "the" && p.word(i+1) == "day" && p.word(i+2) == "after" && p.word(i+3) == "tomorrow" {
+		return 4, p.today().AddDate(0, 0, 2)
+	}
+	if word == "day" && p.word(i+1) == "after" && p.word(i+2) == "tomorrow" {
+		return 3, p.today().AddDate(0, 0, 2)
+	}
+	if n, date := p.matchWeekday(i); n > 0 {
+		return n, date
+	}
+	if n, date := p.matchAfter(i); n > 0 {
+		return n, date
+	}
+	prefix := 0
+	if datePrepositions[word] {
+		prefix = 1
+	}
+	if n, date := p.matchCalendarDate(i + prefix); n > 0 {
+		return prefix + n, date
+	}
+	return 0, time.Time{}
+}
+
+// matchWeekday parses "friday", "on fri", "next monday", "в следующую пятницу". The nearest weekday
+// is taken, today included, "next" ones are after today.
+func (p *parser) matchWeekday(i int) (int, time.Time) {
+	n := 0
+	if datePrepositions[p.word(i)] {
+		n++
+	}
+	next := nextWords[p.word(i+n)]
+	if next {
+		n++
+	}
+	weekday, ok := weekdays[p.word(i+n)]
+	if !ok && n > 0 {
+		weekday, ok = shortWeekdays[p.word(i+n)]
+	}
+	if !ok {
+		return 0, time.Time{}
+	}
+	today := p.today()
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if next && days == 0 {
+		days = 7
+	}
+	return n + 1, today.AddDate(0, 0, days)
+}
+
+// matchAfter parses "in 3 days", "in a week", "через 2 дня", "через неделю".
+func (p *parser) matchAfter(i int) (int, time.Time) {
+	if !afterDays[p.word(i)] {
+		return 0, time.Time{}
+	}
+	n, duration := p.matchAmount(i + 1)
+	if whole, ok := wholeDurations[p.word(i+1)]; ok && n == 0 {
+		n, duration = 1, whole
+	}
+	if n == 0 || duration%day != 0 {
+		return 0, time.Time{}
+	}
+	return 1 + n, p.today().AddDate(0, 0, int(duration/day))
+}
+
+// matchCalendarDate parses "24.10", "24.10.2022", "2022-10-24", "24 oct", "october 24th, 2022",
+// "24 октября". Dates without a year are the nearest ones, today included.
+func (p *parser) matchCalendarDate(i int) (int, time.Time) {
+	word := p.word(i)
+	if date, err := time.ParseInLocation("2006-01-02", word, p.now.Location()); err == nil {
+		return 1, date
+	}
+	if m := dottedRe.FindStringSubmatch(word); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		year, _ := strconv.Atoi(m[3])
+		if date, ok := p.dayOfYear(year, time.Month(month), d); ok {
+			return 1, date
+		}
+		return 0, time.Time{}
+	}
+	var (
+		d     int
+		month time.Month
+		ok    bool
+	)
+	if m := dayNumberRe.FindStringSubmatch(word); m != nil {
+		// 24 oct
+		if month, ok = months[p.word(i+1)]; !ok {
+			return 0, time.Time{}
+		}
+		d, _ = strconv.Atoi(m[1])
+	} else if month, ok = months[word]; ok {
+		// oct 24
+		m := dayNumberRe.FindStringSubmatch(p.word(i + 1))
+		if m == nil {
+			return 0, time.Time{}
+		}
+		d, _ = strconv.Atoi(m[1])
+	} else {
+		return 0, time.Time{}
+	}
+	n, year := 2, 0
+	if yearRe.MatchString(p.word(i + 2)) {
+		year, _ = strconv.Atoi(p.word(i + 2))
+		n++
+	}
+	date, ok := p.dayOfYear(year, month, d)
+	if !ok {
+		return 0, time.Time{}
+	}
+	return n, date
+}
+
+// dayOfYear returns the date, the nearest one from today if the year is zero.
+func (p *parser) dayOfYear(year int, month time.Month, d int) (time.Time, bool) {
+	nearest := year == 0
+	if nearest {
+		year = p.now.Year()
+	}
+	date := time.Date(year, month, d, 0, 0, 0, 0, p.now.Location())
+	if date.Day() != d || date.Month() != month {
+		return time.Time{}, false
+	}
+	if nearest && date.Before(p.today()) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, true
+}
+
+// matchTime parses times and ranges of them. Bare numbers are taken after a preposition
+// or with an exact end of the range only: "at 10", "10-11am".
+func (p *parser) matchTime(i int) int {
+	n := 0
+	if timePrepositions[p.word(i)] {
+		n++
+	}
+	exact := n > 0
+	m, start := p.matchClock(i + n)
+	if m == 0 {
+		return 0
+	}
+	n += m
+	exact = exact || start.exact
+	var (
+		end    clock
+		hasEnd bool
+	)
+	if rangeSeparators[p.word(i+n)] {
+		if m, end = p.matchClock(i + n + 1); m > 0 {
+			n += 1 + m
+			hasEnd, exact = true, exact || end.exact
+		}
+	}
+	if !exact {
+		return 0
+	}
+	// 1-2pm is 13-14, 11-1pm is 11-13
+	if hasEnd && start.meridiem == "" && end.meridiem != "" && start.hour <= 12 {
+		start.meridiem = end.meridiem
+		if start.hourOfDay() > end.hourOfDay() {
+			start.meridiem = am
+		}
+	}
+	p.start, p.hasStart = start, true
+	p.end, p.hasEnd = end, hasEnd
+	return n
+}
+
+// matchClock parses "13", "13:30", "1pm", "1 pm", "noon", "в 3 часа дня", "в 8 вечера".
+func (p *parser) matchClock(i int) (int, clock) {
+	word := p.word(i)
+	if c, ok := namedTimes[word]; ok {
+		c.exact = true
+		return 1, c
+	}
+	m := clockRe.FindStringSubmatch(word)
+	if m == nil {
+		return 0, clock{}
+	}
+	c := clock{meridiem: meridiems[m[3]], exact: m[2] != "" || m[3] != ""}
+	c.hour, _ = strconv.Atoi(m[1])
+	c.minute, _ = strconv.Atoi(m[2])
+	n := 1
+	if c.meridiem == "" {
+		// в 15 часов
+		if hourWords[p.word(i+n)] && m[2] == "" {
+			c.exact = true
+			n++
+		}
+		if meridiem, ok := meridiems[p.word(i+n)]; ok {
+			c.meridiem, c.exact = meridiem, true
+			n++
+		}
+	}
+	if !c.valid() {
+		return 0, clock{}
+	}
+	return n, c
+}
+
+// matchDuration parses "for 1h", "for 1 hour 30 minutes", "for half an hour", "на 2 часа",
+// "на полчаса", "на час" and compact durations like "1h30m" or "2ч" without a preposition.
+func (p *parser) matchDuration(i int) (int, time.Duration) {
+	word := p.word(i)
+	if !durationPrepositions[word] {
+		if duration, ok := parseCompact(word); ok {
+			return 1, duration
+		}
+		return 0, 0
+	}
+	next := p.word(i + 1)
+	if duration, ok := wholeDurations[next]; ok {
+		return 2, duration
+	}
+	if next == "half" && ones[p.word(i+2)] && units[p.word(i+3)] == time.Hour {
+		return 4, 30 * time.Minute
+	}
+	if next == "полтора" && units[p.word(i+2)] == time.Hour {
+		return 3, 90 * time.Minute
+	}
+	n, duration := p.matchAmount(i + 1)
+	if n == 0 {
+		return 0, 0
+	}
+	n++
+	// for 1 hour 30 minutes, на 1 час 30 минут
+	if p.word(i+n) == "and" || p.word(i+n) == "и" {
+		if m, more := p.matchAmount(i + n + 1); m > 0 {
+			return n + 1 + m, duration + more
+		}
+	}
+	if m, more := p.matchAmount(i + n); m > 0 {
+		return n + m, duration + more
+	}
+	return n, duration
+}
+
+// matchAmount parses "2 hours", "an hour", "1.5h", "3 дня".
+func (p *parser) matchAmount(i int) (int, time.Duration) {
+	word := p.word(i)
+	if duration, ok := parseCompact(word); ok {
+		return 1, duration
+	}
+	unit, ok := units[p.word(i+1)]
+	if !ok {
+		return 0, 0
+	}
+	if ones[word] {
+		return 2, unit
+	}
+	if !numberRe.MatchString(word) {
+		return 0, 0
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(word, ",", ".", 1), 64)
+	if err != nil || amount <= 0 {
+		return 0, 0
+	}
+	return 2, time.Duration(amount * float64(unit))
+}
+
+// parseCompact parses amounts with units attached like "90m", "1.5h", "1h30m", "2ч".
+func parseCompact(word string) (time.Duration, bool) {
+	matches := compactRe.FindAllStringSubmatchIndex(word, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	var duration time.Duration
+	end := 0
+	for _, m := range matches {
+		if m[0] != end {
+			return 0, false
+		}
+		end = m[1]
+		unit, ok := units[word[m[4]:m[5]]]
+		if !ok {
+			return 0, false
+		}
+		amount, err := strconv.ParseFloat(strings.Replace(word[m[2]:m[3]], ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+		duration += time.Duration(amount * float64(unit))
+	}
+	if end != len(word) || duration <= 0 {
+		return 0, false
+	}
+	return duration, true
+}
+
+func (p *parser) matchAllDay(i int) int {
+	for _, phrase := range allDayPhrases {
+		matched := true
+		for j, word := range phrase {
+			if p.word(i+j) != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(phrase)
+		}
+	}
+	return 0
+}
+
+func (p *parser) title() string {
+	words := make([]string, 0, len(p.tokens))
+	for _, t := range p.tokens {
+		if !t.used && t.text != "" {
+			words = append(words, t.text)
+		}
+	}
+	return strings.Trim(strings.Join(words, " "), " ,;:-–—")
+}
+
+func (p *parser) event() (*storage.Event, error) {
+	title := p.title()
+	switch {
+	case title == "":
+		return nil, ErrNoTitle
+	case !p.hasDate && !p.hasStart:
+		return nil, ErrNoTime
+	case p.allDay && p.hasStart:
+		return nil, ErrAllDayTime
+	}
+	event := &storage.Event{Title: title, TimeZone: p.now.Location().String()}
+	if !p.hasStart {
+		if p.duration%day != 0 {
+			return nil, ErrNoStart
+		}
+		days := int(p.duration / day)
+		if days == 0 {
+			days = 1
+		}
+		event.AllDay = true
+		event.StartAt, event.EndAt = p.date, p.date.AddDate(0, 0, days)
+		return event, nil
+	}
+	date := p.date
+	if !p.hasDate {
+		date = p.today()
+		if at(date, p.start).Before(p.now) {
+			date = date.AddDate(0, 0, 1)
+		}
+	}
+	event.StartAt = at(date, p.start)
+	switch {
+	case p.hasEnd:
+		event.EndAt = at(date, p.end)
+		if !event.EndAt.After(event.StartAt) {
+			// ranges over midnight end the next day
+			event.EndAt = at(date.AddDate(0, 0, 1), p.end)
+		}
+	case p.duration > 0:
+		event.EndAt = event.StartAt.Add(p.duration)
+	default:
+		event.EndAt = event.StartAt.Add(DefaultDuration)
+	}
+	return event, nil
+}
+
+func at(date time.Time, c clock) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), c.hourOfDay(), c.minute, 0, 0, date.Location())
+}
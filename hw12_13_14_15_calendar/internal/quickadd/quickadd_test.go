@@ -0,0 +1,173 @@
+package quickadd_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/quickadd"
+	"github.com/stretchr/testify/require"
+)
+
+const layout = "2006-01-02 15:04"
+
+func TestParse(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	// Monday
+	now := time.Date(2022, time.October, 24, 10, 30, 0, 0, moscow)
+
+	for _, tc := range []struct {
+		text       string
+		title      string
+		start, end string
+		allDay     bool
+	}{
+		// the example of the feature
+		{"Lunch with Sam tomorrow 1pm for 1h", "Lunch with Sam", "2022-10-25 13:00", "2022-10-25 14:00", false},
+
+		// relative dates
+		{"Dentist today at 16:00", "Dentist", "2022-10-24 16:00", "2022-10-24 17:00", false},
+		{"Party the day after tomorrow", "Party", "2022-10-26 00:00", "2022-10-27 00:00", true},
+		{"Party day after tomorrow", "Party", "2022-10-26 00:00", "2022-10-27 00:00", true},
+		{"Review in 3 days", "Review", "2022-10-27 00:00", "2022-10-28 00:00", true},
+		{"Review in a week at 10am", "Review", "2022-10-31 10:00", "2022-10-31 11:00", false},
+		{"Day off tomorrow", "Day off", "2022-10-25 00:00", "2022-10-26 00:00", true},
+
+		// weekdays are the nearest ones, today included, "next" ones are after today
+		{"Retro friday 17:00", "Retro", "2022-10-28 17:00", "2022-10-28 18:00", false},
+		{"Retro on Friday at 5pm", "Retro", "2022-10-28 17:00", "2022-10-28 18:00", false},
+		{"Standup monday 9:30", "Standup", "2022-10-24 09:30", "2022-10-24 10:30", false},
+		{"Standup next monday 9:30", "Standup", "2022-10-31 09:30", "2022-10-31 10:30", false},
+		{"Yoga on sat 8am", "Yoga", "2022-10-29 08:00", "2022-10-29 09:00", false},
+		{"Picnic in the sun tomorrow", "Picnic in the sun", "2022-10-25 00:00", "2022-10-26 00:00", true},
+
+		// calendar dates
+		{"Release 2022-11-01 12:00", "Release", "2022-11-01 12:00", "2022-11-01 13:00", false},
+		{"Release on 1.11 at 12", "Release", "2022-11-01 12:00", "2022-11-01 13:00", false},
+		{"Release 01.11.2023", "Release", "2023-11-01 00:00", "2023-11-02 00:00", true},
+		{"Birthday oct 30", "Birthday", "2022-10-30 00:00", "2022-10-31 00:00", true},
+		{"Birthday October 30th, 2023", "Birthday", "2023-10-30 00:00", "2023-10-31 00:00", true},
+		{"Birthday 30 oct", "Birthday", "2022-10-30 00:00", "2022-10-31 00:00", true},
+		{"New year party 31 dec 20:00", "New year party", "2022-12-31 20:00", "2022-12-31 21:00", false},
+		{"Anniversary 1 jan", "Anniversary", "2023-01-01 00:00", "2023-01-02 00:00", true},
+		{"Past date 20.10 is next year", "Past date is next year", "2023-10-20 00:00", "2023-10-21 00:00", true},
+		{"Demo 24 oct", "Demo", "2022-10-24 00:00", "2022-10-25 00:00", true},
+
+		// times without a date are today or tomorrow if they have passed
+		{"Call mom at 18", "Call mom", "2022-10-24 18:00", "2022-10-24 19:00", false},
+		{"Call mom at 9", "Call mom", "2022-10-25 09:00", "2022-10-25 10:00", false},
+		{"Lunch at noon", "Lunch", "2022-10-24 12:00", "2022-10-24 13:00", false},
+		{"Deploy at midnight", "Deploy", "2022-10-25 00:00", "2022-10-25 01:00", false},
+		{"Call 1:30 pm tomorrow", "Call", "2022-10-25 13:30", "2022-10-25 14:30", false},
+		{"Call tomorrow 1 p.m.", "Call", "2022-10-25 13:00", "2022-10-25 14:00", false},
+		{"Call tomorrow 12am", "Call", "2022-10-25 00:00", "2022-10-25 01:00", false},
+		{"Call tomorrow 12pm", "Call", "2022-10-25 12:00", "2022-10-25 13:00", false},
+		{"Email @ 11:15", "Email", "2022-10-24 11:15", "2022-10-24 12:15", false},
+
+		// ranges, bare ones may be numbers of the title
+		{"Workshop tomorrow 10-12", "Workshop 10-12", "2022-10-25 00:00", "2022-10-26 00:00", true},
+		{"Workshop tomorrow at 10-12", "Workshop", "2022-10-25 10:00", "2022-10-25 12:00", false},
+		{"Workshop tomorrow 10:00-11:30", "Workshop", "2022-10-25 10:00", "2022-10-25 11:30", false},
+		{"Workshop tomorrow 1-2pm", "Workshop", "2022-10-25 13:00", "2022-10-25 14:00", false},
+		{"Workshop tomorrow 11-1pm", "Workshop", "2022-10-25 11:00", "2022-10-25 13:00", false},
+		{"Workshop tomorrow 10am - 11am", "Workshop", "2022-10-25 10:00", "2022-10-25 11:00", false},
+		{"Workshop tomorrow from 9 to 11:30", "Workshop", "2022-10-25 09:00", "2022-10-25 11:30", false},
+		{"Night shift friday from 22 till 6", "Night shift", "2022-10-28 22:00", "2022-10-29 06:00", false},
+
+		// durations
+		{"Gym tomorrow 7pm for 90 minutes", "Gym", "2022-10-25 19:00", "2022-10-25 20:30", false},
+		{"Gym tomorrow 7pm for an hour", "Gym", "2022-10-25 19:00", "2022-10-25 20:00", false},
+		{"Gym tomorrow 7pm for half an hour", "Gym", "2022-10-25 19:00", "2022-10-25 19:30", false},
+		{"Gym tomorrow 7pm for 1.5h", "Gym", "2022-10-25 19:00", "2022-10-25 20:30", false},
+		{"Gym tomorrow 7pm 1h30m", "Gym", "2022-10-25 19:00", "2022-10-25 20:30", false},
+		{"Gym tomorrow 7pm for 1 hour and 15 minutes", "Gym", "2022-10-25 19:00", "2022-10-25 20:15", false},
+		{"Gym tomorrow 7pm for 2 hours 30 min", "Gym", "2022-10-25 19:00", "2022-10-25 21:30", false},
+		{"Conference 2022-11-01 for 3 days", "Conference", "2022-11-01 00:00", "2022-11-04 00:00", true},
+		{"Vacation next monday for a week", "Vacation", "2022-10-31 00:00", "2022-11-07 00:00", true},
+		{"Offsite friday all day", "Offsite", "2022-10-28 00:00", "2022-10-29 00:00", true},
+
+		// numbers of titles are kept
+		{"Buy 2 tickets tomorrow", "Buy 2 tickets", "2022-10-25 00:00", "2022-10-26 00:00", true},
+		{"Read chapters 3-4 tomorrow", "Read chapters 3-4", "2022-10-25 00:00", "2022-10-26 00:00", true},
+		{"Meeting at office at 3pm tomorrow", "Meeting at office", "2022-10-25 15:00", "2022-10-25 16:00", false},
+		{"Trip to Rome, tomorrow", "Trip to Rome", "2022-10-25 00:00", "2022-10-26 00:00", true},
+		{"Lunch tomorrow, lunch friday", "Lunch lunch friday", "2022-10-25 00:00", "2022-10-26 00:00", true},
+
+		// Russian
+		{"Обед с Сэмом завтра в 13:00 на час", "Обед с Сэмом", "2022-10-25 13:00", "2022-10-25 14:00", false},
+		{"Созвон сегодня в 15", "Созвон", "2022-10-24 15:00", "2022-10-24 16:00", false},
+		{"Поход послезавтра", "Поход", "2022-10-26 00:00", "2022-10-27 00:00", true},
+		{"Ревью через 2 дня в 11", "Ревью", "2022-10-26 11:00", "2022-10-26 12:00", false},
+		{"Ревью через неделю", "Ревью", "2022-10-31 00:00", "2022-11-01 00:00", true},
+		{"Ретро в пятницу с 10 до 11", "Ретро", "2022-10-28 10:00", "2022-10-28 11:00", false},
+		{"Ретро во вторник в 17:30", "Ретро", "2022-10-25 17:30", "2022-10-25 18:30", false},
+		{"Планёрка в следующий понедельник в 9", "Планёрка", "2022-10-31 09:00", "2022-10-31 10:00", false},
+		{"Йога в сб в 8 утра", "Йога", "2022-10-29 08:00", "2022-10-29 09:00", false},
+		{"Кино завтра в 8 вечера", "Кино", "2022-10-25 20:00", "2022-10-25 21:00", false},
+		{"Звонок завтра в 3 часа дня", "Звонок", "2022-10-25 15:00", "2022-10-25 16:00", false},
+		{"Звонок завтра в 15 часов", "Звонок", "2022-10-25 15:00", "2022-10-25 16:00", false},
+		{"Релиз завтра в 2 ночи", "Релиз", "2022-10-25 02:00", "2022-10-25 03:00", false},
+		{"Релиз завтра в 11 ночи", "Релиз", "2022-10-25 23:00", "2022-10-26 00:00", false},
+		{"Обед в полдень", "Обед", "2022-10-24 12:00", "2022-10-24 13:00", false},
+		{"День рождения 30 октября", "День рождения", "2022-10-30 00:00", "2022-10-31 00:00", true},
+		{"Встреча 1 ноября 2022 в 15:30 на 2 часа", "Встреча", "2022-11-01 15:30", "2022-11-01 17:30", false},
+		{"Встреча завтра в 10 на полчаса", "Встреча", "2022-10-25 10:00", "2022-10-25 10:30", false},
+		{"Встреча завтра в 10 на полтора часа", "Встреча", "2022-10-25 10:00", "2022-10-25 11:30", false},
+		{"Встреча завтра в 10 на 1ч30м", "Встреча", "2022-10-25 10:00", "2022-10-25 11:30", false},
+		{"Встреча завтра в 10 на 45 минут", "Встреча", "2022-10-25 10:00", "2022-10-25 10:45", false},
+		{"Командировка 1.11 на 3 дня", "Командировка", "2022-11-01 00:00", "2022-11-04 00:00", true},
+		{"Отпуск в понедельник на неделю", "Отпуск", "2022-10-24 00:00", "2022-10-31 00:00", true},
+		{"Хакатон в субботу весь день", "Хакатон", "2022-10-29 00:00", "2022-10-30 00:00", true},
+		{"Купить 2 билета завтра", "Купить 2 билета", "2022-10-25 00:00", "2022-10-26 00:00", true},
+	} {
+		tc := tc
+		t.Run(tc.text, func(t *testing.T) {
+			event, err := quickadd.Parse(tc.text, now)
+			require.NoError(t, err)
+			require.Equal(t, tc.title, event.Title)
+			require.Equal(t, tc.start, event.StartAt.Format(layout))
+			require.Equal(t, tc.end, event.EndAt.Format(layout))
+			require.Equal(t, tc.allDay, event.AllDay)
+			require.Equal(t, "Europe/Moscow", event.TimeZone)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Date(2022, time.October, 24, 10, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		text string
+		err  error
+	}{
+		{"", quickadd.ErrNoTitle},
+		{"tomorrow at 10", quickadd.ErrNoTitle},
+		{"Lunch with Sam", quickadd.ErrNoTime},
+		{"Lunch at 25:00", quickadd.ErrNoTime},
+		{"Lunch 13pm", quickadd.ErrNoTime},
+		{"Lunch 31.02", quickadd.ErrNoTime},
+		{"Lunch with Sam for 1h", quickadd.ErrNoTime},
+		{"Workshop tomorrow for 2h", quickadd.ErrNoStart},
+		{"Workshop tomorrow at 10 all day", quickadd.ErrAllDayTime},
+	} {
+		tc := tc
+		t.Run(tc.text, func(t *testing.T) {
+			_, err := quickadd.Parse(tc.text, now)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	// clocks go back on Sunday, 2022-10-30 at 3:00
+	now := time.Date(2022, time.October, 29, 12, 0, 0, 0, berlin)
+
+	event, err := quickadd.Parse("Brunch tomorrow at 11", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2022, time.October, 30, 10, 0, 0, 0, time.UTC), event.StartAt.UTC())
+
+	event, err = quickadd.Parse("Hike tomorrow", now)
+	require.NoError(t, err)
+	require.Equal(t, 25*time.Hour, event.EndAt.Sub(event.StartAt), "the day is one hour longer")
+}
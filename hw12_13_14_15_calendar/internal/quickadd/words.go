@@ -0,0 +1,104 @@
+package quickadd
+
+import "time"
+
+// Words are compared in lower case, Russian ones are listed in the forms used in phrases,
+// e.g. "в пятницу", "24 октября", "на 2 часа".
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday, "thursday": time.Thursday,
+	"friday": time.Friday, "saturday": time.Saturday, "sunday": time.Sunday,
+
+	"понедельник": time.Monday, "вторник": time.Tuesday, "среда": time.Wednesday, "среду": time.Wednesday,
+	"четверг": time.Thursday, "пятница": time.Friday, "пятницу": time.Friday, "суббота": time.Saturday,
+	"субботу": time.Saturday, "воскресенье": time.Sunday,
+}
+
+// shortWeekdays are common words too ("sun", "sat"), so they are taken after a preposition only.
+var shortWeekdays = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "fri": time.Friday,
+	"sat": time.Saturday, "sun": time.Sunday,
+
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January, "feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March, "apr": time.April, "april": time.April, "may": time.May,
+	"jun": time.June, "june": time.June, "jul": time.July, "july": time.July, "aug": time.August,
+	"august": time.August, "sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October, "nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+
+	"января": time.January, "февраля": time.February, "марта": time.March, "апреля": time.April,
+	"мая": time.May, "июня": time.June, "июля": time.July, "августа": time.August,
+	"сентября": time.September, "октября": time.October, "ноября": time.November, "декабря": time.December,
+}
+
+// relativeDays are offsets of dates from today.
+var relativeDays = map[string]int{
+	"today": 0, "tomorrow": 1,
+	"сегодня": 0, "завтра": 1, "послезавтра": 2,
+}
+
+// datePrepositions may precede weekdays and dates: "on friday", "в пятницу", "во вторник".
+var datePrepositions = map[string]bool{"on": true, "в": true, "во": true}
+
+// nextWords make weekdays strictly after today: "next monday", "в следующую пятницу".
+var nextWords = map[string]bool{
+	"next": true, "следующий": true, "следующую": true, "следующее": true, "следующая": true,
+}
+
+// timePrepositions may precede times: "at 13:00", "в 13", "from 10 to 11", "с 10 до 11".
+var timePrepositions = map[string]bool{"at": true, "@": true, "from": true, "в": true, "с": true}
+
+// rangeSeparators join the start and the end of time ranges.
+var rangeSeparators = map[string]bool{
+	"-": true, "–": true, "—": true, "to": true, "till": true, "until": true, "до": true, "по": true,
+}
+
+// durationPrepositions precede durations: "for 1h", "на 2 часа".
+var durationPrepositions = map[string]bool{"for": true, "на": true}
+
+// afterDays are "in" of "in 3 days" and "через" of "через 3 дня".
+var afterDays = map[string]bool{"in": true, "через": true}
+
+// meridiems set the half of the day of hours: "1pm", "1 pm", "в 8 вечера".
+var meridiems = map[string]string{
+	"am": am, "a.m.": am, "pm": pm, "p.m.": pm,
+	"утра": am, "ночи": night, "дня": pm, "вечера": pm,
+}
+
+// hourWords may follow hours of Russian times: "в 3 часа дня".
+var hourWords = map[string]bool{"час": true, "часа": true, "часов": true}
+
+// namedTimes are times said by words.
+var namedTimes = map[string]clock{
+	"noon": {hour: 12}, "midday": {hour: 12}, "midnight": {hour: 0},
+	"полдень": {hour: 12}, "полночь": {hour: 0},
+}
+
+// units of durations, days make durations of all-day events.
+var units = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": day, "day": day, "days": day, "week": week, "weeks": week,
+
+	"м": time.Minute, "мин": time.Minute, "минуту": time.Minute, "минуты": time.Minute, "минут": time.Minute,
+	"ч": time.Hour, "час": time.Hour, "часа": time.Hour, "часов": time.Hour,
+	"д": day, "день": day, "дня": day, "дней": day, "сутки": day,
+	"неделю": week, "недели": week, "недель": week,
+}
+
+// ones are amounts said by words: "for an hour", "in a week".
+var ones = map[string]bool{"a": true, "an": true, "one": true, "один": true, "одну": true, "одна": true}
+
+// wholeDurations are durations said by a single word: "на час", "на полчаса".
+var wholeDurations = map[string]time.Duration{
+	"час": time.Hour, "полчаса": 30 * time.Minute, "неделю": week, "день": day, "сутки": day,
+}
+
+// allDayPhrases make events all-day.
+var allDayPhrases = [][]string{{"all", "day"}, {"all-day"}, {"весь", "день"}, {"целый", "день"}}
@@ -0,0 +1,211 @@
+// Package scheduler finds events whose reminders are due and publishes notifications about them
+// to the queue for the sender.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/notifier"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+)
+
+// LeaseName is the lease replicas of the scheduler compete for, only its holder schedules reminders.
+const LeaseName = "scheduler"
+
+const releaseTimeout = 3 * time.Second
+
+// Options of the Scheduler. Every Interval it looks for reminders due in the last Lookback,
+// so reminders missed while the scheduler was down for less than Lookback are sent late.
+// Handled reminders are kept for ReminderRetention, it has to be longer than Lookback.
+// The lease is renewed every Interval and lost by a replica which did not renew it for LeaseTTL,
+// it is at least two intervals.
+type Options struct {
+	Interval          time.Duration
+	Lookback          time.Duration
+	ReminderRetention time.Duration
+	LeaseTTL          time.Duration
+}
+
+// Scheduler publishes a notification per recipient of the event when its reminder is due.
+// Published reminders are recorded in the storage, so they are not published again by the next
+// cycles. If the scheduler fails between publishing and recording, notifications are published
+// again with the same IDs and the sender skips them.
+//
+// Replicas of the scheduler elect a leader by the lease in the storage, the others wait until
+// it is released or expires. A leader which stalls for longer than the lease ttl may run a cycle
+// together with the new one, the sender skips notifications published twice in that case.
+type Scheduler struct {
+	log       app.Logger
+	store     app.Storage
+	publisher queue.Publisher
+	options   Options
+	holder    string
+	now       func() time.Time
+
+	mu     sync.Mutex
+	leader bool
+}
+
+func New(log app.Logger, store app.Storage, publisher queue.Publisher, options Options) *Scheduler {
+	if options.Lookback < options.Interval {
+		options.Lookback = options.Interval
+	}
+	if options.LeaseTTL < 2*options.Interval {
+		options.LeaseTTL = 2 * options.Interval
+	}
+	return &Scheduler{
+		log:       log,
+		store:     store,
+		publisher: publisher,
+		options:   options,
+		holder:    xid.New().String(),
+		now:       time.Now,
+	}
+}
+
+// Run schedules reminders every interval while the scheduler holds the lease until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.log.Info().Msgf("Scheduler %s is started with interval %v", s.holder, s.options.Interval)
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			s.stepDown()
+			s.log.Info().Msgf("Scheduler %s is stopped", s.holder)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Leader reports whether the scheduler held the lease on the last cycle.
+func (s *Scheduler) Leader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader
+}
+
+// tick acquires or renews the lease and runs a cycle if the scheduler holds it.
+func (s *Scheduler) tick(ctx context.Context) {
+	leader, err := s.store.AcquireLease(ctx, LeaseName, s.holder, s.options.LeaseTTL)
+	if err != nil {
+		// the lease may be lost already, running the cycle could race with another leader
+		s.log.Error().Err(err).Msg("Failed to renew scheduler lease")
+		leader = false
+	}
+	s.setLeader(leader)
+	if !leader {
+		return
+	}
+	if err := s.RunOnce(ctx); err != nil {
+		s.log.Error().Err(err).Msg("Failed to schedule reminders")
+	}
+}
+
+func (s *Scheduler) setLeader(leader bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leader == leader {
+		return
+	}
+	s.leader = leader
+	if leader {
+		s.log.Info().Msgf("Scheduler %s is the leader", s.holder)
+	} else {
+		s.log.Warn().Msgf("Scheduler %s stepped down", s.holder)
+	}
+}
+
+// stepDown releases the lease, so another replica takes over without waiting for the ttl.
+func (s *Scheduler) stepDown() {
+	if !s.Leader() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if err := s.store.ReleaseLease(ctx, LeaseName, s.holder); err != nil {
+		s.log.Error().Err(err).Msg("Failed to release scheduler lease")
+	}
+	s.setLeader(false)
+}
+
+// RunOnce publishes reminders which are due now and were not published yet
+// and removes records of reminders older than the retention.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	now := s.now().UTC()
+	events, err := s.store.ListEventsToNotify(ctx, now.Add(-s.options.Lookback), now)
+	if err != nil {
+		return err
+	}
+	published := 0
+	for _, event := range events {
+		if !event.StartAt.After(now) {
+			// the reminder is too late to be useful
+			continue
+		}
+		sent, err := s.schedule(ctx, event, now)
+		if err != nil {
+			return fmt.Errorf("failed to schedule reminder about event %s: %w", event.ID, err)
+		}
+		if sent {
+			published++
+		}
+	}
+	if published > 0 {
+		s.log.Info().Msgf("Published reminders about %d events", published)
+	}
+
+	if s.options.ReminderRetention > 0 {
+		deleted, err := s.store.DeleteReminders(ctx, now.Add(-s.options.ReminderRetention))
+		if err != nil {
+			return err
+		}
+		s.log.Debug().Msgf("Deleted %d old reminders", deleted)
+	}
+	return nil
+}
+
+// schedule publishes notifications about the event unless the reminder is recorded,
+// it reports whether they were published.
+func (s *Scheduler) schedule(ctx context.Context, event *storage.Event, now time.Time) (bool, error) {
+	reminder := &storage.Reminder{
+		ID:           storage.ReminderID(event.ID, event.StartAt, event.NotifyAt()),
+		EventID:      event.ID,
+		OccurrenceAt: event.StartAt,
+		NotifyAt:     event.NotifyAt(),
+		HandledAt:    now,
+	}
+	_, err := s.store.GetReminder(ctx, reminder.ID)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.As(err, &errs.ErrNotFoundReminder{}) {
+		return false, err
+	}
+
+	for _, userID := range event.NotificationRecipients() {
+		notification := notifier.NewNotification(event, userID)
+		body, err := json.Marshal(notification)
+		if err != nil {
+			return false, err
+		}
+		if err := s.publisher.Publish(ctx, queue.Message{ID: notification.ID, Body: body}); err != nil {
+			return false, fmt.Errorf("failed to publish notification: %w", err)
+		}
+	}
+	if err := s.store.AddReminder(ctx, reminder); err != nil && !errors.As(err, &errs.ErrReminderExists{}) {
+		return false, err
+	}
+	return true, nil
+}
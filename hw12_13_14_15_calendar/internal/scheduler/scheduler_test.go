@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue"
+	memoryqueue "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2022, time.November, 20, 10, 0, 0, 0, time.UTC)
+
+// crashingStorage fails to record reminders while crash is set, as if the scheduler
+// stopped right after publishing notifications.
+type crashingStorage struct {
+	app.Storage
+	crash bool
+}
+
+func (s *crashingStorage) AddReminder(ctx context.Context, reminder *storage.Reminder) error {
+	if s.crash {
+		return errors.New("crashed")
+	}
+	return s.Storage.AddReminder(ctx, reminder)
+}
+
+// leaseStorage fails to renew leases while fail is set, as if the connection to it was lost.
+type leaseStorage struct {
+	app.Storage
+	mu   sync.Mutex
+	fail bool
+}
+
+func (s *leaseStorage) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	fail := s.fail
+	s.mu.Unlock()
+	if fail {
+		return false, errors.New("connection refused")
+	}
+	return s.Storage.AcquireLease(ctx, name, holder, ttl)
+}
+
+func (s *leaseStorage) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func addEvent(t *testing.T, store app.Storage, start time.Time, notifyBefore time.Duration) *storage.Event {
+	t.Helper()
+	event := &storage.Event{
+		Title:        "planning",
+		StartAt:      start,
+		EndAt:        start.Add(time.Hour),
+		TimeZone:     "UTC",
+		NotifyBefore: notifyBefore,
+		UserID:       "alice",
+		Attendees: []storage.Attendee{
+			{UserID: "bob", Status: storage.AttendeeStatusAccepted},
+			{UserID: "carol", Status: storage.AttendeeStatusDeclined},
+		},
+	}
+	require.NoError(t, store.AddEvent(context.Background(), event))
+	return event
+}
+
+// drain returns IDs of messages in the queue and acknowledges them.
+func drain(t *testing.T, q *memoryqueue.Queue) []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deliveries, err := q.Consume(ctx)
+	require.NoError(t, err)
+	ids := make([]string, 0)
+	for q.Len() > 0 {
+		var delivery queue.Delivery
+		select {
+		case delivery = <-deliveries:
+		case <-time.After(time.Second):
+			require.FailNow(t, "no delivery received")
+		}
+		ids = append(ids, delivery.ID)
+		require.NoError(t, delivery.Ack())
+	}
+	return ids
+}
+
+func newScheduler(store app.Storage, q queue.Publisher) *Scheduler {
+	s := New(logger.New("error"), store, q, Options{
+		Interval:          time.Minute,
+		Lookback:          10 * time.Minute,
+		ReminderRetention: 24 * time.Hour,
+	})
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publishes due reminders once", func(t *testing.T) {
+		store := memorystorage.New(logger.New("error"))
+		q := memoryqueue.New()
+		due := addEvent(t, store, now.Add(10*time.Minute), 15*time.Minute)
+		addEvent(t, store, now.Add(time.Hour), 15*time.Minute)
+		addEvent(t, store, now.Add(10*time.Minute), 0)
+		// the reminder is due, but the event has started
+		addEvent(t, store, now.Add(-time.Minute), 5*time.Minute)
+		s := newScheduler(store, q)
+
+		require.NoError(t, s.RunOnce(ctx))
+		ids := drain(t, q)
+		id := storage.ReminderID(due.ID, due.StartAt, due.NotifyAt())
+		require.Equal(t, []string{id + "/alice", id + "/bob"}, ids)
+		reminder, err := store.GetReminder(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, now, reminder.HandledAt)
+
+		s.now = func() time.Time { return now.Add(time.Minute) }
+		require.NoError(t, s.RunOnce(ctx))
+		require.Zero(t, q.Len())
+
+		// moved event is reminded again
+		due.StartAt = due.StartAt.Add(time.Minute)
+		require.NoError(t, store.ModifyEvent(ctx, due))
+		require.NoError(t, s.RunOnce(ctx))
+		require.Len(t, drain(t, q), 2)
+	})
+
+	t.Run("reminders are republished after crash before recording", func(t *testing.T) {
+		store := &crashingStorage{Storage: memorystorage.New(logger.New("error")), crash: true}
+		q := memoryqueue.New()
+		addEvent(t, store, now.Add(10*time.Minute), 15*time.Minute)
+		s := newScheduler(store, q)
+
+		require.Error(t, s.RunOnce(ctx))
+		published := drain(t, q)
+		require.Len(t, published, 2)
+
+		store.crash = false
+		require.NoError(t, s.RunOnce(ctx))
+		// the sender deduplicates them by IDs
+		require.Equal(t, published, drain(t, q))
+		require.NoError(t, s.RunOnce(ctx))
+		require.Zero(t, q.Len())
+	})
+
+	t.Run("old reminders are deleted", func(t *testing.T) {
+		store := memorystorage.New(logger.New("error"))
+		old := &storage.Reminder{ID: "old", HandledAt: now.Add(-25 * time.Hour)}
+		recent := &storage.Reminder{ID: "recent", HandledAt: now.Add(-time.Hour)}
+		require.NoError(t, store.AddReminder(ctx, old))
+		require.NoError(t, store.AddReminder(ctx, recent))
+
+		require.NoError(t, newScheduler(store, memoryqueue.New()).RunOnce(ctx))
+		_, err := store.GetReminder(ctx, old.ID)
+		require.Error(t, err)
+		_, err = store.GetReminder(ctx, recent.ID)
+		require.NoError(t, err)
+	})
+}
+
+// start runs the scheduler until stop is called or the test ends.
+func start(t *testing.T, s *Scheduler) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestLeaderElection(t *testing.T) {
+	const interval = 5 * time.Millisecond
+	newReplica := func(store app.Storage, q queue.Publisher) *Scheduler {
+		s := New(logger.New("error"), store, q, Options{
+			Interval:          interval,
+			Lookback:          10 * time.Minute,
+			ReminderRetention: 24 * time.Hour,
+			LeaseTTL:          10 * interval,
+		})
+		s.now = func() time.Time { return now }
+		return s
+	}
+	// leader waits until exactly one of replicas is the leader and returns it.
+	leader := func(t *testing.T, replicas ...*Scheduler) *Scheduler {
+		t.Helper()
+		var current *Scheduler
+		require.Eventually(t, func() bool {
+			current = nil
+			for _, replica := range replicas {
+				if !replica.Leader() {
+					continue
+				}
+				if current != nil {
+					return false
+				}
+				current = replica
+			}
+			return current != nil
+		}, 2*time.Second, time.Millisecond)
+		return current
+	}
+
+	t.Run("one replica publishes reminders", func(t *testing.T) {
+		store := memorystorage.New(logger.New("error"))
+		q := memoryqueue.New()
+		addEvent(t, store, now.Add(10*time.Minute), 15*time.Minute)
+		first, second := newReplica(store, q), newReplica(store, q)
+		start(t, first)
+		start(t, second)
+
+		leader(t, first, second)
+		time.Sleep(10 * interval)
+		require.False(t, first.Leader() && second.Leader())
+		require.Len(t, drain(t, q), 2)
+	})
+
+	t.Run("stopped leader hands over the lease", func(t *testing.T) {
+		store := memorystorage.New(logger.New("error"))
+		first, second := newReplica(store, memoryqueue.New()), newReplica(store, memoryqueue.New())
+		stopFirst := start(t, first)
+		require.Same(t, first, leader(t, first, second))
+		stopSecond := start(t, second)
+		time.Sleep(2 * interval)
+		require.False(t, second.Leader())
+
+		stopFirst()
+		require.False(t, first.Leader())
+		// released lease is taken before it would expire
+		require.Eventually(t, second.Leader, 5*interval, time.Millisecond)
+		stopSecond()
+	})
+
+	t.Run("leader steps down when it can not renew the lease", func(t *testing.T) {
+		memory := memorystorage.New(logger.New("error"))
+		failing := &leaseStorage{Storage: memory}
+		first, second := newReplica(failing, memoryqueue.New()), newReplica(memory, memoryqueue.New())
+		start(t, first)
+		require.Same(t, first, leader(t, first))
+		start(t, second)
+
+		failing.setFail(true)
+		require.Eventually(t, func() bool { return !first.Leader() }, 2*time.Second, time.Millisecond)
+		require.Same(t, second, leader(t, first, second))
+		failing.setFail(false)
+		time.Sleep(2 * interval)
+		require.Same(t, second, leader(t, first, second))
+	})
+}
@@ -0,0 +1,122 @@
+// Package sender consumes notifications published by the scheduler and delivers them to users.
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/notifier"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+type Router interface {
+	Send(ctx context.Context, notification *notifier.Notification) error
+}
+
+// Sender delivers every notification once: delivered notifications are recorded in the storage
+// as reminders with the ID of the message, redelivered and republished copies are skipped.
+// A notification is delivered again only if the sender stops after delivering it and before
+// recording it, so channels get the same ID, e.g. Message-ID of emails, in both copies.
+type Sender struct {
+	log      app.Logger
+	store    app.Storage
+	consumer queue.Consumer
+	router   Router
+	// retryDelay is waited before the message is returned to the queue after a failure of the storage
+	retryDelay time.Duration
+	now        func() time.Time
+}
+
+func New(log app.Logger, store app.Storage, consumer queue.Consumer, router Router, retryDelay time.Duration) *Sender {
+	return &Sender{
+		log:        log,
+		store:      store,
+		consumer:   consumer,
+		router:     router,
+		retryDelay: retryDelay,
+		now:        time.Now,
+	}
+}
+
+// Run delivers notifications until ctx is done.
+func (s *Sender) Run(ctx context.Context) error {
+	deliveries, err := s.consumer.Consume(ctx)
+	if err != nil {
+		return err
+	}
+	s.log.Info().Msg("Sender is started")
+	for delivery := range deliveries {
+		s.handle(ctx, delivery)
+	}
+	s.log.Info().Msg("Sender is stopped")
+	return nil
+}
+
+func (s *Sender) handle(ctx context.Context, delivery queue.Delivery) {
+	var notification notifier.Notification
+	if err := json.Unmarshal(delivery.Body, &notification); err != nil {
+		s.log.Error().Err(err).Msgf("Dropping invalid notification %s", delivery.ID)
+		s.settle(delivery, delivery.Nack(false))
+		return
+	}
+	notification.ID = delivery.ID
+
+	_, err := s.store.GetReminder(ctx, notification.ID)
+	if err == nil {
+		s.log.Debug().Msgf("Notification %s is already delivered", notification.ID)
+		s.settle(delivery, delivery.Ack())
+		return
+	}
+	if !errors.As(err, &errs.ErrNotFoundReminder{}) {
+		s.retry(ctx, delivery, err)
+		return
+	}
+
+	if err := s.router.Send(ctx, &notification); err != nil {
+		var sendErr notifier.ErrSend
+		if !errors.As(err, &sendErr) {
+			s.retry(ctx, delivery, err)
+			return
+		}
+		// channels which failed are not retried, others would deliver the notification twice
+		s.log.Warn().Err(err).Msgf("Notification %s is not delivered by some channels", notification.ID)
+	}
+
+	err = s.store.AddReminder(ctx, &storage.Reminder{
+		ID:           notification.ID,
+		EventID:      notification.EventID,
+		UserID:       notification.UserID,
+		OccurrenceAt: notification.StartAt,
+		NotifyAt:     notification.NotifyAt,
+		HandledAt:    s.now().UTC(),
+	})
+	if err != nil && !errors.As(err, &errs.ErrReminderExists{}) {
+		// the notification is delivered, returning it to the queue would deliver it again
+		s.log.Error().Err(err).Msgf("Failed to record notification %s", notification.ID)
+	}
+	s.log.Info().Msgf("Notification %s to user %s is handled", notification.ID, notification.UserID)
+	s.settle(delivery, delivery.Ack())
+}
+
+// retry returns the message to the queue after retryDelay.
+func (s *Sender) retry(ctx context.Context, delivery queue.Delivery, err error) {
+	s.log.Error().Err(err).Msgf("Failed to deliver notification %s, it is returned to the queue", delivery.ID)
+	select {
+	case <-ctx.Done():
+		// the queue returns unsettled messages itself
+		return
+	case <-time.After(s.retryDelay):
+	}
+	s.settle(delivery, delivery.Nack(true))
+}
+
+func (s *Sender) settle(delivery queue.Delivery, err error) {
+	if err != nil {
+		s.log.Error().Err(err).Msgf("Failed to settle notification %s", delivery.ID)
+	}
+}
@@ -0,0 +1,153 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/notifier"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue"
+	memoryqueue "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// router records sent notifications, err is returned for every notification.
+type router struct {
+	mu   sync.Mutex
+	sent []string
+	err  error
+}
+
+func (r *router) Send(_ context.Context, notification *notifier.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, notification.ID)
+	return r.err
+}
+
+func (r *router) sentIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sent...)
+}
+
+// failingStorage fails to get reminders failures times.
+type failingStorage struct {
+	app.Storage
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *failingStorage) GetReminder(ctx context.Context, id string) (*storage.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("connection refused")
+	}
+	return s.Storage.GetReminder(ctx, id)
+}
+
+// lostAcks settles nothing, as if the sender stopped before settling the delivery.
+type lostAcks struct{}
+
+func (lostAcks) Ack(uint64) error {
+	return nil
+}
+
+func (lostAcks) Nack(uint64, bool) error {
+	return nil
+}
+
+func message(t *testing.T, userID string) queue.Message {
+	t.Helper()
+	start := time.Date(2022, time.November, 20, 10, 0, 0, 0, time.UTC)
+	notification := notifier.NewNotification(&storage.Event{
+		ID:           "event",
+		Title:        "planning",
+		StartAt:      start,
+		EndAt:        start.Add(time.Hour),
+		NotifyBefore: 15 * time.Minute,
+	}, userID)
+	body, err := json.Marshal(notification)
+	require.NoError(t, err)
+	return queue.Message{ID: notification.ID, Body: body}
+}
+
+// run starts the sender until the end of the test and waits until the queue is empty.
+func run(t *testing.T, s *Sender, q *memoryqueue.Queue) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, s.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	require.Eventually(t, func() bool { return q.Len() == 0 }, 2*time.Second, time.Millisecond)
+}
+
+func TestSender(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("duplicates are delivered once", func(t *testing.T) {
+		store := memorystorage.New(logger.New("error"))
+		q := memoryqueue.New()
+		r := &router{}
+		alice, bob := message(t, "alice"), message(t, "bob")
+		for _, m := range []queue.Message{alice, bob, alice} {
+			require.NoError(t, q.Publish(ctx, m))
+		}
+
+		run(t, New(logger.New("error"), store, q, r, time.Millisecond), q)
+		require.Equal(t, []string{alice.ID, bob.ID}, r.sentIDs())
+		reminder, err := store.GetReminder(ctx, alice.ID)
+		require.NoError(t, err)
+		require.Equal(t, "alice", reminder.UserID)
+		require.Equal(t, "event", reminder.EventID)
+	})
+
+	t.Run("redelivery after crash before acknowledgement is skipped", func(t *testing.T) {
+		r := &router{}
+		s := New(logger.New("error"), memorystorage.New(logger.New("error")), memoryqueue.New(), r, time.Millisecond)
+		alice := message(t, "alice")
+
+		s.handle(ctx, queue.Delivery{Message: alice, Tag: 1, Acknowledger: lostAcks{}})
+		s.handle(ctx, queue.Delivery{Message: alice, Tag: 2, Acknowledger: lostAcks{}})
+		require.Equal(t, []string{alice.ID}, r.sentIDs())
+	})
+
+	t.Run("message is retried after storage failure", func(t *testing.T) {
+		store := &failingStorage{Storage: memorystorage.New(logger.New("error")), failures: 2}
+		q := memoryqueue.New()
+		r := &router{}
+		alice := message(t, "alice")
+		require.NoError(t, q.Publish(ctx, alice))
+
+		run(t, New(logger.New("error"), store, q, r, time.Millisecond), q)
+		require.Equal(t, []string{alice.ID}, r.sentIDs())
+	})
+
+	t.Run("failed channels are not retried", func(t *testing.T) {
+		q := memoryqueue.New()
+		r := &router{err: notifier.ErrSend{Failed: map[storage.NotificationChannel]error{
+			storage.NotificationChannelEmail: errors.New("connection refused"),
+		}}}
+		alice := message(t, "alice")
+		require.NoError(t, q.Publish(ctx, alice))
+		require.NoError(t, q.Publish(ctx, queue.Message{ID: "invalid", Body: []byte("{")}))
+
+		run(t, New(logger.New("error"), memorystorage.New(logger.New("error")), q, r, time.Millisecond), q)
+		require.Equal(t, []string{alice.ID}, r.sentIDs())
+	})
+}
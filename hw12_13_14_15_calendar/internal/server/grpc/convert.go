@@ -0,0 +1,277 @@
+package internalgrpc
+
+import (
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// EventToProto converts event to its message, nil is kept.
+func EventToProto(event *storage.Event) *pb.Event {
+	if event == nil {
+		return nil
+	}
+	message := &pb.Event{
+		Id:          event.ID,
+		Title:       event.Title,
+		Description: event.Description,
+		StartAt:     timestamppb.New(event.StartAt),
+		EndAt:       timestamppb.New(event.EndAt),
+		TimeZone:    event.TimeZone,
+		AllDay:      event.AllDay,
+		UserId:      event.UserID,
+		Tags:        event.Tags,
+		Resources:   event.Resources,
+	}
+	if event.NotifyBefore > 0 {
+		message.NotifyBefore = durationpb.New(event.NotifyBefore)
+	}
+	for _, attendee := range event.Attendees {
+		message.Attendees = append(message.Attendees, &pb.Attendee{
+			UserId: attendee.UserID,
+			Status: string(attendee.Status),
+		})
+	}
+	if event.DeletedAt != nil {
+		message.DeletedAt = timestamppb.New(*event.DeletedAt)
+	}
+	return message
+}
+
+// EventFromProto converts message to event, nil is kept. Missing times are zero.
+func EventFromProto(message *pb.Event) *storage.Event {
+	if message == nil {
+		return nil
+	}
+	event := &storage.Event{
+		ID:           message.GetId(),
+		Title:        message.GetTitle(),
+		Description:  message.GetDescription(),
+		StartAt:      timeFromProto(message.GetStartAt()),
+		EndAt:        timeFromProto(message.GetEndAt()),
+		TimeZone:     message.GetTimeZone(),
+		AllDay:       message.GetAllDay(),
+		NotifyBefore: message.GetNotifyBefore().AsDuration(),
+		UserID:       message.GetUserId(),
+		Tags:         message.GetTags(),
+		Resources:    message.GetResources(),
+	}
+	for _, attendee := range message.GetAttendees() {
+		event.Attendees = append(event.Attendees, storage.Attendee{
+			UserID: attendee.GetUserId(),
+			Status: storage.AttendeeStatus(attendee.GetStatus()),
+		})
+	}
+	if message.GetDeletedAt() != nil {
+		deletedAt := message.GetDeletedAt().AsTime()
+		event.DeletedAt = &deletedAt
+	}
+	return event
+}
+
+// EventsToProto converts events to the listing response.
+func EventsToProto(events []*storage.Event) *pb.ListEventsResponse {
+	response := &pb.ListEventsResponse{Events: make([]*pb.Event, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, EventToProto(event))
+	}
+	return response
+}
+
+// EventsFromProto converts the listing response to events.
+func EventsFromProto(response *pb.ListEventsResponse) []*storage.Event {
+	events := make([]*storage.Event, 0, len(response.GetEvents()))
+	for _, message := range response.GetEvents() {
+		events = append(events, EventFromProto(message))
+	}
+	return events
+}
+
+// BatchOperationsFromProto converts messages to operations of the batch.
+func BatchOperationsFromProto(messages []*pb.BatchOperation) []app.BatchOperation {
+	operations := make([]app.BatchOperation, 0, len(messages))
+	for _, message := range messages {
+		operations = append(operations, app.BatchOperation{
+			Op:    app.BatchOp(message.GetOp()),
+			Event: EventFromProto(message.GetEvent()),
+			ID:    message.GetId(),
+		})
+	}
+	return operations
+}
+
+func timeFromProto(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Time{}
+	}
+	return timestamp.AsTime()
+}
+
+func slotsToProto(slots []app.TimeSlot) *pb.FreeSlotsResponse {
+	response := &pb.FreeSlotsResponse{Slots: make([]*pb.TimeSlot, 0, len(slots))}
+	for _, slot := range slots {
+		response.Slots = append(response.Slots, &pb.TimeSlot{
+			Start: timestamppb.New(slot.Start),
+			End:   timestamppb.New(slot.End),
+		})
+	}
+	return response
+}
+
+func tagToProto(tag *storage.Tag) *pb.Tag {
+	return &pb.Tag{UserId: tag.UserID, Name: tag.Name, Color: tag.Color}
+}
+
+func tagsToProto(tags []*storage.Tag) *pb.ListTagsResponse {
+	response := &pb.ListTagsResponse{Tags: make([]*pb.Tag, 0, len(tags))}
+	for _, tag := range tags {
+		response.Tags = append(response.Tags, tagToProto(tag))
+	}
+	return response
+}
+
+// ResourceToProto converts resource to its message.
+func ResourceToProto(resource *storage.Resource) *pb.Resource {
+	return &pb.Resource{
+		Id:       resource.ID,
+		Name:     resource.Name,
+		Kind:     string(resource.Kind),
+		Capacity: int32(resource.Capacity),
+	}
+}
+
+// ResourceFromProto converts message to resource.
+func ResourceFromProto(message *pb.Resource) *storage.Resource {
+	return &storage.Resource{
+		ID:       message.GetId(),
+		Name:     message.GetName(),
+		Kind:     storage.ResourceKind(message.GetKind()),
+		Capacity: int(message.GetCapacity()),
+	}
+}
+
+// ResourcesToProto converts resources to the listing response.
+func ResourcesToProto(resources []*storage.Resource) *pb.ListResourcesResponse {
+	response := &pb.ListResourcesResponse{Resources: make([]*pb.Resource, 0, len(resources))}
+	for _, resource := range resources {
+		response.Resources = append(response.Resources, ResourceToProto(resource))
+	}
+	return response
+}
+
+func shareToProto(share *storage.Share) *pb.Share {
+	return &pb.Share{
+		OwnerId:   share.OwnerID,
+		UserId:    share.UserID,
+		Access:    string(share.Access),
+		CreatedAt: timestamppb.New(share.CreatedAt),
+	}
+}
+
+func sharesToProto(shares []*storage.Share) *pb.ListSharesResponse {
+	response := &pb.ListSharesResponse{Shares: make([]*pb.Share, 0, len(shares))}
+	for _, share := range shares {
+		response.Shares = append(response.Shares, shareToProto(share))
+	}
+	return response
+}
+
+func auditRecordsToProto(records []*storage.AuditRecord) *pb.GetEventHistoryResponse {
+	response := &pb.GetEventHistoryResponse{Records: make([]*pb.AuditRecord, 0, len(records))}
+	for _, record := range records {
+		response.Records = append(response.Records, &pb.AuditRecord{
+			Id:        record.ID,
+			EventId:   record.EventID,
+			Actor:     record.Actor,
+			Action:    string(record.Action),
+			CreatedAt: timestamppb.New(record.CreatedAt),
+			Before:    EventToProto(record.Before),
+			After:     EventToProto(record.After),
+		})
+	}
+	return response
+}
+
+func webhookToProto(webhook *storage.Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:        webhook.ID,
+		UserId:    webhook.UserID,
+		Url:       webhook.URL,
+		Secret:    webhook.Secret,
+		CreatedAt: timestamppb.New(webhook.CreatedAt),
+	}
+}
+
+func webhooksToProto(webhooks []*storage.Webhook) *pb.ListWebhooksResponse {
+	response := &pb.ListWebhooksResponse{Webhooks: make([]*pb.Webhook, 0, len(webhooks))}
+	for _, webhook := range webhooks {
+		response.Webhooks = append(response.Webhooks, webhookToProto(webhook))
+	}
+	return response
+}
+
+func deliveriesToProto(deliveries []*storage.WebhookDelivery) *pb.ListWebhookDeliveriesResponse {
+	response := &pb.ListWebhookDeliveriesResponse{Deliveries: make([]*pb.WebhookDelivery, 0, len(deliveries))}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, &pb.WebhookDelivery{
+			Id:            delivery.ID,
+			WebhookId:     delivery.WebhookID,
+			EventId:       delivery.EventID,
+			Type:          delivery.Type,
+			Payload:       delivery.Payload,
+			Status:        string(delivery.Status),
+			Attempts:      int32(delivery.Attempts),
+			NextAttemptAt: timestamppb.New(delivery.NextAttemptAt),
+			LastError:     delivery.LastError,
+			ResponseCode:  int32(delivery.ResponseCode),
+			CreatedAt:     timestamppb.New(delivery.CreatedAt),
+			UpdatedAt:     timestamppb.New(delivery.UpdatedAt),
+		})
+	}
+	return response
+}
+
+func profileToProto(profile *storage.UserProfile) *pb.UserProfile {
+	message := &pb.UserProfile{
+		UserId:    profile.UserID,
+		Email:     profile.Email,
+		UpdatedAt: timestamppb.New(profile.UpdatedAt),
+	}
+	for _, channel := range profile.Channels {
+		message.Channels = append(message.Channels, string(channel))
+	}
+	return message
+}
+
+func profileFromProto(message *pb.UserProfile) *storage.UserProfile {
+	profile := &storage.UserProfile{Email: message.GetEmail()}
+	for _, channel := range message.GetChannels() {
+		profile.Channels = append(profile.Channels, storage.NotificationChannel(channel))
+	}
+	return profile
+}
+
+func attachmentToProto(attachment *storage.Attachment) *pb.Attachment {
+	return &pb.Attachment{
+		Id:          attachment.ID,
+		EventId:     attachment.EventID,
+		UserId:      attachment.UserID,
+		Name:        attachment.Name,
+		Url:         attachment.URL,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		CreatedAt:   timestamppb.New(attachment.CreatedAt),
+	}
+}
+
+func attachmentsToProto(attachments []*storage.Attachment) *pb.ListAttachmentsResponse {
+	response := &pb.ListAttachmentsResponse{Attachments: make([]*pb.Attachment, 0, len(attachments))}
+	for _, attachment := range attachments {
+		response.Attachments = append(response.Attachments, attachmentToProto(attachment))
+	}
+	return response
+}
@@ -0,0 +1,60 @@
+package internalgrpc
+
+import (
+	"errors"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"google.golang.org/grpc/codes"
+)
+
+// errorCode returns the code of errors of the app, codes match statuses of the HTTP API.
+func errorCode(err error) codes.Code {
+	var (
+		notFoundEventErr    errs.ErrNotFoundEvent
+		notFoundAttendeeErr errs.ErrNotFoundAttendee
+		userRequiredErr     apperrors.ErrUserRequired
+		invalidStatusErr    apperrors.ErrInvalidAttendeeStatus
+		invalidFreeBusyErr  apperrors.ErrInvalidFreeBusyQuery
+		invalidTimeZoneErr  apperrors.ErrInvalidTimeZone
+		invalidBatchErr     apperrors.ErrInvalidBatchOperation
+		invalidEventErr     apperrors.ErrInvalidEvent
+		invalidTagErr       apperrors.ErrInvalidTag
+		notFoundResourceErr errs.ErrNotFoundResource
+		resourceBusyErr     errs.ErrResourceBusy
+		invalidResourceErr  apperrors.ErrInvalidResource
+		notFoundShareErr    errs.ErrNotFoundShare
+		accessDeniedErr     apperrors.ErrAccessDenied
+		invalidShareErr     apperrors.ErrInvalidShare
+		invalidBookingErr   errs.ErrInvalidBookingPeriod
+		notFoundWebhookErr  errs.ErrNotFoundWebhook
+		notFoundProfileErr  errs.ErrNotFoundUserProfile
+		notFoundAttachErr   errs.ErrNotFoundAttachment
+		notFoundBlobErr     errs.ErrNotFoundBlob
+		invalidWebhookErr   apperrors.ErrInvalidWebhook
+		invalidProfileErr   apperrors.ErrInvalidProfile
+		invalidAttachErr    apperrors.ErrInvalidAttachment
+		invalidQuickAddErr  apperrors.ErrInvalidQuickAdd
+	)
+	switch {
+	case errors.As(err, &notFoundEventErr), errors.As(err, &notFoundAttendeeErr),
+		errors.As(err, &notFoundResourceErr), errors.As(err, &notFoundShareErr),
+		errors.As(err, &notFoundWebhookErr), errors.As(err, &notFoundProfileErr),
+		errors.As(err, &notFoundAttachErr), errors.As(err, &notFoundBlobErr):
+		return codes.NotFound
+	case errors.As(err, &accessDeniedErr):
+		return codes.PermissionDenied
+	case errors.As(err, &resourceBusyErr):
+		return codes.AlreadyExists
+	case errors.As(err, &userRequiredErr), errors.As(err, &invalidStatusErr),
+		errors.As(err, &invalidFreeBusyErr), errors.As(err, &invalidTimeZoneErr),
+		errors.As(err, &invalidBatchErr), errors.As(err, &invalidEventErr),
+		errors.As(err, &invalidTagErr), errors.As(err, &invalidResourceErr),
+		errors.As(err, &invalidShareErr), errors.As(err, &invalidBookingErr),
+		errors.As(err, &invalidWebhookErr), errors.As(err, &invalidProfileErr),
+		errors.As(err, &invalidAttachErr), errors.As(err, &invalidQuickAddErr):
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,506 @@
+package internalgrpc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// gatewayHeaders are passed to calls as metadata, the rest of headers is dropped.
+var gatewayHeaders = []string{UserIDKey, CalendarIDKey, TimeZoneKey, ConsistencyKey}
+
+var (
+	gatewayMarshal   = protojson.MarshalOptions{UseProtoNames: true}
+	gatewayUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// Gateway serves EventService as JSON over HTTP by google.api.http options of its methods
+// the way grpc-gateway does. Calls go through the interceptors of the gRPC server,
+// so both transports share the service and the app behind it.
+type Gateway struct {
+	service     pb.EventServiceServer
+	interceptor grpc.UnaryServerInterceptor
+	routes      []gatewayRoute
+}
+
+// methodHandler is the type of handlers in grpc.MethodDesc.
+type methodHandler = func(
+	srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error)
+
+type gatewayRoute struct {
+	method   string
+	template string
+	// segments of the path template, variables are empty and bound by variables
+	segments  []string
+	variables map[int]string
+	// body is the field the body is bound to, "*" binds the whole request
+	body         string
+	responseBody string
+	handler      methodHandler
+}
+
+func NewGateway(logger app.Logger, application Application) (*Gateway, error) {
+	routes, err := gatewayRoutes()
+	if err != nil {
+		return nil, err
+	}
+	return &Gateway{
+		service:     NewService(application),
+		interceptor: chainInterceptors(interceptors(logger)),
+		routes:      routes,
+	}, nil
+}
+
+// gatewayRoutes builds routes from the options of EventService in the compiled descriptor.
+func gatewayRoutes() ([]gatewayRoute, error) {
+	handlers := make(map[string]methodHandler, len(pb.EventService_ServiceDesc.Methods))
+	for _, method := range pb.EventService_ServiceDesc.Methods {
+		handlers[method.MethodName] = method.Handler
+	}
+	service := pb.File_EventService_proto.Services().ByName("EventService")
+	var routes []gatewayRoute
+	for i := 0; i < service.Methods().Len(); i++ {
+		method := service.Methods().Get(i)
+		rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		handler, ok := handlers[string(method.Name())]
+		if !ok {
+			return nil, fmt.Errorf("method %s has no handler", method.Name())
+		}
+		route, err := newGatewayRoute(rule, method.Input())
+		if err != nil {
+			return nil, fmt.Errorf("http rule of %s: %w", method.Name(), err)
+		}
+		route.handler = handler
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+func newGatewayRoute(rule *annotations.HttpRule, input protoreflect.MessageDescriptor) (gatewayRoute, error) {
+	route := gatewayRoute{
+		variables:    map[int]string{},
+		body:         rule.GetBody(),
+		responseBody: rule.GetResponseBody(),
+	}
+	var template string
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		route.method, template = http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Put:
+		route.method, template = http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Post:
+		route.method, template = http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Delete:
+		route.method, template = http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		route.method, template = http.MethodPatch, pattern.Patch
+	default:
+		return route, fmt.Errorf("unsupported pattern %T", pattern)
+	}
+	if !strings.HasPrefix(template, "/") {
+		return route, fmt.Errorf("template %q must start with /", template)
+	}
+	route.template = template
+
+	route.segments = strings.Split(strings.TrimPrefix(template, "/"), "/")
+	for i, segment := range route.segments {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		// only single segment variables are supported: {field.path} or {field.path=*}
+		field := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"), "=*")
+		if strings.ContainsAny(field, "{}=*/") || !strings.HasSuffix(segment, "}") {
+			return route, fmt.Errorf("unsupported variable %s of %q", segment, template)
+		}
+		if _, err := fieldPath(input, field); err != nil {
+			return route, err
+		}
+		route.segments[i] = ""
+		route.variables[i] = field
+	}
+	if route.body != "" && route.body != "*" {
+		fields, err := fieldPath(input, route.body)
+		if err != nil {
+			return route, err
+		}
+		if body := fields[len(fields)-1]; body.Message() == nil || body.IsList() || body.IsMap() {
+			return route, fmt.Errorf("body %s must be a message field", route.body)
+		}
+	}
+	return route, nil
+}
+
+// fieldPath returns fields of the dot separated path, all of them but the last are messages.
+func fieldPath(message protoreflect.MessageDescriptor, path string) ([]protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+	fields := make([]protoreflect.FieldDescriptor, 0, len(names))
+	for i, name := range names {
+		if message == nil {
+			return nil, fmt.Errorf("%s is not a message", strings.Join(names[:i], "."))
+		}
+		field := message.Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			field = message.Fields().ByJSONName(name)
+		}
+		if field == nil {
+			return nil, fmt.Errorf("%s has no field %s", message.FullName(), name)
+		}
+		fields = append(fields, field)
+		message = nil
+		if field.Kind() == protoreflect.MessageKind && !field.IsList() && !field.IsMap() {
+			message = field.Message()
+		}
+	}
+	return fields, nil
+}
+
+// Route is an HTTP method and a path template of the gateway, e.g. PUT /v1/events/{event.id}.
+type Route struct {
+	Method   string
+	Template string
+}
+
+// Routes returns routes of the gateway in the order of methods of the service.
+func (g *Gateway) Routes() []Route {
+	routes := make([]Route, 0, len(g.routes))
+	for _, route := range g.routes {
+		routes = append(routes, Route{Method: route.method, Template: route.template})
+	}
+	return routes
+}
+
+// WriteInvalidRequest answers the request rejected with the HTTP code before it reached the gateway,
+// e.g. by validation against the OpenAPI document, with InvalidArgument status as the gateway
+// answers invalid requests.
+func (g *Gateway) WriteInvalidRequest(w http.ResponseWriter, code int, err error) {
+	writeGatewayError(w, code, status.Newf(codes.InvalidArgument, "invalid request: %s", err))
+}
+
+// match returns the route and values of its variables, the last value is false if the path
+// is served with other methods only.
+func (g *Gateway) match(method, path string) (*gatewayRoute, map[string]string, bool) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	pathFound := false
+	for i := range g.routes {
+		route := &g.routes[i]
+		values, ok := route.bind(segments)
+		if !ok {
+			continue
+		}
+		if route.method == method {
+			return route, values, true
+		}
+		pathFound = true
+	}
+	return nil, nil, pathFound
+}
+
+func (r *gatewayRoute) bind(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	values := make(map[string]string, len(r.variables))
+	for i, segment := range segments {
+		field, ok := r.variables[i]
+		if !ok {
+			if segment != r.segments[i] {
+				return nil, false
+			}
+			continue
+		}
+		value, err := url.PathUnescape(segment)
+		if err != nil || value == "" {
+			return nil, false
+		}
+		values[field] = value
+	}
+	return values, true
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, values, found := g.match(r.Method, r.URL.Path)
+	if route == nil {
+		if found {
+			writeGatewayError(w, http.StatusMethodNotAllowed, status.New(codes.Unimplemented, "method is not allowed"))
+			return
+		}
+		writeGatewayError(w, http.StatusNotFound, status.New(codes.NotFound, "path is not found"))
+		return
+	}
+
+	var body []byte
+	if route.body != "" {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			writeStatus(w, status.Newf(codes.InvalidArgument, "failed to read body: %s", err))
+			return
+		}
+	}
+	md := metadata.MD{}
+	for _, key := range gatewayHeaders {
+		if value := r.Header.Get(key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	decode := func(in interface{}) error {
+		message, ok := in.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "unexpected request %T", in)
+		}
+		if err := route.decode(message.ProtoReflect(), values, r.URL.Query(), body); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid request: %s", err)
+		}
+		return nil
+	}
+	resp, err := route.handler(g.service, ctx, decode, g.interceptor)
+	if err != nil {
+		writeStatus(w, status.Convert(err))
+		return
+	}
+	message, ok := resp.(proto.Message)
+	if !ok {
+		writeStatus(w, status.Newf(codes.Internal, "unexpected response %T", resp))
+		return
+	}
+	data, err := route.encode(message.ProtoReflect())
+	if err != nil {
+		writeStatus(w, status.Newf(codes.Internal, "failed to encode response: %s", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// decode fills the request from the body, variables of the path and the query in this order.
+func (r *gatewayRoute) decode(
+	message protoreflect.Message, values map[string]string, query url.Values, body []byte,
+) error {
+	switch {
+	case len(strings.TrimSpace(string(body))) == 0:
+	case r.body == "*":
+		if err := gatewayUnmarshal.Unmarshal(body, message.Interface()); err != nil {
+			return fmt.Errorf("body: %w", err)
+		}
+	case r.body != "":
+		target, field, err := mutableField(message, r.body)
+		if err != nil {
+			return err
+		}
+		if err := gatewayUnmarshal.Unmarshal(body, target.Mutable(field).Message().Interface()); err != nil {
+			return fmt.Errorf("body: %w", err)
+		}
+	}
+	for path, value := range values {
+		if err := setField(message, path, []string{value}); err != nil {
+			return err
+		}
+	}
+	if r.body == "*" {
+		return nil
+	}
+	for key, params := range query {
+		// unknown parameters are ignored as grpc-gateway does
+		if _, err := fieldPath(message.Descriptor(), key); err != nil || r.bound(key) {
+			continue
+		}
+		if err := setField(message, key, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bound reports whether the field of the query parameter is taken from the path or the body.
+func (r *gatewayRoute) bound(key string) bool {
+	if r.body != "" && (key == r.body || strings.HasPrefix(key, r.body+".")) {
+		return true
+	}
+	for _, path := range r.variables {
+		if key == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *gatewayRoute) encode(message protoreflect.Message) ([]byte, error) {
+	if r.responseBody == "" {
+		return gatewayMarshal.Marshal(message.Interface())
+	}
+	fields, err := fieldPath(message.Descriptor(), r.responseBody)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 || fields[0].Message() == nil || fields[0].IsList() || fields[0].IsMap() {
+		return nil, fmt.Errorf("response_body %s must be a message field", r.responseBody)
+	}
+	return gatewayMarshal.Marshal(message.Get(fields[0]).Message().Interface())
+}
+
+// mutableField returns the message holding the last field of the path, messages on the path are created.
+func mutableField(
+	message protoreflect.Message, path string,
+) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	fields, err := fieldPath(message.Descriptor(), path)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, field := range fields[:len(fields)-1] {
+		message = message.Mutable(field).Message()
+	}
+	return message, fields[len(fields)-1], nil
+}
+
+// setField sets the field of the path from values of a query parameter, repeated fields get all of them.
+func setField(message protoreflect.Message, path string, values []string) error {
+	target, field, err := mutableField(message, path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case field.IsMap():
+		return fmt.Errorf("%s: map fields are not supported", path)
+	case field.IsList():
+		list := target.Mutable(field).List()
+		for _, raw := range values {
+			value, err := parseValue(field, list.NewElement(), raw)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			list.Append(value)
+		}
+	case len(values) > 0:
+		value, err := parseValue(field, target.NewField(field), values[len(values)-1])
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		target.Set(field, value)
+	}
+	return nil
+}
+
+// parseValue converts the raw value to the kind of the field, messages are parsed from their
+// JSON string form, such as timestamps and durations.
+func parseValue(field protoreflect.FieldDescriptor, empty protoreflect.Value, raw string) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(raw), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(raw)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(raw, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(raw, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(raw, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if value := field.Enum().Values().ByName(protoreflect.Name(raw)); value != nil {
+			return protoreflect.ValueOfEnum(value.Number()), nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.MessageKind:
+		err := gatewayUnmarshal.Unmarshal([]byte(strconv.Quote(raw)), empty.Message().Interface())
+		return empty, err
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", field.Kind())
+	}
+}
+
+func writeStatus(w http.ResponseWriter, st *status.Status) {
+	writeGatewayError(w, httpStatus(st.Code()), st)
+}
+
+// writeGatewayError writes the status as google.rpc.Status in JSON as grpc-gateway does.
+func writeGatewayError(w http.ResponseWriter, code int, st *status.Status) {
+	data, err := gatewayMarshal.Marshal(st.Proto())
+	if err != nil {
+		data = []byte(`{"code":13,"message":"failed to encode status"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
+
+// httpStatus maps codes to HTTP statuses the same way grpc-gateway does.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// the client closed the request, nginx uses the status for it
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// chainInterceptors calls interceptors in order as grpc.ChainUnaryInterceptor does.
+func chainInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,319 @@
+package internalgrpc_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type gatewayClient struct {
+	t   *testing.T
+	url string
+}
+
+func newGatewayClient(t *testing.T) *gatewayClient {
+	t.Helper()
+	logg := logger.New("error")
+	gateway, err := internalgrpc.NewGateway(logg, app.New(logg, memorystorage.New(logg)))
+	require.NoError(t, err)
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+	return &gatewayClient{t: t, url: server.URL}
+}
+
+// do makes the request as the user and decodes the JSON response to a map.
+func (c *gatewayClient) do(method, path, userID, body string) (int, map[string]interface{}) {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	require.NoError(c.t, err)
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	require.Equal(c.t, "application/json", resp.Header.Get("Content-Type"))
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	var decoded map[string]interface{}
+	require.NoError(c.t, json.Unmarshal(data, &decoded), string(data))
+	return resp.StatusCode, decoded
+}
+
+func TestGateway(t *testing.T) {
+	client := newGatewayClient(t)
+
+	code, created := client.do(http.MethodPost, "/v1/events", "alice", `{
+		"title": "planning",
+		"start_at": "2022-10-24T10:00:00Z",
+		"end_at": "2022-10-24T11:00:00Z"
+	}`)
+	require.Equal(t, http.StatusOK, code, created)
+	id, _ := created["id"].(string)
+	require.NotEmpty(t, id)
+	require.Equal(t, "alice", created["user_id"])
+
+	t.Run("get", func(t *testing.T) {
+		code, got := client.do(http.MethodGet, "/v1/events/"+id, "alice", "")
+		require.Equal(t, http.StatusOK, code, got)
+		require.Equal(t, "planning", got["title"])
+		require.Equal(t, "2022-10-24T10:00:00Z", got["start_at"])
+	})
+
+	t.Run("update takes the id from the path", func(t *testing.T) {
+		code, updated := client.do(http.MethodPut, "/v1/events/"+id, "alice", `{
+			"title": "weekly planning",
+			"startAt": "2022-10-24T10:00:00Z",
+			"endAt": "2022-10-24T11:00:00Z",
+			"notifyBefore": "900s"
+		}`)
+		require.Equal(t, http.StatusOK, code, updated)
+		require.Equal(t, id, updated["id"])
+
+		_, got := client.do(http.MethodGet, "/v1/events/"+id, "alice", "")
+		require.Equal(t, "weekly planning", got["title"])
+		require.Equal(t, "900s", got["notify_before"])
+	})
+
+	t.Run("list", func(t *testing.T) {
+		_, list := client.do(http.MethodGet, "/v1/events", "alice", "")
+		require.Len(t, list["events"], 1)
+
+		_, others := client.do(http.MethodGet, "/v1/events", "bob", "")
+		require.Empty(t, others["events"])
+	})
+
+	t.Run("list period binds the date from the path and the zone from the query", func(t *testing.T) {
+		code, week := client.do(http.MethodGet, "/v1/weeks/2022-10-24/events?time_zone=Europe%2FMoscow", "alice", "")
+		require.Equal(t, http.StatusOK, code, week)
+		require.Len(t, week["events"], 1)
+
+		_, day := client.do(http.MethodGet, "/v1/days/2022-10-25/events", "alice", "")
+		require.Empty(t, day["events"])
+
+		_, month := client.do(http.MethodGet, "/v1/months/2022-10-01/events", "alice", "")
+		require.Len(t, month["events"], 1)
+	})
+
+	t.Run("tags bind the name from the path", func(t *testing.T) {
+		code, tag := client.do(http.MethodPut, "/v1/tags/work", "alice", `{"color": "#ff8800"}`)
+		require.Equal(t, http.StatusOK, code, tag)
+		require.Equal(t, "work", tag["name"])
+
+		_, tags := client.do(http.MethodGet, "/v1/tags", "alice", "")
+		require.Equal(t, []interface{}{
+			map[string]interface{}{"user_id": "alice", "name": "work", "color": "#ff8800"},
+		}, tags["tags"])
+	})
+
+	t.Run("free slots bind repeated and well-known types from the query", func(t *testing.T) {
+		code, share := client.do(http.MethodPost, "/v1/shares", "bob", `{"user_id": "alice", "access": "free_busy"}`)
+		require.Equal(t, http.StatusOK, code, share)
+		require.Equal(t, "bob", share["owner_id"])
+		query := url.Values{
+			"user_ids":     {"alice", "bob"},
+			"from":         {"2022-10-24T09:00:00Z"},
+			"to":           {"2022-10-24T12:00:00Z"},
+			"min_duration": {"1800s"},
+		}
+		code, slots := client.do(http.MethodGet, "/v1/free-slots?"+query.Encode(), "alice", "")
+		require.Equal(t, http.StatusOK, code, slots)
+		require.Len(t, slots["slots"], 2)
+	})
+
+	t.Run("resources bind the id from the path and double booking is a conflict", func(t *testing.T) {
+		code, room := client.do(http.MethodPost, "/v1/resources", "alice", `{"name": "Everest", "kind": "room"}`)
+		require.Equal(t, http.StatusOK, code, room)
+		booking := `{
+			"title": "review",
+			"start_at": "2022-10-24T14:00:00Z",
+			"end_at": "2022-10-24T15:00:00Z",
+			"resources": ["` + room["id"].(string) + `"]
+		}`
+		code, resp := client.do(http.MethodPost, "/v1/events", "alice", booking)
+		require.Equal(t, http.StatusOK, code, resp)
+		code, resp = client.do(http.MethodPost, "/v1/events", "bob", booking)
+		require.Equal(t, http.StatusConflict, code, resp)
+
+		query := url.Values{"from": {"2022-10-24T00:00:00Z"}, "to": {"2022-10-25T00:00:00Z"}}
+		code, events := client.do(http.MethodGet, "/v1/resources/"+room["id"].(string)+"/events?"+query.Encode(),
+			"alice", "")
+		require.Equal(t, http.StatusOK, code, events)
+		require.Len(t, events["events"], 1)
+	})
+
+	t.Run("invitations bind the event id from the path", func(t *testing.T) {
+		code, resp := client.do(http.MethodPost, "/v1/events/"+id+"/invite", "alice", `{"user_ids": ["bob"]}`)
+		require.Equal(t, http.StatusOK, code, resp)
+		code, resp = client.do(http.MethodPost, "/v1/events/"+id+"/respond", "bob", `{"status": "accepted"}`)
+		require.Equal(t, http.StatusOK, code, resp)
+
+		_, got := client.do(http.MethodGet, "/v1/events/"+id, "alice", "")
+		attendee := map[string]interface{}{"user_id": "bob", "status": "accepted"}
+		require.Equal(t, []interface{}{attendee}, got["attendees"])
+	})
+
+	t.Run("links bind the event id from the path", func(t *testing.T) {
+		code, link := client.do(http.MethodPost, "/v1/events/"+id+"/links", "alice",
+			`{"name": "notes", "url": "https://example.com/notes"}`)
+		require.Equal(t, http.StatusOK, code, link)
+		require.Equal(t, id, link["event_id"])
+
+		_, attachments := client.do(http.MethodGet, "/v1/events/"+id+"/attachments", "alice", "")
+		require.Len(t, attachments["attachments"], 1)
+		linkID, _ := link["id"].(string)
+		code, resp := client.do(http.MethodDelete, "/v1/attachments/"+linkID, "alice", "")
+		require.Equal(t, http.StatusOK, code, resp)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		code, resp := client.do(http.MethodDelete, "/v1/events/"+id, "alice", "")
+		require.Equal(t, http.StatusOK, code, resp)
+		require.Empty(t, resp)
+
+		code, resp = client.do(http.MethodGet, "/v1/events/"+id, "alice", "")
+		require.Equal(t, http.StatusNotFound, code)
+		require.Equal(t, float64(5), resp["code"])
+		require.NotEmpty(t, resp["message"])
+	})
+}
+
+func TestGatewayTrash(t *testing.T) {
+	client := newGatewayClient(t)
+
+	_, created := client.do(http.MethodPost, "/v1/events:quickAdd", "alice",
+		`{"text": "Lunch with Sam tomorrow 1pm for 1h", "time_zone": "Europe/Moscow"}`)
+	require.Equal(t, "Europe/Moscow", created["time_zone"])
+	id, _ := created["id"].(string)
+	require.NotEmpty(t, id)
+
+	code, resp := client.do(http.MethodDelete, "/v1/events/"+id, "alice", "")
+	require.Equal(t, http.StatusOK, code, resp)
+	_, trash := client.do(http.MethodGet, "/v1/deleted-events", "alice", "")
+	require.Len(t, trash["events"], 1)
+
+	code, resp = client.do(http.MethodPost, "/v1/events/"+id+"/restore", "alice", "")
+	require.Equal(t, http.StatusOK, code, resp)
+	_, history := client.do(http.MethodGet, "/v1/events/"+id+"/history", "alice", "")
+	require.Len(t, history["records"], 3)
+}
+
+func TestGatewayWebhooksAndProfile(t *testing.T) {
+	client := newGatewayClient(t)
+
+	code, hook := client.do(http.MethodPost, "/v1/webhooks", "alice", `{"url": "https://example.com/hook"}`)
+	require.Equal(t, http.StatusOK, code, hook)
+	require.NotEmpty(t, hook["secret"])
+	id, _ := hook["id"].(string)
+
+	_, deliveries := client.do(http.MethodGet, "/v1/webhooks/"+id+"/deliveries", "alice", "")
+	require.Empty(t, deliveries)
+	code, resp := client.do(http.MethodGet, "/v1/webhooks/"+id+"/deliveries", "bob", "")
+	require.Equal(t, http.StatusNotFound, code, resp)
+
+	code, profile := client.do(http.MethodPut, "/v1/profile", "alice",
+		`{"email": "alice@example.com", "channels": ["email", "webhook"]}`)
+	require.Equal(t, http.StatusOK, code, profile)
+	_, profile = client.do(http.MethodGet, "/v1/profile", "alice", "")
+	require.Equal(t, []interface{}{"email", "webhook"}, profile["channels"])
+}
+
+func TestGatewayBatch(t *testing.T) {
+	client := newGatewayClient(t)
+
+	code, created := client.do(http.MethodPost, "/v1/events:batch", "alice", `{"operations": [
+		{"op": "create", "event": {"title": "review",
+			"start_at": "2022-10-24T10:00:00Z", "end_at": "2022-10-24T11:00:00Z"}},
+		{"op": "create", "event": {"title": "retro",
+			"start_at": "2022-10-24T12:00:00Z", "end_at": "2022-10-24T13:00:00Z"}}
+	]}`)
+	require.Equal(t, http.StatusOK, code, created)
+	require.Len(t, created["events"], 2)
+
+	code, resp := client.do(http.MethodPost, "/v1/events:batch", "alice", `{"operations": [{"op": "delete"}]}`)
+	require.Equal(t, http.StatusBadRequest, code, resp)
+
+	_, list := client.do(http.MethodGet, "/v1/events", "alice", "")
+	require.Len(t, list["events"], 2)
+}
+
+func TestGatewayErrors(t *testing.T) {
+	client := newGatewayClient(t)
+
+	for _, tc := range []struct {
+		name         string
+		method, path string
+		userID, body string
+		status       int
+	}{
+		{"invalid date", http.MethodGet, "/v1/days/24.10.2022/events", "alice", "", http.StatusBadRequest},
+		{"invalid body", http.MethodPost, "/v1/events", "alice", `{"title": 1}`, http.StatusBadRequest},
+		{"event is required", http.MethodPost, "/v1/events", "alice", "", http.StatusBadRequest},
+		{"invalid query", http.MethodGet, "/v1/free-slots?from=yesterday", "alice", "", http.StatusBadRequest},
+		{
+			"invalid time zone", http.MethodGet, "/v1/days/2022-10-24/events?time_zone=Mars%2FOlympus",
+			"alice", "", http.StatusBadRequest,
+		},
+		{"not found", http.MethodGet, "/v1/events/missing", "alice", "", http.StatusNotFound},
+		{
+			"calendar is not shared", http.MethodGet,
+			"/v1/free-slots?user_ids=bob&from=2022-10-24T09:00:00Z&to=2022-10-24T12:00:00Z",
+			"alice", "", http.StatusForbidden,
+		},
+		{"share is not found", http.MethodDelete, "/v1/shares/bob", "alice", "", http.StatusNotFound},
+		{"unknown access", http.MethodPost, "/v1/shares", "alice", `{"user_id": "bob", "access": "admin"}`,
+			http.StatusBadRequest},
+		{
+			"missing resource", http.MethodPost, "/v1/events", "alice",
+			`{"start_at": "2022-10-24T10:00:00Z", "end_at": "2022-10-24T11:00:00Z", "resources": ["missing"]}`,
+			http.StatusNotFound,
+		},
+		{"unknown path", http.MethodGet, "/v1/calendars", "alice", "", http.StatusNotFound},
+		{"method is not allowed", http.MethodPatch, "/v1/events/1", "alice", "", http.StatusMethodNotAllowed},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			code, resp := client.do(tc.method, tc.path, tc.userID, tc.body)
+			require.Equal(t, tc.status, code, resp)
+			require.Contains(t, resp, "code")
+			require.NotEmpty(t, resp["message"])
+		})
+	}
+
+	t.Run("unknown query parameters are ignored", func(t *testing.T) {
+		code, resp := client.do(http.MethodGet, "/v1/events?cache=1", "alice", "")
+		require.Equal(t, http.StatusOK, code, resp)
+	})
+}
+
+func TestGatewayPassesHeadersAsMetadata(t *testing.T) {
+	client := newGatewayClient(t)
+	// 2022-10-25 01:00 in Moscow
+	code, resp := client.do(http.MethodPost, "/v1/events", "alice",
+		`{"title": "late call", "start_at": "2022-10-24T22:00:00Z", "end_at": "2022-10-24T23:00:00Z"}`)
+	require.Equal(t, http.StatusOK, code, resp)
+
+	req, err := http.NewRequest(http.MethodGet, client.url+"/v1/days/2022-10-25/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-User-ID", "alice")
+	req.Header.Set("X-Time-Zone", "Europe/Moscow")
+	httpResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+	require.Equal(t, http.StatusOK, httpResp.StatusCode)
+	var day struct {
+		Events []json.RawMessage `json:"events"`
+	}
+	require.NoError(t, json.NewDecoder(httpResp.Body).Decode(&day))
+	require.Len(t, day.Events, 1)
+}
@@ -0,0 +1,584 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: server.go
+
+// Package grpcmocks is a generated GoMock package.
+package grpcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	app "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	storage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// MockApplication is a mock of Application interface.
+type MockApplication struct {
+	ctrl     *gomock.Controller
+	recorder *MockApplicationMockRecorder
+}
+
+// MockApplicationMockRecorder is the mock recorder for MockApplication.
+type MockApplicationMockRecorder struct {
+	mock *MockApplication
+}
+
+// NewMockApplication creates a new mock instance.
+func NewMockApplication(ctrl *gomock.Controller) *MockApplication {
+	mock := &MockApplication{ctrl: ctrl}
+	mock.recorder = &MockApplicationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApplication) EXPECT() *MockApplicationMockRecorder {
+	return m.recorder
+}
+
+// AddLink mocks base method.
+func (m *MockApplication) AddLink(ctx context.Context, eventID, name, link string) (*storage.Attachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddLink", ctx, eventID, name, link)
+	ret0, _ := ret[0].(*storage.Attachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddLink indicates an expected call of AddLink.
+func (mr *MockApplicationMockRecorder) AddLink(ctx, eventID, name, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLink", reflect.TypeOf((*MockApplication)(nil).AddLink), ctx, eventID, name, link)
+}
+
+// ApplyBatch mocks base method.
+func (m *MockApplication) ApplyBatch(ctx context.Context, operations []app.BatchOperation) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyBatch", ctx, operations)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyBatch indicates an expected call of ApplyBatch.
+func (mr *MockApplicationMockRecorder) ApplyBatch(ctx, operations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyBatch", reflect.TypeOf((*MockApplication)(nil).ApplyBatch), ctx, operations)
+}
+
+// CreateEvent mocks base method.
+func (m *MockApplication) CreateEvent(ctx context.Context, event *storage.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateEvent indicates an expected call of CreateEvent.
+func (mr *MockApplicationMockRecorder) CreateEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockApplication)(nil).CreateEvent), ctx, event)
+}
+
+// CreateResource mocks base method.
+func (m *MockApplication) CreateResource(ctx context.Context, resource *storage.Resource) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateResource", ctx, resource)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateResource indicates an expected call of CreateResource.
+func (mr *MockApplicationMockRecorder) CreateResource(ctx, resource interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateResource", reflect.TypeOf((*MockApplication)(nil).CreateResource), ctx, resource)
+}
+
+// DeleteAttachment mocks base method.
+func (m *MockApplication) DeleteAttachment(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAttachment", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAttachment indicates an expected call of DeleteAttachment.
+func (mr *MockApplicationMockRecorder) DeleteAttachment(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAttachment", reflect.TypeOf((*MockApplication)(nil).DeleteAttachment), ctx, id)
+}
+
+// DeleteEvent mocks base method.
+func (m *MockApplication) DeleteEvent(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteEvent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteEvent indicates an expected call of DeleteEvent.
+func (mr *MockApplicationMockRecorder) DeleteEvent(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteEvent", reflect.TypeOf((*MockApplication)(nil).DeleteEvent), ctx, id)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockApplication) DeleteWebhook(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockApplicationMockRecorder) DeleteWebhook(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockApplication)(nil).DeleteWebhook), ctx, id)
+}
+
+// FreeSlots mocks base method.
+func (m *MockApplication) FreeSlots(ctx context.Context, userIDs []string, from, to time.Time, minDuration time.Duration) ([]app.TimeSlot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FreeSlots", ctx, userIDs, from, to, minDuration)
+	ret0, _ := ret[0].([]app.TimeSlot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FreeSlots indicates an expected call of FreeSlots.
+func (mr *MockApplicationMockRecorder) FreeSlots(ctx, userIDs, from, to, minDuration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FreeSlots", reflect.TypeOf((*MockApplication)(nil).FreeSlots), ctx, userIDs, from, to, minDuration)
+}
+
+// GetEvent mocks base method.
+func (m *MockApplication) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEvent", ctx, id)
+	ret0, _ := ret[0].(*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEvent indicates an expected call of GetEvent.
+func (mr *MockApplicationMockRecorder) GetEvent(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEvent", reflect.TypeOf((*MockApplication)(nil).GetEvent), ctx, id)
+}
+
+// GetEventHistory mocks base method.
+func (m *MockApplication) GetEventHistory(ctx context.Context, id string) ([]*storage.AuditRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEventHistory", ctx, id)
+	ret0, _ := ret[0].([]*storage.AuditRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEventHistory indicates an expected call of GetEventHistory.
+func (mr *MockApplicationMockRecorder) GetEventHistory(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventHistory", reflect.TypeOf((*MockApplication)(nil).GetEventHistory), ctx, id)
+}
+
+// GetProfile mocks base method.
+func (m *MockApplication) GetProfile(ctx context.Context) (*storage.UserProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfile", ctx)
+	ret0, _ := ret[0].(*storage.UserProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfile indicates an expected call of GetProfile.
+func (mr *MockApplicationMockRecorder) GetProfile(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockApplication)(nil).GetProfile), ctx)
+}
+
+// InviteAttendees mocks base method.
+func (m *MockApplication) InviteAttendees(ctx context.Context, eventID string, userIDs []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InviteAttendees", ctx, eventID, userIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InviteAttendees indicates an expected call of InviteAttendees.
+func (mr *MockApplicationMockRecorder) InviteAttendees(ctx, eventID, userIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InviteAttendees", reflect.TypeOf((*MockApplication)(nil).InviteAttendees), ctx, eventID, userIDs)
+}
+
+// ListAttachments mocks base method.
+func (m *MockApplication) ListAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAttachments", ctx, eventID)
+	ret0, _ := ret[0].([]*storage.Attachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAttachments indicates an expected call of ListAttachments.
+func (mr *MockApplicationMockRecorder) ListAttachments(ctx, eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAttachments", reflect.TypeOf((*MockApplication)(nil).ListAttachments), ctx, eventID)
+}
+
+// ListAvailableResources mocks base method.
+func (m *MockApplication) ListAvailableResources(ctx context.Context, from, to time.Time, kind storage.ResourceKind, minCapacity int) ([]*storage.Resource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAvailableResources", ctx, from, to, kind, minCapacity)
+	ret0, _ := ret[0].([]*storage.Resource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAvailableResources indicates an expected call of ListAvailableResources.
+func (mr *MockApplicationMockRecorder) ListAvailableResources(ctx, from, to, kind, minCapacity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAvailableResources", reflect.TypeOf((*MockApplication)(nil).ListAvailableResources), ctx, from, to, kind, minCapacity)
+}
+
+// ListDayEvents mocks base method.
+func (m *MockApplication) ListDayEvents(ctx context.Context, day time.Time, tags ...string) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, day}
+	for _, a := range tags {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListDayEvents", varargs...)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDayEvents indicates an expected call of ListDayEvents.
+func (mr *MockApplicationMockRecorder) ListDayEvents(ctx, day interface{}, tags ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, day}, tags...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDayEvents", reflect.TypeOf((*MockApplication)(nil).ListDayEvents), varargs...)
+}
+
+// ListDeletedEvents mocks base method.
+func (m *MockApplication) ListDeletedEvents(ctx context.Context) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeletedEvents", ctx)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeletedEvents indicates an expected call of ListDeletedEvents.
+func (mr *MockApplicationMockRecorder) ListDeletedEvents(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeletedEvents", reflect.TypeOf((*MockApplication)(nil).ListDeletedEvents), ctx)
+}
+
+// ListEvents mocks base method.
+func (m *MockApplication) ListEvents(ctx context.Context, tags ...string) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range tags {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListEvents", varargs...)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockApplicationMockRecorder) ListEvents(ctx interface{}, tags ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, tags...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockApplication)(nil).ListEvents), varargs...)
+}
+
+// ListMonthEvents mocks base method.
+func (m *MockApplication) ListMonthEvents(ctx context.Context, monthStart time.Time, tags ...string) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, monthStart}
+	for _, a := range tags {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListMonthEvents", varargs...)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMonthEvents indicates an expected call of ListMonthEvents.
+func (mr *MockApplicationMockRecorder) ListMonthEvents(ctx, monthStart interface{}, tags ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, monthStart}, tags...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMonthEvents", reflect.TypeOf((*MockApplication)(nil).ListMonthEvents), varargs...)
+}
+
+// ListResourceEvents mocks base method.
+func (m *MockApplication) ListResourceEvents(ctx context.Context, id string, from, to time.Time) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListResourceEvents", ctx, id, from, to)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListResourceEvents indicates an expected call of ListResourceEvents.
+func (mr *MockApplicationMockRecorder) ListResourceEvents(ctx, id, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResourceEvents", reflect.TypeOf((*MockApplication)(nil).ListResourceEvents), ctx, id, from, to)
+}
+
+// ListResources mocks base method.
+func (m *MockApplication) ListResources(ctx context.Context) ([]*storage.Resource, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListResources", ctx)
+	ret0, _ := ret[0].([]*storage.Resource)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListResources indicates an expected call of ListResources.
+func (mr *MockApplicationMockRecorder) ListResources(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResources", reflect.TypeOf((*MockApplication)(nil).ListResources), ctx)
+}
+
+// ListSharedCalendars mocks base method.
+func (m *MockApplication) ListSharedCalendars(ctx context.Context) ([]*storage.Share, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSharedCalendars", ctx)
+	ret0, _ := ret[0].([]*storage.Share)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSharedCalendars indicates an expected call of ListSharedCalendars.
+func (mr *MockApplicationMockRecorder) ListSharedCalendars(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSharedCalendars", reflect.TypeOf((*MockApplication)(nil).ListSharedCalendars), ctx)
+}
+
+// ListShares mocks base method.
+func (m *MockApplication) ListShares(ctx context.Context) ([]*storage.Share, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListShares", ctx)
+	ret0, _ := ret[0].([]*storage.Share)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListShares indicates an expected call of ListShares.
+func (mr *MockApplicationMockRecorder) ListShares(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListShares", reflect.TypeOf((*MockApplication)(nil).ListShares), ctx)
+}
+
+// ListTags mocks base method.
+func (m *MockApplication) ListTags(ctx context.Context) ([]*storage.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", ctx)
+	ret0, _ := ret[0].([]*storage.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockApplicationMockRecorder) ListTags(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockApplication)(nil).ListTags), ctx)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockApplication) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", ctx, webhookID)
+	ret0, _ := ret[0].([]*storage.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockApplicationMockRecorder) ListWebhookDeliveries(ctx, webhookID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockApplication)(nil).ListWebhookDeliveries), ctx, webhookID)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockApplication) ListWebhooks(ctx context.Context) ([]*storage.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", ctx)
+	ret0, _ := ret[0].([]*storage.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockApplicationMockRecorder) ListWebhooks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockApplication)(nil).ListWebhooks), ctx)
+}
+
+// ListWeekEvents mocks base method.
+func (m *MockApplication) ListWeekEvents(ctx context.Context, weekStart time.Time, tags ...string) ([]*storage.Event, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, weekStart}
+	for _, a := range tags {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListWeekEvents", varargs...)
+	ret0, _ := ret[0].([]*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWeekEvents indicates an expected call of ListWeekEvents.
+func (mr *MockApplicationMockRecorder) ListWeekEvents(ctx, weekStart interface{}, tags ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, weekStart}, tags...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWeekEvents", reflect.TypeOf((*MockApplication)(nil).ListWeekEvents), varargs...)
+}
+
+// QuickAddEvent mocks base method.
+func (m *MockApplication) QuickAddEvent(ctx context.Context, text string, loc *time.Location) (*storage.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuickAddEvent", ctx, text, loc)
+	ret0, _ := ret[0].(*storage.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuickAddEvent indicates an expected call of QuickAddEvent.
+func (mr *MockApplicationMockRecorder) QuickAddEvent(ctx, text, loc interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuickAddEvent", reflect.TypeOf((*MockApplication)(nil).QuickAddEvent), ctx, text, loc)
+}
+
+// RegisterWebhook mocks base method.
+func (m *MockApplication) RegisterWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterWebhook", ctx, webhook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterWebhook indicates an expected call of RegisterWebhook.
+func (mr *MockApplicationMockRecorder) RegisterWebhook(ctx, webhook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterWebhook", reflect.TypeOf((*MockApplication)(nil).RegisterWebhook), ctx, webhook)
+}
+
+// RespondToInvitation mocks base method.
+func (m *MockApplication) RespondToInvitation(ctx context.Context, eventID string, status storage.AttendeeStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RespondToInvitation", ctx, eventID, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RespondToInvitation indicates an expected call of RespondToInvitation.
+func (mr *MockApplicationMockRecorder) RespondToInvitation(ctx, eventID, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RespondToInvitation", reflect.TypeOf((*MockApplication)(nil).RespondToInvitation), ctx, eventID, status)
+}
+
+// RestoreEvent mocks base method.
+func (m *MockApplication) RestoreEvent(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreEvent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreEvent indicates an expected call of RestoreEvent.
+func (mr *MockApplicationMockRecorder) RestoreEvent(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreEvent", reflect.TypeOf((*MockApplication)(nil).RestoreEvent), ctx, id)
+}
+
+// RevokeShare mocks base method.
+func (m *MockApplication) RevokeShare(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeShare", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeShare indicates an expected call of RevokeShare.
+func (mr *MockApplicationMockRecorder) RevokeShare(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeShare", reflect.TypeOf((*MockApplication)(nil).RevokeShare), ctx, userID)
+}
+
+// ShareCalendar mocks base method.
+func (m *MockApplication) ShareCalendar(ctx context.Context, userID string, access storage.ShareAccess) (*storage.Share, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShareCalendar", ctx, userID, access)
+	ret0, _ := ret[0].(*storage.Share)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShareCalendar indicates an expected call of ShareCalendar.
+func (mr *MockApplicationMockRecorder) ShareCalendar(ctx, userID, access interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShareCalendar", reflect.TypeOf((*MockApplication)(nil).ShareCalendar), ctx, userID, access)
+}
+
+// UpdateEvent mocks base method.
+func (m *MockApplication) UpdateEvent(ctx context.Context, event *storage.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEvent indicates an expected call of UpdateEvent.
+func (mr *MockApplicationMockRecorder) UpdateEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEvent", reflect.TypeOf((*MockApplication)(nil).UpdateEvent), ctx, event)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockApplication) UpdateProfile(ctx context.Context, profile *storage.UserProfile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, profile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockApplicationMockRecorder) UpdateProfile(ctx, profile interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockApplication)(nil).UpdateProfile), ctx, profile)
+}
+
+// UpdateResource mocks base method.
+func (m *MockApplication) UpdateResource(ctx context.Context, resource *storage.Resource) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateResource", ctx, resource)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateResource indicates an expected call of UpdateResource.
+func (mr *MockApplicationMockRecorder) UpdateResource(ctx, resource interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateResource", reflect.TypeOf((*MockApplication)(nil).UpdateResource), ctx, resource)
+}
+
+// UpdateTag mocks base method.
+func (m *MockApplication) UpdateTag(ctx context.Context, tag *storage.Tag) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTag", ctx, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTag indicates an expected call of UpdateTag.
+func (mr *MockApplicationMockRecorder) UpdateTag(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTag", reflect.TypeOf((*MockApplication)(nil).UpdateTag), ctx, tag)
+}
@@ -0,0 +1,197 @@
+package internalgrpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys are the HTTP headers of the same meaning in lower case.
+const (
+	// UserIDKey carries the ID of the user who makes the call,
+	// authentication is out of scope of the service.
+	UserIDKey = "x-user-id"
+	// CalendarIDKey selects the calendar of another user shared with the caller.
+	CalendarIDKey = "x-calendar-id"
+	// TimeZoneKey sets the zone of the caller, time_zone fields have precedence over it.
+	TimeZoneKey = "x-time-zone"
+	// ConsistencyKey set to ReadYourWritesValue makes reads see all preceding writes.
+	ConsistencyKey      = "x-consistency"
+	ReadYourWritesValue = "read-your-writes"
+)
+
+//go:generate buf generate ../../../api
+//go:generate mockgen -destination mocks/server_mocks.go -source server.go -package grpcmocks
+
+type Application interface {
+	CreateEvent(ctx context.Context, event *storage.Event) error
+	UpdateEvent(ctx context.Context, event *storage.Event) error
+	DeleteEvent(ctx context.Context, id string) error
+	GetEvent(ctx context.Context, id string) (*storage.Event, error)
+	ListEvents(ctx context.Context, tags ...string) ([]*storage.Event, error)
+	QuickAddEvent(ctx context.Context, text string, loc *time.Location) (*storage.Event, error)
+	GetEventHistory(ctx context.Context, id string) ([]*storage.AuditRecord, error)
+	RestoreEvent(ctx context.Context, id string) error
+	ListDeletedEvents(ctx context.Context) ([]*storage.Event, error)
+	InviteAttendees(ctx context.Context, eventID string, userIDs []string) error
+	RespondToInvitation(ctx context.Context, eventID string, status storage.AttendeeStatus) error
+	ListDayEvents(ctx context.Context, day time.Time, tags ...string) ([]*storage.Event, error)
+	ListWeekEvents(ctx context.Context, weekStart time.Time, tags ...string) ([]*storage.Event, error)
+	ListMonthEvents(ctx context.Context, monthStart time.Time, tags ...string) ([]*storage.Event, error)
+	ApplyBatch(ctx context.Context, operations []app.BatchOperation) ([]*storage.Event, error)
+	FreeSlots(
+		ctx context.Context, userIDs []string, from, to time.Time, minDuration time.Duration,
+	) ([]app.TimeSlot, error)
+	ListTags(ctx context.Context) ([]*storage.Tag, error)
+	UpdateTag(ctx context.Context, tag *storage.Tag) error
+	CreateResource(ctx context.Context, resource *storage.Resource) error
+	UpdateResource(ctx context.Context, resource *storage.Resource) error
+	ListResources(ctx context.Context) ([]*storage.Resource, error)
+	ListResourceEvents(ctx context.Context, id string, from, to time.Time) ([]*storage.Event, error)
+	ListAvailableResources(
+		ctx context.Context, from, to time.Time, kind storage.ResourceKind, minCapacity int,
+	) ([]*storage.Resource, error)
+	ShareCalendar(ctx context.Context, userID string, access storage.ShareAccess) (*storage.Share, error)
+	RevokeShare(ctx context.Context, userID string) error
+	ListShares(ctx context.Context) ([]*storage.Share, error)
+	ListSharedCalendars(ctx context.Context) ([]*storage.Share, error)
+	RegisterWebhook(ctx context.Context, webhook *storage.Webhook) error
+	ListWebhooks(ctx context.Context) ([]*storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error)
+	GetProfile(ctx context.Context) (*storage.UserProfile, error)
+	UpdateProfile(ctx context.Context, profile *storage.UserProfile) error
+	AddLink(ctx context.Context, eventID, name, link string) (*storage.Attachment, error)
+	ListAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error)
+	DeleteAttachment(ctx context.Context, id string) error
+}
+
+type Server struct {
+	Logg   app.Logger
+	App    Application
+	Server *grpc.Server
+
+	ShutdownTimeout time.Duration
+
+	addr string
+}
+
+func NewServer(logger app.Logger, application Application, host, port string, shutDownTimeout time.Duration) *Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors(logger)...))
+	pb.RegisterEventServiceServer(server, NewService(application))
+	return &Server{
+		Logg:   logger,
+		App:    application,
+		Server: server,
+
+		ShutdownTimeout: shutDownTimeout,
+
+		addr: net.JoinHostPort(host, port),
+	}
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return errors.Wrap(err, "Got unexpected error while starting grpc server")
+	}
+	errChan := make(chan error)
+	go func() {
+		defer close(errChan)
+		errChan <- s.Server.Serve(listener)
+	}()
+
+	select {
+	case <-ctx.Done():
+		s.Logg.Info().Msg("Stop grpc server by signal")
+		return nil
+	case err = <-errChan:
+		if err == nil || errors.Is(err, grpc.ErrServerStopped) {
+			s.Logg.Info().Msg("Grpc server is shutdowning...")
+			return nil
+		}
+		return errors.Wrap(err, "Got unexpected error while starting grpc server")
+	}
+}
+
+// Stop waits for running calls for ShutdownTimeout and then cancels them.
+func (s *Server) Stop(ctx context.Context) error {
+	s.Logg.Info().Msg("Start stopping grpc server...")
+	ctx, cancel := context.WithTimeout(ctx, s.ShutdownTimeout)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.Server.Stop()
+	}
+	s.Logg.Info().Err(ctx.Err()).Msg("Grpc server is shutdown")
+	return ctx.Err()
+}
+
+// interceptors of calls, the gateway calls the service through them too.
+func interceptors(logger app.Logger) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		loggingInterceptor(logger),
+		metadataInterceptor,
+	}
+}
+
+func loggingInterceptor(logger app.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		logger.Debug().Msgf("%s [%s] %s %s %d",
+			addr,
+			time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			info.FullMethod,
+			status.Code(err),
+			time.Since(start).Milliseconds(),
+		)
+		return resp, err
+	}
+}
+
+func metadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// metadataInterceptor puts the user, the calendar and the consistency from metadata to ctx
+// as userIDMiddleware, calendarIDMiddleware and consistencyMiddleware of the HTTP server do.
+func metadataInterceptor(
+	ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if userID := metadataValue(md, UserIDKey); userID != "" {
+		ctx = app.ContextWithUserID(ctx, userID)
+	}
+	if calendarID := metadataValue(md, CalendarIDKey); calendarID != "" {
+		ctx = app.ContextWithCalendarID(ctx, calendarID)
+	}
+	if metadataValue(md, ConsistencyKey) == ReadYourWritesValue {
+		ctx = app.ContextWithReadYourWrites(ctx)
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,523 @@
+package internalgrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newClient serves the app with memory storage in process and returns a client to it.
+func newClient(t *testing.T) pb.EventServiceClient {
+	t.Helper()
+	logg := logger.New("error")
+	server := internalgrpc.NewServer(logg, app.New(logg, memorystorage.New(logg)), "", "", time.Second)
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Server.Serve(listener)
+	}()
+	t.Cleanup(server.Server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewEventServiceClient(conn)
+}
+
+func asUser(userID string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), internalgrpc.UserIDKey, userID)
+}
+
+func TestEventService(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+
+	created, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:   "planning",
+		StartAt: timestamppb.New(start),
+		EndAt:   timestamppb.New(start.Add(time.Hour)),
+	}})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.GetId())
+	require.Equal(t, "alice", created.GetUserId())
+
+	t.Run("get", func(t *testing.T) {
+		got, err := client.GetEvent(ctx, &pb.GetEventRequest{Id: created.GetId()})
+		require.NoError(t, err)
+		require.Equal(t, "planning", got.GetTitle())
+		require.True(t, start.Equal(got.GetStartAt().AsTime()))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		update := &pb.Event{
+			Id:           created.GetId(),
+			Title:        "weekly planning",
+			Description:  "agenda",
+			StartAt:      created.GetStartAt(),
+			EndAt:        created.GetEndAt(),
+			NotifyBefore: durationpb.New(15 * time.Minute),
+		}
+		_, err := client.UpdateEvent(ctx, &pb.UpdateEventRequest{Event: update})
+		require.NoError(t, err)
+
+		got, err := client.GetEvent(ctx, &pb.GetEventRequest{Id: created.GetId()})
+		require.NoError(t, err)
+		require.Equal(t, "weekly planning", got.GetTitle())
+		require.Equal(t, "agenda", got.GetDescription())
+		require.Equal(t, 15*time.Minute, got.GetNotifyBefore().AsDuration())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		events, err := client.ListEvents(ctx, &pb.ListEventsRequest{})
+		require.NoError(t, err)
+		require.Len(t, events.GetEvents(), 1)
+
+		others, err := client.ListEvents(asUser("bob"), &pb.ListEventsRequest{})
+		require.NoError(t, err)
+		require.Empty(t, others.GetEvents())
+	})
+
+	t.Run("list period", func(t *testing.T) {
+		week, err := client.ListWeekEvents(ctx, &pb.ListPeriodEventsRequest{
+			Date: "2022-10-24", TimeZone: "Europe/Moscow",
+		})
+		require.NoError(t, err)
+		require.Len(t, week.GetEvents(), 1)
+
+		none, err := client.ListDayEvents(ctx, &pb.ListPeriodEventsRequest{Date: "2022-10-25"})
+		require.NoError(t, err)
+		require.Empty(t, none.GetEvents())
+	})
+
+	t.Run("free slots", func(t *testing.T) {
+		slots, err := client.FreeSlots(ctx, &pb.FreeSlotsRequest{
+			UserIds:     []string{"alice"},
+			From:        timestamppb.New(start.Add(-time.Hour)),
+			To:          timestamppb.New(start.Add(2 * time.Hour)),
+			MinDuration: durationpb.New(30 * time.Minute),
+		})
+		require.NoError(t, err)
+		require.Len(t, slots.GetSlots(), 2)
+		require.True(t, start.Equal(slots.GetSlots()[0].GetEnd().AsTime()))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := client.DeleteEvent(ctx, &pb.DeleteEventRequest{Id: created.GetId()})
+		require.NoError(t, err)
+
+		_, err = client.GetEvent(ctx, &pb.GetEventRequest{Id: created.GetId()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestTags(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+
+	for _, tags := range [][]string{{"work", "oncall"}, {"travel"}} {
+		_, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+			Title:   tags[0],
+			StartAt: timestamppb.New(start),
+			EndAt:   timestamppb.New(start.Add(time.Hour)),
+			Tags:    tags,
+		}})
+		require.NoError(t, err)
+	}
+
+	t.Run("list by tag", func(t *testing.T) {
+		events, err := client.ListEvents(ctx, &pb.ListEventsRequest{Tags: []string{"oncall"}})
+		require.NoError(t, err)
+		require.Len(t, events.GetEvents(), 1)
+		require.Equal(t, []string{"oncall", "work"}, events.GetEvents()[0].GetTags())
+
+		day, err := client.ListDayEvents(ctx, &pb.ListPeriodEventsRequest{Date: "2022-10-24", Tags: []string{"travel"}})
+		require.NoError(t, err)
+		require.Len(t, day.GetEvents(), 1)
+		require.Equal(t, "travel", day.GetEvents()[0].GetTitle())
+	})
+
+	t.Run("update tag", func(t *testing.T) {
+		tag, err := client.UpdateTag(ctx, &pb.UpdateTagRequest{Tag: &pb.Tag{Name: "work", Color: "#FF8800"}})
+		require.NoError(t, err)
+		require.Equal(t, "#ff8800", tag.GetColor())
+		require.Equal(t, "alice", tag.GetUserId())
+
+		tags, err := client.ListTags(ctx, &pb.ListTagsRequest{})
+		require.NoError(t, err)
+		require.Len(t, tags.GetTags(), 3)
+		require.Equal(t, "work", tags.GetTags()[2].GetName())
+		require.Equal(t, "#ff8800", tags.GetTags()[2].GetColor())
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		_, err := client.UpdateTag(ctx, &pb.UpdateTagRequest{Tag: &pb.Tag{Name: "work", Color: "orange"}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = client.UpdateTag(ctx, &pb.UpdateTagRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestResources(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+
+	everest, err := client.CreateResource(ctx, &pb.CreateResourceRequest{Resource: &pb.Resource{
+		Name: "Everest", Kind: "room", Capacity: 10,
+	}})
+	require.NoError(t, err)
+	require.NotEmpty(t, everest.GetId())
+	planning, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title:     "planning",
+		StartAt:   timestamppb.New(start),
+		EndAt:     timestamppb.New(start.Add(time.Hour)),
+		Resources: []string{everest.GetId()},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []string{everest.GetId()}, planning.GetResources())
+
+	t.Run("double booking", func(t *testing.T) {
+		_, err := client.CreateEvent(asUser("bob"), &pb.CreateEventRequest{Event: &pb.Event{
+			Title:     "retro",
+			StartAt:   timestamppb.New(start.Add(30 * time.Minute)),
+			EndAt:     timestamppb.New(start.Add(90 * time.Minute)),
+			Resources: []string{everest.GetId()},
+		}})
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("calendar of the resource", func(t *testing.T) {
+		events, err := client.ListResourceEvents(ctx, &pb.ListResourceEventsRequest{
+			Id: everest.GetId(), From: timestamppb.New(start), To: timestamppb.New(start.Add(time.Hour)),
+		})
+		require.NoError(t, err)
+		require.Len(t, events.GetEvents(), 1)
+		require.Equal(t, planning.GetId(), events.GetEvents()[0].GetId())
+
+		_, err = client.ListResourceEvents(ctx, &pb.ListResourceEventsRequest{
+			Id: "missing", From: timestamppb.New(start), To: timestamppb.New(start.Add(time.Hour)),
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("available resources", func(t *testing.T) {
+		available, err := client.ListAvailableResources(ctx, &pb.ListAvailableResourcesRequest{
+			From: timestamppb.New(start), To: timestamppb.New(start.Add(time.Hour)),
+		})
+		require.NoError(t, err)
+		require.Empty(t, available.GetResources())
+
+		available, err = client.ListAvailableResources(ctx, &pb.ListAvailableResourcesRequest{
+			From: timestamppb.New(start.Add(time.Hour)), To: timestamppb.New(start.Add(2 * time.Hour)),
+			Kind: "room", MinCapacity: 10,
+		})
+		require.NoError(t, err)
+		require.Len(t, available.GetResources(), 1)
+		require.Equal(t, "Everest", available.GetResources()[0].GetName())
+	})
+
+	t.Run("update resource", func(t *testing.T) {
+		updated, err := client.UpdateResource(ctx, &pb.UpdateResourceRequest{Resource: &pb.Resource{
+			Id: everest.GetId(), Name: "Everest", Kind: "room", Capacity: 12,
+		}})
+		require.NoError(t, err)
+		require.Equal(t, int32(12), updated.GetCapacity())
+
+		_, err = client.UpdateResource(ctx, &pb.UpdateResourceRequest{Resource: &pb.Resource{
+			Id: everest.GetId(), Name: "Everest", Kind: "boat",
+		}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = client.UpdateResource(ctx, &pb.UpdateResourceRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestBatch(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+
+	created, err := client.ApplyBatch(ctx, &pb.ApplyBatchRequest{Operations: []*pb.BatchOperation{
+		{Op: "create", Event: &pb.Event{
+			Title: "review", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+		}},
+		{Op: "create", Event: &pb.Event{
+			Title: "retro", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+		}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, created.GetEvents(), 2)
+
+	t.Run("batch is applied all or none", func(t *testing.T) {
+		_, err := client.ApplyBatch(ctx, &pb.ApplyBatchRequest{Operations: []*pb.BatchOperation{
+			{Op: "delete", Id: created.GetEvents()[0].GetId()},
+			{Op: "delete"},
+		}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		events, err := client.ListEvents(ctx, &pb.ListEventsRequest{})
+		require.NoError(t, err)
+		require.Len(t, events.GetEvents(), 2)
+	})
+}
+
+func TestShares(t *testing.T) {
+	client := newClient(t)
+	alice := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+	planning, err := client.CreateEvent(alice, &pb.CreateEventRequest{Event: &pb.Event{
+		Title: "planning", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+	}})
+	require.NoError(t, err)
+	// bob manages the calendar of alice
+	bob := metadata.AppendToOutgoingContext(asUser("bob"), internalgrpc.CalendarIDKey, "alice")
+
+	_, err = client.ListEvents(bob, &pb.ListEventsRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	share, err := client.ShareCalendar(alice, &pb.ShareCalendarRequest{UserId: "bob", Access: "read_write"})
+	require.NoError(t, err)
+	require.Equal(t, "alice", share.GetOwnerId())
+	shares, err := client.ListShares(alice, &pb.ListSharesRequest{})
+	require.NoError(t, err)
+	require.Len(t, shares.GetShares(), 1)
+	shared, err := client.ListSharedCalendars(asUser("bob"), &pb.ListSharesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "alice", shared.GetShares()[0].GetOwnerId())
+
+	events, err := client.ListEvents(bob, &pb.ListEventsRequest{})
+	require.NoError(t, err)
+	require.Len(t, events.GetEvents(), 1)
+	review, err := client.CreateEvent(bob, &pb.CreateEventRequest{Event: &pb.Event{
+		Title: "review", StartAt: planning.GetEndAt(), EndAt: timestamppb.New(start.Add(2 * time.Hour)),
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "alice", review.GetUserId())
+
+	_, err = client.RevokeShare(alice, &pb.RevokeShareRequest{UserId: "bob"})
+	require.NoError(t, err)
+	_, err = client.GetEvent(asUser("bob"), &pb.GetEventRequest{Id: review.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.ShareCalendar(alice, &pb.ShareCalendarRequest{UserId: "alice", Access: "read"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHistoryAndTrash(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+	event, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title: "planning", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+	}})
+	require.NoError(t, err)
+
+	_, err = client.DeleteEvent(ctx, &pb.DeleteEventRequest{Id: event.GetId()})
+	require.NoError(t, err)
+	trash, err := client.ListDeletedEvents(ctx, &pb.ListDeletedEventsRequest{})
+	require.NoError(t, err)
+	require.Len(t, trash.GetEvents(), 1)
+	require.NotNil(t, trash.GetEvents()[0].GetDeletedAt())
+
+	_, err = client.RestoreEvent(ctx, &pb.RestoreEventRequest{Id: event.GetId()})
+	require.NoError(t, err)
+	trash, err = client.ListDeletedEvents(ctx, &pb.ListDeletedEventsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, trash.GetEvents())
+
+	history, err := client.GetEventHistory(ctx, &pb.GetEventHistoryRequest{Id: event.GetId()})
+	require.NoError(t, err)
+	var actions []string
+	for _, record := range history.GetRecords() {
+		require.Equal(t, "alice", record.GetActor())
+		actions = append(actions, record.GetAction())
+	}
+	require.Equal(t, []string{"create", "delete", "restore"}, actions)
+	require.Nil(t, history.GetRecords()[0].GetBefore())
+	require.Equal(t, "planning", history.GetRecords()[0].GetAfter().GetTitle())
+
+	_, err = client.GetEventHistory(ctx, &pb.GetEventHistoryRequest{Id: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestInvitations(t *testing.T) {
+	client := newClient(t)
+	alice := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+	event, err := client.CreateEvent(alice, &pb.CreateEventRequest{Event: &pb.Event{
+		Title: "planning", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+	}})
+	require.NoError(t, err)
+
+	_, err = client.InviteAttendees(alice, &pb.InviteAttendeesRequest{EventId: event.GetId()})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.InviteAttendees(alice, &pb.InviteAttendeesRequest{EventId: event.GetId(), UserIds: []string{"bob"}})
+	require.NoError(t, err)
+
+	_, err = client.RespondToInvitation(asUser("bob"), &pb.RespondToInvitationRequest{
+		EventId: event.GetId(), Status: "maybe",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.RespondToInvitation(asUser("bob"), &pb.RespondToInvitationRequest{
+		EventId: event.GetId(), Status: "accepted",
+	})
+	require.NoError(t, err)
+
+	got, err := client.GetEvent(alice, &pb.GetEventRequest{Id: event.GetId()})
+	require.NoError(t, err)
+	require.Len(t, got.GetAttendees(), 1)
+	require.Equal(t, "bob", got.GetAttendees()[0].GetUserId())
+	require.Equal(t, "accepted", got.GetAttendees()[0].GetStatus())
+}
+
+func TestQuickAdd(t *testing.T) {
+	client := newClient(t)
+	ctx := metadata.AppendToOutgoingContext(asUser("alice"), internalgrpc.TimeZoneKey, "Europe/Moscow")
+
+	event, err := client.QuickAddEvent(ctx, &pb.QuickAddEventRequest{Text: "Lunch with Sam tomorrow 1pm for 1h"})
+	require.NoError(t, err)
+	require.Equal(t, "Lunch with Sam", event.GetTitle())
+	require.Equal(t, "Europe/Moscow", event.GetTimeZone())
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	require.Equal(t, 13, event.GetStartAt().AsTime().In(moscow).Hour())
+	require.Equal(t, time.Hour, event.GetEndAt().AsTime().Sub(event.GetStartAt().AsTime()))
+
+	_, err = client.QuickAddEvent(ctx, &pb.QuickAddEventRequest{Text: "tomorrow"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.QuickAddEvent(ctx, &pb.QuickAddEventRequest{Text: "Lunch tomorrow 1pm", TimeZone: "Mars/Olympus"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWebhooks(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+
+	_, err := client.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Webhook: &pb.Webhook{Url: "ftp://host"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	webhook, err := client.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{
+		Webhook: &pb.Webhook{Url: "https://example.com/hook"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, webhook.GetSecret())
+	require.Equal(t, "alice", webhook.GetUserId())
+
+	webhooks, err := client.ListWebhooks(ctx, &pb.ListWebhooksRequest{})
+	require.NoError(t, err)
+	require.Len(t, webhooks.GetWebhooks(), 1)
+	require.Empty(t, webhooks.GetWebhooks()[0].GetSecret())
+
+	deliveries, err := client.ListWebhookDeliveries(ctx, &pb.ListWebhookDeliveriesRequest{WebhookId: webhook.GetId()})
+	require.NoError(t, err)
+	require.Empty(t, deliveries.GetDeliveries())
+	_, err = client.ListWebhookDeliveries(asUser("bob"), &pb.ListWebhookDeliveriesRequest{WebhookId: webhook.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{Id: webhook.GetId()})
+	require.NoError(t, err)
+	_, err = client.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{Id: webhook.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestProfile(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+
+	_, err := client.GetProfile(ctx, &pb.GetProfileRequest{})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.UpdateProfile(ctx, &pb.UpdateProfileRequest{Profile: &pb.UserProfile{Channels: []string{"pigeon"}}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	updated, err := client.UpdateProfile(ctx, &pb.UpdateProfileRequest{Profile: &pb.UserProfile{
+		Email: "alice@example.com", Channels: []string{"email", "email"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "alice", updated.GetUserId())
+	require.Equal(t, []string{"email"}, updated.GetChannels())
+
+	got, err := client.GetProfile(ctx, &pb.GetProfileRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com", got.GetEmail())
+}
+
+func TestLinks(t *testing.T) {
+	client := newClient(t)
+	ctx := asUser("alice")
+	start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+	event, err := client.CreateEvent(ctx, &pb.CreateEventRequest{Event: &pb.Event{
+		Title: "planning", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+	}})
+	require.NoError(t, err)
+
+	_, err = client.AddLink(ctx, &pb.AddLinkRequest{EventId: event.GetId(), Url: "notes"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	link, err := client.AddLink(ctx, &pb.AddLinkRequest{
+		EventId: event.GetId(), Name: "notes", Url: "https://example.com/notes",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "alice", link.GetUserId())
+
+	attachments, err := client.ListAttachments(ctx, &pb.ListAttachmentsRequest{EventId: event.GetId()})
+	require.NoError(t, err)
+	require.Len(t, attachments.GetAttachments(), 1)
+	require.Equal(t, "https://example.com/notes", attachments.GetAttachments()[0].GetUrl())
+
+	_, err = client.DeleteAttachment(ctx, &pb.DeleteAttachmentRequest{Id: link.GetId()})
+	require.NoError(t, err)
+	_, err = client.DeleteAttachment(ctx, &pb.DeleteAttachmentRequest{Id: link.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestEventServiceErrors(t *testing.T) {
+	client := newClient(t)
+
+	t.Run("invalid free/busy query", func(t *testing.T) {
+		now := time.Now()
+		_, err := client.FreeSlots(asUser("alice"), &pb.FreeSlotsRequest{
+			UserIds: []string{"alice"},
+			From:    timestamppb.New(now),
+			To:      timestamppb.New(now.Add(-time.Hour)),
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid time zone", func(t *testing.T) {
+		_, err := client.ListDayEvents(asUser("alice"), &pb.ListPeriodEventsRequest{
+			Date: "2022-10-24", TimeZone: "Mars/Olympus",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("event is required", func(t *testing.T) {
+		_, err := client.CreateEvent(asUser("alice"), &pb.CreateEventRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := client.GetEvent(asUser("alice"), &pb.GetEventRequest{Id: "missing"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
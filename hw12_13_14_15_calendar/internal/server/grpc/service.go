@@ -0,0 +1,429 @@
+package internalgrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Service implements EventService on top of the app, errors of the app are converted
+// to statuses with codes matching the statuses of the HTTP API.
+type Service struct {
+	pb.UnimplementedEventServiceServer
+
+	App Application
+}
+
+func NewService(application Application) *Service {
+	return &Service{App: application}
+}
+
+func (s *Service) CreateEvent(ctx context.Context, req *pb.CreateEventRequest) (*pb.Event, error) {
+	if req.GetEvent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "event is required")
+	}
+	event := EventFromProto(req.GetEvent())
+	if err := s.App.CreateEvent(ctx, event); err != nil {
+		return nil, toStatus(err)
+	}
+	return EventToProto(event), nil
+}
+
+func (s *Service) UpdateEvent(ctx context.Context, req *pb.UpdateEventRequest) (*pb.Event, error) {
+	if req.GetEvent().GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	event := EventFromProto(req.GetEvent())
+	if err := s.App.UpdateEvent(ctx, event); err != nil {
+		return nil, toStatus(err)
+	}
+	return EventToProto(event), nil
+}
+
+func (s *Service) DeleteEvent(ctx context.Context, req *pb.DeleteEventRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	if err := s.App.DeleteEvent(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Service) GetEvent(ctx context.Context, req *pb.GetEventRequest) (*pb.Event, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	event, err := s.App.GetEvent(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventToProto(event), nil
+}
+
+func (s *Service) ListEvents(ctx context.Context, req *pb.ListEventsRequest) (*pb.ListEventsResponse, error) {
+	events, err := s.App.ListEvents(ctx, req.GetTags()...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventsToProto(events), nil
+}
+
+func (s *Service) QuickAddEvent(ctx context.Context, req *pb.QuickAddEventRequest) (*pb.Event, error) {
+	loc, err := callerLocation(ctx, req.GetTimeZone())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	event, err := s.App.QuickAddEvent(ctx, req.GetText(), loc)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventToProto(event), nil
+}
+
+func (s *Service) GetEventHistory(
+	ctx context.Context, req *pb.GetEventHistoryRequest,
+) (*pb.GetEventHistoryResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	records, err := s.App.GetEventHistory(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return auditRecordsToProto(records), nil
+}
+
+func (s *Service) RestoreEvent(ctx context.Context, req *pb.RestoreEventRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	if err := s.App.RestoreEvent(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Service) ListDeletedEvents(
+	ctx context.Context, _ *pb.ListDeletedEventsRequest,
+) (*pb.ListEventsResponse, error) {
+	events, err := s.App.ListDeletedEvents(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventsToProto(events), nil
+}
+
+func (s *Service) InviteAttendees(ctx context.Context, req *pb.InviteAttendeesRequest) (*emptypb.Empty, error) {
+	if req.GetEventId() == "" || len(req.GetUserIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "event id and user ids are required")
+	}
+	if err := s.App.InviteAttendees(ctx, req.GetEventId(), req.GetUserIds()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Service) RespondToInvitation(
+	ctx context.Context, req *pb.RespondToInvitationRequest,
+) (*emptypb.Empty, error) {
+	if req.GetEventId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	err := s.App.RespondToInvitation(ctx, req.GetEventId(), storage.AttendeeStatus(req.GetStatus()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Service) ListDayEvents(ctx context.Context, req *pb.ListPeriodEventsRequest) (*pb.ListEventsResponse, error) {
+	return s.listPeriod(ctx, req, s.App.ListDayEvents)
+}
+
+func (s *Service) ListWeekEvents(ctx context.Context, req *pb.ListPeriodEventsRequest) (*pb.ListEventsResponse, error) {
+	return s.listPeriod(ctx, req, s.App.ListWeekEvents)
+}
+
+func (s *Service) ListMonthEvents(
+	ctx context.Context, req *pb.ListPeriodEventsRequest,
+) (*pb.ListEventsResponse, error) {
+	return s.listPeriod(ctx, req, s.App.ListMonthEvents)
+}
+
+func (s *Service) ApplyBatch(ctx context.Context, req *pb.ApplyBatchRequest) (*pb.ListEventsResponse, error) {
+	events, err := s.App.ApplyBatch(ctx, BatchOperationsFromProto(req.GetOperations()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventsToProto(events), nil
+}
+
+func (s *Service) FreeSlots(ctx context.Context, req *pb.FreeSlotsRequest) (*pb.FreeSlotsResponse, error) {
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, status.Error(codes.InvalidArgument, "from and to are required")
+	}
+	slots, err := s.App.FreeSlots(ctx, req.GetUserIds(), req.GetFrom().AsTime(), req.GetTo().AsTime(),
+		req.GetMinDuration().AsDuration())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return slotsToProto(slots), nil
+}
+
+func (s *Service) ListTags(ctx context.Context, _ *pb.ListTagsRequest) (*pb.ListTagsResponse, error) {
+	tags, err := s.App.ListTags(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return tagsToProto(tags), nil
+}
+
+func (s *Service) UpdateTag(ctx context.Context, req *pb.UpdateTagRequest) (*pb.Tag, error) {
+	if req.GetTag() == nil {
+		return nil, status.Error(codes.InvalidArgument, "tag is required")
+	}
+	tag := &storage.Tag{Name: req.GetTag().GetName(), Color: req.GetTag().GetColor()}
+	if err := s.App.UpdateTag(ctx, tag); err != nil {
+		return nil, toStatus(err)
+	}
+	return tagToProto(tag), nil
+}
+
+func (s *Service) CreateResource(ctx context.Context, req *pb.CreateResourceRequest) (*pb.Resource, error) {
+	if req.GetResource() == nil {
+		return nil, status.Error(codes.InvalidArgument, "resource is required")
+	}
+	resource := ResourceFromProto(req.GetResource())
+	if err := s.App.CreateResource(ctx, resource); err != nil {
+		return nil, toStatus(err)
+	}
+	return ResourceToProto(resource), nil
+}
+
+func (s *Service) UpdateResource(ctx context.Context, req *pb.UpdateResourceRequest) (*pb.Resource, error) {
+	if req.GetResource().GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "resource id is required")
+	}
+	resource := ResourceFromProto(req.GetResource())
+	if err := s.App.UpdateResource(ctx, resource); err != nil {
+		return nil, toStatus(err)
+	}
+	return ResourceToProto(resource), nil
+}
+
+func (s *Service) ListResources(ctx context.Context, _ *pb.ListResourcesRequest) (*pb.ListResourcesResponse, error) {
+	resources, err := s.App.ListResources(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return ResourcesToProto(resources), nil
+}
+
+func (s *Service) ListResourceEvents(
+	ctx context.Context, req *pb.ListResourceEventsRequest,
+) (*pb.ListEventsResponse, error) {
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, status.Error(codes.InvalidArgument, "from and to are required")
+	}
+	events, err := s.App.ListResourceEvents(ctx, req.GetId(), req.GetFrom().AsTime(), req.GetTo().AsTime())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventsToProto(events), nil
+}
+
+func (s *Service) ListAvailableResources(
+	ctx context.Context, req *pb.ListAvailableResourcesRequest,
+) (*pb.ListResourcesResponse, error) {
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, status.Error(codes.InvalidArgument, "from and to are required")
+	}
+	resources, err := s.App.ListAvailableResources(ctx, req.GetFrom().AsTime(), req.GetTo().AsTime(),
+		storage.ResourceKind(req.GetKind()), int(req.GetMinCapacity()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return ResourcesToProto(resources), nil
+}
+
+func (s *Service) ShareCalendar(ctx context.Context, req *pb.ShareCalendarRequest) (*pb.Share, error) {
+	share, err := s.App.ShareCalendar(ctx, req.GetUserId(), storage.ShareAccess(req.GetAccess()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return shareToProto(share), nil
+}
+
+func (s *Service) RevokeShare(ctx context.Context, req *pb.RevokeShareRequest) (*emptypb.Empty, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if err := s.App.RevokeShare(ctx, req.GetUserId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Service) ListShares(ctx context.Context, _ *pb.ListSharesRequest) (*pb.ListSharesResponse, error) {
+	shares, err := s.App.ListShares(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return sharesToProto(shares), nil
+}
+
+func (s *Service) ListSharedCalendars(ctx context.Context, _ *pb.ListSharesRequest) (*pb.ListSharesResponse, error) {
+	shares, err := s.App.ListSharedCalendars(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return sharesToProto(shares), nil
+}
+
+func (s *Service) RegisterWebhook(ctx context.Context, req *pb.RegisterWebhookRequest) (*pb.Webhook, error) {
+	if req.GetWebhook() == nil {
+		return nil, status.Error(codes.InvalidArgument, "webhook is required")
+	}
+	webhook := &storage.Webhook{URL: req.GetWebhook().GetUrl(), Secret: req.GetWebhook().GetSecret()}
+	if err := s.App.RegisterWebhook(ctx, webhook); err != nil {
+		return nil, toStatus(err)
+	}
+	return webhookToProto(webhook), nil
+}
+
+func (s *Service) ListWebhooks(ctx context.Context, _ *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := s.App.ListWebhooks(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return webhooksToProto(webhooks), nil
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "webhook id is required")
+	}
+	if err := s.App.DeleteWebhook(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Service) ListWebhookDeliveries(
+	ctx context.Context, req *pb.ListWebhookDeliveriesRequest,
+) (*pb.ListWebhookDeliveriesResponse, error) {
+	if req.GetWebhookId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "webhook id is required")
+	}
+	deliveries, err := s.App.ListWebhookDeliveries(ctx, req.GetWebhookId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return deliveriesToProto(deliveries), nil
+}
+
+func (s *Service) GetProfile(ctx context.Context, _ *pb.GetProfileRequest) (*pb.UserProfile, error) {
+	profile, err := s.App.GetProfile(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return profileToProto(profile), nil
+}
+
+func (s *Service) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.UserProfile, error) {
+	if req.GetProfile() == nil {
+		return nil, status.Error(codes.InvalidArgument, "profile is required")
+	}
+	profile := profileFromProto(req.GetProfile())
+	if err := s.App.UpdateProfile(ctx, profile); err != nil {
+		return nil, toStatus(err)
+	}
+	return profileToProto(profile), nil
+}
+
+func (s *Service) AddLink(ctx context.Context, req *pb.AddLinkRequest) (*pb.Attachment, error) {
+	if req.GetEventId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	attachment, err := s.App.AddLink(ctx, req.GetEventId(), req.GetName(), req.GetUrl())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return attachmentToProto(attachment), nil
+}
+
+func (s *Service) ListAttachments(
+	ctx context.Context, req *pb.ListAttachmentsRequest,
+) (*pb.ListAttachmentsResponse, error) {
+	if req.GetEventId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event id is required")
+	}
+	attachments, err := s.App.ListAttachments(ctx, req.GetEventId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return attachmentsToProto(attachments), nil
+}
+
+func (s *Service) DeleteAttachment(ctx context.Context, req *pb.DeleteAttachmentRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "attachment id is required")
+	}
+	if err := s.App.DeleteAttachment(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// callerLocation returns the zone of the caller, UTC by default.
+func callerLocation(ctx context.Context, zone string) (*time.Location, error) {
+	if zone == "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		zone = metadataValue(md, TimeZoneKey)
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, apperrors.ErrInvalidTimeZone{Name: zone}
+	}
+	return loc, nil
+}
+
+func (s *Service) listPeriod(
+	ctx context.Context,
+	req *pb.ListPeriodEventsRequest,
+	list func(ctx context.Context, start time.Time, tags ...string) ([]*storage.Event, error),
+) (*pb.ListEventsResponse, error) {
+	loc, err := callerLocation(ctx, req.GetTimeZone())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	date, err := time.ParseInLocation("2006-01-02", req.GetDate(), loc)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid date: "+err.Error())
+	}
+	events, err := list(ctx, date, req.GetTags()...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return EventsToProto(events), nil
+}
+
+// toStatus converts error of the app to status, ctx errors keep their codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(errorCode(err), err.Error())
+}
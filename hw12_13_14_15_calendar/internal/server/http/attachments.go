@@ -0,0 +1,57 @@
+package internalhttp
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// UploadAttachment attaches the body of the request to the event as a file. The body is the
+// content itself, not a multipart form.
+func (h EventHandlers) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	eventID, name := r.URL.Query().Get("event_id"), r.URL.Query().Get("name")
+	if eventID == "" {
+		h.writeBadRequest(w, "event id is required")
+		return
+	}
+	attachment, err := h.App.UploadAttachment(r.Context(), eventID, name, r.Body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, attachment)
+}
+
+// DownloadAttachment sends content of the file as an attachment of the response, so browsers
+// save it instead of rendering content uploaded by other users.
+func (h EventHandlers) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		h.writeBadRequest(w, "attachment id is required")
+		return
+	}
+	attachment, content, err := h.App.OpenAttachment(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	defer func() {
+		if closeErr := content.Close(); closeErr != nil {
+			h.Logg.Error().Err(closeErr).Msgf("Failed to close content of attachment %s", id)
+		}
+	}()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})
+	if disposition == "" {
+		// the name cannot be encoded, clients choose a name themselves
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(w, content); err != nil {
+		h.Logg.Error().Err(err).Msgf("Failed to send content of attachment %s", id)
+	}
+}
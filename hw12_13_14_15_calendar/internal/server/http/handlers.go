@@ -1,6 +1,15 @@
 package internalhttp
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+)
 
 type HelloHandler struct{}
 
@@ -12,3 +21,90 @@ func (h HelloHandler) ServeHTTP(writer http.ResponseWriter, r *http.Request) {
 	}
 	writer.WriteHeader(http.StatusOK)
 }
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// EventHandlers serves the events API on top of the Application.
+type EventHandlers struct {
+	Logg app.Logger
+	App  Application
+
+	// Heartbeat is the interval of comments sent to keep idle streams open.
+	Heartbeat time.Duration
+	// MaxStreamDuration ends streams before the write timeout of the server,
+	// clients reconnect and resume. Zero means no limit.
+	MaxStreamDuration time.Duration
+	// StreamsDone is closed when the server shuts down.
+	StreamsDone <-chan struct{}
+}
+
+func (h EventHandlers) writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Logg.Error().Err(err).Msg("Failed to write response")
+	}
+}
+
+func (h EventHandlers) writeError(w http.ResponseWriter, err error) {
+	h.writeJSON(w, errorStatus(err), errorResponse{Error: err.Error()})
+}
+
+func errorStatus(err error) int {
+	var (
+		notFoundEventErr    errs.ErrNotFoundEvent
+		notFoundAttendeeErr errs.ErrNotFoundAttendee
+		notFoundWebhookErr  errs.ErrNotFoundWebhook
+		notFoundProfileErr  errs.ErrNotFoundUserProfile
+		notFoundAttachErr   errs.ErrNotFoundAttachment
+		notFoundBlobErr     errs.ErrNotFoundBlob
+		notFoundResourceErr errs.ErrNotFoundResource
+		resourceBusyErr     errs.ErrResourceBusy
+		notFoundShareErr    errs.ErrNotFoundShare
+		accessDeniedErr     apperrors.ErrAccessDenied
+		userRequiredErr     apperrors.ErrUserRequired
+		invalidStatusErr    apperrors.ErrInvalidAttendeeStatus
+		invalidFreeBusyErr  apperrors.ErrInvalidFreeBusyQuery
+		invalidTimeZoneErr  apperrors.ErrInvalidTimeZone
+		invalidBatchErr     apperrors.ErrInvalidBatchOperation
+		invalidWebhookErr   apperrors.ErrInvalidWebhook
+		invalidProfileErr   apperrors.ErrInvalidProfile
+		invalidEventErr     apperrors.ErrInvalidEvent
+		invalidTagErr       apperrors.ErrInvalidTag
+		invalidAttachErr    apperrors.ErrInvalidAttachment
+		tooLargeErr         apperrors.ErrAttachmentTooLarge
+		invalidResourceErr  apperrors.ErrInvalidResource
+		invalidShareErr     apperrors.ErrInvalidShare
+		invalidQuickAddErr  apperrors.ErrInvalidQuickAdd
+		invalidBookingErr   errs.ErrInvalidBookingPeriod
+	)
+	switch {
+	case errors.As(err, &notFoundEventErr), errors.As(err, &notFoundAttendeeErr),
+		errors.As(err, &notFoundWebhookErr), errors.As(err, &notFoundProfileErr),
+		errors.As(err, &notFoundAttachErr), errors.As(err, &notFoundBlobErr),
+		errors.As(err, &notFoundResourceErr), errors.As(err, &notFoundShareErr):
+		return http.StatusNotFound
+	case errors.As(err, &accessDeniedErr):
+		return http.StatusForbidden
+	case errors.As(err, &resourceBusyErr):
+		return http.StatusConflict
+	case errors.As(err, &tooLargeErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &userRequiredErr), errors.As(err, &invalidStatusErr),
+		errors.As(err, &invalidFreeBusyErr), errors.As(err, &invalidTimeZoneErr),
+		errors.As(err, &invalidBatchErr), errors.As(err, &invalidWebhookErr),
+		errors.As(err, &invalidProfileErr), errors.As(err, &invalidEventErr),
+		errors.As(err, &invalidTagErr), errors.As(err, &invalidAttachErr),
+		errors.As(err, &invalidResourceErr), errors.As(err, &invalidShareErr),
+		errors.As(err, &invalidQuickAddErr), errors.As(err, &invalidBookingErr):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h EventHandlers) writeBadRequest(w http.ResponseWriter, message string) {
+	h.writeJSON(w, http.StatusBadRequest, errorResponse{Error: message})
+}
@@ -0,0 +1,131 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	apperrors "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/app_errors"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	server_mocks "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/http/mocks"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentHandlers(t *testing.T) {
+	t.Run("upload streams the body", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		a := server_mocks.NewMockApplication(mc)
+		a.EXPECT().UploadAttachment(gomock.Any(), "event", "agenda.txt", gomock.Any()).DoAndReturn(
+			func(ctx context.Context, eventID, name string, content io.Reader) (*storage.Attachment, error) {
+				require.Equal(t, "alice", app.UserIDFromContext(ctx))
+				data, err := ioutil.ReadAll(content)
+				require.NoError(t, err)
+				require.Equal(t, "1. budget", string(data))
+				return &storage.Attachment{ID: "agenda", EventID: eventID, Name: name, Size: int64(len(data))}, nil
+			})
+		server := NewServer(logger.New("error"), a, "", "", 0, 0, 0)
+
+		request := httptest.NewRequest(http.MethodPost,
+			"/attachments/upload?event_id=event&name=agenda.txt", strings.NewReader("1. budget"))
+		request.Header.Set(UserIDHeader, "alice")
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusCreated, recorder.Code)
+		var got storage.Attachment
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&got))
+		require.Equal(t, "agenda", got.ID)
+	})
+
+	t.Run("too large upload", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		a := server_mocks.NewMockApplication(mc)
+		a.EXPECT().UploadAttachment(gomock.Any(), "event", "large.bin", gomock.Any()).
+			Return(nil, apperrors.ErrAttachmentTooLarge{MaxSize: 1024})
+		server := NewServer(logger.New("error"), a, "", "", 0, 0, 0)
+
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost,
+			"/attachments/upload?event_id=event&name=large.bin", strings.NewReader("data")))
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+
+	t.Run("download is not rendered by browsers", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		a := server_mocks.NewMockApplication(mc)
+		attachment := &storage.Attachment{
+			ID: "page", Name: "page.html", ContentType: "text/html; charset=utf-8", Size: 6,
+		}
+		a.EXPECT().OpenAttachment(gomock.Any(), "page").
+			Return(attachment, ioutil.NopCloser(strings.NewReader("<html>")), nil)
+		server := NewServer(logger.New("error"), a, "", "", 0, 0, 0)
+
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/attachments/download?id=page", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "<html>", recorder.Body.String())
+		require.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+		require.Equal(t, "6", recorder.Header().Get("Content-Length"))
+		require.Equal(t, `attachment; filename=page.html`, recorder.Header().Get("Content-Disposition"))
+		require.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+	})
+
+	t.Run("missing attachment", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		a := server_mocks.NewMockApplication(mc)
+		a.EXPECT().OpenAttachment(gomock.Any(), "missing").Return(nil, nil, errs.ErrNotFoundAttachment{ID: "missing"})
+		server := NewServer(logger.New("error"), a, "", "", 0, 0, 0)
+
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/attachments/download?id=missing", nil))
+
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		server := NewServer(logger.New("error"), server_mocks.NewMockApplication(mc), "", "", 0, 0, 0)
+
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/attachments/upload?event_id=event&name=agenda.txt", nil))
+
+		require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+}
+
+func TestConsistencyHeader(t *testing.T) {
+	for _, value := range []string{"", ReadYourWritesValue} {
+		value := value
+		t.Run("header "+value, func(t *testing.T) {
+			mc := gomock.NewController(t)
+			a := server_mocks.NewMockApplication(mc)
+			a.EXPECT().OpenAttachment(gomock.Any(), "agenda").DoAndReturn(
+				func(ctx context.Context, id string) (*storage.Attachment, io.ReadCloser, error) {
+					require.Equal(t, value != "", app.ReadYourWritesFromContext(ctx))
+					return &storage.Attachment{ID: id}, ioutil.NopCloser(strings.NewReader("")), nil
+				})
+			server := NewServer(logger.New("error"), a, "", "", 0, 0, 0)
+
+			request := httptest.NewRequest(http.MethodGet, "/attachments/download?id=agenda", nil)
+			request.Header.Set(ConsistencyHeader, value)
+			recorder := httptest.NewRecorder()
+			server.Server.(*http.Server).Handler.ServeHTTP(recorder, request)
+
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package internalhttp
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
@@ -23,6 +28,13 @@ func (r *ResponseWriterWithStatus) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+// Flush lets streaming handlers flush through the wrapper.
+func (r *ResponseWriterWithStatus) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func loggingMiddleware(logger app.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -40,3 +52,177 @@ func loggingMiddleware(logger app.Logger, next http.Handler) http.Handler {
 		)
 	})
 }
+
+// UserIDHeader carries the ID of the user who makes the request,
+// authorization is out of scope of the service.
+const UserIDHeader = "X-User-ID"
+
+func userIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get(UserIDHeader); userID != "" {
+			r = r.WithContext(app.ContextWithUserID(r.Context(), userID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CalendarIDHeader selects the calendar of another user to work with, e.g. an assistant managing
+// the calendar of a manager. The calendar has to be shared with the user who makes the request.
+const CalendarIDHeader = "X-Calendar-ID"
+
+func calendarIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calendarID := r.Header.Get(CalendarIDHeader); calendarID != "" {
+			r = r.WithContext(app.ContextWithCalendarID(r.Context(), calendarID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ConsistencyHeader set to read-your-writes makes reads see all preceding writes,
+// e.g. right after creating an event. Otherwise reads may be served by a lagging replica.
+const (
+	ConsistencyHeader   = "X-Consistency"
+	ReadYourWritesValue = "read-your-writes"
+)
+
+func consistencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(ConsistencyHeader) == ReadYourWritesValue {
+			r = r.WithContext(app.ContextWithReadYourWrites(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func methodMiddleware(method string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InvalidRequestWriter answers the request rejected by validation against the spec with the status,
+// 400 or 413 for too large bodies. Handlers with their own format of errors, e.g. the gRPC gateway,
+// provide it.
+type InvalidRequestWriter func(w http.ResponseWriter, code int, err error)
+
+// errorResponseWriter answers invalid requests with errorResponse as handlers of the API do.
+func errorResponseWriter(logger app.Logger) InvalidRequestWriter {
+	return func(w http.ResponseWriter, code int, err error) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(errorResponse{Error: "invalid request: " + err.Error()}); err != nil {
+			logger.Error().Err(err).Msg("Failed to write response")
+		}
+	}
+}
+
+// validationMiddleware rejects requests which do not match the operation of the spec. Responses
+// are checked too, a mismatch is only logged since the response is sent already.
+func validationMiddleware(
+	logger app.Logger, spec *Spec, operation *Operation, invalid InvalidRequestWriter, next http.Handler,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body *limitedBody
+		if operation.RequestBody != nil && operation.RequestBody.Content[jsonContentType] != nil && r.Body != nil {
+			body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, maxJSONBodySize), limit: maxJSONBodySize}
+			r.Body = body
+		}
+		if err := spec.ValidateRequest(operation, r); err != nil {
+			if body != nil && body.exceeded() {
+				err = fmt.Errorf("body is larger than %d bytes", maxJSONBodySize)
+				invalid(w, http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			invalid(w, http.StatusBadRequest, err)
+			return
+		}
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		err := spec.ValidateResponse(operation, recorder.status(), w.Header().Get("Content-Type"), recorder.body.Bytes())
+		if err != nil {
+			logger.Error().Err(err).Msgf("Response of %s %s does not match the OpenAPI document", r.Method, r.URL.Path)
+		}
+	})
+}
+
+// limitedBody tells the error of http.MaxBytesReader from other errors of the body, the reader
+// returns limit bytes and then fails if the body is larger.
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+	err   error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *limitedBody) exceeded() bool {
+	return b.err != nil && b.read >= b.limit
+}
+
+// documentedMiddleware validates requests of paths matching templates of the spec as
+// validationMiddleware does, requests of other paths are passed as is.
+func documentedMiddleware(logger app.Logger, spec *Spec, invalid InvalidRequestWriter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operation := spec.Match(r.URL.Path, r.Method)
+		if operation == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		validationMiddleware(logger, spec, operation, invalid, next).ServeHTTP(w, r)
+	})
+}
+
+// responseRecorder keeps the status and a copy of JSON bodies, other bodies, e.g. streams, are
+// passed through only.
+type responseRecorder struct {
+	http.ResponseWriter
+	code    int
+	capture bool
+	body    bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.code != 0 {
+		return
+	}
+	r.code = code
+	r.capture = strings.HasPrefix(r.Header().Get("Content-Type"), jsonContentType)
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.code == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.capture {
+		r.body.Write(data)
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *responseRecorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
@@ -6,9 +6,12 @@ package servermocks
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	app "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	storage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
 	zerolog "github.com/rs/zerolog"
 )
 
@@ -179,16 +182,48 @@ func (m *MockApplication) EXPECT() *MockApplicationMockRecorder {
 	return m.recorder
 }
 
-// CreateEvent mocks base method.
-func (m *MockApplication) CreateEvent(ctx context.Context, id, title string) error {
+// OpenAttachment mocks base method.
+func (m *MockApplication) OpenAttachment(ctx context.Context, id string) (*storage.Attachment, io.ReadCloser, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEvent", ctx, id, title)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "OpenAttachment", ctx, id)
+	ret0, _ := ret[0].(*storage.Attachment)
+	ret1, _ := ret[1].(io.ReadCloser)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// OpenAttachment indicates an expected call of OpenAttachment.
+func (mr *MockApplicationMockRecorder) OpenAttachment(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenAttachment", reflect.TypeOf((*MockApplication)(nil).OpenAttachment), ctx, id)
+}
+
+// SubscribeChanges mocks base method.
+func (m *MockApplication) SubscribeChanges(ctx context.Context, lastID string) (*app.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeChanges", ctx, lastID)
+	ret0, _ := ret[0].(*app.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeChanges indicates an expected call of SubscribeChanges.
+func (mr *MockApplicationMockRecorder) SubscribeChanges(ctx, lastID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeChanges", reflect.TypeOf((*MockApplication)(nil).SubscribeChanges), ctx, lastID)
+}
+
+// UploadAttachment mocks base method.
+func (m *MockApplication) UploadAttachment(ctx context.Context, eventID, name string, content io.Reader) (*storage.Attachment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadAttachment", ctx, eventID, name, content)
+	ret0, _ := ret[0].(*storage.Attachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CreateEvent indicates an expected call of CreateEvent.
-func (mr *MockApplicationMockRecorder) CreateEvent(ctx, id, title interface{}) *gomock.Call {
+// UploadAttachment indicates an expected call of UploadAttachment.
+func (mr *MockApplicationMockRecorder) UploadAttachment(ctx, eventID, name, content interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockApplication)(nil).CreateEvent), ctx, id, title)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadAttachment", reflect.TypeOf((*MockApplication)(nil).UploadAttachment), ctx, eventID, name, content)
 }
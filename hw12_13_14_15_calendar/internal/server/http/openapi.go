@@ -0,0 +1,458 @@
+package internalhttp
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// specJSON is the OpenAPI document of the API, it is served on /openapi.json and
+// requests and responses are validated against it.
+//
+//go:embed openapi.json
+var specJSON []byte
+
+// Spec is the subset of OpenAPI 3 the validator understands: parameters in query and headers,
+// JSON bodies and schemas with $ref, allOf, type, format, enum, nullable, properties,
+// required and items. Path parameters are left to handlers binding them. Other bodies are only
+// checked to be present, responses of any type are described by */*.
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Parameters map[string]*Parameter `json:"parameters"`
+		Schemas    map[string]*Schema    `json:"schemas"`
+	} `json:"components"`
+
+	// templates are paths with variables in the order Match tries them
+	templates []string
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref      string      `json:"$ref"`
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *Schema     `json:"schema"`
+	Example  interface{} `json:"example"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Content map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	AllOf      []*Schema          `json:"allOf"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Enum       []interface{}      `json:"enum"`
+	Nullable   bool               `json:"nullable"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+	Example    interface{}        `json:"example"`
+}
+
+const (
+	jsonContentType = "application/json"
+	schemaRefPrefix = "#/components/schemas/"
+	paramRefPrefix  = "#/components/parameters/"
+	// maxJSONBodySize limits JSON bodies read by validation, larger ones are rejected with 413.
+	maxJSONBodySize = 1 << 20
+)
+
+// LoadSpec parses the embedded OpenAPI document.
+func LoadSpec() (*Spec, error) {
+	return parseSpec(specJSON)
+}
+
+func parseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	for path := range spec.Paths {
+		if strings.Contains(path, "{") {
+			spec.templates = append(spec.templates, path)
+		}
+	}
+	// literal segments go before variables, so /v1/resources/available wins over /v1/resources/{id}
+	sort.Slice(spec.templates, func(i, j int) bool {
+		return templateLess(strings.Split(spec.templates[i], "/"), strings.Split(spec.templates[j], "/"))
+	})
+	return &spec, nil
+}
+
+func templateLess(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if isVariable(a[i]) != isVariable(b[i]) {
+			return isVariable(b[i])
+		}
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func isVariable(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// Operation returns the operation of the path and the method, nil if the spec has none.
+func (s *Spec) Operation(path, method string) *Operation {
+	return s.Paths[path][strings.ToLower(method)]
+}
+
+// Match returns the operation of the method and the path template the path matches, e.g.
+// /v1/events/{id} for /v1/events/42, nil if the spec has none. Exact paths go first, then
+// templates with literal segments where others have variables.
+func (s *Spec) Match(path, method string) *Operation {
+	if operation := s.Operation(path, method); operation != nil {
+		return operation
+	}
+	segments := strings.Split(path, "/")
+	for _, template := range s.templates {
+		operation, ok := s.Paths[template][strings.ToLower(method)]
+		if ok && matchTemplate(strings.Split(template, "/"), segments) {
+			return operation
+		}
+	}
+	return nil
+}
+
+// matchTemplate reports whether segments of the path match segments of the template,
+// a {variable} segment matches any non-empty one.
+func matchTemplate(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, segment := range template {
+		if isVariable(segment) {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Spec) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	resolved, ok := s.Components.Parameters[strings.TrimPrefix(p.Ref, paramRefPrefix)]
+	if !ok {
+		return nil, fmt.Errorf("unknown parameter %s", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (s *Spec) schema(schema *Schema) (*Schema, error) {
+	for schema.Ref != "" {
+		resolved, ok := s.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema %s", schema.Ref)
+		}
+		schema = resolved
+	}
+	return schema, nil
+}
+
+// ValidateRequest checks parameters and the body of the request, the body is left readable.
+// Only JSON bodies are read, other ones, e.g. uploaded files, are streamed to the handler.
+func (s *Spec) ValidateRequest(operation *Operation, r *http.Request) error {
+	for _, p := range operation.Parameters {
+		p, err := s.parameter(p)
+		if err != nil {
+			return err
+		}
+		var values []string
+		switch p.In {
+		case "query":
+			values = r.URL.Query()[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		default:
+			continue
+		}
+		if err := s.validateParameter(p, values); err != nil {
+			return err
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return nil
+	}
+	media, ok := operation.RequestBody.Content[jsonContentType]
+	if !ok {
+		if operation.RequestBody.Required && (r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0) {
+			return fmt.Errorf("body is required")
+		}
+		return nil
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if operation.RequestBody.Required {
+			return fmt.Errorf("body is required")
+		}
+		return nil
+	}
+	if media.Schema == nil {
+		return nil
+	}
+	return s.validateJSON(media.Schema, body, "body")
+}
+
+func (s *Spec) validateParameter(p *Parameter, values []string) error {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+		if p.Required {
+			return fmt.Errorf("%s parameter %s is required", p.In, p.Name)
+		}
+		return nil
+	}
+	if p.Schema == nil {
+		return nil
+	}
+	schema, err := s.schema(p.Schema)
+	if err != nil {
+		return err
+	}
+	if schema.Type == "array" && schema.Items != nil {
+		for _, value := range values {
+			if err := s.validateParameterValue(schema.Items, value, p.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return s.validateParameterValue(schema, values[0], p.Name)
+}
+
+// validateParameterValue converts the raw value to the type of the schema before validating it.
+func (s *Spec) validateParameterValue(schema *Schema, raw, path string) error {
+	schema, err := s.schema(schema)
+	if err != nil {
+		return err
+	}
+	var value interface{} = raw
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return fmt.Errorf("%s must be a number", path)
+		}
+		value = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+		value = b
+	}
+	return s.validate(schema, value, path)
+}
+
+// ValidateResponse checks that the status is documented and the JSON body matches its schema.
+func (s *Spec) ValidateResponse(operation *Operation, status int, contentType string, body []byte) error {
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = operation.Responses["default"]; !ok {
+			return fmt.Errorf("status %d is not documented", status)
+		}
+	}
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d has no body in the spec", status)
+		}
+		return nil
+	}
+	media, ok := response.Content[mediaType]
+	if !ok {
+		if media, ok = response.Content["*/*"]; !ok {
+			return fmt.Errorf("content type %q of status %d is not documented", mediaType, status)
+		}
+	}
+	if mediaType != jsonContentType || media.Schema == nil {
+		return nil
+	}
+	return s.validateJSON(media.Schema, body, "response")
+}
+
+func (s *Spec) validateJSON(schema *Schema, data []byte, path string) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s is not valid JSON: %w", path, err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s has data after the JSON value", path)
+	}
+	return s.validate(schema, value, path)
+}
+
+func (s *Spec) validate(schema *Schema, value interface{}, path string) error {
+	schema, err := s.schema(schema)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+	for _, sub := range schema.AllOf {
+		if err := s.validate(sub, value, path); err != nil {
+			return err
+		}
+	}
+	if err := s.validateType(schema, value, path); err != nil {
+		return err
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fmt.Errorf("%s must be one of %v", path, schema.Enum)
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		// the first error is reported, the order keeps it stable
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := value[name]; ok {
+				if err := s.validate(schema.Properties[name], property, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if schema.Items == nil {
+			return nil
+		}
+		for i, item := range value {
+			if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Spec) validateType(schema *Schema, value interface{}, path string) error {
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+	case "array":
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+		if _, err := number.Int64(); err != nil {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+		return validateFormat(schema.Format, str, path)
+	default:
+		return fmt.Errorf("unsupported type %s of %s", schema.Type, path)
+	}
+	return nil
+}
+
+func validateFormat(format, value, path string) error {
+	var layout string
+	switch format {
+	case "date-time":
+		layout = time.RFC3339Nano
+	case "date":
+		layout = "2006-01-02"
+	default:
+		return nil
+	}
+	if _, err := time.Parse(layout, value); err != nil {
+		return fmt.Errorf("%s must be a %s: %w", path, format, err)
+	}
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenAPIHandler serves the OpenAPI document.
+type OpenAPIHandler struct{}
+
+func (OpenAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", jsonContentType)
+	_, _ = w.Write(specJSON)
+}
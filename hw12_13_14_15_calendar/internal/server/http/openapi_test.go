@@ -0,0 +1,472 @@
+package internalhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
+	grpcmocks "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/mocks"
+	server_mocks "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/http/mocks"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeApplication returns complete objects from every method served by the gateway,
+// so responses contain all fields.
+func fakeApplication(mc *gomock.Controller) *grpcmocks.MockApplication {
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+	deletedAt := start.Add(-time.Hour)
+	event := func() *storage.Event {
+		return &storage.Event{
+			ID: "event", Title: "planning", Description: "weekly", StartAt: start, EndAt: start.Add(time.Hour),
+			TimeZone: "UTC", AllDay: false, NotifyBefore: 15 * time.Minute, UserID: "alice",
+			Attendees: []storage.Attendee{{UserID: "bob", Status: storage.AttendeeStatusAccepted}},
+			Tags:      []string{"oncall"}, Resources: []string{"everest"}, DeletedAt: &deletedAt,
+		}
+	}
+	events := []*storage.Event{event()}
+	attachment := &storage.Attachment{
+		ID: "attachment", EventID: "event", UserID: "alice", Name: "agenda.txt",
+		ContentType: "text/plain; charset=utf-8", Size: 9, CreatedAt: start,
+	}
+	link := &storage.Attachment{
+		ID: "link", EventID: "event", UserID: "alice", Name: "notes", URL: "https://example.com/notes",
+		CreatedAt: start,
+	}
+	webhook := &storage.Webhook{ID: "hook", UserID: "alice", URL: "https://example.com/hook", CreatedAt: start}
+	profile := &storage.UserProfile{
+		UserID: "alice", Email: "alice@example.com", UpdatedAt: start,
+		Channels: []storage.NotificationChannel{storage.NotificationChannelEmail},
+	}
+	resources := []*storage.Resource{{ID: "everest", Name: "Everest", Kind: storage.ResourceKindRoom, Capacity: 10}}
+	share := &storage.Share{OwnerID: "alice", UserID: "bob", Access: storage.ShareAccessRead, CreatedAt: start}
+
+	a := grpcmocks.NewMockApplication(mc)
+	a.EXPECT().CreateEvent(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().QuickAddEvent(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(event(), nil)
+	a.EXPECT().UpdateEvent(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().DeleteEvent(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().GetEvent(gomock.Any(), gomock.Any()).AnyTimes().Return(event(), nil)
+	a.EXPECT().ListEvents(gomock.Any(), gomock.Any()).AnyTimes().Return(events, nil)
+	a.EXPECT().GetEventHistory(gomock.Any(), gomock.Any()).AnyTimes().Return([]*storage.AuditRecord{{
+		ID: "1", EventID: "event", Actor: "alice", Action: storage.AuditActionUpdate, CreatedAt: start,
+		Before: event(), After: event(),
+	}}, nil)
+	a.EXPECT().RestoreEvent(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListDeletedEvents(gomock.Any()).AnyTimes().Return(events, nil)
+	a.EXPECT().InviteAttendees(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().RespondToInvitation(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListDayEvents(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(events, nil)
+	a.EXPECT().ListWeekEvents(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(events, nil)
+	a.EXPECT().ListMonthEvents(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(events, nil)
+	a.EXPECT().FreeSlots(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		Return([]app.TimeSlot{{Start: start, End: start.Add(time.Hour)}}, nil)
+	a.EXPECT().ApplyBatch(gomock.Any(), gomock.Any()).AnyTimes().Return([]*storage.Event{event(), nil}, nil)
+	a.EXPECT().RegisterWebhook(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListWebhooks(gomock.Any()).AnyTimes().Return([]*storage.Webhook{webhook}, nil)
+	a.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).AnyTimes().Return([]*storage.WebhookDelivery{{
+		ID: "1", WebhookID: "hook", EventID: "event", Type: "event.create", Payload: "{}",
+		Status: storage.DeliveryStatusDead, Attempts: 8, NextAttemptAt: start, LastError: "timeout",
+		ResponseCode: 502, CreatedAt: start, UpdatedAt: start,
+	}}, nil)
+	a.EXPECT().GetProfile(gomock.Any()).AnyTimes().Return(profile, nil)
+	a.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListTags(gomock.Any()).AnyTimes().
+		Return([]*storage.Tag{{UserID: "alice", Name: "oncall", Color: "#ff8800"}}, nil)
+	a.EXPECT().UpdateTag(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().AddLink(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(link, nil)
+	a.EXPECT().ListAttachments(gomock.Any(), gomock.Any()).AnyTimes().
+		Return([]*storage.Attachment{attachment, link}, nil)
+	a.EXPECT().DeleteAttachment(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().CreateResource(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().UpdateResource(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListResources(gomock.Any()).AnyTimes().Return(resources, nil)
+	a.EXPECT().ListResourceEvents(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(events, nil)
+	a.EXPECT().ListAvailableResources(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		Return(resources, nil)
+	a.EXPECT().ShareCalendar(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(share, nil)
+	a.EXPECT().RevokeShare(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	a.EXPECT().ListShares(gomock.Any()).AnyTimes().Return([]*storage.Share{share}, nil)
+	a.EXPECT().ListSharedCalendars(gomock.Any()).AnyTimes().Return([]*storage.Share{share}, nil)
+	return a
+}
+
+// fakeUploads returns complete attachments from uploads served by handlers of the server.
+func fakeUploads(mc *gomock.Controller) *server_mocks.MockApplication {
+	a := server_mocks.NewMockApplication(mc)
+	a.EXPECT().UploadAttachment(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().
+		Return(&storage.Attachment{
+			ID: "attachment", EventID: "event", UserID: "alice", Name: "agenda.txt",
+			ContentType: "text/plain; charset=utf-8", Size: 9,
+			CreatedAt: time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC),
+		}, nil)
+	return a
+}
+
+// exampleRequest builds the request of the operation from examples of its parameters and body,
+// variables of the path template are replaced by examples too.
+func exampleRequest(t *testing.T, path, method string, operation *Operation) *http.Request {
+	t.Helper()
+	query := url.Values{}
+	headers := http.Header{}
+	for _, p := range operation.Parameters {
+		p, err := spec.parameter(p)
+		require.NoError(t, err)
+		if p.Example == nil {
+			require.False(t, p.Required, "required parameter %s has no example", p.Name)
+			continue
+		}
+		values := []interface{}{p.Example}
+		if list, ok := p.Example.([]interface{}); ok {
+			values = list
+		}
+		for _, value := range values {
+			switch p.In {
+			case "query":
+				query.Add(p.Name, value.(string))
+			case "path":
+				path = strings.Replace(path, "{"+p.Name+"}", url.PathEscape(value.(string)), 1)
+			default:
+				headers.Add(p.Name, value.(string))
+			}
+		}
+	}
+
+	var body []byte
+	if operation.RequestBody != nil {
+		for contentType, media := range operation.RequestBody.Content {
+			schema, err := spec.schema(media.Schema)
+			require.NoError(t, err)
+			require.NotNil(t, schema.Example, "body has no example")
+			if contentType != jsonContentType {
+				body = []byte(schema.Example.(string))
+				headers.Set("Content-Type", contentType)
+				break
+			}
+			body, err = json.Marshal(schema.Example)
+			require.NoError(t, err)
+		}
+	}
+	request := httptest.NewRequest(method, path+"?"+query.Encode(), bytes.NewReader(body))
+	for name, values := range headers {
+		request.Header[name] = values
+	}
+	return request
+}
+
+// templateShape drops names of variables of the path template, e.g. /v1/events/{event.id}
+// and /v1/events/{id} are both /v1/events/{}.
+func templateShape(template string) string {
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if isVariable(segment) {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// newGatewayServer returns the server with the gRPC gateway mounted as main does.
+func newGatewayServer(t *testing.T, a *grpcmocks.MockApplication) *Server {
+	t.Helper()
+	server := NewServer(logger.New("error"), server_mocks.NewMockApplication(gomock.NewController(t)), "", "", 0, 0, 0)
+	gateway, err := internalgrpc.NewGateway(logger.New("error"), a)
+	require.NoError(t, err)
+	server.HandleDocumented("/v1/", gateway, gateway.WriteInvalidRequest)
+	return server
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	routes := EventHandlers{}.routes()
+	mc := gomock.NewController(t)
+	gateway, err := internalgrpc.NewGateway(logger.New("error"), grpcmocks.NewMockApplication(mc))
+	require.NoError(t, err)
+	gatewayRoutes := make(map[string]bool)
+	for _, route := range gateway.Routes() {
+		gatewayRoutes[strings.ToLower(route.Method)+" "+templateShape(route.Template)] = true
+	}
+
+	t.Run("every route is described", func(t *testing.T) {
+		registered := make(map[string]bool)
+		for _, route := range routes {
+			require.NotNil(t, spec.Operation(route.pattern, route.method),
+				"%s %s is not in the OpenAPI document", route.method, route.pattern)
+			registered[strings.ToLower(route.method)+" "+route.pattern] = true
+		}
+		for _, route := range gateway.Routes() {
+			require.NotNil(t, spec.Match(route.Template, route.Method),
+				"%s %s of the gateway is not in the OpenAPI document", route.Method, route.Template)
+		}
+		for path, operations := range spec.Paths {
+			for method := range operations {
+				require.True(t, registered[method+" "+path] || gatewayRoutes[method+" "+templateShape(path)],
+					"%s %s is not registered", method, path)
+			}
+		}
+	})
+
+	t.Run("responses match the document", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		server := NewServer(logger.New("error"), fakeUploads(mc), "", "", 0, 0, 0)
+		for _, route := range routes {
+			route := route
+			operation := spec.Operation(route.pattern, route.method)
+			if _, ok := operation.Responses["200"]; ok && operation.Responses["200"].Content[jsonContentType] == nil {
+				// streams are covered by their own tests
+				continue
+			}
+			t.Run(route.pattern, func(t *testing.T) {
+				recorder := httptest.NewRecorder()
+				server.Server.(*http.Server).Handler.ServeHTTP(recorder,
+					exampleRequest(t, route.pattern, route.method, operation))
+
+				require.Less(t, recorder.Code, 300, recorder.Body.String())
+				require.NoError(t, spec.ValidateResponse(operation, recorder.Code,
+					recorder.Header().Get("Content-Type"), recorder.Body.Bytes()))
+			})
+		}
+	})
+
+	t.Run("responses of the gateway match the document", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		server := newGatewayServer(t, fakeApplication(mc))
+		for path, operations := range spec.Paths {
+			if !strings.HasPrefix(path, "/v1/") {
+				continue
+			}
+			for method, operation := range operations {
+				path, method, operation := path, strings.ToUpper(method), operation
+				t.Run(method+" "+path, func(t *testing.T) {
+					recorder := httptest.NewRecorder()
+					server.Handler().ServeHTTP(recorder, exampleRequest(t, path, method, operation))
+
+					require.Less(t, recorder.Code, 300, recorder.Body.String())
+					require.NoError(t, spec.ValidateResponse(operation, recorder.Code,
+						recorder.Header().Get("Content-Type"), recorder.Body.Bytes()))
+				})
+			}
+		}
+	})
+
+	t.Run("document is served", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		server := NewServer(logger.New("error"), server_mocks.NewMockApplication(mc), "", "", 0, 0, 0)
+
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var document struct {
+			OpenAPI string                 `json:"openapi"`
+			Paths   map[string]interface{} `json:"paths"`
+		}
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&document))
+		require.Equal(t, "3.0.3", document.OpenAPI)
+		gatewayPaths := make(map[string]bool)
+		for _, route := range gateway.Routes() {
+			gatewayPaths[templateShape(route.Template)] = true
+		}
+		require.Len(t, document.Paths, len(routes)+len(gatewayPaths))
+	})
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	t.Run("requests of handlers are answered with error", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			method string
+			target string
+			reason string
+		}{
+			{"missing parameter", http.MethodGet, "/attachments/download", "query parameter id is required"},
+			{"missing one of parameters", http.MethodPost, "/attachments/upload?event_id=event",
+				"query parameter name is required"},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				mc := gomock.NewController(t)
+				server := NewServer(logger.New("error"), server_mocks.NewMockApplication(mc), "", "", 0, 0, 0)
+
+				recorder := httptest.NewRecorder()
+				server.Server.(*http.Server).Handler.ServeHTTP(recorder,
+					httptest.NewRequest(tc.method, tc.target, strings.NewReader("data")))
+
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				var response errorResponse
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				require.Contains(t, response.Error, tc.reason)
+			})
+		}
+	})
+
+	t.Run("requests of the gateway are answered with status", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			method string
+			target string
+			body   string
+			reason string
+		}{
+			{"invalid parameter", http.MethodGet, "/v1/resources/everest/events?from=today&to=2022-10-24T18:00:00Z", "",
+				"from must be a date-time"},
+			{"invalid repeated parameter", http.MethodGet,
+				"/v1/free-slots?user_ids=alice&from=2022-10-24T08:00:00Z&to=tomorrow", "", "to must be a date-time"},
+			{"missing body", http.MethodPost, "/v1/events", "", "body is required"},
+			{"invalid JSON", http.MethodPost, "/v1/events", "{", "body is not valid JSON"},
+			{"wrong type", http.MethodPut, "/v1/events/event", `{"title": 1}`, "body.title must be a string"},
+			{"nested field", http.MethodPost, "/v1/events",
+				`{"attendees": [{"user_id": "bob", "status": "maybe"}]}`, "body.attendees[0].status must be one of"},
+			{"missing field", http.MethodPost, "/v1/events/event/respond", `{}`, "body.status is required"},
+			{"null", http.MethodPost, "/v1/events/event/invite", `{"user_ids": null}`,
+				"body.user_ids must not be null"},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				mc := gomock.NewController(t)
+				server := newGatewayServer(t, grpcmocks.NewMockApplication(mc))
+
+				recorder := httptest.NewRecorder()
+				request := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+				server.Handler().ServeHTTP(recorder, request)
+
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				var response struct {
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+				}
+				require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+				require.Equal(t, 3, response.Code)
+				require.Contains(t, response.Message, tc.reason)
+			})
+		}
+	})
+
+	t.Run("body is passed to the gateway", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		a := grpcmocks.NewMockApplication(mc)
+		a.EXPECT().CreateEvent(gomock.Any(), &storage.Event{Title: "planning"}).Return(nil)
+		server := newGatewayServer(t, a)
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(`{"title": "planning"}`)))
+
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	})
+
+	t.Run("too large body of the gateway", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		server := newGatewayServer(t, grpcmocks.NewMockApplication(mc))
+		body := `{"title": "` + strings.Repeat("a", maxJSONBodySize) + `"}`
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		require.Contains(t, recorder.Body.String(), `"code":3`)
+		require.Contains(t, recorder.Body.String(), "body is larger than")
+	})
+
+	t.Run("paths of the gateway out of the document are passed", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		server := newGatewayServer(t, grpcmocks.NewMockApplication(mc))
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/calendars", nil))
+
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestSpecMatch(t *testing.T) {
+	spec, err := parseSpec([]byte(`{"paths": {
+		"/v1/resources/{id}": {"get": {"operationId": "getResource"}, "put": {"operationId": "updateResource"}},
+		"/v1/resources/available": {"get": {"operationId": "listAvailable"}},
+		"/v1/{kind}/available": {"get": {"operationId": "listKindAvailable"}},
+		"/v1/resources/{id}/events": {"get": {"operationId": "listResourceEvents"}}
+	}}`))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodGet, "/v1/resources/available", "listAvailable"},
+		{http.MethodGet, "/v1/resources/everest", "getResource"},
+		{http.MethodPut, "/v1/resources/available", "updateResource"},
+		{http.MethodGet, "/v1/rooms/available", "listKindAvailable"},
+		{http.MethodGet, "/v1/resources/everest/events", "listResourceEvents"},
+		{http.MethodGet, "/v1/resources/", ""},
+		{http.MethodDelete, "/v1/resources/everest", ""},
+	} {
+		tc := tc
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			// templates are kept in a map, the order of its iteration changes from run to run
+			for i := 0; i < 20; i++ {
+				operation := spec.Match(tc.path, tc.method)
+				if tc.expected == "" {
+					require.Nil(t, operation)
+					continue
+				}
+				require.NotNil(t, operation)
+				require.Equal(t, tc.expected, operation.OperationID)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	operation := spec.Match("/v1/events:batch", http.MethodPost)
+	validate := func(status int, contentType, body string) error {
+		return spec.ValidateResponse(operation, status, contentType, []byte(body))
+	}
+
+	require.NoError(t, validate(http.StatusOK, "application/json", `{"events": [{"id": "event"}]}`))
+	require.NoError(t, validate(http.StatusNotFound, "application/json", `{"code": 5, "message": "not found"}`))
+	require.EqualError(t, validate(http.StatusOK, "application/json", `{"events": [{"id": 1}]}`),
+		"response.events[0].id must be a string")
+	require.EqualError(t, validate(http.StatusBadRequest, "application/json", `{}`), "response.code is required")
+	require.EqualError(t, validate(http.StatusOK, "text/plain", `ok`),
+		`content type "text/plain" of status 200 is not documented`)
+
+	// responses without body
+	noContent, err := parseSpec([]byte(`{"paths": {
+		"/v1/events/{id}": {"delete": {"responses": {"204": {"description": "Deleted"}}}}
+	}}`))
+	require.NoError(t, err)
+	operation = noContent.Match("/v1/events/event", http.MethodDelete)
+	validate = func(status int, contentType, body string) error {
+		return noContent.ValidateResponse(operation, status, contentType, []byte(body))
+	}
+	require.NoError(t, validate(http.StatusNoContent, "", ""))
+	require.EqualError(t, validate(http.StatusNoContent, "application/json", `{}`), "status 204 has no body in the spec")
+}
+
+func TestResponseRecorder(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		_, _ = w.Write([]byte("data"))
+	}
+	captured := map[string]string{"application/json; charset=utf-8": "data", "text/event-stream": ""}
+	for contentType, body := range captured {
+		response := httptest.NewRecorder()
+		recorder := &responseRecorder{ResponseWriter: response}
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/?type="+url.QueryEscape(contentType), nil))
+
+		require.Equal(t, http.StatusOK, recorder.status())
+		require.Equal(t, body, recorder.body.String())
+		require.Equal(t, "data", response.Body.String())
+	}
+}
@@ -2,11 +2,14 @@ package internalhttp
 
 import (
 	"context"
+	"expvar"
+	"io"
 	"net"
 	"net/http"
 	"time"
 
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
 	"github.com/pkg/errors"
 )
 
@@ -16,8 +19,12 @@ type Serverer interface {
 	Shutdown(ctx context.Context) error
 }
 
+// Application is the part of the app served by handlers of the server, the rest of the API
+// is served by the gRPC gateway mounted on the server, see HandleDocumented.
 type Application interface {
-	CreateEvent(ctx context.Context, id, title string) error
+	UploadAttachment(ctx context.Context, eventID, name string, content io.Reader) (*storage.Attachment, error)
+	OpenAttachment(ctx context.Context, id string) (*storage.Attachment, io.ReadCloser, error)
+	SubscribeChanges(ctx context.Context, lastID string) (*app.Subscription, error)
 }
 
 type Server struct {
@@ -26,6 +33,9 @@ type Server struct {
 	Server Serverer
 
 	ShutdownTimeout time.Duration
+
+	mux    *http.ServeMux
+	logger app.Logger
 }
 
 func NewServer(
@@ -36,6 +46,25 @@ func NewServer(
 ) *Server {
 	mux := http.NewServeMux()
 	mux.Handle("/hello", loggingMiddleware(logger, HelloHandler{}))
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/openapi.json", OpenAPIHandler{})
+
+	streamsDone := make(chan struct{})
+	events := EventHandlers{
+		Logg:              logger,
+		App:               app,
+		Heartbeat:         defaultHeartbeat,
+		MaxStreamDuration: writeTimeout - writeTimeout/10,
+		StreamsDone:       streamsDone,
+	}
+	for _, route := range events.routes() {
+		var handler http.Handler = route.handler
+		if operation := spec.Operation(route.pattern, route.method); operation != nil {
+			handler = validationMiddleware(logger, spec, operation, errorResponseWriter(logger), handler)
+		}
+		mux.Handle(route.pattern, loggingMiddleware(logger,
+			userIDMiddleware(calendarIDMiddleware(consistencyMiddleware(methodMiddleware(route.method, handler))))))
+	}
 
 	server := &http.Server{
 		Addr:              net.JoinHostPort(host, port),
@@ -44,12 +73,64 @@ func NewServer(
 		Handler:           mux,
 		ReadHeaderTimeout: readTimeout,
 	}
+	// Shutdown waits for active requests, streams would hold it until the timeout
+	server.RegisterOnShutdown(func() { close(streamsDone) })
 	return &Server{
 		Logg:   logger,
 		App:    app,
 		Server: server,
 
 		ShutdownTimeout: shutDownTimeout,
+
+		mux:    mux,
+		logger: logger,
+	}
+}
+
+// Handler returns the handler of all routes, it serves them on listeners other than the address
+// of the server, such as test ones.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Handle serves requests of the pattern by the handler along with the API,
+// it is used to mount handlers of other packages, such as the gRPC gateway.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, loggingMiddleware(s.logger, handler))
+}
+
+// HandleDocumented serves requests of the pattern as Handle does, requests of operations of the OpenAPI
+// document are validated against it, rejected ones are answered by invalid.
+func (s *Server) HandleDocumented(pattern string, handler http.Handler, invalid InvalidRequestWriter) {
+	s.mux.Handle(pattern, loggingMiddleware(s.logger, documentedMiddleware(s.logger, spec, invalid, handler)))
+}
+
+// spec is loaded once, the document is embedded and checked by tests.
+var spec = mustLoadSpec()
+
+func mustLoadSpec() *Spec {
+	spec, err := LoadSpec()
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+type route struct {
+	pattern string
+	method  string
+	handler http.HandlerFunc
+}
+
+// routes of the API, each of them is described in the OpenAPI document. The rest of the API is
+// transcoded to EventService by the gateway, these requests can't be: bodies of uploads and downloads
+// are raw content streamed as is rather than JSON of a message, and changes are sent as Server-Sent
+// Events for as long as the client listens, while a unary call has a single response.
+func (h EventHandlers) routes() []route {
+	return []route{
+		{"/events/stream", http.MethodGet, h.Stream},
+		{"/attachments/upload", http.MethodPost, h.UploadAttachment},
+		{"/attachments/download", http.MethodGet, h.DownloadAttachment},
 	}
 }
 
@@ -0,0 +1,84 @@
+package internalhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHeartbeat = 15 * time.Second
+	// streamRetry is the reconnection delay suggested to clients in milliseconds.
+	streamRetry = 3000
+	// LastEventIDHeader is sent by EventSource on reconnect, the first connection
+	// may pass the ID in the last_event_id query parameter instead.
+	LastEventIDHeader = "Last-Event-ID"
+	// streamResetEvent tells the client that missed changes are not kept,
+	// so events have to be reloaded with /v1/events.
+	streamResetEvent = "reset"
+)
+
+// Stream sends changes of events of the user as Server-Sent Events. The event name
+// is the audit action, the data is the audit record and the ID can be used to resume.
+func (h EventHandlers) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	lastID := r.Header.Get(LastEventIDHeader)
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	ctx := r.Context()
+	if h.MaxStreamDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.MaxStreamDuration)
+		defer cancel()
+	}
+	subscription, err := h.App.SubscribeChanges(ctx, lastID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers responses by default
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if subscription.Reset {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", streamResetEvent)
+	}
+	flusher.Flush()
+
+	heartbeat := h.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.StreamsDone:
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case change, ok := <-subscription.Changes:
+			if !ok {
+				// ctx is done, the client is too slow or its share is revoked, access is checked on reconnect
+				return
+			}
+			data, err := json.Marshal(change.Record)
+			if err != nil {
+				h.Logg.Error().Err(err).Msgf("Failed to marshal change %s", change.ID)
+				return
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", change.ID, change.Record.Action, data)
+		}
+		flusher.Flush()
+	}
+}
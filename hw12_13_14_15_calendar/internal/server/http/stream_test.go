@@ -0,0 +1,152 @@
+package internalhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	server_mocks "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/http/mocks"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type sseMessage struct {
+	id, event, data, comment string
+}
+
+// readMessages parses Server-Sent Events from body into the returned channel.
+func readMessages(t *testing.T, response *http.Response) <-chan sseMessage {
+	t.Helper()
+	messages := make(chan sseMessage, 16)
+	go func() {
+		defer close(messages)
+		scanner := bufio.NewScanner(response.Body)
+		var message sseMessage
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				messages <- message
+				message = sseMessage{}
+			case strings.HasPrefix(line, ":"):
+				message.comment = strings.TrimSpace(strings.TrimPrefix(line, ":"))
+			case strings.HasPrefix(line, "id: "):
+				message.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				message.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				message.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return messages
+}
+
+func nextMessage(t *testing.T, messages <-chan sseMessage) sseMessage {
+	t.Helper()
+	select {
+	case message, ok := <-messages:
+		require.True(t, ok, "stream is closed")
+		return message
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "no message received")
+	}
+	return sseMessage{}
+}
+
+func openStream(t *testing.T, url, userID, lastID string) *http.Response {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/events/stream", nil)
+	require.NoError(t, err)
+	request.Header.Set(UserIDHeader, userID)
+	if lastID != "" {
+		request.Header.Set(LastEventIDHeader, lastID)
+	}
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func TestStreamHandler(t *testing.T) {
+	t.Run("streams and resumes changes of the user", func(t *testing.T) {
+		logg := logger.New("error")
+		calendar := app.New(logg, memorystorage.New(logg))
+		server := httptest.NewServer(NewServer(logg, calendar, "", "", 0, 0, 0).Server.(*http.Server).Handler)
+		// streams are closed by cleanups of openStream before the server
+		t.Cleanup(server.Close)
+		alice := app.ContextWithUserID(context.Background(), "alice")
+
+		response := openStream(t, server.URL, "alice", "")
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+		messages := readMessages(t, response)
+		nextMessage(t, messages) // retry
+
+		start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+		event := &storage.Event{Title: "meeting", StartAt: start, EndAt: start.Add(time.Hour)}
+		require.NoError(t, calendar.CreateEvent(alice, event))
+		created := nextMessage(t, messages)
+		require.Equal(t, string(storage.AuditActionCreate), created.event)
+		var record storage.AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(created.data), &record))
+		require.Equal(t, event.ID, record.EventID)
+		require.Equal(t, "meeting", record.After.Title)
+
+		// changes made while disconnected are sent after reconnect
+		response.Body.Close()
+		require.NoError(t, calendar.DeleteEvent(alice, event.ID))
+		messages = readMessages(t, openStream(t, server.URL, "alice", created.id))
+		nextMessage(t, messages) // retry
+		deleted := nextMessage(t, messages)
+		require.Equal(t, string(storage.AuditActionDelete), deleted.event)
+		require.NotEqual(t, created.id, deleted.id)
+	})
+
+	t.Run("reset and heartbeat", func(t *testing.T) {
+		mc := gomock.NewController(t)
+		a := server_mocks.NewMockApplication(mc)
+		changes := make(chan app.Change)
+		a.EXPECT().SubscribeChanges(gomock.Any(), "42").Return(&app.Subscription{Changes: changes, Reset: true}, nil)
+		handlers := EventHandlers{Logg: logger.New("error"), App: a, Heartbeat: 10 * time.Millisecond}
+		server := httptest.NewServer(http.HandlerFunc(handlers.Stream))
+		defer server.Close()
+
+		response, err := http.Get(server.URL + "/events/stream?last_event_id=42")
+		require.NoError(t, err)
+		defer response.Body.Close()
+		messages := readMessages(t, response)
+		nextMessage(t, messages) // retry
+		require.Equal(t, streamResetEvent, nextMessage(t, messages).event)
+		require.Equal(t, "heartbeat", nextMessage(t, messages).comment)
+
+		// closed subscription ends the stream
+		close(changes)
+		require.Eventually(t, func() bool {
+			_, ok := <-messages
+			return !ok
+		}, 2*time.Second, time.Millisecond)
+	})
+
+	t.Run("requires user", func(t *testing.T) {
+		logg := logger.New("error")
+		server := NewServer(logg, app.New(logg, memorystorage.New(logg)), "", "", 0, 0, 0)
+
+		recorder := httptest.NewRecorder()
+		server.Server.(*http.Server).Handler.ServeHTTP(recorder,
+			httptest.NewRequest(http.MethodGet, "/events/stream", nil))
+
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
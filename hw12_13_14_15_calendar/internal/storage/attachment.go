@@ -0,0 +1,29 @@
+package storage
+
+import "time"
+
+// Attachment is a file or a link attached to the event, such as an agenda of the meeting.
+// Content of files is kept in the blob store under BlobKey, links have URL instead.
+type Attachment struct {
+	ID      string `db:"id" json:"id"`
+	EventID string `db:"event_id" json:"event_id"`
+	// UserID is the user who attached the file or the link.
+	UserID      string `db:"user_id" json:"user_id,omitempty"`
+	Name        string `db:"name" json:"name"`
+	URL         string `db:"url" json:"url,omitempty"`
+	ContentType string `db:"content_type" json:"content_type,omitempty"`
+	Size        int64  `db:"size" json:"size"`
+	// BlobKey is internal to the app, it is not returned to clients.
+	BlobKey   string    `db:"blob_key" json:"blob_key,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// IsLink reports whether the attachment is a link, links have no content.
+func (a Attachment) IsLink() bool {
+	return a.URL != ""
+}
+
+// Clone returns a copy of the attachment.
+func (a Attachment) Clone() *Attachment {
+	return &a
+}
@@ -0,0 +1,49 @@
+package storage
+
+type AttendeeStatus string
+
+const (
+	AttendeeStatusNeedsAction AttendeeStatus = "needs-action"
+	AttendeeStatusAccepted    AttendeeStatus = "accepted"
+	AttendeeStatusDeclined    AttendeeStatus = "declined"
+	AttendeeStatusTentative   AttendeeStatus = "tentative"
+)
+
+// Valid reports whether status is one of the known statuses.
+func (s AttendeeStatus) Valid() bool {
+	switch s {
+	case AttendeeStatusNeedsAction, AttendeeStatusAccepted, AttendeeStatusDeclined, AttendeeStatusTentative:
+		return true
+	}
+	return false
+}
+
+type Attendee struct {
+	UserID string         `db:"user_id" json:"user_id"`
+	Status AttendeeStatus `db:"status" json:"status"`
+}
+
+// IsAttendee reports whether user is invited to the event.
+func (e *Event) IsAttendee(userID string) bool {
+	for _, attendee := range e.Attendees {
+		if attendee.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationRecipients returns users who should be notified about the event:
+// its owner and attendees who accepted the invitation.
+func (e *Event) NotificationRecipients() []string {
+	recipients := make([]string, 0, len(e.Attendees)+1)
+	if e.UserID != "" {
+		recipients = append(recipients, e.UserID)
+	}
+	for _, attendee := range e.Attendees {
+		if attendee.Status == AttendeeStatusAccepted && attendee.UserID != e.UserID {
+			recipients = append(recipients, attendee.UserID)
+		}
+	}
+	return recipients
+}
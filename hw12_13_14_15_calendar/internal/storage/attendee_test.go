@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationRecipients(t *testing.T) {
+	event := Event{
+		UserID: "owner",
+		Attendees: []Attendee{
+			{UserID: "accepted", Status: AttendeeStatusAccepted},
+			{UserID: "declined", Status: AttendeeStatusDeclined},
+			{UserID: "tentative", Status: AttendeeStatusTentative},
+			{UserID: "unanswered", Status: AttendeeStatusNeedsAction},
+			{UserID: "owner", Status: AttendeeStatusAccepted},
+		},
+	}
+	require.Equal(t, []string{"owner", "accepted"}, event.NotificationRecipients())
+}
@@ -0,0 +1,58 @@
+package storage
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditActionCreate  AuditAction = "create"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+)
+
+// AuditRecord describes a single change of an event. Records are append-only:
+// storages never update or delete them.
+type AuditRecord struct {
+	ID        string      `json:"id"`
+	EventID   string      `json:"event_id"`
+	Actor     string      `json:"actor"`
+	Action    AuditAction `json:"action"`
+	CreatedAt time.Time   `json:"created_at"`
+	// Before is empty for created events, After is empty for deleted ones.
+	Before *Event `json:"before,omitempty"`
+	After  *Event `json:"after,omitempty"`
+}
+
+// Clone returns a deep copy of the record.
+func (r AuditRecord) Clone() *AuditRecord {
+	if r.Before != nil {
+		r.Before = r.Before.Clone()
+	}
+	if r.After != nil {
+		r.After = r.After.Clone()
+	}
+	return &r
+}
+
+// Users returns users who see the event before or after the change: owners and attendees.
+func (r *AuditRecord) Users() []string {
+	users := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(userID string) {
+		if userID != "" && !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+	for _, event := range []*Event{r.Before, r.After} {
+		if event == nil {
+			continue
+		}
+		add(event.UserID)
+		for _, attendee := range event.Attendees {
+			add(attendee.UserID)
+		}
+	}
+	return users
+}
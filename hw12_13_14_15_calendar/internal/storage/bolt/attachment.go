@@ -0,0 +1,110 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+func getAttachment(tx *bolt.Tx, id string) (*storage.Attachment, error) {
+	data := tx.Bucket(attachmentsBucket).Get([]byte(id))
+	if data == nil {
+		return nil, errs.ErrNotFoundAttachment{ID: id}
+	}
+	var attachment storage.Attachment
+	if err := json.Unmarshal(data, &attachment); err != nil {
+		return nil, errs.ErrGetAttachment{Err: err}
+	}
+	return &attachment, nil
+}
+
+// selectAttachments returns up to limit attachments matching filter in order of their IDs,
+// i.e. of creation. Negative limit means no limit.
+func (s *Storage) selectAttachments(limit int, filter func(tx *bolt.Tx, attachment *storage.Attachment) bool) (
+	[]*storage.Attachment, error,
+) {
+	attachments := make([]*storage.Attachment, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(attachmentsBucket).Cursor()
+		for key, data := cursor.First(); key != nil && len(attachments) != limit; key, data = cursor.Next() {
+			var attachment storage.Attachment
+			if err := json.Unmarshal(data, &attachment); err != nil {
+				return err
+			}
+			if filter(tx, &attachment) {
+				attachments = append(attachments, &attachment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.ErrListAttachments{Err: err}
+	}
+	return attachments, nil
+}
+
+func (s *Storage) AddAttachment(ctx context.Context, attachment *storage.Attachment) error {
+	attachment.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding attachment %s to event %s", attachment.ID, attachment.EventID)
+	err := s.update(func(tx *bolt.Tx) error {
+		if _, err := getActiveEvent(tx, attachment.EventID); err != nil {
+			return err
+		}
+		if err := put(tx.Bucket(attachmentsBucket), attachment.ID, attachment); err != nil {
+			return errs.ErrAddAttachment{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully add attachment %s", attachment.ID)
+	return nil
+}
+
+func (s *Storage) GetAttachment(ctx context.Context, id string) (*storage.Attachment, error) {
+	var attachment *storage.Attachment
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		attachment, err = getAttachment(tx, id)
+		return err
+	})
+	return attachment, err
+}
+
+func (s *Storage) ListEventAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error) {
+	return s.selectAttachments(-1, func(_ *bolt.Tx, attachment *storage.Attachment) bool {
+		return attachment.EventID == eventID
+	})
+}
+
+func (s *Storage) DeleteAttachment(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting attachment %s", id)
+	err := s.update(func(tx *bolt.Tx) error {
+		if _, err := getAttachment(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Bucket(attachmentsBucket).Delete([]byte(id)); err != nil {
+			return errs.ErrDeleteAttachment{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully deleted attachment %s", id)
+	return nil
+}
+
+func (s *Storage) ListOrphanedAttachments(ctx context.Context, limit int) ([]*storage.Attachment, error) {
+	if limit <= 0 {
+		return []*storage.Attachment{}, nil
+	}
+	return s.selectAttachments(limit, func(tx *bolt.Tx, attachment *storage.Attachment) bool {
+		return tx.Bucket(eventsBucket).Get([]byte(attachment.EventID)) == nil
+	})
+}
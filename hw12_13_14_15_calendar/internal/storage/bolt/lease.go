@@ -0,0 +1,17 @@
+package boltstorage
+
+import (
+	"context"
+	"time"
+)
+
+// Leases are kept in memory: bolt locks the database file, so it is never shared between processes.
+
+func (s *Storage) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return s.leases.Acquire(name, holder, ttl, time.Now()), nil
+}
+
+func (s *Storage) ReleaseLease(ctx context.Context, name, holder string) error {
+	s.leases.Release(name, holder)
+	return nil
+}
@@ -0,0 +1,39 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *Storage) SaveUserProfile(ctx context.Context, profile *storage.UserProfile) error {
+	s.log.Debug().Msgf("Start saving profile of user %s", profile.UserID)
+	if err := s.update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(profilesBucket), profile.UserID, profile)
+	}); err != nil {
+		return errs.ErrSaveUserProfile{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved profile of user %s", profile.UserID)
+	return nil
+}
+
+func (s *Storage) GetUserProfile(ctx context.Context, userID string) (*storage.UserProfile, error) {
+	var profile storage.UserProfile
+	err := s.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(profilesBucket).Get([]byte(userID))
+		if data == nil {
+			return errs.ErrNotFoundUserProfile{UserID: userID}
+		}
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return errs.ErrGetUserProfile{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
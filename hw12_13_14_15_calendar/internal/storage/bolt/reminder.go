@@ -0,0 +1,92 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *Storage) ListEventsToNotify(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events to notify from %v to %v", from, to)
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		if event.DeletedAt != nil || event.NotifyBefore <= 0 {
+			return false
+		}
+		notifyAt := event.NotifyAt()
+		return notifyAt.After(from) && !notifyAt.After(to)
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events to notify, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) AddReminder(ctx context.Context, reminder *storage.Reminder) error {
+	s.log.Debug().Msgf("Start adding reminder %s", reminder.ID)
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(remindersBucket)
+		if bucket.Get([]byte(reminder.ID)) != nil {
+			return errs.ErrReminderExists{ID: reminder.ID}
+		}
+		return put(bucket, reminder.ID, reminder)
+	})
+	if err != nil {
+		if errors.As(err, &errs.ErrReminderExists{}) {
+			return err
+		}
+		return errs.ErrAddReminder{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully added reminder %s", reminder.ID)
+	return nil
+}
+
+func (s *Storage) GetReminder(ctx context.Context, id string) (*storage.Reminder, error) {
+	var reminder storage.Reminder
+	err := s.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(remindersBucket).Get([]byte(id))
+		if data == nil {
+			return errs.ErrNotFoundReminder{ID: id}
+		}
+		if err := json.Unmarshal(data, &reminder); err != nil {
+			return errs.ErrGetReminder{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+func (s *Storage) DeleteReminders(ctx context.Context, handledBefore time.Time) (int, error) {
+	s.log.Debug().Msgf("Start deleting reminders handled before %v", handledBefore)
+	deleted := 0
+	err := s.update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(remindersBucket).Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			var reminder storage.Reminder
+			if err := json.Unmarshal(data, &reminder); err != nil {
+				return err
+			}
+			if !reminder.HandledAt.Before(handledBefore) {
+				continue
+			}
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errs.ErrDeleteReminders{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted reminders, total: %d", deleted)
+	return deleted, nil
+}
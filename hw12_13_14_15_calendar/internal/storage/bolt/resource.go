@@ -0,0 +1,129 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// checkResourcesFree fails if resources of the event are booked for other events at the same time.
+// It runs in the write transaction of the change, so nothing is booked in between.
+func checkResourcesFree(tx *bolt.Tx, event *storage.Event) error {
+	if len(event.Resources) == 0 {
+		return nil
+	}
+	events := make([]*storage.Event, 0)
+	err := tx.Bucket(eventsBucket).ForEach(func(_, data []byte) error {
+		var other storage.Event
+		if err := json.Unmarshal(data, &other); err != nil {
+			return err
+		}
+		events = append(events, &other)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return storage.CheckResourcesFree(event, events)
+}
+
+func getResource(tx *bolt.Tx, id string) (*storage.Resource, error) {
+	data := tx.Bucket(resourcesBucket).Get([]byte(id))
+	if data == nil {
+		return nil, errs.ErrNotFoundResource{ID: id}
+	}
+	var resource storage.Resource
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, errs.ErrGetResource{Err: err}
+	}
+	return &resource, nil
+}
+
+func (s *Storage) AddResource(ctx context.Context, resource *storage.Resource) error {
+	resource.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding resource with id %s", resource.ID)
+	if err := s.update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(resourcesBucket), resource.ID, resource)
+	}); err != nil {
+		return errs.ErrAddResource{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add resource with id %s", resource.ID)
+	return nil
+}
+
+func (s *Storage) ModifyResource(ctx context.Context, resource *storage.Resource) error {
+	s.log.Debug().Msgf("Start modifying resource with id %s", resource.ID)
+	err := s.update(func(tx *bolt.Tx) error {
+		if _, err := getResource(tx, resource.ID); err != nil {
+			return err
+		}
+		if err := put(tx.Bucket(resourcesBucket), resource.ID, resource); err != nil {
+			return errs.ErrUpdateResource{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully modified resource with id %s", resource.ID)
+	return nil
+}
+
+func (s *Storage) GetResource(ctx context.Context, id string) (*storage.Resource, error) {
+	var resource *storage.Resource
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		resource, err = getResource(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+func (s *Storage) ListResources(ctx context.Context) ([]*storage.Resource, error) {
+	resources := make([]*storage.Resource, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(resourcesBucket).ForEach(func(_, data []byte) error {
+			var resource storage.Resource
+			if err := json.Unmarshal(data, &resource); err != nil {
+				return err
+			}
+			resources = append(resources, &resource)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.ErrListResources{Err: err}
+	}
+	storage.SortResources(resources)
+	return resources, nil
+}
+
+func (s *Storage) ListResourcesEventsInRange(
+	ctx context.Context, resourceIDs []string, from, to time.Time,
+) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of resources %v from %v to %v", resourceIDs, from, to)
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		if event.DeletedAt != nil || !event.Overlaps(from, to) {
+			return false
+		}
+		for _, resourceID := range resourceIDs {
+			if event.UsesResource(resourceID) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events of resources %v, total: %d", resourceIDs, len(events))
+	return events, nil
+}
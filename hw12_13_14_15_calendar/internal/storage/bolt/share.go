@@ -0,0 +1,90 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *Storage) SaveShare(ctx context.Context, share *storage.Share) error {
+	s.log.Debug().Msgf("Start saving share of calendar of %s with %s", share.OwnerID, share.UserID)
+	if err := s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(sharesBucket).CreateBucketIfNotExists([]byte(share.OwnerID))
+		if err != nil {
+			return err
+		}
+		return put(bucket, share.UserID, share)
+	}); err != nil {
+		return errs.ErrSaveShare{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved share of calendar of %s with %s", share.OwnerID, share.UserID)
+	return nil
+}
+
+func (s *Storage) DeleteShare(ctx context.Context, ownerID, userID string) error {
+	s.log.Debug().Msgf("Start deleting share of calendar of %s with %s", ownerID, userID)
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sharesBucket).Bucket([]byte(ownerID))
+		if bucket == nil || bucket.Get([]byte(userID)) == nil {
+			return errs.ErrNotFoundShare{OwnerID: ownerID, UserID: userID}
+		}
+		if err := bucket.Delete([]byte(userID)); err != nil {
+			return errs.ErrDeleteShare{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully deleted share of calendar of %s with %s", ownerID, userID)
+	return nil
+}
+
+// ListOwnerShares returns shares of the owner, keys of the nested bucket keep them sorted by user.
+func (s *Storage) ListOwnerShares(ctx context.Context, ownerID string) ([]*storage.Share, error) {
+	shares := make([]*storage.Share, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sharesBucket).Bucket([]byte(ownerID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var share storage.Share
+			if err := json.Unmarshal(data, &share); err != nil {
+				return err
+			}
+			shares = append(shares, &share)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.ErrListShares{Err: err}
+	}
+	return shares, nil
+}
+
+// ListUserShares looks the user up in buckets of all owners, which are iterated in order of owners.
+func (s *Storage) ListUserShares(ctx context.Context, userID string) ([]*storage.Share, error) {
+	shares := make([]*storage.Share, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(sharesBucket).ForEach(func(ownerID, _ []byte) error {
+			data := tx.Bucket(sharesBucket).Bucket(ownerID).Get([]byte(userID))
+			if data == nil {
+				return nil
+			}
+			var share storage.Share
+			if err := json.Unmarshal(data, &share); err != nil {
+				return err
+			}
+			shares = append(shares, &share)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.ErrListShares{Err: err}
+	}
+	return shares, nil
+}
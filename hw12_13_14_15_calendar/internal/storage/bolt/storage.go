@@ -0,0 +1,506 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	eventsBucket = []byte("events")
+	// audit bucket contains a nested bucket per event, records in it are keyed by sequence number.
+	auditBucket = []byte("audit")
+	// webhooks and deliveries buckets are keyed by ID, deliveries refer to webhooks by WebhookID.
+	webhooksBucket   = []byte("webhooks")
+	deliveriesBucket = []byte("deliveries")
+	// profiles bucket is keyed by user ID.
+	profilesBucket = []byte("profiles")
+	// reminders bucket is keyed by ID.
+	remindersBucket = []byte("reminders")
+	// tags bucket contains a nested bucket per user, tags in it are keyed by name.
+	tagsBucket = []byte("tags")
+	// resources bucket is keyed by ID, events refer to resources by IDs in Resources.
+	resourcesBucket = []byte("resources")
+	// shares bucket contains a nested bucket per owner, shares in it are keyed by user ID.
+	sharesBucket = []byte("shares")
+	// attachments bucket is keyed by ID, attachments refer to events by EventID.
+	attachmentsBucket = []byte("attachments")
+)
+
+// Storage keeps events in a single bbolt file, events are stored as JSON.
+type Storage struct {
+	app.Storage
+	path string
+
+	connectionTimeout time.Duration
+
+	log app.Logger
+
+	db     *bolt.DB
+	leases *storage.Leases
+	// tx is set for storage passed to WithTx callback, all operations run in it
+	tx *bolt.Tx
+}
+
+func New(log *logger.Logger, path string, connectionTimeout time.Duration) *Storage {
+	return &Storage{
+		path:              path,
+		connectionTimeout: connectionTimeout,
+		leases:            storage.NewLeases(),
+		log:               log,
+	}
+}
+
+func (s *Storage) Connect(ctx context.Context) error {
+	s.log.Info().Msgf("Start opening database file %s with timeout %v", s.path, s.connectionTimeout)
+	// bolt holds an exclusive lock on the file, timeout limits waiting for it
+	db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: s.connectionTimeout})
+	if err != nil {
+		return errs.ErrConnectionFailed{Err: err}
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{
+			eventsBucket, auditBucket, webhooksBucket, deliveriesBucket, profilesBucket, remindersBucket, tagsBucket,
+			resourcesBucket, sharesBucket, attachmentsBucket,
+		} {
+			if _, bucketErr := tx.CreateBucketIfNotExists(bucket); bucketErr != nil {
+				return bucketErr
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return errs.ErrConnectionFailed{Err: err}
+	}
+	s.db = db
+	s.log.Info().Msg("Successfully opened database file")
+	return nil
+}
+
+func (s *Storage) Close(ctx context.Context) error {
+	s.log.Info().Msg("Start closing database file...")
+	if err := s.db.Close(); err != nil {
+		return errs.ErrCloseConnectionFailed{Err: err}
+	}
+	s.log.Info().Msg("Successfully closed database file")
+	return nil
+}
+
+func (s *Storage) update(fn func(tx *bolt.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.db.Update(fn)
+}
+
+func (s *Storage) view(fn func(tx *bolt.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	return s.db.View(fn)
+}
+
+// WithTx runs fn in a read-write bolt transaction, which is committed if fn returns nil.
+// Bolt allows a single writer, so other writes wait until fn returns.
+func (s *Storage) WithTx(ctx context.Context, fn func(tx app.Storage) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	s.log.Debug().Msg("Start transaction")
+	var fnErr error
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		txStorage := *s
+		txStorage.tx = tx
+		fnErr = fn(&txStorage)
+		return fnErr
+	})
+	if fnErr != nil {
+		s.log.Debug().Err(fnErr).Msg("Transaction is rolled back")
+		return fnErr
+	}
+	if err != nil {
+		return errs.ErrTransaction{Err: err}
+	}
+	s.log.Debug().Msg("Successfully committed transaction")
+	return nil
+}
+
+func getEvent(tx *bolt.Tx, id string) (*storage.Event, error) {
+	data := tx.Bucket(eventsBucket).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	var event storage.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func putEvent(tx *bolt.Tx, event *storage.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(eventsBucket).Put([]byte(event.ID), data)
+}
+
+// getActiveEvent returns event which is not in the trash or ErrNotFoundEvent.
+func getActiveEvent(tx *bolt.Tx, id string) (*storage.Event, error) {
+	event, err := getEvent(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil || event.DeletedAt != nil {
+		return nil, errs.ErrNotFoundEvent{ID: id}
+	}
+	return event, nil
+}
+
+// selectEvents returns events matching filter sorted by start time.
+func (s *Storage) selectEvents(filter func(event *storage.Event) bool) ([]*storage.Event, error) {
+	events := make([]*storage.Event, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(eventsBucket).ForEach(func(_, data []byte) error {
+			var event storage.Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			if filter(&event) {
+				events = append(events, &event)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartAt.Before(events[j].StartAt)
+	})
+	return events, nil
+}
+
+func (s *Storage) AddEvent(ctx context.Context, event *storage.Event) error {
+	event.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding event with id %s", event.ID)
+	if err := s.update(func(tx *bolt.Tx) error {
+		if err := checkResourcesFree(tx, event); err != nil {
+			return err
+		}
+		return putEvent(tx, event)
+	}); err != nil {
+		return errs.ErrAddEvent{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add event with id %s", event.ID)
+	return nil
+}
+
+func (s *Storage) ModifyEvent(ctx context.Context, event *storage.Event) error {
+	s.log.Debug().Msgf("Start modifying event with id %s", event.ID)
+	err := s.update(func(tx *bolt.Tx) error {
+		stored, err := getEvent(tx, event.ID)
+		if err != nil {
+			return errs.ErrUpdateEvent{Err: err}
+		}
+		if stored == nil || stored.DeletedAt != nil {
+			return errs.ErrNotFoundEvent{ID: event.ID}
+		}
+		modified := event.Clone()
+		modified.Attendees = stored.Attendees
+		modified.DeletedAt = nil
+		if err = checkResourcesFree(tx, modified); err != nil {
+			return errs.ErrUpdateEvent{Err: err}
+		}
+		if err = putEvent(tx, modified); err != nil {
+			return errs.ErrUpdateEvent{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully modified event with id %s", event.ID)
+	return nil
+}
+
+// DeleteEvent moves event to the trash, it is removed completely by PurgeDeletedEvents.
+func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting event with id %s", id)
+	err := s.update(func(tx *bolt.Tx) error {
+		event, err := getEvent(tx, id)
+		if err != nil || event == nil || event.DeletedAt != nil {
+			return err
+		}
+		deletedAt := time.Now().UTC()
+		event.DeletedAt = &deletedAt
+		return putEvent(tx, event)
+	})
+	if err != nil {
+		return errs.ErrDeleteEvent{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted event with id %s", id)
+	return nil
+}
+
+func (s *Storage) RestoreEvent(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start restoring event with id %s", id)
+	err := s.update(func(tx *bolt.Tx) error {
+		event, err := getEvent(tx, id)
+		if err != nil {
+			return errs.ErrRestoreEvent{Err: err}
+		}
+		if event == nil || event.DeletedAt == nil {
+			return errs.ErrNotFoundEvent{ID: id}
+		}
+		event.DeletedAt = nil
+		if err = checkResourcesFree(tx, event); err != nil {
+			return errs.ErrRestoreEvent{Err: err}
+		}
+		if err = putEvent(tx, event); err != nil {
+			return errs.ErrRestoreEvent{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully restored event with id %s", id)
+	return nil
+}
+
+func (s *Storage) ListDeletedEvents(ctx context.Context) ([]*storage.Event, error) {
+	s.log.Debug().Msg("Start listing deleted events")
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		return event.DeletedAt != nil
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed deleted events, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	s.log.Debug().Msgf("Start purging events deleted before %v", deletedBefore)
+	purged := 0
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(eventsBucket)
+		cursor := bucket.Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			var event storage.Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			if event.DeletedAt == nil || !event.DeletedAt.Before(deletedBefore) {
+				continue
+			}
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errs.ErrPurgeEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully purged events, total: %d", purged)
+	return purged, nil
+}
+
+func (s *Storage) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	s.log.Debug().Msgf("Start getting event with id %s", id)
+	var event *storage.Event
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		event, err = getActiveEvent(tx, id)
+		return err
+	})
+	if err != nil {
+		var notFoundErr errs.ErrNotFoundEvent
+		if errors.As(err, &notFoundErr) {
+			return nil, err
+		}
+		return nil, errs.ErrGetEvent{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully got event with id %s", id)
+	return event, nil
+}
+
+func (s *Storage) ListEvents(ctx context.Context) ([]*storage.Event, error) {
+	s.log.Debug().Msg("Start listing events")
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		return event.DeletedAt == nil
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) ListUserEvents(ctx context.Context, userID string) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of user %s", userID)
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		return event.DeletedAt == nil && (event.UserID == userID || event.IsAttendee(userID))
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events of user %s, total: %d", userID, len(events))
+	return events, nil
+}
+
+// ListEventsInRange returns events which overlap with [from, to).
+func (s *Storage) ListEventsInRange(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events from %v to %v", from, to)
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		return event.DeletedAt == nil && event.StartAt.Before(to) && event.EndAt.After(from)
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events from %v to %v, total: %d", from, to, len(events))
+	return events, nil
+}
+
+// ListUsersEventsInRange returns events owned by or attended by any of users,
+// which overlap with [from, to).
+func (s *Storage) ListUsersEventsInRange(
+	ctx context.Context, userIDs []string, from, to time.Time,
+) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of users %v from %v to %v", userIDs, from, to)
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		if event.DeletedAt != nil || !event.StartAt.Before(to) || !event.EndAt.After(from) {
+			return false
+		}
+		for _, userID := range userIDs {
+			if event.UserID == userID || event.IsAttendee(userID) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events of users %v, total: %d", userIDs, len(events))
+	return events, nil
+}
+
+func (s *Storage) AddAttendees(ctx context.Context, eventID string, userIDs []string) error {
+	s.log.Debug().Msgf("Start adding attendees to event %s", eventID)
+	err := s.update(func(tx *bolt.Tx) error {
+		event, err := getActiveEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			if !event.IsAttendee(userID) {
+				event.Attendees = append(event.Attendees, storage.Attendee{
+					UserID: userID,
+					Status: storage.AttendeeStatusNeedsAction,
+				})
+			}
+		}
+		if err = putEvent(tx, event); err != nil {
+			return errs.ErrAddAttendees{Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully added attendees to event %s", eventID)
+	return nil
+}
+
+func (s *Storage) SetAttendeeStatus(
+	ctx context.Context, eventID, userID string, status storage.AttendeeStatus,
+) error {
+	s.log.Debug().Msgf("Start setting status %s of attendee %s in event %s", status, userID, eventID)
+	err := s.update(func(tx *bolt.Tx) error {
+		event, err := getActiveEvent(tx, eventID)
+		if err != nil {
+			return err
+		}
+		for i := range event.Attendees {
+			if event.Attendees[i].UserID == userID {
+				event.Attendees[i].Status = status
+				if err = putEvent(tx, event); err != nil {
+					return errs.ErrUpdateAttendee{Err: err}
+				}
+				return nil
+			}
+		}
+		return errs.ErrNotFoundAttendee{EventID: eventID, UserID: userID}
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully set status of attendee %s in event %s", userID, eventID)
+	return nil
+}
+
+func (s *Storage) AddAuditRecord(ctx context.Context, record *storage.AuditRecord) error {
+	record.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding audit record %s for event %s", record.ID, record.EventID)
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(auditBucket).CreateBucketIfNotExists([]byte(record.EventID))
+		if err != nil {
+			return err
+		}
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return bucket.Put(key, data)
+	})
+	if err != nil {
+		return errs.ErrAddAuditRecord{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add audit record %s", record.ID)
+	return nil
+}
+
+func (s *Storage) ListAuditRecords(ctx context.Context, eventID string) ([]*storage.AuditRecord, error) {
+	s.log.Debug().Msgf("Start listing audit records of event %s", eventID)
+	records := make([]*storage.AuditRecord, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(auditBucket).Bucket([]byte(eventID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var record storage.AuditRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			records = append(records, &record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.ErrListAuditRecords{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed audit records of event %s, total: %d", eventID, len(records))
+	return records, nil
+}
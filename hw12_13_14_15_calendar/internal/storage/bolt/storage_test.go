@@ -0,0 +1,48 @@
+package boltstorage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func connect(t *testing.T, path string) *Storage {
+	t.Helper()
+	s := New(logger.New("error"), path, time.Second)
+	require.NoError(t, s.Connect(context.Background()))
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		t.Helper()
+		s := connect(t, filepath.Join(t.TempDir(), "calendar.db"))
+		t.Cleanup(func() {
+			require.NoError(t, s.Close(context.Background()))
+		})
+		return s
+	})
+
+	t.Run("events survive reopening", func(t *testing.T) {
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "calendar.db")
+
+		s := connect(t, path)
+		event := &storage.Event{Title: "title", TimeZone: "UTC"}
+		require.NoError(t, s.AddEvent(ctx, event))
+		require.NoError(t, s.Close(ctx))
+
+		s = connect(t, path)
+		defer s.Close(ctx)
+		got, err := s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, event, got)
+	})
+}
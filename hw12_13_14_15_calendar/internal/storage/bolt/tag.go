@@ -0,0 +1,53 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *Storage) SaveTag(ctx context.Context, tag *storage.Tag) error {
+	s.log.Debug().Msgf("Start saving tag %s of user %s", tag.Name, tag.UserID)
+	if err := s.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(tagsBucket).CreateBucketIfNotExists([]byte(tag.UserID))
+		if err != nil {
+			return err
+		}
+		return put(bucket, tag.Name, tag)
+	}); err != nil {
+		return errs.ErrSaveTag{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved tag %s of user %s", tag.Name, tag.UserID)
+	return nil
+}
+
+func (s *Storage) ListUserTags(ctx context.Context, userID string) ([]*storage.Tag, error) {
+	saved := make([]*storage.Tag, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tagsBucket).Bucket([]byte(userID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var tag storage.Tag
+			if err := json.Unmarshal(data, &tag); err != nil {
+				return err
+			}
+			saved = append(saved, &tag)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.ErrListTags{Err: err}
+	}
+	events, err := s.selectEvents(func(event *storage.Event) bool {
+		return event.UserID == userID
+	})
+	if err != nil {
+		return nil, errs.ErrListTags{Err: err}
+	}
+	return storage.CollectTags(userID, saved, events), nil
+}
@@ -0,0 +1,187 @@
+package boltstorage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+	bolt "go.etcd.io/bbolt"
+)
+
+func put(bucket *bolt.Bucket, id string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(id), data)
+}
+
+func getWebhook(tx *bolt.Tx, id string) (*storage.Webhook, error) {
+	data := tx.Bucket(webhooksBucket).Get([]byte(id))
+	if data == nil {
+		return nil, errs.ErrNotFoundWebhook{ID: id}
+	}
+	var webhook storage.Webhook
+	if err := json.Unmarshal(data, &webhook); err != nil {
+		return nil, errs.ErrGetWebhook{Err: err}
+	}
+	return &webhook, nil
+}
+
+// selectDeliveries returns deliveries matching filter in order of their IDs, i.e. of creation.
+func (s *Storage) selectDeliveries(filter func(delivery *storage.WebhookDelivery) bool) (
+	[]*storage.WebhookDelivery, error,
+) {
+	deliveries := make([]*storage.WebhookDelivery, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).ForEach(func(_, data []byte) error {
+			var delivery storage.WebhookDelivery
+			if err := json.Unmarshal(data, &delivery); err != nil {
+				return err
+			}
+			if filter(&delivery) {
+				deliveries = append(deliveries, &delivery)
+			}
+			return nil
+		})
+	})
+	return deliveries, err
+}
+
+func (s *Storage) AddWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	webhook.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding webhook %s of user %s", webhook.ID, webhook.UserID)
+	if err := s.update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(webhooksBucket), webhook.ID, webhook)
+	}); err != nil {
+		return errs.ErrAddWebhook{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add webhook %s", webhook.ID)
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id string) (*storage.Webhook, error) {
+	var webhook *storage.Webhook
+	err := s.view(func(tx *bolt.Tx) error {
+		var err error
+		webhook, err = getWebhook(tx, id)
+		return err
+	})
+	return webhook, err
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting webhook %s", id)
+	err := s.update(func(tx *bolt.Tx) error {
+		if _, err := getWebhook(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Bucket(webhooksBucket).Delete([]byte(id)); err != nil {
+			return errs.ErrDeleteWebhook{Err: err}
+		}
+		cursor := tx.Bucket(deliveriesBucket).Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			var delivery storage.WebhookDelivery
+			if err := json.Unmarshal(data, &delivery); err != nil {
+				return errs.ErrDeleteWebhook{Err: err}
+			}
+			if delivery.WebhookID != id {
+				continue
+			}
+			if err := cursor.Delete(); err != nil {
+				return errs.ErrDeleteWebhook{Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.log.Debug().Msgf("Successfully deleted webhook %s", id)
+	return nil
+}
+
+func (s *Storage) ListUsersWebhooks(ctx context.Context, userIDs []string) ([]*storage.Webhook, error) {
+	webhooks := make([]*storage.Webhook, 0)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(webhooksBucket).ForEach(func(_, data []byte) error {
+			var webhook storage.Webhook
+			if err := json.Unmarshal(data, &webhook); err != nil {
+				return err
+			}
+			for _, userID := range userIDs {
+				if webhook.UserID == userID {
+					webhooks = append(webhooks, &webhook)
+					break
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errs.ErrListWebhooks{Err: err}
+	}
+	return webhooks, nil
+}
+
+func (s *Storage) AddWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	delivery.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding delivery %s to webhook %s", delivery.ID, delivery.WebhookID)
+	return s.update(func(tx *bolt.Tx) error {
+		if _, err := getWebhook(tx, delivery.WebhookID); err != nil {
+			return err
+		}
+		if err := put(tx.Bucket(deliveriesBucket), delivery.ID, delivery); err != nil {
+			return errs.ErrSaveWebhookDelivery{Err: err}
+		}
+		return nil
+	})
+}
+
+// UpdateWebhookDelivery does nothing if the delivery was removed with its webhook.
+func (s *Storage) UpdateWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	s.log.Debug().Msgf("Start updating delivery %s", delivery.ID)
+	err := s.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(deliveriesBucket)
+		if bucket.Get([]byte(delivery.ID)) == nil {
+			return nil
+		}
+		return put(bucket, delivery.ID, delivery)
+	})
+	if err != nil {
+		return errs.ErrSaveWebhookDelivery{Err: err}
+	}
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error) {
+	deliveries, err := s.selectDeliveries(func(delivery *storage.WebhookDelivery) bool {
+		return delivery.WebhookID == webhookID
+	})
+	if err != nil {
+		return nil, errs.ErrListWebhookDeliveries{Err: err}
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) ListDueWebhookDeliveries(
+	ctx context.Context, now time.Time, limit int,
+) ([]*storage.WebhookDelivery, error) {
+	deliveries, err := s.selectDeliveries(func(delivery *storage.WebhookDelivery) bool {
+		return delivery.Status == storage.DeliveryStatusPending && !delivery.NextAttemptAt.After(now)
+	})
+	if err != nil {
+		return nil, errs.ErrListWebhookDeliveries{Err: err}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
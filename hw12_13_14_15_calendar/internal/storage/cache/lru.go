@@ -0,0 +1,81 @@
+package cachestorage
+
+import (
+	"container/list"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// lru keeps up to maxEntries values, each for ttl. It is not safe for concurrent use.
+type lru struct {
+	maxEntries int
+	ttl        time.Duration
+
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRU(maxEntries int, ttl time.Duration) *lru {
+	return &lru{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *lru) get(key string, now time.Time) (interface{}, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !now.Before(entry.expiresAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set stores the value and reports whether the least recently used value was evicted to fit it.
+func (c *lru) set(key string, value interface{}, now time.Time) (evicted bool) {
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = now.Add(c.ttl)
+		c.order.MoveToFront(elem)
+		return false
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: now.Add(c.ttl)})
+	if c.order.Len() <= c.maxEntries {
+		return false
+	}
+	c.removeElement(c.order.Back())
+	return true
+}
+
+func (c *lru) remove(key string) {
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lru) clear() {
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *lru) len() int {
+	return c.order.Len()
+}
+
+func (c *lru) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,276 @@
+// Package cachestorage contains app.Storage decorator which caches event lookups and range listings.
+package cachestorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Stats are counters of the cache since it was created.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	// Events and Ranges are the numbers of cached event lookups and range listings.
+	Events int `json:"events"`
+	Ranges int `json:"ranges"`
+}
+
+type cache struct {
+	mu     sync.Mutex
+	events *lru
+	ranges *lru
+	// generation is increased by every invalidation, values loaded before it are not cached
+	generation uint64
+	stats      Stats
+	now        func() time.Time
+}
+
+// Storage caches results of GetEvent, ListEventsInRange and ListUsersEventsInRange of the wrapped
+// storage. Changes made through Storage invalidate the cache, changes made by other processes
+// become visible when cached values expire.
+//
+// Reads from ctx with app.ContextWithReadYourWrites and reads in transactions bypass the cache.
+type Storage struct {
+	app.Storage
+	cache *cache
+
+	// changes is set for storage passed to WithTx callback, changed events are
+	// invalidated when the transaction is finished
+	changes *changes
+}
+
+type changes struct {
+	mu       sync.Mutex
+	eventIDs []string
+}
+
+func (c *changes) add(eventID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.eventIDs = append(c.eventIDs, eventID)
+}
+
+// New returns storage which caches up to maxEntries event lookups and up to maxEntries
+// range listings of st for ttl.
+func New(st app.Storage, maxEntries int, ttl time.Duration) *Storage {
+	return &Storage{
+		Storage: st,
+		cache: &cache{
+			events: newLRU(maxEntries, ttl),
+			ranges: newLRU(maxEntries, ttl),
+			now:    time.Now,
+		},
+	}
+}
+
+// Stats returns counters of the cache.
+func (s *Storage) Stats() Stats {
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+	stats := s.cache.stats
+	stats.Events = s.cache.events.len()
+	stats.Ranges = s.cache.ranges.len()
+	return stats
+}
+
+func (c *cache) get(values *lru, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := values.get(key, c.now())
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	return value, ok
+}
+
+func (c *cache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// set caches value loaded at generation, unless the cache was invalidated since then.
+func (c *cache) set(values *lru, generation uint64, key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if values.set(key, value, c.now()) {
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops the events and all range listings, as any change may affect them.
+func (c *cache) invalidate(eventIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, id := range eventIDs {
+		c.events.remove(id)
+	}
+	c.ranges.clear()
+}
+
+func (s *Storage) cacheable(ctx context.Context) bool {
+	return s.changes == nil && !app.ReadYourWritesFromContext(ctx)
+}
+
+// changed invalidates the event after it was changed. In a transaction
+// the event is invalidated when the transaction is finished.
+func (s *Storage) changed(eventID string) {
+	if s.changes != nil {
+		s.changes.add(eventID)
+		return
+	}
+	s.cache.invalidate(eventID)
+}
+
+func (s *Storage) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	if s.cacheable(ctx) {
+		if event, ok := s.cache.get(s.cache.events, id); ok {
+			return event.(*storage.Event).Clone(), nil
+		}
+	}
+	generation := s.cache.currentGeneration()
+	event, err := s.Storage.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.changes == nil {
+		s.cache.set(s.cache.events, generation, id, event.Clone())
+	}
+	return event, nil
+}
+
+func (s *Storage) ListEventsInRange(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	return s.listRange(ctx, rangeKey(nil, from, to), func() ([]*storage.Event, error) {
+		return s.Storage.ListEventsInRange(ctx, from, to)
+	})
+}
+
+func (s *Storage) ListUsersEventsInRange(
+	ctx context.Context,
+	userIDs []string,
+	from, to time.Time,
+) ([]*storage.Event, error) {
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+	return s.listRange(ctx, rangeKey(userIDs, from, to), func() ([]*storage.Event, error) {
+		return s.Storage.ListUsersEventsInRange(ctx, userIDs, from, to)
+	})
+}
+
+// rangeKey identifies range listing of events of userIDs, nil userIDs stands for all events.
+func rangeKey(userIDs []string, from, to time.Time) string {
+	if userIDs == nil {
+		return fmt.Sprintf("all/%d/%d", from.UnixNano(), to.UnixNano())
+	}
+	return fmt.Sprintf("users/%d/%d/%s", from.UnixNano(), to.UnixNano(), strings.Join(userIDs, "\x00"))
+}
+
+func (s *Storage) listRange(
+	ctx context.Context,
+	key string,
+	list func() ([]*storage.Event, error),
+) ([]*storage.Event, error) {
+	if s.cacheable(ctx) {
+		if events, ok := s.cache.get(s.cache.ranges, key); ok {
+			return cloneEvents(events.([]*storage.Event)), nil
+		}
+	}
+	generation := s.cache.currentGeneration()
+	events, err := list()
+	if err != nil {
+		return nil, err
+	}
+	if s.changes == nil {
+		s.cache.set(s.cache.ranges, generation, key, cloneEvents(events))
+	}
+	return events, nil
+}
+
+func cloneEvents(events []*storage.Event) []*storage.Event {
+	if events == nil {
+		return nil
+	}
+	cloned := make([]*storage.Event, 0, len(events))
+	for _, event := range events {
+		cloned = append(cloned, event.Clone())
+	}
+	return cloned
+}
+
+func (s *Storage) AddEvent(ctx context.Context, event *storage.Event) error {
+	err := s.Storage.AddEvent(ctx, event)
+	// a failed change may still be applied, e.g. when the response was lost
+	s.changed(event.ID)
+	return err
+}
+
+func (s *Storage) ModifyEvent(ctx context.Context, event *storage.Event) error {
+	err := s.Storage.ModifyEvent(ctx, event)
+	s.changed(event.ID)
+	return err
+}
+
+func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
+	err := s.Storage.DeleteEvent(ctx, id)
+	s.changed(id)
+	return err
+}
+
+func (s *Storage) RestoreEvent(ctx context.Context, id string) error {
+	err := s.Storage.RestoreEvent(ctx, id)
+	s.changed(id)
+	return err
+}
+
+func (s *Storage) AddAttendees(ctx context.Context, eventID string, userIDs []string) error {
+	err := s.Storage.AddAttendees(ctx, eventID, userIDs)
+	s.changed(eventID)
+	return err
+}
+
+func (s *Storage) SetAttendeeStatus(
+	ctx context.Context,
+	eventID, userID string,
+	status storage.AttendeeStatus,
+) error {
+	err := s.Storage.SetAttendeeStatus(ctx, eventID, userID, status)
+	s.changed(eventID)
+	return err
+}
+
+// PurgeDeletedEvents does not touch the cache, events in the trash are neither returned
+// by GetEvent nor listed.
+func (s *Storage) PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return s.Storage.PurgeDeletedEvents(ctx, deletedBefore)
+}
+
+func (s *Storage) WithTx(ctx context.Context, fn func(tx app.Storage) error) error {
+	if s.changes != nil {
+		return s.Storage.WithTx(ctx, func(tx app.Storage) error {
+			return fn(&Storage{Storage: tx, cache: s.cache, changes: s.changes})
+		})
+	}
+	txChanges := &changes{}
+	err := s.Storage.WithTx(ctx, func(tx app.Storage) error {
+		return fn(&Storage{Storage: tx, cache: s.cache, changes: txChanges})
+	})
+	// the cache is invalidated even after rollback, the changes might be committed anyway
+	if len(txChanges.eventIDs) > 0 {
+		s.cache.invalidate(txChanges.eventIDs...)
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package cachestorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStorage counts reads which reach the wrapped storage.
+type countingStorage struct {
+	app.Storage
+	gets  int
+	lists int
+}
+
+func (s *countingStorage) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
+	s.gets++
+	return s.Storage.GetEvent(ctx, id)
+}
+
+func (s *countingStorage) ListEventsInRange(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.lists++
+	return s.Storage.ListEventsInRange(ctx, from, to)
+}
+
+func newTestStorage(t *testing.T, maxEntries int) (*Storage, *countingStorage, *time.Time) {
+	t.Helper()
+	backend := &countingStorage{Storage: memorystorage.New(logger.New("error"))}
+	s := New(backend, maxEntries, time.Minute)
+	now := time.Now()
+	s.cache.now = func() time.Time { return now }
+	return s, backend, &now
+}
+
+func addEvent(t *testing.T, s app.Storage, title string, start time.Time) *storage.Event {
+	t.Helper()
+	event := &storage.Event{Title: title, StartAt: start, EndAt: start.Add(time.Hour), TimeZone: "UTC"}
+	require.NoError(t, s.AddEvent(context.Background(), event))
+	return event
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		t.Helper()
+		return New(memorystorage.New(logger.New("error")), 100, time.Minute)
+	})
+}
+
+func TestGetEvent(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("caches event until it is changed", func(t *testing.T) {
+		s, backend, _ := newTestStorage(t, 10)
+		event := addEvent(t, s, "title", time.Now())
+
+		for i := 0; i < 3; i++ {
+			got, err := s.GetEvent(ctx, event.ID)
+			require.NoError(t, err)
+			require.Equal(t, "title", got.Title)
+		}
+		require.Equal(t, 1, backend.gets)
+		require.Equal(t, Stats{Hits: 2, Misses: 1, Events: 1}, s.Stats())
+
+		event.Title = "changed"
+		require.NoError(t, s.ModifyEvent(ctx, event))
+		got, err := s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "changed", got.Title)
+		require.Equal(t, 2, backend.gets)
+
+		require.NoError(t, s.DeleteEvent(ctx, event.ID))
+		_, err = s.GetEvent(ctx, event.ID)
+		require.Error(t, err)
+	})
+
+	t.Run("cached event is not affected by caller", func(t *testing.T) {
+		s, _, _ := newTestStorage(t, 10)
+		event := addEvent(t, s, "title", time.Now())
+
+		got, err := s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		got.Title = "changed outside"
+
+		got, err = s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "title", got.Title)
+	})
+
+	t.Run("expires after ttl", func(t *testing.T) {
+		s, backend, now := newTestStorage(t, 10)
+		event := addEvent(t, s, "title", time.Now())
+
+		_, err := s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		*now = now.Add(time.Minute)
+		_, err = s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, backend.gets)
+	})
+
+	t.Run("evicts least recently used event", func(t *testing.T) {
+		s, backend, _ := newTestStorage(t, 2)
+		first := addEvent(t, s, "first", time.Now())
+		second := addEvent(t, s, "second", time.Now())
+		third := addEvent(t, s, "third", time.Now())
+
+		for _, id := range []string{first.ID, second.ID, first.ID, third.ID, first.ID, second.ID} {
+			_, err := s.GetEvent(ctx, id)
+			require.NoError(t, err)
+		}
+		// second is evicted by third and read again
+		require.Equal(t, 4, backend.gets)
+		require.Equal(t, Stats{Hits: 2, Misses: 4, Evictions: 2, Events: 2}, s.Stats())
+	})
+
+	t.Run("read your writes bypasses cache", func(t *testing.T) {
+		s, backend, _ := newTestStorage(t, 10)
+		event := addEvent(t, s, "title", time.Now())
+
+		_, err := s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		_, err = s.GetEvent(app.ContextWithReadYourWrites(ctx), event.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, backend.gets)
+	})
+}
+
+func TestListEventsInRange(t *testing.T) {
+	ctx := context.Background()
+	s, backend, _ := newTestStorage(t, 10)
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	event := addEvent(t, s, "title", from.Add(time.Hour))
+
+	list := func() []*storage.Event {
+		t.Helper()
+		events, err := s.ListEventsInRange(ctx, from, to)
+		require.NoError(t, err)
+		return events
+	}
+	require.Len(t, list(), 1)
+	list()[0].Title = "changed outside"
+	require.Equal(t, "title", list()[0].Title)
+	require.Equal(t, 1, backend.lists)
+
+	// a change of any event may affect the listing
+	addEvent(t, s, "other", from.Add(2*time.Hour))
+	require.Len(t, list(), 2)
+	require.Equal(t, 2, backend.lists)
+
+	require.NoError(t, s.AddAttendees(ctx, event.ID, []string{"bob"}))
+	list()
+	require.Equal(t, 3, backend.lists)
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	s, backend, _ := newTestStorage(t, 10)
+	event := addEvent(t, s, "title", time.Now())
+	_, err := s.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+
+	err = s.WithTx(ctx, func(tx app.Storage) error {
+		event.Title = "changed"
+		if err := tx.ModifyEvent(ctx, event); err != nil {
+			return err
+		}
+		// reads in the transaction see its changes
+		got, err := tx.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "changed", got.Title)
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err := s.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, "changed", got.Title)
+	require.Equal(t, 2, backend.gets)
+
+	errRollback := errors.New("rollback")
+	err = s.WithTx(ctx, func(tx app.Storage) error {
+		return tx.DeleteEvent(ctx, event.ID)
+	})
+	require.NoError(t, err)
+	err = s.WithTx(ctx, func(tx app.Storage) error {
+		require.NoError(t, tx.RestoreEvent(ctx, event.ID))
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	_, err = s.GetEvent(ctx, event.ID)
+	require.Error(t, err)
+}
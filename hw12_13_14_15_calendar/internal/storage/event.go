@@ -1,7 +1,55 @@
 package storage
 
+import "time"
+
 type Event struct {
-	ID    string `db:"id"`
-	Title string `db:"title"`
+	ID    string `db:"id" json:"id"`
+	Title string `db:"title" json:"title"`
+	// Description is a long text, it is optional.
+	Description string `db:"description" json:"description,omitempty"`
+	// StartAt and EndAt bound the time when the event takes place, they are stored in UTC.
+	StartAt time.Time `db:"start_at" json:"start_at"`
+	EndAt   time.Time `db:"end_at" json:"end_at"`
+	// TimeZone is IANA name of the zone the event is planned in.
+	TimeZone string `db:"time_zone" json:"time_zone"`
+	// AllDay events last whole days from midnight to midnight in their TimeZone.
+	AllDay bool `db:"all_day" json:"all_day"`
+	// NotifyBefore is how long before StartAt the reminder is sent, zero means no reminder.
+	NotifyBefore time.Duration `db:"notify_before" json:"notify_before,omitempty"`
+	// UserID is the owner of the event.
+	UserID    string     `db:"user_id" json:"user_id"`
+	Attendees []Attendee `db:"-" json:"attendees,omitempty"`
+	// Tags label the event, they are unique and sorted, see Tag.
+	Tags []string `db:"-" json:"tags,omitempty"`
+	// Resources are IDs of rooms and equipment booked for the event, they are unique and sorted.
+	Resources []string `db:"-" json:"resources,omitempty"`
+	// DeletedAt is set when event is moved to the trash.
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 	// TODO
 }
+
+// NotifyAt returns the time to send the reminder about the event, it is zero if no reminder is needed.
+func (e *Event) NotifyAt() time.Time {
+	if e.NotifyBefore <= 0 {
+		return time.Time{}
+	}
+	return e.StartAt.Add(-e.NotifyBefore)
+}
+
+// Clone returns a copy of the event, so it can be kept as a snapshot.
+func (e Event) Clone() *Event {
+	if e.Attendees != nil {
+		e.Attendees = append([]Attendee(nil), e.Attendees...)
+	}
+	if e.Tags != nil {
+		e.Tags = append([]string(nil), e.Tags...)
+	}
+	if e.Resources != nil {
+		e.Resources = append([]string(nil), e.Resources...)
+	}
+	if e.DeletedAt != nil {
+		deletedAt := *e.DeletedAt
+		e.DeletedAt = &deletedAt
+	}
+	return &e
+}
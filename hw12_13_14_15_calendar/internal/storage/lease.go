@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// Leases is a lease table for storages which are not shared between processes.
+type Leases struct {
+	mu     sync.Mutex
+	leases map[string]lease
+}
+
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func NewLeases() *Leases {
+	return &Leases{leases: make(map[string]lease)}
+}
+
+// Acquire takes the lease for holder until now+ttl unless another holder has it and it is not expired.
+func (l *Leases) Acquire(name, holder string, ttl time.Duration, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	current, ok := l.leases[name]
+	if ok && current.holder != holder && now.Before(current.expiresAt) {
+		return false
+	}
+	l.leases[name] = lease{holder: holder, expiresAt: now.Add(ttl)}
+	return true
+}
+
+func (l *Leases) Release(name, holder string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if current, ok := l.leases[name]; ok && current.holder == holder {
+		delete(l.leases, name)
+	}
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeases(t *testing.T) {
+	now := time.Date(2022, time.November, 20, 10, 0, 0, 0, time.UTC)
+	leases := NewLeases()
+
+	require.True(t, leases.Acquire("scheduler", "first", time.Minute, now))
+	require.False(t, leases.Acquire("scheduler", "second", time.Minute, now.Add(59*time.Second)))
+	require.True(t, leases.Acquire("other", "second", time.Minute, now))
+	// renewal extends the lease
+	require.True(t, leases.Acquire("scheduler", "first", time.Minute, now.Add(30*time.Second)))
+	require.False(t, leases.Acquire("scheduler", "second", time.Minute, now.Add(80*time.Second)))
+	// expired lease is taken over
+	require.True(t, leases.Acquire("scheduler", "second", time.Minute, now.Add(90*time.Second)))
+	require.False(t, leases.Acquire("scheduler", "first", time.Minute, now.Add(90*time.Second)))
+
+	leases.Release("scheduler", "first")
+	require.False(t, leases.Acquire("scheduler", "first", time.Minute, now.Add(90*time.Second)))
+	leases.Release("scheduler", "second")
+	require.True(t, leases.Acquire("scheduler", "first", time.Minute, now.Add(90*time.Second)))
+}
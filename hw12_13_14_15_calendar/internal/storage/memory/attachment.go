@@ -0,0 +1,88 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+)
+
+func (s *Storage) AddAttachment(ctx context.Context, attachment *storage.Attachment) error {
+	attachment.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding attachment %s to event %s", attachment.ID, attachment.EventID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event, ok := s.data[attachment.EventID]; !ok || event.DeletedAt != nil {
+		return errs.ErrNotFoundEvent{ID: attachment.EventID}
+	}
+	if err := s.commit(walRecord{Op: walOpPutAttachment, Attachment: attachment.Clone()}); err != nil {
+		return errs.ErrAddAttachment{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add attachment %s", attachment.ID)
+	return nil
+}
+
+func (s *Storage) GetAttachment(ctx context.Context, id string) (*storage.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	attachment, ok := s.attachments[id]
+	if !ok {
+		return nil, errs.ErrNotFoundAttachment{ID: id}
+	}
+	return attachment.Clone(), nil
+}
+
+func (s *Storage) ListEventAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error) {
+	attachments := make([]*storage.Attachment, 0)
+	s.mu.RLock()
+	for _, attachment := range s.attachments {
+		if attachment.EventID == eventID {
+			attachments = append(attachments, attachment.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	sortAttachments(attachments)
+	return attachments, nil
+}
+
+func (s *Storage) DeleteAttachment(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting attachment %s", id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.attachments[id]; !ok {
+		return errs.ErrNotFoundAttachment{ID: id}
+	}
+	if err := s.commit(walRecord{Op: walOpDeleteAttachments, IDs: []string{id}}); err != nil {
+		return errs.ErrDeleteAttachment{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted attachment %s", id)
+	return nil
+}
+
+func (s *Storage) ListOrphanedAttachments(ctx context.Context, limit int) ([]*storage.Attachment, error) {
+	attachments := make([]*storage.Attachment, 0)
+	if limit <= 0 {
+		return attachments, nil
+	}
+	s.mu.RLock()
+	for _, attachment := range s.attachments {
+		if _, ok := s.data[attachment.EventID]; !ok {
+			attachments = append(attachments, attachment.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	sortAttachments(attachments)
+	if len(attachments) > limit {
+		attachments = attachments[:limit]
+	}
+	return attachments, nil
+}
+
+// sortAttachments sorts attachments from the oldest to the newest, IDs are ordered by time.
+func sortAttachments(attachments []*storage.Attachment) {
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ID < attachments[j].ID
+	})
+}
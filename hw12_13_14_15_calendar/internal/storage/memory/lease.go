@@ -0,0 +1,15 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Storage) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return s.leases.Acquire(name, holder, ttl, time.Now()), nil
+}
+
+func (s *Storage) ReleaseLease(ctx context.Context, name, holder string) error {
+	s.leases.Release(name, holder)
+	return nil
+}
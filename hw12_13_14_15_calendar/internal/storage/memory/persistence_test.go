@@ -0,0 +1,210 @@
+package memorystorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func connectPersistent(t *testing.T, dir string) *Storage {
+	t.Helper()
+	s := NewPersistent(logger.New("error"), dir, SyncAlways, 0, 0)
+	require.NoError(t, s.Connect(context.Background()))
+	t.Cleanup(func() {
+		require.NoError(t, s.Close(context.Background()))
+	})
+	return s
+}
+
+func addEvents(t *testing.T, s *Storage, titles ...string) []*storage.Event {
+	t.Helper()
+	events := make([]*storage.Event, 0, len(titles))
+	for _, title := range titles {
+		event := &storage.Event{Title: title}
+		require.NoError(t, s.AddEvent(context.Background(), event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func listTitles(t *testing.T, s *Storage) []string {
+	t.Helper()
+	events, err := s.ListEvents(context.Background())
+	require.NoError(t, err)
+	titles := make([]string, 0, len(events))
+	for _, event := range events {
+		titles = append(titles, event.Title)
+	}
+	return titles
+}
+
+func TestPersistentStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		t.Helper()
+		return connectPersistent(t, t.TempDir())
+	})
+
+	t.Run("state is restored after restart", func(t *testing.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+
+		s := NewPersistent(logger.New("error"), dir, SyncNever, 0, 0)
+		require.NoError(t, s.Connect(ctx))
+		events := addEvents(t, s, "first", "second", "third")
+		require.NoError(t, s.DeleteEvent(ctx, events[1].ID))
+		require.NoError(t, s.AddAttendees(ctx, events[0].ID, []string{"bob"}))
+		require.NoError(t, s.AddAuditRecord(ctx, &storage.AuditRecord{EventID: events[0].ID}))
+		require.NoError(t, s.Close(ctx))
+
+		s = connectPersistent(t, dir)
+		require.ElementsMatch(t, []string{"first", "third"}, listTitles(t, s))
+		deleted, err := s.ListDeletedEvents(ctx)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		got, err := s.GetEvent(ctx, events[0].ID)
+		require.NoError(t, err)
+		require.True(t, got.IsAttendee("bob"))
+		records, err := s.ListAuditRecords(ctx, events[0].ID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("transaction is written as one WAL record", func(t *testing.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+
+		s := connectPersistent(t, dir)
+		require.NoError(t, s.WithTx(ctx, func(tx app.Storage) error {
+			for _, title := range []string{"first", "second"} {
+				if err := tx.AddEvent(ctx, &storage.Event{Title: title}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		w, records, err := openWAL(s.walPath(), SyncNever)
+		require.NoError(t, err)
+		require.NoError(t, w.close())
+		require.Len(t, records, 1)
+		require.Len(t, records[0].Batch, 2)
+
+		restored := connectPersistent(t, dir)
+		require.ElementsMatch(t, []string{"first", "second"}, listTitles(t, restored))
+	})
+
+	t.Run("snapshot compacts WAL", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s := connectPersistent(t, dir)
+		addEvents(t, s, "first", "second")
+		require.NoError(t, s.Snapshot())
+		info, err := os.Stat(s.walPath())
+		require.NoError(t, err)
+		require.Zero(t, info.Size())
+		addEvents(t, s, "third")
+
+		// the first storage is not closed, as if the process crashed
+		restored := connectPersistent(t, dir)
+		require.ElementsMatch(t, []string{"first", "second", "third"}, listTitles(t, restored))
+	})
+
+	t.Run("crash between snapshot and WAL reset", func(t *testing.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+
+		s := connectPersistent(t, dir)
+		events := addEvents(t, s, "first")
+		require.NoError(t, s.AddAuditRecord(ctx, &storage.AuditRecord{EventID: events[0].ID}))
+		walBeforeSnapshot, err := os.ReadFile(s.walPath())
+		require.NoError(t, err)
+		require.NoError(t, s.Snapshot())
+		require.NoError(t, os.WriteFile(s.walPath(), walBeforeSnapshot, 0o600))
+
+		restored := connectPersistent(t, dir)
+		require.Equal(t, []string{"first"}, listTitles(t, restored))
+		records, err := restored.ListAuditRecords(ctx, events[0].ID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("WAL truncated in the middle of record", func(t *testing.T) {
+		for _, cut := range []int64{1, walHeaderSize / 2, walHeaderSize + 5} {
+			dir := t.TempDir()
+
+			s := connectPersistent(t, dir)
+			addEvents(t, s, "first", "second")
+			info, err := os.Stat(s.walPath())
+			require.NoError(t, err)
+			sizeWithTwoRecords := info.Size()
+			addEvents(t, s, "torn")
+			info, err = os.Stat(s.walPath())
+			require.NoError(t, err)
+			lastRecordSize := info.Size() - sizeWithTwoRecords
+			require.NoError(t, os.Truncate(s.walPath(), sizeWithTwoRecords+lastRecordSize-cut))
+
+			restored := connectPersistent(t, dir)
+			require.ElementsMatch(t, []string{"first", "second"}, listTitles(t, restored))
+
+			// torn tail is cut off, so new records are readable after it
+			addEvents(t, restored, "after crash")
+			require.ElementsMatch(t, []string{"first", "second", "after crash"},
+				listTitles(t, connectPersistent(t, dir)))
+		}
+	})
+
+	t.Run("corrupted record is dropped", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s := connectPersistent(t, dir)
+		addEvents(t, s, "first", "corrupted")
+		data, err := os.ReadFile(s.walPath())
+		require.NoError(t, err)
+		data[len(data)-2] ^= 0xff
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "wal.log"), data, 0o600))
+
+		require.Equal(t, []string{"first"}, listTitles(t, connectPersistent(t, dir)))
+	})
+
+	t.Run("record with corrupted length is dropped", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s := connectPersistent(t, dir)
+		addEvents(t, s, "first")
+		info, err := os.Stat(s.walPath())
+		require.NoError(t, err)
+		addEvents(t, s, "corrupted")
+		data, err := os.ReadFile(s.walPath())
+		require.NoError(t, err)
+		// the length of the last record becomes 4 GiB
+		copy(data[info.Size():], []byte{0xff, 0xff, 0xff, 0xff})
+		require.NoError(t, os.WriteFile(s.walPath(), data, 0o600))
+
+		restored := connectPersistent(t, dir)
+		require.Equal(t, []string{"first"}, listTitles(t, restored))
+		addEvents(t, restored, "after crash")
+		require.ElementsMatch(t, []string{"first", "after crash"}, listTitles(t, connectPersistent(t, dir)))
+	})
+
+	t.Run("background sync and snapshots", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s := NewPersistent(logger.New("error"), dir, SyncInterval, 10*time.Millisecond, 20*time.Millisecond)
+		require.NoError(t, s.Connect(context.Background()))
+		addEvents(t, s, "first")
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(s.snapshotPath())
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		require.NoError(t, s.Close(context.Background()))
+
+		require.Equal(t, []string{"first"}, listTitles(t, connectPersistent(t, dir)))
+	})
+}
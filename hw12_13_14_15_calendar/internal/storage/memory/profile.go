@@ -0,0 +1,30 @@
+package memorystorage
+
+import (
+	"context"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (s *Storage) SaveUserProfile(ctx context.Context, profile *storage.UserProfile) error {
+	s.log.Debug().Msgf("Start saving profile of user %s", profile.UserID)
+	s.mu.Lock()
+	err := s.commit(walRecord{Op: walOpPutProfile, Profile: profile.Clone()})
+	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrSaveUserProfile{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved profile of user %s", profile.UserID)
+	return nil
+}
+
+func (s *Storage) GetUserProfile(ctx context.Context, userID string) (*storage.UserProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	profile, ok := s.profiles[userID]
+	if !ok {
+		return nil, errs.ErrNotFoundUserProfile{UserID: userID}
+	}
+	return profile.Clone(), nil
+}
@@ -0,0 +1,72 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (s *Storage) ListEventsToNotify(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events to notify from %v to %v", from, to)
+	events := make([]*storage.Event, 0)
+	s.mu.RLock()
+	for _, event := range s.data {
+		if event.DeletedAt != nil || event.NotifyBefore <= 0 {
+			continue
+		}
+		if notifyAt := event.NotifyAt(); notifyAt.After(from) && !notifyAt.After(to) {
+			events = append(events, event.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	sortByStart(events)
+	s.log.Debug().Msgf("Successfully listed events to notify, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) AddReminder(ctx context.Context, reminder *storage.Reminder) error {
+	s.log.Debug().Msgf("Start adding reminder %s", reminder.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.reminders[reminder.ID]; ok {
+		return errs.ErrReminderExists{ID: reminder.ID}
+	}
+	if err := s.commit(walRecord{Op: walOpPutReminder, Reminder: reminder.Clone()}); err != nil {
+		return errs.ErrAddReminder{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully added reminder %s", reminder.ID)
+	return nil
+}
+
+func (s *Storage) GetReminder(ctx context.Context, id string) (*storage.Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reminder, ok := s.reminders[id]
+	if !ok {
+		return nil, errs.ErrNotFoundReminder{ID: id}
+	}
+	return reminder.Clone(), nil
+}
+
+func (s *Storage) DeleteReminders(ctx context.Context, handledBefore time.Time) (int, error) {
+	s.log.Debug().Msgf("Start deleting reminders handled before %v", handledBefore)
+	ids := make([]string, 0)
+	s.mu.Lock()
+	for id, reminder := range s.reminders {
+		if reminder.HandledAt.Before(handledBefore) {
+			ids = append(ids, id)
+		}
+	}
+	var err error
+	if len(ids) > 0 {
+		err = s.commit(walRecord{Op: walOpDeleteReminders, IDs: ids})
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return 0, errs.ErrDeleteReminders{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted reminders, total: %d", len(ids))
+	return len(ids), nil
+}
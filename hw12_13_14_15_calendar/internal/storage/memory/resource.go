@@ -0,0 +1,98 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+)
+
+// checkResourcesFree fails if resources of the event are booked for other events at the same time.
+// Callers must hold the write lock, so nothing is booked between the check and the change.
+func (s *Storage) checkResourcesFree(event *storage.Event) error {
+	if len(event.Resources) == 0 || event.DeletedAt != nil {
+		return nil
+	}
+	events := make([]*storage.Event, 0, len(s.data))
+	for _, other := range s.data {
+		events = append(events, other)
+	}
+	return storage.CheckResourcesFree(event, events)
+}
+
+func (s *Storage) AddResource(ctx context.Context, resource *storage.Resource) error {
+	resource.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding resource with id %s", resource.ID)
+	added := *resource
+	s.mu.Lock()
+	err := s.commit(walRecord{Op: walOpPutResource, Resource: &added})
+	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrAddResource{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add resource with id %s", resource.ID)
+	return nil
+}
+
+func (s *Storage) ModifyResource(ctx context.Context, resource *storage.Resource) error {
+	s.log.Debug().Msgf("Start modifying resource with id %s", resource.ID)
+	modified := *resource
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.resources[resource.ID]; !ok {
+		return errs.ErrNotFoundResource{ID: resource.ID}
+	}
+	if err := s.commit(walRecord{Op: walOpPutResource, Resource: &modified}); err != nil {
+		return errs.ErrUpdateResource{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully modified resource with id %s", resource.ID)
+	return nil
+}
+
+func (s *Storage) GetResource(ctx context.Context, id string) (*storage.Resource, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	resource, ok := s.resources[id]
+	if !ok {
+		return nil, errs.ErrNotFoundResource{ID: id}
+	}
+	got := *resource
+	return &got, nil
+}
+
+func (s *Storage) ListResources(ctx context.Context) ([]*storage.Resource, error) {
+	s.mu.RLock()
+	resources := make([]*storage.Resource, 0, len(s.resources))
+	for _, resource := range s.resources {
+		listed := *resource
+		resources = append(resources, &listed)
+	}
+	s.mu.RUnlock()
+	storage.SortResources(resources)
+	return resources, nil
+}
+
+func (s *Storage) ListResourcesEventsInRange(
+	ctx context.Context, resourceIDs []string, from, to time.Time,
+) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of resources %v from %v to %v", resourceIDs, from, to)
+	events := make([]*storage.Event, 0)
+	s.mu.RLock()
+	for _, event := range s.data {
+		if event.DeletedAt != nil || !event.Overlaps(from, to) {
+			continue
+		}
+		for _, resourceID := range resourceIDs {
+			if event.UsesResource(resourceID) {
+				events = append(events, event.Clone())
+				break
+			}
+		}
+	}
+	s.mu.RUnlock()
+	sortByStart(events)
+	s.log.Debug().Msgf("Successfully listed events of resources %v, total: %d", resourceIDs, len(events))
+	return events, nil
+}
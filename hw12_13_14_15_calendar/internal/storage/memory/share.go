@@ -0,0 +1,72 @@
+package memorystorage
+
+import (
+	"context"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// shareKey identifies a share, the owner has a single share with each user.
+type shareKey struct {
+	ownerID string
+	userID  string
+}
+
+func keyOfShare(share *storage.Share) shareKey {
+	return shareKey{ownerID: share.OwnerID, userID: share.UserID}
+}
+
+func (s *Storage) SaveShare(ctx context.Context, share *storage.Share) error {
+	s.log.Debug().Msgf("Start saving share of calendar of %s with %s", share.OwnerID, share.UserID)
+	saved := *share
+	s.mu.Lock()
+	err := s.commit(walRecord{Op: walOpPutShare, Share: &saved})
+	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrSaveShare{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved share of calendar of %s with %s", share.OwnerID, share.UserID)
+	return nil
+}
+
+func (s *Storage) DeleteShare(ctx context.Context, ownerID, userID string) error {
+	s.log.Debug().Msgf("Start deleting share of calendar of %s with %s", ownerID, userID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	share, ok := s.shares[shareKey{ownerID: ownerID, userID: userID}]
+	if !ok {
+		return errs.ErrNotFoundShare{OwnerID: ownerID, UserID: userID}
+	}
+	if err := s.commit(walRecord{Op: walOpDeleteShare, Share: share}); err != nil {
+		return errs.ErrDeleteShare{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted share of calendar of %s with %s", ownerID, userID)
+	return nil
+}
+
+func (s *Storage) ListOwnerShares(ctx context.Context, ownerID string) ([]*storage.Share, error) {
+	return s.listShares(func(key shareKey) bool {
+		return key.ownerID == ownerID
+	}), nil
+}
+
+func (s *Storage) ListUserShares(ctx context.Context, userID string) ([]*storage.Share, error) {
+	return s.listShares(func(key shareKey) bool {
+		return key.userID == userID
+	}), nil
+}
+
+func (s *Storage) listShares(match func(key shareKey) bool) []*storage.Share {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	shares := make([]*storage.Share, 0)
+	for key, share := range s.shares {
+		if match(key) {
+			copied := *share
+			shares = append(shares, &copied)
+		}
+	}
+	storage.SortShares(shares)
+	return shares
+}
@@ -0,0 +1,75 @@
+package memorystorage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// snapshot is a compacted state of the storage. Seq is the last WAL record included
+// in it, older records are skipped on replay.
+type snapshot struct {
+	Seq         uint64                     `json:"seq"`
+	Events      []*storage.Event           `json:"events"`
+	Audit       []*storage.AuditRecord     `json:"audit"`
+	Webhooks    []*storage.Webhook         `json:"webhooks"`
+	Deliveries  []*storage.WebhookDelivery `json:"deliveries"`
+	Profiles    []*storage.UserProfile     `json:"profiles"`
+	Reminders   []*storage.Reminder        `json:"reminders"`
+	Tags        []*storage.Tag             `json:"tags"`
+	Shares      []*storage.Share           `json:"shares"`
+	Resources   []*storage.Resource        `json:"resources"`
+	Attachments []*storage.Attachment      `json:"attachments"`
+}
+
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snap snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// writeSnapshot replaces the snapshot atomically: it is written to a temporary file,
+// which is renamed over the old one.
+func writeSnapshot(path string, snap *snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
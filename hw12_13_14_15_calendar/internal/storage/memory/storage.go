@@ -2,7 +2,12 @@ package memorystorage
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
@@ -13,28 +18,404 @@ import (
 
 type Storage struct {
 	app.Storage
-	data map[string]*storage.Event
+	data        map[string]*storage.Event
+	audit       []*storage.AuditRecord
+	webhooks    map[string]*storage.Webhook
+	deliveries  map[string]*storage.WebhookDelivery
+	profiles    map[string]*storage.UserProfile
+	reminders   map[string]*storage.Reminder
+	tags        map[tagKey]*storage.Tag
+	shares      map[shareKey]*storage.Share
+	resources   map[string]*storage.Resource
+	attachments map[string]*storage.Attachment
+	leases      *storage.Leases
 
-	getID xid.ID
-	mu    sync.RWMutex
-	log   app.Logger
+	mu  sync.RWMutex
+	log app.Logger
+
+	// persistence is optional, see NewPersistent
+	dir              string
+	sync             SyncPolicy
+	syncInterval     time.Duration
+	snapshotInterval time.Duration
+	wal              *wal
+	seq              uint64
+	stop             context.CancelFunc
+	stopped          chan struct{}
+
+	// tx is set for storage passed to WithTx callback, its mutations are collected
+	// in pending and committed to the parent storage as a single batch
+	tx      bool
+	pending []walRecord
 }
 
 func New(log *logger.Logger) *Storage {
 	return &Storage{
-		data:  make(map[string]*storage.Event),
-		getID: xid.New(),
-		mu:    sync.RWMutex{},
-		log:   log,
+		data:        make(map[string]*storage.Event),
+		webhooks:    make(map[string]*storage.Webhook),
+		deliveries:  make(map[string]*storage.WebhookDelivery),
+		profiles:    make(map[string]*storage.UserProfile),
+		reminders:   make(map[string]*storage.Reminder),
+		tags:        make(map[tagKey]*storage.Tag),
+		shares:      make(map[shareKey]*storage.Share),
+		resources:   make(map[string]*storage.Resource),
+		attachments: make(map[string]*storage.Attachment),
+		leases:      storage.NewLeases(),
+		mu:          sync.RWMutex{},
+		log:         log,
+	}
+}
+
+// NewPersistent returns storage which appends every mutation to a WAL in dir and
+// periodically compacts it into a snapshot. State is restored by Connect.
+func NewPersistent(
+	log *logger.Logger,
+	dir string,
+	syncPolicy SyncPolicy,
+	syncInterval, snapshotInterval time.Duration,
+) *Storage {
+	s := New(log)
+	s.dir = dir
+	s.sync = syncPolicy
+	s.syncInterval = syncInterval
+	s.snapshotInterval = snapshotInterval
+	return s
+}
+
+func (s *Storage) snapshotPath() string {
+	return filepath.Join(s.dir, "snapshot.json")
+}
+
+func (s *Storage) walPath() string {
+	return filepath.Join(s.dir, "wal.log")
+}
+
+// Connect restores state from the snapshot and the WAL and starts background syncing
+// and compaction. It does nothing for a storage created by New.
+func (s *Storage) Connect(ctx context.Context) error {
+	if s.dir == "" {
+		return nil
+	}
+	s.log.Info().Msgf("Start restoring storage from %s", s.dir)
+	switch s.sync {
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return errs.ErrPersistence{Err: fmt.Errorf("unknown sync policy %q", s.sync)}
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return errs.ErrPersistence{Err: err}
+	}
+	snap, err := readSnapshot(s.snapshotPath())
+	if err != nil {
+		return errs.ErrPersistence{Err: err}
+	}
+	w, records, err := openWAL(s.walPath(), s.sync)
+	if err != nil {
+		return errs.ErrPersistence{Err: err}
+	}
+
+	s.mu.Lock()
+	for _, event := range snap.Events {
+		s.data[event.ID] = event
+	}
+	s.audit = append(s.audit, snap.Audit...)
+	for _, webhook := range snap.Webhooks {
+		s.webhooks[webhook.ID] = webhook
+	}
+	for _, delivery := range snap.Deliveries {
+		s.deliveries[delivery.ID] = delivery
+	}
+	for _, profile := range snap.Profiles {
+		s.profiles[profile.UserID] = profile
+	}
+	for _, reminder := range snap.Reminders {
+		s.reminders[reminder.ID] = reminder
+	}
+	for _, tag := range snap.Tags {
+		s.tags[keyOfTag(tag)] = tag
+	}
+	for _, share := range snap.Shares {
+		s.shares[keyOfShare(share)] = share
+	}
+	for _, resource := range snap.Resources {
+		s.resources[resource.ID] = resource
+	}
+	for _, attachment := range snap.Attachments {
+		s.attachments[attachment.ID] = attachment
+	}
+	s.seq = snap.Seq
+	replayed := 0
+	for _, record := range records {
+		if record.Seq <= s.seq {
+			continue
+		}
+		s.apply(record)
+		s.seq = record.Seq
+		replayed++
+	}
+	s.wal = w
+	s.mu.Unlock()
+
+	ctx, s.stop = context.WithCancel(ctx)
+	s.stopped = make(chan struct{})
+	go s.runBackground(ctx)
+	s.log.Info().Msgf("Successfully restored %d events, replayed %d WAL records", len(snap.Events), replayed)
+	return nil
+}
+
+// Close stops background work and flushes the WAL.
+func (s *Storage) Close(ctx context.Context) error {
+	if s.wal == nil {
+		return nil
+	}
+	s.log.Info().Msg("Start closing storage WAL...")
+	s.stop()
+	<-s.stopped
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.wal.close(); err != nil {
+		return errs.ErrPersistence{Err: err}
+	}
+	s.wal = nil
+	s.log.Info().Msg("Successfully closed storage WAL")
+	return nil
+}
+
+func (s *Storage) runBackground(ctx context.Context) {
+	defer close(s.stopped)
+	var syncTick, snapshotTick <-chan time.Time
+	if s.sync == SyncInterval && s.syncInterval > 0 {
+		ticker := time.NewTicker(s.syncInterval)
+		defer ticker.Stop()
+		syncTick = ticker.C
+	}
+	if s.snapshotInterval > 0 {
+		ticker := time.NewTicker(s.snapshotInterval)
+		defer ticker.Stop()
+		snapshotTick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncTick:
+			s.mu.Lock()
+			err := s.wal.flush()
+			s.mu.Unlock()
+			if err != nil {
+				s.log.Error().Err(err).Msg("Failed to sync WAL")
+			}
+		case <-snapshotTick:
+			if err := s.Snapshot(); err != nil {
+				s.log.Error().Err(err).Msg("Failed to write snapshot")
+			}
+		}
+	}
+}
+
+// Snapshot writes the current state to the snapshot file and truncates the WAL.
+func (s *Storage) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.wal == nil {
+		return nil
+	}
+	s.log.Debug().Msgf("Start writing snapshot at WAL record %d", s.seq)
+	snap := &snapshot{
+		Seq:         s.seq,
+		Events:      make([]*storage.Event, 0, len(s.data)),
+		Audit:       s.audit,
+		Webhooks:    make([]*storage.Webhook, 0, len(s.webhooks)),
+		Deliveries:  make([]*storage.WebhookDelivery, 0, len(s.deliveries)),
+		Profiles:    make([]*storage.UserProfile, 0, len(s.profiles)),
+		Reminders:   make([]*storage.Reminder, 0, len(s.reminders)),
+		Tags:        make([]*storage.Tag, 0, len(s.tags)),
+		Shares:      make([]*storage.Share, 0, len(s.shares)),
+		Resources:   make([]*storage.Resource, 0, len(s.resources)),
+		Attachments: make([]*storage.Attachment, 0, len(s.attachments)),
+	}
+	for _, event := range s.data {
+		snap.Events = append(snap.Events, event)
+	}
+	for _, webhook := range s.webhooks {
+		snap.Webhooks = append(snap.Webhooks, webhook)
+	}
+	for _, delivery := range s.deliveries {
+		snap.Deliveries = append(snap.Deliveries, delivery)
+	}
+	for _, profile := range s.profiles {
+		snap.Profiles = append(snap.Profiles, profile)
+	}
+	for _, reminder := range s.reminders {
+		snap.Reminders = append(snap.Reminders, reminder)
+	}
+	for _, tag := range s.tags {
+		snap.Tags = append(snap.Tags, tag)
+	}
+	for _, share := range s.shares {
+		snap.Shares = append(snap.Shares, share)
+	}
+	for _, resource := range s.resources {
+		snap.Resources = append(snap.Resources, resource)
+	}
+	for _, attachment := range s.attachments {
+		snap.Attachments = append(snap.Attachments, attachment)
+	}
+	if err := writeSnapshot(s.snapshotPath(), snap); err != nil {
+		return errs.ErrPersistence{Err: err}
+	}
+	// records are skipped by seq on replay, so a crash before reset is harmless
+	if err := s.wal.reset(); err != nil {
+		return errs.ErrPersistence{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully wrote snapshot with %d events", len(snap.Events))
+	return nil
+}
+
+// commit writes the mutation to the WAL, if storage is persistent, and applies it.
+// Callers must hold the write lock.
+func (s *Storage) commit(record walRecord) error {
+	if s.tx {
+		s.pending = append(s.pending, record)
+	} else if s.wal != nil {
+		record.Seq = s.seq + 1
+		if err := s.wal.append(record); err != nil {
+			return err
+		}
+		s.seq = record.Seq
+	}
+	s.apply(record)
+	return nil
+}
+
+func (s *Storage) apply(record walRecord) {
+	switch record.Op {
+	case walOpPut:
+		s.data[record.Event.ID] = record.Event
+	case walOpPurge:
+		for _, id := range record.IDs {
+			delete(s.data, id)
+		}
+	case walOpAudit:
+		s.audit = append(s.audit, record.Audit)
+	case walOpPutWebhook:
+		s.webhooks[record.Webhook.ID] = record.Webhook
+	case walOpDeleteWebhook:
+		for _, id := range record.IDs {
+			delete(s.webhooks, id)
+			for deliveryID, delivery := range s.deliveries {
+				if delivery.WebhookID == id {
+					delete(s.deliveries, deliveryID)
+				}
+			}
+		}
+	case walOpPutDelivery:
+		s.deliveries[record.Delivery.ID] = record.Delivery
+	case walOpPutProfile:
+		s.profiles[record.Profile.UserID] = record.Profile
+	case walOpPutReminder:
+		s.reminders[record.Reminder.ID] = record.Reminder
+	case walOpPutTag:
+		s.tags[keyOfTag(record.Tag)] = record.Tag
+	case walOpPutShare:
+		s.shares[keyOfShare(record.Share)] = record.Share
+	case walOpDeleteShare:
+		delete(s.shares, keyOfShare(record.Share))
+	case walOpPutResource:
+		s.resources[record.Resource.ID] = record.Resource
+	case walOpPutAttachment:
+		s.attachments[record.Attachment.ID] = record.Attachment
+	case walOpDeleteAttachments:
+		for _, id := range record.IDs {
+			delete(s.attachments, id)
+		}
+	case walOpDeleteReminders:
+		for _, id := range record.IDs {
+			delete(s.reminders, id)
+		}
+	case walOpBatch:
+		for _, batched := range record.Batch {
+			s.apply(batched)
+		}
+	}
+}
+
+// WithTx runs fn on a copy of the storage and applies collected changes to the storage
+// if fn succeeds. Stored values are never changed in place, so copying the maps is enough.
+// The storage is locked until fn returns, so fn must use only tx.
+func (s *Storage) WithTx(ctx context.Context, fn func(tx app.Storage) error) error {
+	if s.tx {
+		return fn(s)
+	}
+	s.log.Debug().Msg("Start transaction")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := &Storage{
+		data:        make(map[string]*storage.Event, len(s.data)),
+		audit:       append(make([]*storage.AuditRecord, 0, len(s.audit)), s.audit...),
+		webhooks:    make(map[string]*storage.Webhook, len(s.webhooks)),
+		deliveries:  make(map[string]*storage.WebhookDelivery, len(s.deliveries)),
+		profiles:    make(map[string]*storage.UserProfile, len(s.profiles)),
+		reminders:   make(map[string]*storage.Reminder, len(s.reminders)),
+		tags:        make(map[tagKey]*storage.Tag, len(s.tags)),
+		shares:      make(map[shareKey]*storage.Share, len(s.shares)),
+		resources:   make(map[string]*storage.Resource, len(s.resources)),
+		attachments: make(map[string]*storage.Attachment, len(s.attachments)),
+		leases:      s.leases,
+		log:         s.log,
+		tx:          true,
+	}
+	for id, event := range s.data {
+		tx.data[id] = event
+	}
+	for id, webhook := range s.webhooks {
+		tx.webhooks[id] = webhook
+	}
+	for id, delivery := range s.deliveries {
+		tx.deliveries[id] = delivery
+	}
+	for userID, profile := range s.profiles {
+		tx.profiles[userID] = profile
+	}
+	for id, reminder := range s.reminders {
+		tx.reminders[id] = reminder
 	}
+	for key, tag := range s.tags {
+		tx.tags[key] = tag
+	}
+	for key, share := range s.shares {
+		tx.shares[key] = share
+	}
+	for id, resource := range s.resources {
+		tx.resources[id] = resource
+	}
+	for id, attachment := range s.attachments {
+		tx.attachments[id] = attachment
+	}
+	if err := fn(tx); err != nil {
+		s.log.Debug().Err(err).Msg("Transaction is rolled back")
+		return err
+	}
+	if len(tx.pending) > 0 {
+		if err := s.commit(walRecord{Op: walOpBatch, Batch: tx.pending}); err != nil {
+			return errs.ErrTransaction{Err: err}
+		}
+	}
+	s.log.Debug().Msgf("Successfully committed transaction with %d changes", len(tx.pending))
+	return nil
 }
 
 func (s *Storage) AddEvent(ctx context.Context, event *storage.Event) error {
-	event.ID = s.getID.String()
+	event.ID = xid.New().String()
 	s.log.Debug().Msgf("Start adding event with id %s", event.ID)
 	s.mu.Lock()
-	s.data[event.ID] = event
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+	if err := s.checkResourcesFree(event); err != nil {
+		return err
+	}
+	if err := s.commit(walRecord{Op: walOpPut, Event: event.Clone()}); err != nil {
+		return errs.ErrAddEvent{Err: err}
+	}
 	s.log.Debug().Msgf("Successfully add event with id %s", event.ID)
 	return nil
 }
@@ -42,25 +423,110 @@ func (s *Storage) AddEvent(ctx context.Context, event *storage.Event) error {
 func (s *Storage) ModifyEvent(ctx context.Context, event *storage.Event) error {
 	s.log.Debug().Msgf("Start modifying event with id %s", event.ID)
 	s.mu.Lock()
-	s.data[event.ID] = event
-	s.mu.Unlock()
+	defer s.mu.Unlock()
+	stored, ok := s.data[event.ID]
+	if !ok || stored.DeletedAt != nil {
+		err := errs.ErrNotFoundEvent{ID: event.ID}
+		s.log.Debug().Err(err).Msgf("Can't find event with id %s", event.ID)
+		return err
+	}
+	modified := event.Clone()
+	modified.Attendees = stored.Clone().Attendees
+	if err := s.checkResourcesFree(modified); err != nil {
+		return err
+	}
+	if err := s.commit(walRecord{Op: walOpPut, Event: modified}); err != nil {
+		return errs.ErrUpdateEvent{Err: err}
+	}
 	s.log.Debug().Msgf("Successfully modified event with id %s", event.ID)
 	return nil
 }
 
+// DeleteEvent moves event to the trash, it is removed completely by PurgeDeletedEvents.
 func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
 	s.log.Debug().Msgf("Start deleting event with id %s", id)
 	s.mu.Lock()
-	delete(s.data, id)
+	var err error
+	if event, ok := s.data[id]; ok && event.DeletedAt == nil {
+		deleted := event.Clone()
+		deletedAt := time.Now().UTC()
+		deleted.DeletedAt = &deletedAt
+		err = s.commit(walRecord{Op: walOpPut, Event: deleted})
+	}
 	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrDeleteEvent{Err: err}
+	}
 	s.log.Debug().Msgf("Successfully deleted event with id %s", id)
 	return nil
 }
 
+func (s *Storage) RestoreEvent(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start restoring event with id %s", id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.data[id]
+	if !ok || event.DeletedAt == nil {
+		err := errs.ErrNotFoundEvent{ID: id}
+		s.log.Debug().Err(err).Msgf("Can't find deleted event with id %s", id)
+		return err
+	}
+	restored := event.Clone()
+	restored.DeletedAt = nil
+	if err := s.checkResourcesFree(restored); err != nil {
+		return err
+	}
+	if err := s.commit(walRecord{Op: walOpPut, Event: restored}); err != nil {
+		return errs.ErrRestoreEvent{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully restored event with id %s", id)
+	return nil
+}
+
+func (s *Storage) ListDeletedEvents(ctx context.Context) ([]*storage.Event, error) {
+	s.log.Debug().Msg("Start listing deleted events")
+	events := make([]*storage.Event, 0)
+	s.mu.RLock()
+	for _, event := range s.data {
+		if event.DeletedAt != nil {
+			events = append(events, event.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	s.log.Debug().Msgf("Successfully listed deleted events, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	s.log.Debug().Msgf("Start purging events deleted before %v", deletedBefore)
+	ids := make([]string, 0)
+	s.mu.Lock()
+	for id, event := range s.data {
+		if event.DeletedAt != nil && event.DeletedAt.Before(deletedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	var err error
+	if len(ids) > 0 {
+		err = s.commit(walRecord{Op: walOpPurge, IDs: ids})
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return 0, errs.ErrPurgeEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully purged events, total: %d", len(ids))
+	return len(ids), nil
+}
+
 func (s *Storage) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
 	s.log.Debug().Msgf("Start getting event with id %s", id)
+	s.mu.RLock()
 	event, ok := s.data[id]
-	if !ok {
+	if ok {
+		event = event.Clone()
+	}
+	s.mu.RUnlock()
+	if !ok || event.DeletedAt != nil {
 		err := errs.ErrNotFoundEvent{ID: id}
 		s.log.Debug().Err(err).Msgf("Can't find event with id %s", id)
 		return nil, err
@@ -71,10 +537,147 @@ func (s *Storage) GetEvent(ctx context.Context, id string) (*storage.Event, erro
 
 func (s *Storage) ListEvents(ctx context.Context) ([]*storage.Event, error) {
 	s.log.Debug().Msg("Start listing all events")
+	s.mu.RLock()
 	events := make([]*storage.Event, 0, len(s.data))
 	for _, event := range s.data {
-		events = append(events, event)
+		if event.DeletedAt == nil {
+			events = append(events, event.Clone())
+		}
 	}
+	s.mu.RUnlock()
 	s.log.Debug().Msgf("Successfully listed all events, total: %d", len(events))
 	return events, nil
 }
+
+func (s *Storage) ListUserEvents(ctx context.Context, userID string) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of user %s", userID)
+	events := make([]*storage.Event, 0)
+	s.mu.RLock()
+	for _, event := range s.data {
+		if event.DeletedAt == nil && (event.UserID == userID || event.IsAttendee(userID)) {
+			events = append(events, event.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	s.log.Debug().Msgf("Successfully listed events of user %s, total: %d", userID, len(events))
+	return events, nil
+}
+
+func (s *Storage) ListEventsInRange(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events from %v to %v", from, to)
+	events := make([]*storage.Event, 0)
+	s.mu.RLock()
+	for _, event := range s.data {
+		if event.DeletedAt == nil && event.StartAt.Before(to) && event.EndAt.After(from) {
+			events = append(events, event.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	sortByStart(events)
+	s.log.Debug().Msgf("Successfully listed events from %v to %v, total: %d", from, to, len(events))
+	return events, nil
+}
+
+func (s *Storage) ListUsersEventsInRange(
+	ctx context.Context, userIDs []string, from, to time.Time,
+) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of users %v from %v to %v", userIDs, from, to)
+	events := make([]*storage.Event, 0)
+	s.mu.RLock()
+	for _, event := range s.data {
+		if event.DeletedAt != nil || !event.StartAt.Before(to) || !event.EndAt.After(from) {
+			continue
+		}
+		for _, userID := range userIDs {
+			if event.UserID == userID || event.IsAttendee(userID) {
+				events = append(events, event.Clone())
+				break
+			}
+		}
+	}
+	s.mu.RUnlock()
+	sortByStart(events)
+	s.log.Debug().Msgf("Successfully listed events of users %v, total: %d", userIDs, len(events))
+	return events, nil
+}
+
+func sortByStart(events []*storage.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartAt.Before(events[j].StartAt)
+	})
+}
+
+func (s *Storage) AddAttendees(ctx context.Context, eventID string, userIDs []string) error {
+	s.log.Debug().Msgf("Start adding attendees to event %s", eventID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.data[eventID]
+	if !ok || event.DeletedAt != nil {
+		return errs.ErrNotFoundEvent{ID: eventID}
+	}
+	updated := event.Clone()
+	for _, userID := range userIDs {
+		if !updated.IsAttendee(userID) {
+			updated.Attendees = append(updated.Attendees, storage.Attendee{
+				UserID: userID,
+				Status: storage.AttendeeStatusNeedsAction,
+			})
+		}
+	}
+	if err := s.commit(walRecord{Op: walOpPut, Event: updated}); err != nil {
+		return errs.ErrAddAttendees{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully added attendees to event %s", eventID)
+	return nil
+}
+
+func (s *Storage) SetAttendeeStatus(
+	ctx context.Context, eventID, userID string, status storage.AttendeeStatus,
+) error {
+	s.log.Debug().Msgf("Start setting status %s of attendee %s in event %s", status, userID, eventID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event, ok := s.data[eventID]
+	if !ok || event.DeletedAt != nil {
+		return errs.ErrNotFoundEvent{ID: eventID}
+	}
+	updated := event.Clone()
+	for i := range updated.Attendees {
+		if updated.Attendees[i].UserID == userID {
+			updated.Attendees[i].Status = status
+			if err := s.commit(walRecord{Op: walOpPut, Event: updated}); err != nil {
+				return errs.ErrUpdateAttendee{Err: err}
+			}
+			s.log.Debug().Msgf("Successfully set status of attendee %s in event %s", userID, eventID)
+			return nil
+		}
+	}
+	return errs.ErrNotFoundAttendee{EventID: eventID, UserID: userID}
+}
+
+func (s *Storage) AddAuditRecord(ctx context.Context, record *storage.AuditRecord) error {
+	record.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding audit record %s for event %s", record.ID, record.EventID)
+	s.mu.Lock()
+	err := s.commit(walRecord{Op: walOpAudit, Audit: record.Clone()})
+	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrAddAuditRecord{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add audit record %s", record.ID)
+	return nil
+}
+
+func (s *Storage) ListAuditRecords(ctx context.Context, eventID string) ([]*storage.AuditRecord, error) {
+	s.log.Debug().Msgf("Start listing audit records of event %s", eventID)
+	records := make([]*storage.AuditRecord, 0)
+	s.mu.RLock()
+	for _, record := range s.audit {
+		if record.EventID == eventID {
+			records = append(records, record.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	s.log.Debug().Msgf("Successfully listed audit records of event %s, total: %d", eventID, len(records))
+	return records, nil
+}
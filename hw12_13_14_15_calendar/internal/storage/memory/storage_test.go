@@ -1,7 +1,34 @@
 package memorystorage
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
 
 func TestStorage(t *testing.T) {
-	// TODO
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		t.Helper()
+		return New(logger.New("error"))
+	})
+
+	t.Run("stored event is not affected by caller", func(t *testing.T) {
+		ctx := context.Background()
+		s := New(logger.New("error"))
+
+		event := &storage.Event{Title: "title", Attendees: []storage.Attendee{{UserID: "bob"}}}
+		require.NoError(t, s.AddEvent(ctx, event))
+		event.Title = "changed outside"
+		event.Attendees[0].UserID = "changed outside"
+
+		got, err := s.GetEvent(ctx, event.ID)
+		require.NoError(t, err)
+		require.Equal(t, "title", got.Title)
+		require.Equal(t, "bob", got.Attendees[0].UserID)
+	})
 }
@@ -0,0 +1,49 @@
+package memorystorage
+
+import (
+	"context"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// tagKey identifies a saved tag, names are unique per user.
+type tagKey struct {
+	userID string
+	name   string
+}
+
+func keyOfTag(tag *storage.Tag) tagKey {
+	return tagKey{userID: tag.UserID, name: tag.Name}
+}
+
+func (s *Storage) SaveTag(ctx context.Context, tag *storage.Tag) error {
+	s.log.Debug().Msgf("Start saving tag %s of user %s", tag.Name, tag.UserID)
+	saved := *tag
+	s.mu.Lock()
+	err := s.commit(walRecord{Op: walOpPutTag, Tag: &saved})
+	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrSaveTag{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved tag %s of user %s", tag.Name, tag.UserID)
+	return nil
+}
+
+func (s *Storage) ListUserTags(ctx context.Context, userID string) ([]*storage.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	saved := make([]*storage.Tag, 0)
+	for key, tag := range s.tags {
+		if key.userID == userID {
+			saved = append(saved, tag)
+		}
+	}
+	events := make([]*storage.Event, 0)
+	for _, event := range s.data {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	return storage.CollectTags(userID, saved, events), nil
+}
@@ -0,0 +1,202 @@
+package memorystorage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs the WAL after every mutation.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs the WAL periodically, last mutations may be lost on power failure.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing of the WAL to the OS.
+	SyncNever SyncPolicy = "never"
+)
+
+type walOp string
+
+const (
+	walOpPut   walOp = "put"
+	walOpPurge walOp = "purge"
+	walOpAudit walOp = "audit"
+	walOpBatch walOp = "batch"
+
+	walOpPutWebhook    walOp = "put_webhook"
+	walOpDeleteWebhook walOp = "delete_webhook"
+	walOpPutDelivery   walOp = "put_delivery"
+	walOpPutProfile    walOp = "put_profile"
+	walOpPutTag        walOp = "put_tag"
+	walOpPutResource   walOp = "put_resource"
+	walOpPutShare      walOp = "put_share"
+	walOpDeleteShare   walOp = "delete_share"
+
+	walOpPutAttachment     walOp = "put_attachment"
+	walOpDeleteAttachments walOp = "delete_attachments"
+
+	walOpPutReminder     walOp = "put_reminder"
+	walOpDeleteReminders walOp = "delete_reminders"
+)
+
+// walRecord is a single mutation of the storage: put replaces the whole event,
+// purge removes events completely, audit appends an audit record and batch applies
+// records of a committed transaction, so they are replayed all or none.
+// Webhooks, their deliveries, user profiles, tags, resources, shares, attachments and reminders are put and deleted
+// the same way as events.
+type walRecord struct {
+	Seq        uint64                   `json:"seq"`
+	Op         walOp                    `json:"op"`
+	Event      *storage.Event           `json:"event,omitempty"`
+	IDs        []string                 `json:"ids,omitempty"`
+	Audit      *storage.AuditRecord     `json:"audit,omitempty"`
+	Batch      []walRecord              `json:"batch,omitempty"`
+	Webhook    *storage.Webhook         `json:"webhook,omitempty"`
+	Delivery   *storage.WebhookDelivery `json:"delivery,omitempty"`
+	Profile    *storage.UserProfile     `json:"profile,omitempty"`
+	Reminder   *storage.Reminder        `json:"reminder,omitempty"`
+	Tag        *storage.Tag             `json:"tag,omitempty"`
+	Resource   *storage.Resource        `json:"resource,omitempty"`
+	Share      *storage.Share           `json:"share,omitempty"`
+	Attachment *storage.Attachment      `json:"attachment,omitempty"`
+}
+
+// frame header is the length of the payload and its CRC32.
+const walHeaderSize = 8
+
+var errCorruptedRecord = errors.New("corrupted WAL record")
+
+type wal struct {
+	file  *os.File
+	sync  SyncPolicy
+	dirty bool
+}
+
+// openWAL opens the log and reads all complete records from it. A torn or corrupted tail,
+// which is left by a crash in the middle of append, is cut off.
+func openWAL(path string, sync SyncPolicy) (*wal, []walRecord, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, validSize, err := readWAL(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	if err = file.Truncate(validSize); err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	if _, err = file.Seek(validSize, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	return &wal{file: file, sync: sync}, records, nil
+}
+
+func readWAL(file *os.File) ([]walRecord, int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	reader := bufio.NewReader(file)
+	records := make([]walRecord, 0)
+	var validSize int64
+	for {
+		record, size, err := readRecord(reader, info.Size()-validSize)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errCorruptedRecord) {
+			return records, validSize, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, record)
+		validSize += size
+	}
+}
+
+// readRecord reads the next record of the WAL, remaining is the size of the rest of the file.
+func readRecord(reader io.Reader, remaining int64) (walRecord, int64, error) {
+	var record walRecord
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return record, 0, err
+	}
+	// the length is checked before the payload is allocated, a corrupted one could take gigabytes
+	length := int64(binary.BigEndian.Uint32(header[:4]))
+	if length > remaining-walHeaderSize {
+		return record, 0, errCorruptedRecord
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return record, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return record, 0, errCorruptedRecord
+	}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, 0, errCorruptedRecord
+	}
+	return record, int64(walHeaderSize + len(payload)), nil
+}
+
+func (w *wal) append(record walRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, walHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:walHeaderSize], crc32.ChecksumIEEE(payload))
+	copy(frame[walHeaderSize:], payload)
+	if _, err = w.file.Write(frame); err != nil {
+		return err
+	}
+	w.dirty = true
+	if w.sync == SyncAlways {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *wal) flush() error {
+	if !w.dirty {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// reset drops all records, it is called after they are saved in a snapshot.
+func (w *wal) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.dirty = true
+	return w.flush()
+}
+
+func (w *wal) close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,132 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+)
+
+func (s *Storage) AddWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	webhook.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding webhook %s of user %s", webhook.ID, webhook.UserID)
+	s.mu.Lock()
+	err := s.commit(walRecord{Op: walOpPutWebhook, Webhook: webhook.Clone()})
+	s.mu.Unlock()
+	if err != nil {
+		return errs.ErrAddWebhook{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add webhook %s", webhook.ID)
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id string) (*storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return nil, errs.ErrNotFoundWebhook{ID: id}
+	}
+	return webhook.Clone(), nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting webhook %s", id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return errs.ErrNotFoundWebhook{ID: id}
+	}
+	if err := s.commit(walRecord{Op: walOpDeleteWebhook, IDs: []string{id}}); err != nil {
+		return errs.ErrDeleteWebhook{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted webhook %s", id)
+	return nil
+}
+
+func (s *Storage) ListUsersWebhooks(ctx context.Context, userIDs []string) ([]*storage.Webhook, error) {
+	webhooks := make([]*storage.Webhook, 0)
+	s.mu.RLock()
+	for _, webhook := range s.webhooks {
+		for _, userID := range userIDs {
+			if webhook.UserID == userID {
+				webhooks = append(webhooks, webhook.Clone())
+				break
+			}
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+func (s *Storage) AddWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	delivery.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding delivery %s to webhook %s", delivery.ID, delivery.WebhookID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[delivery.WebhookID]; !ok {
+		return errs.ErrNotFoundWebhook{ID: delivery.WebhookID}
+	}
+	if err := s.commit(walRecord{Op: walOpPutDelivery, Delivery: delivery.Clone()}); err != nil {
+		return errs.ErrSaveWebhookDelivery{Err: err}
+	}
+	return nil
+}
+
+// UpdateWebhookDelivery does nothing if the delivery was removed with its webhook.
+func (s *Storage) UpdateWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	s.log.Debug().Msgf("Start updating delivery %s", delivery.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return nil
+	}
+	if err := s.commit(walRecord{Op: walOpPutDelivery, Delivery: delivery.Clone()}); err != nil {
+		return errs.ErrSaveWebhookDelivery{Err: err}
+	}
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error) {
+	deliveries := make([]*storage.WebhookDelivery, 0)
+	s.mu.RLock()
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+func (s *Storage) ListDueWebhookDeliveries(
+	ctx context.Context, now time.Time, limit int,
+) ([]*storage.WebhookDelivery, error) {
+	deliveries := make([]*storage.WebhookDelivery, 0)
+	s.mu.RLock()
+	for _, delivery := range s.deliveries {
+		if delivery.Status == storage.DeliveryStatusPending && !delivery.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, delivery.Clone())
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,37 @@
+package storage
+
+import "time"
+
+type NotificationChannel string
+
+const (
+	NotificationChannelEmail   NotificationChannel = "email"
+	NotificationChannelWebhook NotificationChannel = "webhook"
+	NotificationChannelStdout  NotificationChannel = "stdout"
+)
+
+// Valid reports whether channel is one of the known channels.
+func (c NotificationChannel) Valid() bool {
+	switch c {
+	case NotificationChannelEmail, NotificationChannelWebhook, NotificationChannelStdout:
+		return true
+	}
+	return false
+}
+
+// UserProfile keeps settings of the user. Channels are where reminders about events are sent,
+// the webhook channel posts them to webhooks registered by the user.
+type UserProfile struct {
+	UserID    string                `db:"user_id" json:"user_id"`
+	Email     string                `db:"email" json:"email,omitempty"`
+	Channels  []NotificationChannel `db:"-" json:"channels"`
+	UpdatedAt time.Time             `db:"updated_at" json:"updated_at"`
+}
+
+// Clone returns a copy of the profile.
+func (p UserProfile) Clone() *UserProfile {
+	if p.Channels != nil {
+		p.Channels = append(make([]NotificationChannel, 0, len(p.Channels)), p.Channels...)
+	}
+	return &p
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reminder records that the reminder about the occurrence of the event is handled, so it is not
+// handled twice. The scheduler records reminders it has queued, the sender records notifications
+// delivered to users, those have UserID set.
+type Reminder struct {
+	ID           string    `db:"id" json:"id"`
+	EventID      string    `db:"event_id" json:"event_id"`
+	UserID       string    `db:"user_id" json:"user_id,omitempty"`
+	OccurrenceAt time.Time `db:"occurrence_at" json:"occurrence_at"`
+	NotifyAt     time.Time `db:"notify_at" json:"notify_at"`
+	HandledAt    time.Time `db:"handled_at" json:"handled_at"`
+}
+
+// ReminderID identifies the reminder sent at notifyAt about the occurrence of the event
+// starting at occurrenceAt. The reminder is sent again if the event is moved.
+func ReminderID(eventID string, occurrenceAt, notifyAt time.Time) string {
+	return fmt.Sprintf("%s/%d/%d", eventID, occurrenceAt.Unix(), notifyAt.Unix())
+}
+
+// Clone returns a copy of the reminder.
+func (r Reminder) Clone() *Reminder {
+	return &r
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+)
+
+type ResourceKind string
+
+const (
+	ResourceKindRoom      ResourceKind = "room"
+	ResourceKindEquipment ResourceKind = "equipment"
+)
+
+// Valid reports whether kind is one of the known kinds.
+func (k ResourceKind) Valid() bool {
+	switch k {
+	case ResourceKindRoom, ResourceKindEquipment:
+		return true
+	}
+	return false
+}
+
+// Resource is a meeting room or a piece of equipment booked for events. A resource is booked
+// for at most one event at a time, its calendar is the events it is booked for.
+type Resource struct {
+	ID   string       `db:"id" json:"id"`
+	Name string       `db:"name" json:"name"`
+	Kind ResourceKind `db:"kind" json:"kind"`
+	// Capacity is how many people the room seats, zero if it is unknown.
+	Capacity int `db:"capacity" json:"capacity,omitempty"`
+}
+
+// SortResources sorts resources by name, resources with the same name by ID.
+func SortResources(resources []*Resource) {
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Name != resources[j].Name {
+			return resources[i].Name < resources[j].Name
+		}
+		return resources[i].ID < resources[j].ID
+	})
+}
+
+// UsesResource reports whether the resource is booked for the event.
+func (e *Event) UsesResource(resourceID string) bool {
+	for _, id := range e.Resources {
+		if id == resourceID {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps reports whether the event takes place at least partially within [from, to).
+func (e *Event) Overlaps(from, to time.Time) bool {
+	return e.StartAt.Before(to) && e.EndAt.After(from)
+}
+
+// CheckResourcesFree returns ErrResourceBusy if any resource of the event is booked for another
+// of events at the same time, it is used by storages keeping resources inside events.
+// Events in the trash do not hold their resources.
+func CheckResourcesFree(event *Event, events []*Event) error {
+	if len(event.Resources) > 0 && !event.EndAt.After(event.StartAt) {
+		return errs.ErrInvalidBookingPeriod{EventID: event.ID}
+	}
+	for _, other := range events {
+		if other.ID == event.ID || other.DeletedAt != nil || !other.Overlaps(event.StartAt, event.EndAt) {
+			continue
+		}
+		for _, id := range event.Resources {
+			if other.UsesResource(id) {
+				return errs.ErrResourceBusy{ResourceID: id, EventID: other.ID}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// ShareAccess is what the user the calendar is shared with may do in it.
+type ShareAccess string
+
+const (
+	// ShareAccessFreeBusy lets the user see when the owner is busy, but not the events.
+	ShareAccessFreeBusy ShareAccess = "free_busy"
+	// ShareAccessRead lets the user see events of the owner.
+	ShareAccessRead ShareAccess = "read"
+	// ShareAccessReadWrite lets the user manage the calendar on behalf of the owner.
+	ShareAccessReadWrite ShareAccess = "read_write"
+)
+
+var shareAccessRanks = map[ShareAccess]int{
+	ShareAccessFreeBusy:  1,
+	ShareAccessRead:      2,
+	ShareAccessReadWrite: 3,
+}
+
+func (a ShareAccess) Valid() bool {
+	_, ok := shareAccessRanks[a]
+	return ok
+}
+
+// Allows reports whether the access includes the required one, read_write includes read
+// and read includes free_busy.
+func (a ShareAccess) Allows(required ShareAccess) bool {
+	return a.Valid() && shareAccessRanks[a] >= shareAccessRanks[required]
+}
+
+// Share grants the user access to the calendar of the owner, the owner has a single share
+// with each user.
+type Share struct {
+	OwnerID   string      `db:"owner_id" json:"owner_id"`
+	UserID    string      `db:"user_id" json:"user_id"`
+	Access    ShareAccess `db:"access" json:"access"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+}
+
+// SortShares sorts shares by owner and then by user, as storages list them.
+func SortShares(shares []*Share) {
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].OwnerID != shares[j].OwnerID {
+			return shares[i].OwnerID < shares[j].OwnerID
+		}
+		return shares[i].UserID < shares[j].UserID
+	})
+}
@@ -0,0 +1,119 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+)
+
+const attachmentColumns = `id, event_id, user_id, name, url, content_type, size, blob_key, created_at`
+
+// AddAttachment adds the attachment if its event is not in the trash, the check and the insert
+// are a single statement.
+func (s *Storage) AddAttachment(ctx context.Context, attachment *storage.Attachment) error {
+	// parameters of the select list have no types from the table, so they are cast
+	query := `
+	INSERT INTO attachments (` + attachmentColumns + `)
+	SELECT $1, id, $2, $3, $4, $5, $6::bigint, $7, $8::timestamptz
+	FROM events
+	WHERE id = $9 AND deleted_at IS NULL;`
+	attachment.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding attachment %s to event %s", attachment.ID, attachment.EventID)
+	res, err := s.exec(ctx, query, attachment.ID, attachment.UserID, attachment.Name, attachment.URL,
+		attachment.ContentType, attachment.Size, attachment.BlobKey, attachment.CreatedAt, attachment.EventID)
+	if err != nil {
+		return errs.ErrAddAttachment{Err: err}
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrAddAttachment{Err: err}
+	}
+	if added == 0 {
+		return errs.ErrNotFoundEvent{ID: attachment.EventID}
+	}
+	s.log.Debug().Msgf("Successfully add attachment %s", attachment.ID)
+	return nil
+}
+
+func (s *Storage) GetAttachment(ctx context.Context, id string) (*storage.Attachment, error) {
+	query := `
+	SELECT ` + attachmentColumns + `
+	FROM attachments
+	WHERE id = $1;
+	`
+	var attachment storage.Attachment
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, conn, &attachment, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFoundAttachment{ID: id}
+		}
+		return nil, errs.ErrGetAttachment{Err: err}
+	}
+	attachment.CreatedAt = attachment.CreatedAt.UTC()
+	return &attachment, nil
+}
+
+func (s *Storage) ListEventAttachments(ctx context.Context, eventID string) ([]*storage.Attachment, error) {
+	query := `
+	SELECT ` + attachmentColumns + `
+	FROM attachments
+	WHERE event_id = $1
+	ORDER BY id;
+	`
+	return s.selectAttachments(ctx, query, eventID)
+}
+
+func (s *Storage) DeleteAttachment(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting attachment %s", id)
+	res, err := s.exec(ctx, `DELETE FROM attachments WHERE id = $1;`, id)
+	if err != nil {
+		return errs.ErrDeleteAttachment{Err: err}
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrDeleteAttachment{Err: err}
+	}
+	if deleted == 0 {
+		return errs.ErrNotFoundAttachment{ID: id}
+	}
+	s.log.Debug().Msgf("Successfully deleted attachment %s", id)
+	return nil
+}
+
+func (s *Storage) ListOrphanedAttachments(ctx context.Context, limit int) ([]*storage.Attachment, error) {
+	if limit <= 0 {
+		return []*storage.Attachment{}, nil
+	}
+	query := `
+	SELECT ` + attachmentColumns + `
+	FROM attachments
+	WHERE NOT EXISTS (SELECT 1 FROM events WHERE events.id = attachments.event_id)
+	ORDER BY id
+	LIMIT $1;
+	`
+	return s.selectAttachments(ctx, query, limit)
+}
+
+func (s *Storage) selectAttachments(ctx context.Context, query string, args ...interface{}) (
+	[]*storage.Attachment, error,
+) {
+	attachments := make([]*storage.Attachment, 0)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		attachments = attachments[:0]
+		return sqlx.SelectContext(ctx, conn, &attachments, query, args...)
+	})
+	if err != nil {
+		return nil, errs.ErrListAttachments{Err: err}
+	}
+	for _, attachment := range attachments {
+		attachment.CreatedAt = attachment.CreatedAt.UTC()
+	}
+	return attachments, nil
+}
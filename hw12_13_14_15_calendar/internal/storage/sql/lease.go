@@ -0,0 +1,113 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+)
+
+// leaseSessions are connections taken from the pool, each holds an advisory lock of a lease.
+// Postgres releases the lock when the session ends, so a crashed holder loses its leases
+// without waiting for a ttl.
+type leaseSessions struct {
+	mu    sync.Mutex
+	conns map[leaseKey]*sql.Conn
+}
+
+type leaseKey struct {
+	name   string
+	holder string
+}
+
+// advisoryLockID maps the lease name to the key of pg_try_advisory_lock.
+func advisoryLockID(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AcquireLease takes an advisory lock in a session of its own, so holders in one process
+// compete for the lease like holders in different processes. Renewing the lease checks
+// that its session is alive, ttl is not used.
+func (s *Storage) AcquireLease(ctx context.Context, name, holder string, _ time.Duration) (bool, error) {
+	s.leases.mu.Lock()
+	defer s.leases.mu.Unlock()
+	key := leaseKey{name: name, holder: holder}
+	ctx, cancel := s.withOperationTimeout(ctx)
+	defer cancel()
+
+	if conn, ok := s.leases.conns[key]; ok {
+		err := conn.PingContext(ctx)
+		if err == nil {
+			return true, nil
+		}
+		s.log.Warn().Err(err).Msgf("Session holding lease %s is lost", name)
+		delete(s.leases.conns, key)
+		_ = s.unlock(conn, name)
+		return false, errs.ErrAcquireLease{Name: name, Err: err}
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, errs.ErrAcquireLease{Name: name, Err: err}
+	}
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockID(name)).Scan(&locked)
+	if err != nil || !locked {
+		if closeErr := conn.Close(); closeErr != nil {
+			s.log.Error().Err(closeErr).Msg("Failed to return connection to the pool")
+		}
+		if err != nil {
+			return false, errs.ErrAcquireLease{Name: name, Err: err}
+		}
+		return false, nil
+	}
+	s.leases.conns[key] = conn
+	s.log.Info().Msgf("Lease %s is acquired by %s", name, holder)
+	return true, nil
+}
+
+func (s *Storage) ReleaseLease(ctx context.Context, name, holder string) error {
+	s.leases.mu.Lock()
+	defer s.leases.mu.Unlock()
+	key := leaseKey{name: name, holder: holder}
+	conn, ok := s.leases.conns[key]
+	if !ok {
+		return nil
+	}
+	delete(s.leases.conns, key)
+	if err := s.unlock(conn, name); err != nil {
+		return errs.ErrReleaseLease{Name: name, Err: err}
+	}
+	s.log.Info().Msgf("Lease %s is released by %s", name, holder)
+	return nil
+}
+
+// unlock releases the advisory lock before the connection returns to the pool,
+// otherwise the idle session would keep the lease. It is not bound to the context of the caller,
+// the lock has to be released even if the caller is cancelled.
+func (s *Storage) unlock(conn *sql.Conn, name string) error {
+	ctx, cancel := s.withOperationTimeout(context.Background())
+	defer cancel()
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryLockID(name))
+	if closeErr := conn.Close(); closeErr != nil {
+		s.log.Error().Err(closeErr).Msg("Failed to return connection to the pool")
+	}
+	return err
+}
+
+// closeLeases ends sessions holding leases, Postgres releases their locks.
+func (s *Storage) closeLeases() {
+	s.leases.mu.Lock()
+	defer s.leases.mu.Unlock()
+	for key, conn := range s.leases.conns {
+		if err := s.unlock(conn, key.name); err != nil {
+			s.log.Error().Err(err).Msgf("Failed to release lease %s", key.name)
+		}
+		delete(s.leases.conns, key)
+	}
+}
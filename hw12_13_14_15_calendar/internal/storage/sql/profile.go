@@ -0,0 +1,59 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (s *Storage) SaveUserProfile(ctx context.Context, profile *storage.UserProfile) error {
+	query := `
+	INSERT INTO user_profiles (user_id, email, channels, updated_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE
+	SET email = EXCLUDED.email, channels = EXCLUDED.channels, updated_at = EXCLUDED.updated_at;`
+	s.log.Debug().Msgf("Start saving profile of user %s", profile.UserID)
+	channels := make([]string, 0, len(profile.Channels))
+	for _, channel := range profile.Channels {
+		channels = append(channels, string(channel))
+	}
+	if _, err := s.exec(ctx, query,
+		profile.UserID, profile.Email, pq.Array(channels), profile.UpdatedAt); err != nil {
+		return errs.ErrSaveUserProfile{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved profile of user %s", profile.UserID)
+	return nil
+}
+
+func (s *Storage) GetUserProfile(ctx context.Context, userID string) (*storage.UserProfile, error) {
+	query := `
+	SELECT user_id, email, channels, updated_at
+	FROM user_profiles
+	WHERE user_id = $1;
+	`
+	var (
+		profile  storage.UserProfile
+		channels []string
+	)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		return conn.QueryRowxContext(ctx, query, userID).
+			Scan(&profile.UserID, &profile.Email, pq.Array(&channels), &profile.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFoundUserProfile{UserID: userID}
+		}
+		return nil, errs.ErrGetUserProfile{Err: err}
+	}
+	profile.Channels = make([]storage.NotificationChannel, 0, len(channels))
+	for _, channel := range channels {
+		profile.Channels = append(profile.Channels, storage.NotificationChannel(channel))
+	}
+	profile.UpdatedAt = profile.UpdatedAt.UTC()
+	return &profile, nil
+}
@@ -0,0 +1,88 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the code of PostgreSQL error raised on duplicate keys.
+const uniqueViolation = "23505"
+
+func (s *Storage) ListEventsToNotify(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events to notify from %v to %v", from, to)
+	query := `
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NULL AND notify_before > 0
+		AND start_at - notify_before / 1000 * interval '1 microsecond' > $1
+		AND start_at - notify_before / 1000 * interval '1 microsecond' <= $2
+	ORDER BY start_at;
+	`
+	events, err := s.selectEvents(ctx, query, from, to)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events to notify, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) AddReminder(ctx context.Context, reminder *storage.Reminder) error {
+	query := `
+	INSERT INTO reminders (id, event_id, user_id, occurrence_at, notify_at, handled_at)
+	VALUES (:id, :event_id, :user_id, :occurrence_at, :notify_at, :handled_at);`
+	s.log.Debug().Msgf("Start adding reminder %s", reminder.ID)
+	if _, err := s.namedExec(ctx, query, reminder); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return errs.ErrReminderExists{ID: reminder.ID}
+		}
+		return errs.ErrAddReminder{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully added reminder %s", reminder.ID)
+	return nil
+}
+
+// GetReminder reads from the primary, as reminders are checked right before they are handled.
+func (s *Storage) GetReminder(ctx context.Context, id string) (*storage.Reminder, error) {
+	query := `
+	SELECT id, event_id, user_id, occurrence_at, notify_at, handled_at
+	FROM reminders
+	WHERE id = $1;
+	`
+	var reminder storage.Reminder
+	err := s.read(app.ContextWithReadYourWrites(ctx), func(ctx context.Context, conn sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, conn, &reminder, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFoundReminder{ID: id}
+		}
+		return nil, errs.ErrGetReminder{Err: err}
+	}
+	reminder.OccurrenceAt = reminder.OccurrenceAt.UTC()
+	reminder.NotifyAt = reminder.NotifyAt.UTC()
+	reminder.HandledAt = reminder.HandledAt.UTC()
+	return &reminder, nil
+}
+
+func (s *Storage) DeleteReminders(ctx context.Context, handledBefore time.Time) (int, error) {
+	s.log.Debug().Msgf("Start deleting reminders handled before %v", handledBefore)
+	res, err := s.exec(ctx, `DELETE FROM reminders WHERE handled_at < $1;`, handledBefore)
+	if err != nil {
+		return 0, errs.ErrDeleteReminders{Err: err}
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, errs.ErrDeleteReminders{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully deleted reminders, total: %d", deleted)
+	return int(deleted), nil
+}
@@ -0,0 +1,116 @@
+package sqlstorage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReplicaOptions lists read replicas, a failed replica is not used for EjectFor.
+type ReplicaOptions struct {
+	DSNs     []string
+	EjectFor time.Duration
+}
+
+type replica struct {
+	db *sqlx.DB
+
+	mu           sync.Mutex
+	ejectedUntil time.Time
+}
+
+func (r *replica) healthy(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !now.Before(r.ejectedUntil)
+}
+
+func (r *replica) eject(until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ejectedUntil = until
+}
+
+// replicaSet picks replicas in round-robin order skipping ejected ones.
+type replicaSet struct {
+	replicas []*replica
+	ejectFor time.Duration
+	next     uint32
+}
+
+func (rs *replicaSet) pick(now time.Time) *replica {
+	if rs == nil || len(rs.replicas) == 0 {
+		return nil
+	}
+	start := atomic.AddUint32(&rs.next, 1)
+	for i := 0; i < len(rs.replicas); i++ {
+		r := rs.replicas[(int(start)+i)%len(rs.replicas)]
+		if r.healthy(now) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *Storage) connectReplicas(ctx context.Context) {
+	if len(s.replicaOptions.DSNs) == 0 {
+		return
+	}
+	rs := &replicaSet{ejectFor: s.replicaOptions.EjectFor}
+	for i, dsn := range s.replicaOptions.DSNs {
+		db, err := sqlx.Open("postgres", dsn)
+		if err != nil {
+			s.log.Error().Err(err).Msgf("Skip replica #%d with invalid DSN", i)
+			continue
+		}
+		s.configurePool(db)
+		r := &replica{db: db}
+		// unavailable replica does not prevent start, it is tried again after ejection
+		if err = db.PingContext(ctx); err != nil {
+			s.log.Warn().Err(err).Msgf("Replica #%d is unavailable, ejecting it for %v", i, rs.ejectFor)
+			r.eject(time.Now().Add(rs.ejectFor))
+		}
+		rs.replicas = append(rs.replicas, r)
+	}
+	s.replicas = rs
+	s.log.Info().Msgf("Successfully configured %d read replicas", len(rs.replicas))
+}
+
+func (s *Storage) closeReplicas() {
+	if s.replicas == nil {
+		return
+	}
+	for i, r := range s.replicas.replicas {
+		if err := r.db.Close(); err != nil {
+			s.log.Error().Err(err).Msgf("Failed to close connection to replica #%d", i)
+		}
+	}
+}
+
+// read runs query op on a replica. It falls back to the primary if there is no healthy
+// replica, the replica fails or ctx requires read-your-writes consistency.
+// Inside a transaction op always runs in it.
+func (s *Storage) read(ctx context.Context, op func(ctx context.Context, conn sqlx.ExtContext) error) error {
+	if s.tx == nil && !app.ReadYourWritesFromContext(ctx) {
+		if r := s.replicas.pick(time.Now()); r != nil {
+			opCtx, cancel := s.withOperationTimeout(ctx)
+			err := op(opCtx, r.db)
+			cancel()
+			timedOut := errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+			if err == nil || (!isTransient(err) && !timedOut) {
+				return err
+			}
+			s.log.Warn().Err(err).Msgf("Replica failed, ejecting it for %v and reading from primary",
+				s.replicas.ejectFor)
+			r.eject(time.Now().Add(s.replicas.ejectFor))
+		}
+	}
+	return s.retry(ctx, func(ctx context.Context) error {
+		return op(ctx, s.conn())
+	})
+}
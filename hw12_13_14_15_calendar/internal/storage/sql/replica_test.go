@@ -0,0 +1,124 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStorage returns storage with primary and replicas which are never connected,
+// sql.Open does not connect until the first query.
+func newTestStorage(t *testing.T, replicas int) *Storage {
+	t.Helper()
+	s := New(logger.New("error"), "", "", "", "", "", 0, 0,
+		PoolOptions{}, TLSOptions{}, RetryPolicy{}, ReplicaOptions{EjectFor: time.Minute})
+	s.db = sqlx.MustOpen("postgres", "host=primary")
+	s.replicas = &replicaSet{ejectFor: time.Minute}
+	for i := 0; i < replicas; i++ {
+		s.replicas.replicas = append(s.replicas.replicas, &replica{db: sqlx.MustOpen("postgres", "host=replica")})
+	}
+	t.Cleanup(func() {
+		s.closeReplicas()
+		require.NoError(t, s.db.Close())
+	})
+	return s
+}
+
+func TestReplicaSetPick(t *testing.T) {
+	s := newTestStorage(t, 3)
+	now := time.Now()
+
+	picked := make(map[*replica]int)
+	for i := 0; i < 6; i++ {
+		picked[s.replicas.pick(now)]++
+	}
+	require.Len(t, picked, 3)
+	for _, count := range picked {
+		require.Equal(t, 2, count)
+	}
+
+	ejected := s.replicas.replicas[1]
+	ejected.eject(now.Add(time.Minute))
+	for i := 0; i < 6; i++ {
+		require.NotSame(t, ejected, s.replicas.pick(now))
+	}
+	// ejected replica is used again after the ejection period
+	picked = make(map[*replica]int)
+	for i := 0; i < 3; i++ {
+		picked[s.replicas.pick(now.Add(time.Minute))]++
+	}
+	require.Contains(t, picked, ejected)
+
+	for _, r := range s.replicas.replicas {
+		r.eject(now.Add(time.Minute))
+	}
+	require.Nil(t, s.replicas.pick(now))
+
+	var noReplicas *replicaSet
+	require.Nil(t, noReplicas.pick(now))
+}
+
+func TestRead(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads from replica", func(t *testing.T) {
+		s := newTestStorage(t, 1)
+		err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+			require.Same(t, s.replicas.replicas[0].db, conn)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("falls back to primary and ejects failed replica", func(t *testing.T) {
+		s := newTestStorage(t, 1)
+		var used []sqlx.ExtContext
+		err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+			used = append(used, conn)
+			if conn != s.db {
+				return driver.ErrBadConn
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []sqlx.ExtContext{s.replicas.replicas[0].db, s.db}, used)
+		require.False(t, s.replicas.replicas[0].healthy(time.Now()))
+	})
+
+	t.Run("query errors are not retried on primary", func(t *testing.T) {
+		s := newTestStorage(t, 1)
+		calls := 0
+		err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+			calls++
+			return &pq.Error{Code: "42P01"}
+		})
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+		require.True(t, s.replicas.replicas[0].healthy(time.Now()))
+	})
+
+	t.Run("read-your-writes uses primary", func(t *testing.T) {
+		s := newTestStorage(t, 1)
+		err := s.read(app.ContextWithReadYourWrites(ctx), func(ctx context.Context, conn sqlx.ExtContext) error {
+			require.Same(t, s.db, conn)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("without replicas reads from primary", func(t *testing.T) {
+		s := newTestStorage(t, 0)
+		err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+			require.Same(t, s.db, conn)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,209 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/xid"
+)
+
+// setEventResources replaces resources booked for the event, it fails with ErrResourceBusy
+// if any of them is booked for another event at the same time.
+func setEventResources(ctx context.Context, tx *sqlx.Tx, event *storage.Event) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_resources WHERE event_id = $1;`, event.ID); err != nil {
+		return err
+	}
+	if len(event.Resources) == 0 {
+		return nil
+	}
+	if err := checkResourcesFree(ctx, tx, event.ID, event.Resources, event.StartAt, event.EndAt); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO event_resources (event_id, resource_id)
+	SELECT $1, unnest($2::varchar[]);`, event.ID, pq.Array(event.Resources))
+	return err
+}
+
+// checkResourcesFree locks rows of the resources until the end of the transaction and looks for
+// other events they are booked for within [from, to). Concurrent bookings of the same resource
+// wait for the lock, so they see each other once the first one is committed. Rows are locked
+// in the order of IDs, so transactions booking several resources do not deadlock.
+func checkResourcesFree(
+	ctx context.Context, tx *sqlx.Tx, eventID string, resourceIDs []string, from, to time.Time,
+) error {
+	if !to.After(from) {
+		return errs.ErrInvalidBookingPeriod{EventID: eventID}
+	}
+	var locked []string
+	if err := tx.SelectContext(ctx, &locked, `
+	SELECT id FROM resources WHERE id = ANY($1) ORDER BY id FOR UPDATE;`, pq.Array(resourceIDs)); err != nil {
+		return err
+	}
+	if len(locked) < len(resourceIDs) {
+		return errs.ErrNotFoundResource{ID: missingResource(resourceIDs, locked)}
+	}
+	var busy struct {
+		ResourceID string `db:"resource_id"`
+		EventID    string `db:"event_id"`
+	}
+	err := tx.QueryRowxContext(ctx, `
+	SELECT event_resources.resource_id, events.id AS event_id
+	FROM event_resources JOIN events ON events.id = event_resources.event_id
+	WHERE event_resources.resource_id = ANY($1) AND events.id <> $2 AND events.deleted_at IS NULL
+		AND events.start_at < $4 AND events.end_at > $3
+	LIMIT 1;`, pq.Array(resourceIDs), eventID, from, to).StructScan(&busy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return errs.ErrResourceBusy{ResourceID: busy.ResourceID, EventID: busy.EventID}
+}
+
+func missingResource(resourceIDs, found []string) string {
+	exists := make(map[string]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, id := range resourceIDs {
+		if !exists[id] {
+			return id
+		}
+	}
+	return ""
+}
+
+// loadResources sets resources of events, they are sorted by ID as the app sorts them.
+func (s *Storage) loadResources(ctx context.Context, conn sqlx.ExtContext, events []*storage.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	byID := make(map[string]*storage.Event, len(events))
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+		ids = append(ids, event.ID)
+	}
+	query := `
+	SELECT event_id, resource_id
+	FROM event_resources
+	WHERE event_id = ANY($1)
+	ORDER BY event_id, resource_id COLLATE "C";
+	`
+	rows, err := conn.QueryxContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			s.log.Error().Err(closeErr).Msg("Failed to close rows")
+		}
+	}()
+	for rows.Next() {
+		var eventID, resourceID string
+		if scanErr := rows.Scan(&eventID, &resourceID); scanErr != nil {
+			return scanErr
+		}
+		event := byID[eventID]
+		event.Resources = append(event.Resources, resourceID)
+	}
+	return rows.Err()
+}
+
+func (s *Storage) AddResource(ctx context.Context, resource *storage.Resource) error {
+	query := `
+	INSERT INTO resources (id, name, kind, capacity)
+	VALUES (:id, :name, :kind, :capacity);`
+	resource.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding resource with id %s", resource.ID)
+	if _, err := s.namedExec(ctx, query, resource); err != nil {
+		return errs.ErrAddResource{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add resource with id %s", resource.ID)
+	return nil
+}
+
+func (s *Storage) ModifyResource(ctx context.Context, resource *storage.Resource) error {
+	query := `
+	UPDATE resources
+	SET name = :name, kind = :kind, capacity = :capacity
+	WHERE id = :id;`
+	s.log.Debug().Msgf("Start modifying resource with id %s", resource.ID)
+	res, err := s.namedExec(ctx, query, resource)
+	if err != nil {
+		return errs.ErrUpdateResource{Err: err}
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrUpdateResource{Err: err}
+	}
+	if updated == 0 {
+		return errs.ErrNotFoundResource{ID: resource.ID}
+	}
+	s.log.Debug().Msgf("Successfully modified resource with id %s", resource.ID)
+	return nil
+}
+
+func (s *Storage) GetResource(ctx context.Context, id string) (*storage.Resource, error) {
+	query := `
+	SELECT id, name, kind, capacity
+	FROM resources
+	WHERE id = $1;
+	`
+	var resource storage.Resource
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, conn, &resource, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFoundResource{ID: id}
+		}
+		return nil, errs.ErrGetResource{Err: err}
+	}
+	return &resource, nil
+}
+
+func (s *Storage) ListResources(ctx context.Context) ([]*storage.Resource, error) {
+	query := `
+	SELECT id, name, kind, capacity
+	FROM resources
+	ORDER BY name COLLATE "C", id;
+	`
+	resources := make([]*storage.Resource, 0)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		resources = resources[:0]
+		return sqlx.SelectContext(ctx, conn, &resources, query)
+	})
+	if err != nil {
+		return nil, errs.ErrListResources{Err: err}
+	}
+	return resources, nil
+}
+
+// ListResourcesEventsInRange returns events any of resources is booked for, which overlap with [from, to).
+func (s *Storage) ListResourcesEventsInRange(
+	ctx context.Context, resourceIDs []string, from, to time.Time,
+) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of resources %v from %v to %v", resourceIDs, from, to)
+	query := `
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NULL AND start_at < $3 AND end_at > $2 AND
+		id IN (SELECT event_id FROM event_resources WHERE resource_id = ANY($1))
+	ORDER BY start_at;
+	`
+	events, err := s.selectEvents(ctx, query, pq.Array(resourceIDs), from, to)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events of resources %v, total: %d", resourceIDs, len(events))
+	return events, nil
+}
@@ -0,0 +1,100 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RetryPolicy describes how operations failed with a transient error are retried.
+// Zero MaxAttempts means that operations are not retried.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// backoff returns delay before the attempt following the given one, it doubles every attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// isTransient reports whether the operation may succeed if it is repeated:
+// the connection is lost or the transaction lost a conflict with a concurrent one.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08": // connection exception
+			return true
+		case "40": // serialization failure and deadlock
+			return pqErr.Code == "40001" || pqErr.Code == "40P01"
+		case "57": // server is shutting down
+			return pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+		}
+		return pqErr.Code == "53300" // too many connections
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) || errors.As(err, &netErr)
+}
+
+// withOperationTimeout limits ctx by operationTimeout if it is set.
+func (s *Storage) withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.operationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.operationTimeout)
+}
+
+// retry runs op with operation timeout and repeats it on transient errors according to
+// the retry policy. Inside a transaction op is run once, the whole transaction is retried instead.
+func (s *Storage) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	if s.tx != nil {
+		opCtx, cancel := s.withOperationTimeout(ctx)
+		defer cancel()
+		return op(opCtx)
+	}
+	return s.repeat(ctx, func() error {
+		opCtx, cancel := s.withOperationTimeout(ctx)
+		defer cancel()
+		return op(opCtx)
+	})
+}
+
+// repeat calls op until it succeeds, fails with a permanent error or attempts are over.
+func (s *Storage) repeat(ctx context.Context, op func() error) error {
+	attempts := s.retryPolicy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= attempts || !isTransient(err) {
+			return err
+		}
+		delay := s.retryPolicy.backoff(attempt)
+		s.log.Warn().Err(err).Msgf("Database operation failed, retrying in %v (attempt %d of %d)",
+			delay, attempt, attempts)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, transient: true},
+		{name: "deadlock", err: &pq.Error{Code: "40P01"}, transient: true},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, transient: true},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, transient: true},
+		{name: "bad connection", err: driver.ErrBadConn, transient: true},
+		{name: "wrapped", err: errs.ErrAddEvent{Err: &pq.Error{Code: "40001"}}, transient: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}},
+		{name: "query canceled", err: &pq.Error{Code: "57014"}},
+		{name: "deadline", err: context.DeadlineExceeded},
+		{name: "other", err: errors.New("other")},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.transient, isTransient(tc.err))
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	require.Equal(t, 10*time.Millisecond, policy.backoff(1))
+	require.Equal(t, 20*time.Millisecond, policy.backoff(2))
+	require.Equal(t, 40*time.Millisecond, policy.backoff(3))
+	require.Equal(t, 50*time.Millisecond, policy.backoff(4))
+	require.Equal(t, 50*time.Millisecond, policy.backoff(100))
+}
+
+func TestRetry(t *testing.T) {
+	newStorage := func(operationTimeout time.Duration) *Storage {
+		return New(logger.New("error"), "", "", "", "", "", 0, operationTimeout,
+			PoolOptions{}, TLSOptions{}, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}, ReplicaOptions{})
+	}
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		calls := 0
+		err := newStorage(0).retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return &pq.Error{Code: "40001"}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		calls := 0
+		err := newStorage(0).retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			return driver.ErrBadConn
+		})
+		require.ErrorIs(t, err, driver.ErrBadConn)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("permanent errors are not retried", func(t *testing.T) {
+		calls := 0
+		err := newStorage(0).retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			return &pq.Error{Code: "23505"}
+		})
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("operation timeout is applied", func(t *testing.T) {
+		err := newStorage(time.Millisecond).retry(context.Background(), func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok)
+			require.WithinDuration(t, time.Now().Add(time.Millisecond), deadline, time.Second)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestDSN(t *testing.T) {
+	s := New(logger.New("error"), "db", "5432", "calendar", `it's\secret`, "calendar", 0, 0,
+		PoolOptions{}, TLSOptions{Mode: "verify-full", RootCert: "/etc/calendar/ca.pem"}, RetryPolicy{}, ReplicaOptions{})
+	require.Equal(t,
+		`host='db' port='5432' user='calendar' password='it\'s\\secret' dbname='calendar' `+
+			`sslmode='verify-full' sslrootcert='/etc/calendar/ca.pem'`,
+		s.dsn())
+
+	s = New(logger.New("error"), "db", "5432", "calendar", "", "calendar", 0, 0,
+		PoolOptions{}, TLSOptions{}, RetryPolicy{}, ReplicaOptions{})
+	require.Contains(t, s.dsn(), "sslmode='disable'")
+	require.NotContains(t, s.dsn(), "sslrootcert")
+}
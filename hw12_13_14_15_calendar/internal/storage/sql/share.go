@@ -0,0 +1,70 @@
+package sqlstorage
+
+import (
+	"context"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+func (s *Storage) SaveShare(ctx context.Context, share *storage.Share) error {
+	query := `
+	INSERT INTO shares (owner_id, user_id, access, created_at)
+	VALUES (:owner_id, :user_id, :access, :created_at)
+	ON CONFLICT (owner_id, user_id) DO UPDATE
+	SET access = EXCLUDED.access, created_at = EXCLUDED.created_at;`
+	s.log.Debug().Msgf("Start saving share of calendar of %s with %s", share.OwnerID, share.UserID)
+	if _, err := s.namedExec(ctx, query, share); err != nil {
+		return errs.ErrSaveShare{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved share of calendar of %s with %s", share.OwnerID, share.UserID)
+	return nil
+}
+
+func (s *Storage) DeleteShare(ctx context.Context, ownerID, userID string) error {
+	s.log.Debug().Msgf("Start deleting share of calendar of %s with %s", ownerID, userID)
+	res, err := s.exec(ctx, `DELETE FROM shares WHERE owner_id = $1 AND user_id = $2;`, ownerID, userID)
+	if err != nil {
+		return errs.ErrDeleteShare{Err: err}
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrDeleteShare{Err: err}
+	}
+	if deleted == 0 {
+		return errs.ErrNotFoundShare{OwnerID: ownerID, UserID: userID}
+	}
+	s.log.Debug().Msgf("Successfully deleted share of calendar of %s with %s", ownerID, userID)
+	return nil
+}
+
+func (s *Storage) ListOwnerShares(ctx context.Context, ownerID string) ([]*storage.Share, error) {
+	return s.selectShares(ctx, `
+	SELECT owner_id, user_id, access, created_at
+	FROM shares
+	WHERE owner_id = $1
+	ORDER BY user_id COLLATE "C";
+	`, ownerID)
+}
+
+func (s *Storage) ListUserShares(ctx context.Context, userID string) ([]*storage.Share, error) {
+	return s.selectShares(ctx, `
+	SELECT owner_id, user_id, access, created_at
+	FROM shares
+	WHERE user_id = $1
+	ORDER BY owner_id COLLATE "C";
+	`, userID)
+}
+
+func (s *Storage) selectShares(ctx context.Context, query string, args ...interface{}) ([]*storage.Share, error) {
+	shares := make([]*storage.Share, 0)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		shares = shares[:0]
+		return sqlx.SelectContext(ctx, conn, &shares, query, args...)
+	})
+	if err != nil {
+		return nil, errs.ErrListShares{Err: err}
+	}
+	return shares, nil
+}
@@ -2,7 +2,10 @@ package sqlstorage
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
@@ -10,6 +13,7 @@ import (
 	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
 	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/xid"
 )
 
@@ -24,16 +28,39 @@ type Storage struct {
 	connectionTimeout time.Duration
 	operationTimeout  time.Duration
 
-	log   app.Logger
-	getID xid.ID
+	pool           PoolOptions
+	tls            TLSOptions
+	retryPolicy    RetryPolicy
+	replicaOptions ReplicaOptions
 
-	db *sqlx.DB
+	log app.Logger
+
+	db       *sqlx.DB
+	replicas *replicaSet
+	leases   *leaseSessions
+	// tx is set for storage passed to WithTx callback, all queries go through it
+	tx *sqlx.Tx
+}
+
+// PoolOptions limits the connection pool, zero values leave database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// TLSOptions are passed to lib/pq as sslmode and sslrootcert, Mode is disable by default.
+type TLSOptions struct {
+	Mode     string
+	RootCert string
 }
 
 func New(
 	log *logger.Logger,
 	host, port, user, password, dbname string,
 	connectionTimeout, operationTimeout time.Duration,
+	pool PoolOptions, tls TLSOptions, retryPolicy RetryPolicy, replicaOptions ReplicaOptions,
 ) *Storage {
 	return &Storage{
 		host:              host,
@@ -43,66 +70,231 @@ func New(
 		dbname:            dbname,
 		connectionTimeout: connectionTimeout,
 		operationTimeout:  operationTimeout,
+		pool:              pool,
+		tls:               tls,
+		retryPolicy:       retryPolicy,
+		replicaOptions:    replicaOptions,
+		leases:            &leaseSessions{conns: make(map[leaseKey]*sql.Conn)},
 
 		log: log,
+	}
+}
 
-		getID: xid.New(),
+// quoteDSNValue quotes value for the key=value connection string of lib/pq.
+func quoteDSNValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+func (s *Storage) dsn() string {
+	mode := s.tls.Mode
+	if mode == "" {
+		mode = "disable"
+	}
+	params := []string{
+		"host=" + quoteDSNValue(s.host),
+		"port=" + quoteDSNValue(s.port),
+		"user=" + quoteDSNValue(s.user),
+		"password=" + quoteDSNValue(s.password),
+		"dbname=" + quoteDSNValue(s.dbname),
+		"sslmode=" + quoteDSNValue(mode),
 	}
+	if s.tls.RootCert != "" {
+		params = append(params, "sslrootcert="+quoteDSNValue(s.tls.RootCert))
+	}
+	return strings.Join(params, " ")
 }
 
 func (s *Storage) Connect(ctx context.Context) error {
 	s.log.Info().Msgf("Start connection to database %s:%s with timeout %v", s.host, s.port, s.connectionTimeout)
 	ctx, cancel := context.WithTimeout(ctx, s.connectionTimeout)
 	defer cancel()
-	db, err := sqlx.ConnectContext(ctx, "postgres",
-		fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			s.host, s.port, s.user, s.password, s.dbname))
+	var db *sqlx.DB
+	err := s.repeat(ctx, func() error {
+		var err error
+		db, err = sqlx.ConnectContext(ctx, "postgres", s.dsn())
+		return err
+	})
 	if err != nil {
 		return errs.ErrConnectionFailed{Err: err}
 	}
-	err = db.PingContext(ctx)
-	if err != nil {
-		return errs.ErrPingFailed{Err: err}
-	}
+	s.configurePool(db)
 	s.db = db
 	s.log.Info().Msg("Successfully connected to database")
+	s.connectReplicas(ctx)
 	return nil
 }
 
+func (s *Storage) configurePool(db *sqlx.DB) {
+	if s.pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(s.pool.MaxOpenConns)
+	}
+	if s.pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(s.pool.MaxIdleConns)
+	}
+	if s.pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(s.pool.ConnMaxLifetime)
+	}
+	if s.pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(s.pool.ConnMaxIdleTime)
+	}
+}
+
 func (s *Storage) Close(ctx context.Context) error {
 	s.log.Info().Msg("Start closing connection to database...")
+	s.closeLeases()
+	s.closeReplicas()
 	if err := s.db.Close(); err != nil {
 		return errs.ErrCloseConnectionFailed{Err: err}
 	}
 	s.log.Info().Msg("Successfully close connection to database")
-	return s.db.Close()
+	return nil
+}
+
+// exec runs statement with operation timeout and retries.
+func (s *Storage) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = s.conn().ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// namedExec runs statement with named parameters taken from arg with operation timeout and retries.
+func (s *Storage) namedExec(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = sqlx.NamedExecContext(ctx, s.conn(), query, arg)
+		return err
+	})
+	return res, err
+}
+
+// conn returns the transaction of the storage if there is one and the database otherwise.
+func (s *Storage) conn() sqlx.ExtContext {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+func (s *Storage) rollback(tx *sqlx.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		s.log.Error().Err(err).Msg("Failed to rollback transaction")
+	}
+}
+
+// inTx runs fn in the transaction of the storage or in a new one.
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer s.rollback(tx)
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithTx runs fn in a database transaction, which is committed if fn returns nil.
+// The transaction is retried on transient errors, so fn may be called several times.
+func (s *Storage) WithTx(ctx context.Context, fn func(tx app.Storage) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	s.log.Debug().Msg("Start transaction")
+	var fnErr error
+	err := s.repeat(ctx, func() error {
+		tx, err := s.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer s.rollback(tx)
+		txStorage := *s
+		txStorage.tx = tx
+		if fnErr = fn(&txStorage); fnErr != nil {
+			return fnErr
+		}
+		return tx.Commit()
+	})
+	if fnErr != nil {
+		s.log.Debug().Err(fnErr).Msg("Transaction is rolled back")
+		return fnErr
+	}
+	if err != nil {
+		return errs.ErrTransaction{Err: err}
+	}
+	s.log.Debug().Msg("Successfully committed transaction")
+	return nil
 }
 
 func (s *Storage) AddEvent(ctx context.Context, event *storage.Event) error {
 	query := `
-		INSERT INTO events (id, title)
-        VALUES (:id, :title)`
-	event.ID = s.getID.String()
+		INSERT INTO events (id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id)
+        VALUES (:id, :title, :description, :start_at, :end_at, :time_zone, :all_day, :notify_before, :user_id)`
+	event.ID = xid.New().String()
 	s.log.Debug().Msgf("Start adding event with id %s", event.ID)
-	ctx, cancel := context.WithTimeout(ctx, s.connectionTimeout)
-	defer cancel()
-	_, err := s.db.NamedExecContext(ctx, query, event)
+	err := s.retry(ctx, func(ctx context.Context) error {
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			if _, err := tx.NamedExecContext(ctx, query, event); err != nil {
+				return err
+			}
+			for _, attendee := range event.Attendees {
+				if _, err := tx.ExecContext(ctx, `
+			INSERT INTO event_attendees (event_id, user_id, status)
+			VALUES ($1, $2, $3);`, event.ID, attendee.UserID, attendee.Status); err != nil {
+					return err
+				}
+			}
+			if err := setEventTags(ctx, tx, event.ID, event.Tags); err != nil {
+				return err
+			}
+			return setEventResources(ctx, tx, event)
+		})
+	})
 	if err != nil {
 		return errs.ErrAddEvent{Err: err}
 	}
 	s.log.Debug().Msgf("Successfully add event with id %s", event.ID)
-	return err
+	return nil
 }
 
 func (s *Storage) ModifyEvent(ctx context.Context, event *storage.Event) error {
 	s.log.Debug().Msgf("Start editing event with id %s", event.ID)
 	query := `
 	UPDATE events
-	SET title = :title
-	WHERE id = :id;`
-	ctx, cancel := context.WithTimeout(ctx, s.connectionTimeout)
-	defer cancel()
-	_, err := s.db.NamedExecContext(ctx, query, event)
+	SET title = :title, description = :description, start_at = :start_at, end_at = :end_at,
+		time_zone = :time_zone, all_day = :all_day, notify_before = :notify_before
+	WHERE id = :id AND deleted_at IS NULL;`
+	err := s.retry(ctx, func(ctx context.Context) error {
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			res, err := tx.NamedExecContext(ctx, query, event)
+			if err != nil {
+				return err
+			}
+			updated, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if updated == 0 {
+				// the event is missing or it is in the trash
+				return sql.ErrNoRows
+			}
+			if err = setEventTags(ctx, tx, event.ID, event.Tags); err != nil {
+				return err
+			}
+			return setEventResources(ctx, tx, event)
+		})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrNotFoundEvent{ID: event.ID}
+	}
 	if err != nil {
 		return errs.ErrUpdateEvent{Err: err}
 	}
@@ -110,12 +302,14 @@ func (s *Storage) ModifyEvent(ctx context.Context, event *storage.Event) error {
 	return nil
 }
 
+// DeleteEvent moves event to the trash, it is removed completely by PurgeDeletedEvents.
 func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
 	s.log.Debug().Msgf("Start deleting event with id %s", id)
-	query := `DELETE FROM events WHERE id = :id;`
-	ctx, cancel := context.WithTimeout(ctx, s.connectionTimeout)
-	defer cancel()
-	_, err := s.db.ExecContext(ctx, query, id)
+	query := `
+	UPDATE events
+	SET deleted_at = now()
+	WHERE id = $1 AND deleted_at IS NULL;`
+	_, err := s.exec(ctx, query, id)
 	if err != nil {
 		return errs.ErrDeleteEvent{Err: err}
 	}
@@ -123,20 +317,101 @@ func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
 	return nil
 }
 
+// RestoreEvent moves the event back from the trash, its resources have to be free again.
+func (s *Storage) RestoreEvent(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start restoring event with id %s", id)
+	err := s.retry(ctx, func(ctx context.Context) error {
+		return s.inTx(ctx, func(tx *sqlx.Tx) error {
+			var event storage.Event
+			if err := tx.QueryRowxContext(ctx, `
+			SELECT id, start_at, end_at
+			FROM events
+			WHERE id = $1 AND deleted_at IS NOT NULL
+			FOR UPDATE;`, id).StructScan(&event); err != nil {
+				return err
+			}
+			var resources []string
+			if err := tx.SelectContext(ctx, &resources, `
+			SELECT resource_id FROM event_resources WHERE event_id = $1;`, id); err != nil {
+				return err
+			}
+			if len(resources) > 0 {
+				if err := checkResourcesFree(ctx, tx, id, resources, event.StartAt, event.EndAt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, `UPDATE events SET deleted_at = NULL WHERE id = $1;`, id)
+			return err
+		})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrNotFoundEvent{ID: id}
+	}
+	if err != nil {
+		return errs.ErrRestoreEvent{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully restored event with id %s", id)
+	return nil
+}
+
+func (s *Storage) ListDeletedEvents(ctx context.Context) ([]*storage.Event, error) {
+	s.log.Debug().Msg("Start listing deleted events")
+	query := `
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NOT NULL;
+	`
+	events, err := s.selectEvents(ctx, query)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed deleted events, total: %d", len(events))
+	return events, nil
+}
+
+func (s *Storage) PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) (int, error) {
+	s.log.Debug().Msgf("Start purging events deleted before %v", deletedBefore)
+	query := `DELETE FROM events WHERE deleted_at < $1;`
+	res, err := s.exec(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, errs.ErrPurgeEvents{Err: err}
+	}
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, errs.ErrPurgeEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully purged events, total: %d", purged)
+	return int(purged), nil
+}
+
 func (s *Storage) GetEvent(ctx context.Context, id string) (*storage.Event, error) {
 	s.log.Debug().Msgf("Start getting event with id %s", id)
 	query := `
-	SELECT id, title 
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
 	FROM events
-	WHERE id=$1;
+	WHERE id=$1 AND deleted_at IS NULL;
 	`
-	ctx, cancel := context.WithTimeout(ctx, s.connectionTimeout)
-	defer cancel()
-	row := s.db.QueryRowxContext(ctx, query, id)
 	var event storage.Event
-	if err := row.StructScan(&event); err != nil {
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		event = storage.Event{}
+		if err := conn.QueryRowxContext(ctx, query, id).StructScan(&event); err != nil {
+			return err
+		}
+		if err := s.loadAttendees(ctx, conn, []*storage.Event{&event}); err != nil {
+			return err
+		}
+		if err := s.loadTags(ctx, conn, []*storage.Event{&event}); err != nil {
+			return err
+		}
+		return s.loadResources(ctx, conn, []*storage.Event{&event})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFoundEvent{ID: id}
+		}
 		return nil, errs.ErrGetEvent{Err: err}
 	}
+	event.StartAt, event.EndAt = event.StartAt.UTC(), event.EndAt.UTC()
 	s.log.Debug().Msgf("Successfully got event with id %s", id)
 	return &event, nil
 }
@@ -144,28 +419,316 @@ func (s *Storage) GetEvent(ctx context.Context, id string) (*storage.Event, erro
 func (s *Storage) ListEvents(ctx context.Context) ([]*storage.Event, error) {
 	s.log.Debug().Msg("Start listing events")
 	query := `
-	SELECT id, title 
-	FROM events;
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NULL;
 	`
-	ctx, cancel := context.WithTimeout(ctx, s.connectionTimeout)
-	defer cancel()
-	rows, err := s.db.QueryxContext(ctx, query)
+	events, err := s.selectEvents(ctx, query)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully list events")
+	return events, nil
+}
+
+func (s *Storage) ListUserEvents(ctx context.Context, userID string) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of user %s", userID)
+	query := `
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NULL AND (
+		user_id = $1 OR
+		id IN (SELECT event_id FROM event_attendees WHERE user_id = $1)
+	);
+	`
+	events, err := s.selectEvents(ctx, query, userID)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events of user %s, total: %d", userID, len(events))
+	return events, nil
+}
+
+// ListEventsInRange returns events which overlap with [from, to).
+func (s *Storage) ListEventsInRange(ctx context.Context, from, to time.Time) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events from %v to %v", from, to)
+	query := `
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NULL AND start_at < $2 AND end_at > $1
+	ORDER BY start_at;
+	`
+	events, err := s.selectEvents(ctx, query, from, to)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events from %v to %v, total: %d", from, to, len(events))
+	return events, nil
+}
+
+// ListUsersEventsInRange returns events owned by or attended by any of users,
+// which overlap with [from, to).
+func (s *Storage) ListUsersEventsInRange(
+	ctx context.Context, userIDs []string, from, to time.Time,
+) ([]*storage.Event, error) {
+	s.log.Debug().Msgf("Start listing events of users %v from %v to %v", userIDs, from, to)
+	query := `
+	SELECT id, title, description, start_at, end_at, time_zone, all_day, notify_before, user_id, deleted_at
+	FROM events
+	WHERE deleted_at IS NULL AND start_at < $3 AND end_at > $2 AND (
+		user_id = ANY($1) OR
+		id IN (SELECT event_id FROM event_attendees WHERE user_id = ANY($1))
+	)
+	ORDER BY start_at;
+	`
+	events, err := s.selectEvents(ctx, query, pq.Array(userIDs), from, to)
+	if err != nil {
+		return nil, errs.ErrListEvents{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed events of users %v, total: %d", userIDs, len(events))
+	return events, nil
+}
+
+// selectEvents runs query returning events and loads their attendees, tags and resources.
+func (s *Storage) selectEvents(ctx context.Context, query string, args ...interface{}) ([]*storage.Event, error) {
+	var events []*storage.Event
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		var err error
+		events, err = s.queryEvents(ctx, conn, query, args...)
+		return err
+	})
+	return events, err
+}
+
+func (s *Storage) queryEvents(
+	ctx context.Context, conn sqlx.ExtContext, query string, args ...interface{},
+) ([]*storage.Event, error) {
+	rows, err := conn.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
 			s.log.Error().Err(closeErr).Msg("Failed to close rows")
 		}
 	}()
-	if err != nil {
-		return nil, errs.ErrListEvents{Err: err}
-	}
 	events := make([]*storage.Event, 0)
 	for rows.Next() {
 		var event storage.Event
 		if scanErr := rows.StructScan(&event); scanErr != nil {
-			return nil, errs.ErrListEvents{Err: scanErr}
+			return nil, scanErr
 		}
+		event.StartAt, event.EndAt = event.StartAt.UTC(), event.EndAt.UTC()
 		events = append(events, &event)
 	}
-	s.log.Debug().Msgf("Successfully list events")
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = s.loadAttendees(ctx, conn, events); err != nil {
+		return nil, err
+	}
+	if err = s.loadTags(ctx, conn, events); err != nil {
+		return nil, err
+	}
+	if err = s.loadResources(ctx, conn, events); err != nil {
+		return nil, err
+	}
 	return events, nil
 }
+
+func (s *Storage) loadAttendees(ctx context.Context, conn sqlx.ExtContext, events []*storage.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	byID := make(map[string]*storage.Event, len(events))
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+		ids = append(ids, event.ID)
+	}
+	query := `
+	SELECT event_id, user_id, status
+	FROM event_attendees
+	WHERE event_id = ANY($1)
+	ORDER BY event_id, user_id;
+	`
+	rows, err := conn.QueryxContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			s.log.Error().Err(closeErr).Msg("Failed to close rows")
+		}
+	}()
+	for rows.Next() {
+		var row struct {
+			EventID string `db:"event_id"`
+			storage.Attendee
+		}
+		if scanErr := rows.StructScan(&row); scanErr != nil {
+			return scanErr
+		}
+		event := byID[row.EventID]
+		event.Attendees = append(event.Attendees, row.Attendee)
+	}
+	return rows.Err()
+}
+
+func (s *Storage) AddAttendees(ctx context.Context, eventID string, userIDs []string) error {
+	s.log.Debug().Msgf("Start adding attendees to event %s", eventID)
+	if _, err := s.GetEvent(app.ContextWithReadYourWrites(ctx), eventID); err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO event_attendees (event_id, user_id, status)
+	SELECT $1, unnest($2::varchar[]), $3
+	ON CONFLICT (event_id, user_id) DO NOTHING;`
+	if _, err := s.exec(ctx, query, eventID, pq.Array(userIDs), storage.AttendeeStatusNeedsAction); err != nil {
+		return errs.ErrAddAttendees{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully added attendees to event %s", eventID)
+	return nil
+}
+
+func (s *Storage) SetAttendeeStatus(
+	ctx context.Context, eventID, userID string, status storage.AttendeeStatus,
+) error {
+	s.log.Debug().Msgf("Start setting status %s of attendee %s in event %s", status, userID, eventID)
+	if _, err := s.GetEvent(app.ContextWithReadYourWrites(ctx), eventID); err != nil {
+		return err
+	}
+	query := `
+	UPDATE event_attendees
+	SET status = $3
+	WHERE event_id = $1 AND user_id = $2;`
+	res, err := s.exec(ctx, query, eventID, userID, status)
+	if err != nil {
+		return errs.ErrUpdateAttendee{Err: err}
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrUpdateAttendee{Err: err}
+	}
+	if updated == 0 {
+		return errs.ErrNotFoundAttendee{EventID: eventID, UserID: userID}
+	}
+	s.log.Debug().Msgf("Successfully set status of attendee %s in event %s", userID, eventID)
+	return nil
+}
+
+// auditRow is a representation of storage.AuditRecord in event_audit table,
+// event snapshots are kept as jsonb.
+type auditRow struct {
+	ID        string         `db:"id"`
+	EventID   string         `db:"event_id"`
+	Actor     string         `db:"actor"`
+	Action    string         `db:"action"`
+	CreatedAt time.Time      `db:"created_at"`
+	Before    sql.NullString `db:"before"`
+	After     sql.NullString `db:"after"`
+}
+
+func marshalSnapshot(event *storage.Event) (sql.NullString, error) {
+	if event == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func unmarshalSnapshot(data sql.NullString) (*storage.Event, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var event storage.Event
+	if err := json.Unmarshal([]byte(data.String), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (s *Storage) AddAuditRecord(ctx context.Context, record *storage.AuditRecord) error {
+	query := `
+	INSERT INTO event_audit (id, event_id, actor, action, created_at, before, after)
+	VALUES (:id, :event_id, :actor, :action, :created_at, :before, :after);`
+	record.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding audit record %s for event %s", record.ID, record.EventID)
+	row := auditRow{
+		ID:        record.ID,
+		EventID:   record.EventID,
+		Actor:     record.Actor,
+		Action:    string(record.Action),
+		CreatedAt: record.CreatedAt,
+	}
+	var err error
+	if row.Before, err = marshalSnapshot(record.Before); err != nil {
+		return errs.ErrAddAuditRecord{Err: err}
+	}
+	if row.After, err = marshalSnapshot(record.After); err != nil {
+		return errs.ErrAddAuditRecord{Err: err}
+	}
+	if _, err = s.namedExec(ctx, query, row); err != nil {
+		return errs.ErrAddAuditRecord{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add audit record %s", record.ID)
+	return nil
+}
+
+func (s *Storage) ListAuditRecords(ctx context.Context, eventID string) ([]*storage.AuditRecord, error) {
+	s.log.Debug().Msgf("Start listing audit records of event %s", eventID)
+	var records []*storage.AuditRecord
+	err := s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		records, err = s.queryAuditRecords(ctx, eventID)
+		return err
+	})
+	if err != nil {
+		return nil, errs.ErrListAuditRecords{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully listed audit records of event %s, total: %d", eventID, len(records))
+	return records, nil
+}
+
+func (s *Storage) queryAuditRecords(ctx context.Context, eventID string) ([]*storage.AuditRecord, error) {
+	query := `
+	SELECT id, event_id, actor, action, created_at, before, after
+	FROM event_audit
+	WHERE event_id = $1
+	ORDER BY created_at, id;
+	`
+	rows, err := s.conn().QueryxContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			s.log.Error().Err(closeErr).Msg("Failed to close rows")
+		}
+	}()
+	records := make([]*storage.AuditRecord, 0)
+	for rows.Next() {
+		var row auditRow
+		if scanErr := rows.StructScan(&row); scanErr != nil {
+			return nil, scanErr
+		}
+		record := &storage.AuditRecord{
+			ID:        row.ID,
+			EventID:   row.EventID,
+			Actor:     row.Actor,
+			Action:    storage.AuditAction(row.Action),
+			CreatedAt: row.CreatedAt,
+		}
+		if record.Before, err = unmarshalSnapshot(row.Before); err != nil {
+			return nil, err
+		}
+		if record.After, err = unmarshalSnapshot(row.After); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,84 @@
+package sqlstorage
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/storagetest"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// testDBHost returns the host of the test database with applied migrations, tests are skipped
+// unless CALENDAR_TEST_DB_HOST is set, e.g. CALENDAR_TEST_DB_HOST=localhost make migrate-up test.
+func testDBHost(t *testing.T) string {
+	t.Helper()
+	host := os.Getenv("CALENDAR_TEST_DB_HOST")
+	if host == "" {
+		t.Skip("CALENDAR_TEST_DB_HOST is not set")
+	}
+	return host
+}
+
+func connect(t *testing.T, operationTimeout time.Duration) *Storage {
+	t.Helper()
+	s := New(logger.New("error"), testDBHost(t), getEnv("CALENDAR_TEST_DB_PORT", "5432"),
+		getEnv("CALENDAR_TEST_DB_USER", "postgres"), getEnv("CALENDAR_TEST_DB_PASSWORD", ""),
+		getEnv("CALENDAR_TEST_DB_NAME", "postgres"), 5*time.Second, operationTimeout,
+		PoolOptions{}, TLSOptions{}, RetryPolicy{}, ReplicaOptions{})
+	require.NoError(t, s.Connect(context.Background()))
+	t.Cleanup(func() {
+		require.NoError(t, s.Close(context.Background()))
+	})
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	testDBHost(t)
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		t.Helper()
+		return connect(t, time.Second)
+	})
+}
+
+// TestAdvisoryLocks runs holders in storages of their own as schedulers of different processes.
+// Without operation timeout unlock must not be cancelled, otherwise a pooled session keeps the lock.
+func TestAdvisoryLocks(t *testing.T) {
+	ctx := context.Background()
+	name := "advisory-locks-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	first, second := connect(t, 0), connect(t, 0)
+
+	acquired, err := first.AcquireLease(ctx, name, "first", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	acquired, err = second.AcquireLease(ctx, name, "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	require.NoError(t, first.ReleaseLease(ctx, name, "first"))
+	acquired, err = second.AcquireLease(ctx, name, "second", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired, "lease is released")
+	// the connection of the released lease is back in the pool and usable
+	_, err = first.ListEvents(ctx)
+	require.NoError(t, err)
+
+	// the lock is released when the session of the holder ends
+	require.NoError(t, second.Close(ctx))
+	acquired, err = first.AcquireLease(ctx, name, "first", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired, "lease of the closed storage")
+	require.NoError(t, first.ReleaseLease(ctx, name, "first"))
+}
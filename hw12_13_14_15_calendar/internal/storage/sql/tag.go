@@ -0,0 +1,109 @@
+package sqlstorage
+
+import (
+	"context"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// setEventTags replaces tags of the event, tags are created for the owner of the event
+// if the owner has no such tags yet.
+func setEventTags(ctx context.Context, tx *sqlx.Tx, eventID string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_tags WHERE event_id = $1;`, eventID); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO tags (user_id, name)
+	SELECT user_id, unnest($2::varchar[]) FROM events WHERE id = $1
+	ON CONFLICT (user_id, name) DO NOTHING;`, eventID, pq.Array(tags)); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO event_tags (event_id, user_id, name)
+	SELECT id, user_id, unnest($2::varchar[]) FROM events WHERE id = $1;`, eventID, pq.Array(tags))
+	return err
+}
+
+// loadTags sets tags of events, they are sorted by name byte-wise as the app sorts them.
+func (s *Storage) loadTags(ctx context.Context, conn sqlx.ExtContext, events []*storage.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	byID := make(map[string]*storage.Event, len(events))
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+		ids = append(ids, event.ID)
+	}
+	query := `
+	SELECT event_id, name
+	FROM event_tags
+	WHERE event_id = ANY($1)
+	ORDER BY event_id, name COLLATE "C";
+	`
+	rows, err := conn.QueryxContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			s.log.Error().Err(closeErr).Msg("Failed to close rows")
+		}
+	}()
+	for rows.Next() {
+		var eventID, name string
+		if scanErr := rows.Scan(&eventID, &name); scanErr != nil {
+			return scanErr
+		}
+		event := byID[eventID]
+		event.Tags = append(event.Tags, name)
+	}
+	return rows.Err()
+}
+
+func (s *Storage) SaveTag(ctx context.Context, tag *storage.Tag) error {
+	query := `
+	INSERT INTO tags (user_id, name, color)
+	VALUES (:user_id, :name, :color)
+	ON CONFLICT (user_id, name) DO UPDATE
+	SET color = EXCLUDED.color;`
+	s.log.Debug().Msgf("Start saving tag %s of user %s", tag.Name, tag.UserID)
+	if _, err := s.namedExec(ctx, query, tag); err != nil {
+		return errs.ErrSaveTag{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully saved tag %s of user %s", tag.Name, tag.UserID)
+	return nil
+}
+
+// ListUserTags returns categories of the user and tags of the user events which are not in the trash,
+// tags left by deleted events stay in the table, so their colors are kept.
+func (s *Storage) ListUserTags(ctx context.Context, userID string) ([]*storage.Tag, error) {
+	query := `
+	SELECT user_id, name, color
+	FROM tags
+	WHERE user_id = $1 AND (
+		color <> '' OR
+		EXISTS (
+			SELECT 1
+			FROM event_tags JOIN events ON events.id = event_tags.event_id
+			WHERE event_tags.user_id = tags.user_id AND event_tags.name = tags.name AND events.deleted_at IS NULL
+		)
+	)
+	ORDER BY name COLLATE "C";
+	`
+	tags := make([]*storage.Tag, 0)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		tags = tags[:0]
+		return sqlx.SelectContext(ctx, conn, &tags, query, userID)
+	})
+	if err != nil {
+		return nil, errs.ErrListTags{Err: err}
+	}
+	return tags, nil
+}
@@ -0,0 +1,176 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/xid"
+)
+
+func (s *Storage) AddWebhook(ctx context.Context, webhook *storage.Webhook) error {
+	query := `
+	INSERT INTO webhooks (id, user_id, url, secret, created_at)
+	VALUES (:id, :user_id, :url, :secret, :created_at);`
+	webhook.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding webhook %s of user %s", webhook.ID, webhook.UserID)
+	if _, err := s.namedExec(ctx, query, webhook); err != nil {
+		return errs.ErrAddWebhook{Err: err}
+	}
+	s.log.Debug().Msgf("Successfully add webhook %s", webhook.ID)
+	return nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id string) (*storage.Webhook, error) {
+	query := `
+	SELECT id, user_id, url, secret, created_at
+	FROM webhooks
+	WHERE id = $1;
+	`
+	var webhook storage.Webhook
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, conn, &webhook, query, id)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.ErrNotFoundWebhook{ID: id}
+		}
+		return nil, errs.ErrGetWebhook{Err: err}
+	}
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+	return &webhook, nil
+}
+
+// DeleteWebhook removes the webhook, its deliveries are removed by the foreign key cascade.
+func (s *Storage) DeleteWebhook(ctx context.Context, id string) error {
+	s.log.Debug().Msgf("Start deleting webhook %s", id)
+	res, err := s.exec(ctx, `DELETE FROM webhooks WHERE id = $1;`, id)
+	if err != nil {
+		return errs.ErrDeleteWebhook{Err: err}
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrDeleteWebhook{Err: err}
+	}
+	if deleted == 0 {
+		return errs.ErrNotFoundWebhook{ID: id}
+	}
+	s.log.Debug().Msgf("Successfully deleted webhook %s", id)
+	return nil
+}
+
+func (s *Storage) ListUsersWebhooks(ctx context.Context, userIDs []string) ([]*storage.Webhook, error) {
+	query := `
+	SELECT id, user_id, url, secret, created_at
+	FROM webhooks
+	WHERE user_id = ANY($1)
+	ORDER BY id;
+	`
+	webhooks := make([]*storage.Webhook, 0)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		webhooks = webhooks[:0]
+		return sqlx.SelectContext(ctx, conn, &webhooks, query, pq.Array(userIDs))
+	})
+	if err != nil {
+		return nil, errs.ErrListWebhooks{Err: err}
+	}
+	for _, webhook := range webhooks {
+		webhook.CreatedAt = webhook.CreatedAt.UTC()
+	}
+	return webhooks, nil
+}
+
+func (s *Storage) AddWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	// the delivery is not added if the webhook is deleted meanwhile
+	query := `
+	INSERT INTO webhook_deliveries (id, webhook_id, event_id, type, payload, status, attempts,
+		next_attempt_at, last_error, response_code, created_at, updated_at)
+	SELECT :id, :webhook_id, :event_id, :type, :payload, :status, :attempts,
+		:next_attempt_at, :last_error, :response_code, :created_at, :updated_at
+	WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = :webhook_id);`
+	delivery.ID = xid.New().String()
+	s.log.Debug().Msgf("Start adding delivery %s to webhook %s", delivery.ID, delivery.WebhookID)
+	res, err := s.namedExec(ctx, query, delivery)
+	if err != nil {
+		return errs.ErrSaveWebhookDelivery{Err: err}
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return errs.ErrSaveWebhookDelivery{Err: err}
+	}
+	if added == 0 {
+		return errs.ErrNotFoundWebhook{ID: delivery.WebhookID}
+	}
+	return nil
+}
+
+// UpdateWebhookDelivery does nothing if the delivery was removed with its webhook.
+func (s *Storage) UpdateWebhookDelivery(ctx context.Context, delivery *storage.WebhookDelivery) error {
+	s.log.Debug().Msgf("Start updating delivery %s", delivery.ID)
+	query := `
+	UPDATE webhook_deliveries
+	SET status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at,
+		last_error = :last_error, response_code = :response_code, updated_at = :updated_at
+	WHERE id = :id;`
+	if _, err := s.namedExec(ctx, query, delivery); err != nil {
+		return errs.ErrSaveWebhookDelivery{Err: err}
+	}
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*storage.WebhookDelivery, error) {
+	query := `
+	SELECT id, webhook_id, event_id, type, payload, status, attempts,
+		next_attempt_at, last_error, response_code, created_at, updated_at
+	FROM webhook_deliveries
+	WHERE webhook_id = $1
+	ORDER BY created_at, id;
+	`
+	deliveries, err := s.selectDeliveries(ctx, query, webhookID)
+	if err != nil {
+		return nil, errs.ErrListWebhookDeliveries{Err: err}
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) ListDueWebhookDeliveries(
+	ctx context.Context, now time.Time, limit int,
+) ([]*storage.WebhookDelivery, error) {
+	query := `
+	SELECT id, webhook_id, event_id, type, payload, status, attempts,
+		next_attempt_at, last_error, response_code, created_at, updated_at
+	FROM webhook_deliveries
+	WHERE status = $1 AND next_attempt_at <= $2
+	ORDER BY next_attempt_at
+	LIMIT $3;
+	`
+	deliveries, err := s.selectDeliveries(ctx, query, storage.DeliveryStatusPending, now, limit)
+	if err != nil {
+		return nil, errs.ErrListWebhookDeliveries{Err: err}
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) selectDeliveries(
+	ctx context.Context, query string, args ...interface{},
+) ([]*storage.WebhookDelivery, error) {
+	deliveries := make([]*storage.WebhookDelivery, 0)
+	err := s.read(ctx, func(ctx context.Context, conn sqlx.ExtContext) error {
+		deliveries = deliveries[:0]
+		return sqlx.SelectContext(ctx, conn, &deliveries, query, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, delivery := range deliveries {
+		delivery.NextAttemptAt = delivery.NextAttemptAt.UTC()
+		delivery.CreatedAt = delivery.CreatedAt.UTC()
+		delivery.UpdatedAt = delivery.UpdatedAt.UTC()
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,791 @@
+// Package storagetest contains behaviour every app.Storage implementation has to follow.
+// Tests do not expect the storage to be empty, so they can be run against a shared database.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs conformance tests against storages created by newStorage.
+func Run(t *testing.T, newStorage func(t *testing.T) app.Storage) {
+	t.Helper()
+	tests := []struct {
+		name string
+		test func(t *testing.T, s app.Storage)
+	}{
+		{name: "events", test: testEvents},
+		{name: "trash", test: testTrash},
+		{name: "attendees", test: testAttendees},
+		{name: "tags", test: testTags},
+		{name: "shares", test: testShares},
+		{name: "attachments", test: testAttachments},
+		{name: "resources", test: testResources},
+		{name: "concurrent bookings", test: testConcurrentBookings},
+		{name: "range listings", test: testRangeListings},
+		{name: "audit records", test: testAuditRecords},
+		{name: "transactions", test: testTransactions},
+		{name: "webhooks", test: testWebhooks},
+		{name: "user profiles", test: testUserProfiles},
+		{name: "reminders", test: testReminders},
+		{name: "leases", test: testLeases},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newStorage(t))
+		})
+	}
+}
+
+// newEvent returns event with time precision supported by all storages.
+func newEvent(title, userID string, start time.Time, duration time.Duration) *storage.Event {
+	start = start.UTC().Truncate(time.Second)
+	return &storage.Event{
+		Title:    title,
+		StartAt:  start,
+		EndAt:    start.Add(duration),
+		TimeZone: "UTC",
+		UserID:   userID,
+	}
+}
+
+func findEvent(events []*storage.Event, id string) *storage.Event {
+	for _, event := range events {
+		if event.ID == id {
+			return event
+		}
+	}
+	return nil
+}
+
+func testEvents(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+
+	first := newEvent("first", user, time.Now(), time.Hour)
+	second := newEvent("second", user, time.Now(), time.Hour)
+	require.NoError(t, s.AddEvent(ctx, first))
+	require.NoError(t, s.AddEvent(ctx, second))
+	require.NotEmpty(t, first.ID)
+	require.NotEqual(t, first.ID, second.ID)
+
+	first.Title = "first modified"
+	first.Description = "long description"
+	first.NotifyBefore = 15 * time.Minute
+	first.StartAt = first.StartAt.Add(time.Hour)
+	first.EndAt = first.EndAt.Add(time.Hour)
+	require.NoError(t, s.ModifyEvent(ctx, first))
+	got, err := s.GetEvent(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, first, got)
+
+	_, err = s.GetEvent(ctx, xid.New().String())
+	require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+
+	missing := newEvent("missing", user, time.Now(), time.Hour)
+	missing.ID = xid.New().String()
+	require.ErrorAs(t, s.ModifyEvent(ctx, missing), &errs.ErrNotFoundEvent{})
+	_, err = s.GetEvent(ctx, missing.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundEvent{}, "missing event is not created by modification")
+
+	events, err := s.ListEvents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, first, findEvent(events, first.ID))
+	require.Equal(t, second, findEvent(events, second.ID))
+
+	events, err = s.ListUserEvents(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+}
+
+func testTrash(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+
+	event := newEvent("deleted", user, time.Now(), time.Hour)
+	kept := newEvent("kept", user, time.Now(), time.Hour)
+	require.NoError(t, s.AddEvent(ctx, event))
+	require.NoError(t, s.AddEvent(ctx, kept))
+	require.NoError(t, s.DeleteEvent(ctx, event.ID))
+
+	_, err := s.GetEvent(ctx, event.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+	events, err := s.ListEvents(ctx)
+	require.NoError(t, err)
+	require.Nil(t, findEvent(events, event.ID))
+	events, err = s.ListUserEvents(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Event{kept}, events)
+
+	deleted, err := s.ListDeletedEvents(ctx)
+	require.NoError(t, err)
+	trashed := findEvent(deleted, event.ID)
+	require.NotNil(t, trashed)
+	require.NotNil(t, trashed.DeletedAt)
+
+	event.Title = "modified in the trash"
+	require.ErrorAs(t, s.ModifyEvent(ctx, event), &errs.ErrNotFoundEvent{})
+	event.Title = "deleted"
+
+	require.NoError(t, s.RestoreEvent(ctx, event.ID))
+	require.ErrorAs(t, s.RestoreEvent(ctx, event.ID), &errs.ErrNotFoundEvent{})
+	got, err := s.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event, got)
+
+	require.NoError(t, s.DeleteEvent(ctx, event.ID))
+	_, err = s.PurgeDeletedEvents(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	deleted, err = s.ListDeletedEvents(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, findEvent(deleted, event.ID))
+
+	purged, err := s.PurgeDeletedEvents(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, 1)
+	require.ErrorAs(t, s.RestoreEvent(ctx, event.ID), &errs.ErrNotFoundEvent{})
+	require.ErrorAs(t, s.ModifyEvent(ctx, event), &errs.ErrNotFoundEvent{})
+	_, err = s.GetEvent(ctx, event.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundEvent{}, "purged event is not created by modification")
+	_, err = s.GetEvent(ctx, kept.ID)
+	require.NoError(t, err)
+}
+
+func testAttendees(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	owner, guest, other := xid.New().String(), xid.New().String(), xid.New().String()
+
+	event := newEvent("meeting", owner, time.Now(), time.Hour)
+	event.Attendees = []storage.Attendee{{UserID: guest, Status: storage.AttendeeStatusNeedsAction}}
+	require.NoError(t, s.AddEvent(ctx, event))
+
+	require.NoError(t, s.AddAttendees(ctx, event.ID, []string{guest, other}))
+	require.NoError(t, s.SetAttendeeStatus(ctx, event.ID, guest, storage.AttendeeStatusAccepted))
+	require.ErrorAs(t, s.SetAttendeeStatus(ctx, event.ID, xid.New().String(), storage.AttendeeStatusAccepted),
+		&errs.ErrNotFoundAttendee{})
+	require.ErrorAs(t, s.AddAttendees(ctx, xid.New().String(), []string{guest}), &errs.ErrNotFoundEvent{})
+
+	got, err := s.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []storage.Attendee{
+		{UserID: guest, Status: storage.AttendeeStatusAccepted},
+		{UserID: other, Status: storage.AttendeeStatusNeedsAction},
+	}, got.Attendees)
+
+	// modification of event does not touch its attendees
+	modified := got.Clone()
+	modified.Title = "renamed"
+	modified.Attendees = nil
+	require.NoError(t, s.ModifyEvent(ctx, modified))
+	got, err = s.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", got.Title)
+	require.Len(t, got.Attendees, 2)
+
+	for _, user := range []string{owner, guest, other} {
+		events, listErr := s.ListUserEvents(ctx, user)
+		require.NoError(t, listErr)
+		require.Len(t, events, 1)
+		require.Equal(t, event.ID, events[0].ID)
+	}
+}
+
+func testTags(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user, other := xid.New().String(), xid.New().String()
+
+	event := newEvent("standup", user, time.Now(), time.Hour)
+	event.Tags = []string{"oncall", "work"}
+	require.NoError(t, s.AddEvent(ctx, event))
+	got, err := s.GetEvent(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, event, got)
+
+	// modification replaces tags of the event
+	event.Tags = []string{"travel", "work"}
+	require.NoError(t, s.ModifyEvent(ctx, event))
+	events, err := s.ListUserEvents(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, []string{"travel", "work"}, findEvent(events, event.ID).Tags)
+
+	require.NoError(t, s.SaveTag(ctx, &storage.Tag{UserID: user, Name: "work", Color: "#ff8800"}))
+	require.NoError(t, s.SaveTag(ctx, &storage.Tag{UserID: user, Name: "family", Color: "#00ff00"}))
+	require.NoError(t, s.SaveTag(ctx, &storage.Tag{UserID: user, Name: "oncall"}))
+	require.NoError(t, s.SaveTag(ctx, &storage.Tag{UserID: other, Name: "work", Color: "#000000"}))
+
+	tags, err := s.ListUserTags(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Tag{
+		{UserID: user, Name: "family", Color: "#00ff00"},
+		{UserID: user, Name: "travel"},
+		{UserID: user, Name: "work", Color: "#ff8800"},
+	}, tags)
+
+	// tags of events in the trash are not listed, categories are
+	require.NoError(t, s.DeleteEvent(ctx, event.ID))
+	require.NoError(t, s.SaveTag(ctx, &storage.Tag{UserID: user, Name: "family"}))
+	tags, err = s.ListUserTags(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Tag{{UserID: user, Name: "work", Color: "#ff8800"}}, tags)
+}
+
+func testShares(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	manager, assistant, colleague := xid.New().String(), xid.New().String(), xid.New().String()
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	toAssistant := &storage.Share{
+		OwnerID: manager, UserID: assistant, Access: storage.ShareAccessRead, CreatedAt: createdAt,
+	}
+	toColleague := &storage.Share{
+		OwnerID: manager, UserID: colleague, Access: storage.ShareAccessFreeBusy, CreatedAt: createdAt,
+	}
+	fromColleague := &storage.Share{
+		OwnerID: colleague, UserID: assistant, Access: storage.ShareAccessRead, CreatedAt: createdAt,
+	}
+	for _, share := range []*storage.Share{toAssistant, toColleague, fromColleague} {
+		require.NoError(t, s.SaveShare(ctx, share))
+	}
+	// saving the share again replaces its access
+	toAssistant.Access = storage.ShareAccessReadWrite
+	require.NoError(t, s.SaveShare(ctx, toAssistant))
+
+	shares, err := s.ListOwnerShares(ctx, manager)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Share{toAssistant, toColleague}, shares)
+	shares, err = s.ListUserShares(ctx, assistant)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Share{toAssistant, fromColleague}, shares)
+
+	require.NoError(t, s.DeleteShare(ctx, manager, assistant))
+	require.ErrorAs(t, s.DeleteShare(ctx, manager, assistant), &errs.ErrNotFoundShare{})
+	shares, err = s.ListUserShares(ctx, assistant)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Share{fromColleague}, shares)
+}
+
+func testAttachments(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	event := newEvent("planning", user, time.Now(), time.Hour)
+	require.NoError(t, s.AddEvent(ctx, event))
+	agenda := &storage.Attachment{
+		EventID: event.ID, UserID: user, Name: "agenda.txt", ContentType: "text/plain; charset=utf-8",
+		Size: 9, BlobKey: xid.New().String(), CreatedAt: createdAt,
+	}
+	link := &storage.Attachment{
+		EventID: event.ID, UserID: user, Name: "notes", URL: "https://example.com/notes", CreatedAt: createdAt,
+	}
+	require.NoError(t, s.AddAttachment(ctx, agenda))
+	require.NoError(t, s.AddAttachment(ctx, link))
+	require.NotEmpty(t, agenda.ID)
+
+	got, err := s.GetAttachment(ctx, agenda.ID)
+	require.NoError(t, err)
+	require.Equal(t, agenda, got)
+	attachments, err := s.ListEventAttachments(ctx, event.ID)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Attachment{agenda, link}, attachments)
+
+	missing := &storage.Attachment{EventID: xid.New().String(), Name: "notes", URL: "https://example.com"}
+	require.ErrorAs(t, s.AddAttachment(ctx, missing), &errs.ErrNotFoundEvent{})
+
+	require.NoError(t, s.DeleteAttachment(ctx, link.ID))
+	_, err = s.GetAttachment(ctx, link.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundAttachment{})
+	require.ErrorAs(t, s.DeleteAttachment(ctx, link.ID), &errs.ErrNotFoundAttachment{})
+
+	// attachments outlive their events until the app removes their content
+	require.NoError(t, s.DeleteEvent(ctx, event.ID))
+	require.ErrorAs(t, s.AddAttachment(ctx, link), &errs.ErrNotFoundEvent{}, "event is in the trash")
+	orphaned, err := s.ListOrphanedAttachments(ctx, 1000)
+	require.NoError(t, err)
+	require.NotContains(t, orphaned, agenda)
+
+	_, err = s.PurgeDeletedEvents(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	orphaned, err = s.ListOrphanedAttachments(ctx, 1000)
+	require.NoError(t, err)
+	require.Contains(t, orphaned, agenda)
+	orphaned, err = s.ListOrphanedAttachments(ctx, 0)
+	require.NoError(t, err)
+	require.Empty(t, orphaned)
+}
+
+func testResources(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+	start := time.Now().UTC().Truncate(time.Second).AddDate(0, 0, 1)
+
+	room := &storage.Resource{Name: "Everest", Kind: storage.ResourceKindRoom, Capacity: 8}
+	projector := &storage.Resource{Name: "Projector", Kind: storage.ResourceKindEquipment}
+	require.NoError(t, s.AddResource(ctx, room))
+	require.NoError(t, s.AddResource(ctx, projector))
+	room.Capacity = 10
+	require.NoError(t, s.ModifyResource(ctx, room))
+	got, err := s.GetResource(ctx, room.ID)
+	require.NoError(t, err)
+	require.Equal(t, room, got)
+	resources, err := s.ListResources(ctx)
+	require.NoError(t, err)
+	require.Contains(t, resources, room)
+	require.Contains(t, resources, projector)
+	_, err = s.GetResource(ctx, xid.New().String())
+	require.ErrorAs(t, err, &errs.ErrNotFoundResource{})
+	require.ErrorAs(t, s.ModifyResource(ctx, &storage.Resource{ID: xid.New().String(), Name: "Missing"}),
+		&errs.ErrNotFoundResource{})
+
+	planning := newEvent("planning", user, start, time.Hour)
+	planning.Resources = []string{projector.ID, room.ID}
+	require.NoError(t, s.AddEvent(ctx, planning))
+	event, err := s.GetEvent(ctx, planning.ID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, planning.Resources, event.Resources)
+
+	t.Run("resources are booked for one event at a time", func(t *testing.T) {
+		overlapping := newEvent("overlapping", user, start.Add(30*time.Minute), time.Hour)
+		overlapping.Resources = []string{room.ID}
+		var busyErr errs.ErrResourceBusy
+		require.ErrorAs(t, s.AddEvent(ctx, overlapping), &busyErr)
+		require.Equal(t, errs.ErrResourceBusy{ResourceID: room.ID, EventID: planning.ID}, busyErr)
+
+		// events may follow each other, [start, end) ranges do not overlap
+		next := newEvent("next", user, start.Add(time.Hour), time.Hour)
+		next.Resources = []string{room.ID}
+		require.NoError(t, s.AddEvent(ctx, next))
+
+		// moving the event to the booked time fails, moving it within its own time does not
+		next.StartAt, next.EndAt = start.Add(30*time.Minute), start.Add(90*time.Minute)
+		require.ErrorAs(t, s.ModifyEvent(ctx, next), &errs.ErrResourceBusy{})
+		moved := planning.Clone()
+		moved.EndAt = start.Add(50 * time.Minute)
+		require.NoError(t, s.ModifyEvent(ctx, moved))
+
+		events, err := s.ListResourcesEventsInRange(ctx, []string{room.ID}, start, start.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, planning.ID, events[0].ID)
+		require.Equal(t, next.ID, events[1].ID)
+		later := start.Add(time.Hour)
+		events, err = s.ListResourcesEventsInRange(ctx, []string{projector.ID}, later, later.Add(time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("resources are booked for events which end after they start", func(t *testing.T) {
+		for name, duration := range map[string]time.Duration{"inverted": -time.Hour, "empty": 0} {
+			invalid := newEvent(name, user, start.Add(3*time.Hour), duration)
+			invalid.Resources = []string{room.ID}
+			require.ErrorAs(t, s.AddEvent(ctx, invalid), &errs.ErrInvalidBookingPeriod{}, name)
+		}
+		later := start.Add(2 * time.Hour)
+		events, err := s.ListResourcesEventsInRange(ctx, []string{room.ID}, later, later.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, events)
+
+		moved := planning.Clone()
+		moved.EndAt = moved.StartAt.Add(-time.Hour)
+		require.ErrorAs(t, s.ModifyEvent(ctx, moved), &errs.ErrInvalidBookingPeriod{})
+	})
+
+	t.Run("events in the trash do not hold resources", func(t *testing.T) {
+		require.NoError(t, s.DeleteEvent(ctx, planning.ID))
+		replacement := newEvent("replacement", user, start, 30*time.Minute)
+		replacement.Resources = []string{projector.ID}
+		require.NoError(t, s.AddEvent(ctx, replacement))
+
+		var busyErr errs.ErrResourceBusy
+		require.ErrorAs(t, s.RestoreEvent(ctx, planning.ID), &busyErr)
+		require.Equal(t, replacement.ID, busyErr.EventID)
+		_, err := s.GetEvent(ctx, planning.ID)
+		require.ErrorAs(t, err, &errs.ErrNotFoundEvent{}, "event stays in the trash")
+
+		require.NoError(t, s.DeleteEvent(ctx, replacement.ID))
+		require.NoError(t, s.RestoreEvent(ctx, planning.ID))
+	})
+}
+
+// testConcurrentBookings books the same resource by concurrent writers, the storage
+// has to let exactly one of them book it.
+func testConcurrentBookings(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	start := time.Now().UTC().Truncate(time.Second).AddDate(0, 0, 2)
+	room := &storage.Resource{Name: "Kilimanjaro", Kind: storage.ResourceKindRoom}
+	require.NoError(t, s.AddResource(ctx, room))
+
+	const writers = 8
+	results := make(chan error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			event := newEvent("meeting", xid.New().String(), start.Add(time.Duration(i)*time.Minute), time.Hour)
+			event.Resources = []string{room.ID}
+			results <- s.AddEvent(ctx, event)
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	booked := 0
+	for err := range results {
+		if err == nil {
+			booked++
+			continue
+		}
+		require.ErrorAs(t, err, &errs.ErrResourceBusy{})
+	}
+	require.Equal(t, 1, booked)
+	events, err := s.ListResourcesEventsInRange(ctx, []string{room.ID}, start, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
+
+func testRangeListings(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	alice, bob := xid.New().String(), xid.New().String()
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(1, 0, 0)
+
+	before := newEvent("before", alice, day.Add(-2*time.Hour), time.Hour)
+	overlapsStart := newEvent("overlaps start", alice, day.Add(-30*time.Minute), time.Hour)
+	inside := newEvent("inside", bob, day.Add(10*time.Hour), time.Hour)
+	after := newEvent("after", bob, day.Add(24*time.Hour), time.Hour)
+	invited := newEvent("invited", xid.New().String(), day.Add(12*time.Hour), time.Hour)
+	invited.Attendees = []storage.Attendee{{UserID: bob, Status: storage.AttendeeStatusNeedsAction}}
+	for _, event := range []*storage.Event{after, inside, invited, before, overlapsStart} {
+		require.NoError(t, s.AddEvent(ctx, event))
+	}
+
+	events, err := s.ListEventsInRange(ctx, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, findEvent(events, overlapsStart.ID))
+	require.NotNil(t, findEvent(events, inside.ID))
+	require.NotNil(t, findEvent(events, invited.ID))
+	require.Nil(t, findEvent(events, before.ID))
+	require.Nil(t, findEvent(events, after.ID))
+
+	events, err = s.ListUsersEventsInRange(ctx, []string{alice, bob}, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Event{overlapsStart, inside, invited}, events)
+
+	events, err = s.ListUsersEventsInRange(ctx, []string{bob}, day, day.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Event{inside, invited, after}, events)
+}
+
+func testAuditRecords(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	eventID := xid.New().String()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	created := &storage.AuditRecord{
+		EventID:   eventID,
+		Actor:     "user",
+		Action:    storage.AuditActionCreate,
+		CreatedAt: now,
+		After:     &storage.Event{ID: eventID, Title: "title"},
+	}
+	deleted := &storage.AuditRecord{
+		EventID:   eventID,
+		Actor:     "user",
+		Action:    storage.AuditActionDelete,
+		CreatedAt: now.Add(time.Second),
+		Before:    &storage.Event{ID: eventID, Title: "title"},
+	}
+	require.NoError(t, s.AddAuditRecord(ctx, created))
+	require.NoError(t, s.AddAuditRecord(ctx, &storage.AuditRecord{
+		EventID: xid.New().String(), Action: storage.AuditActionCreate, CreatedAt: now,
+	}))
+	require.NoError(t, s.AddAuditRecord(ctx, deleted))
+
+	records, err := s.ListAuditRecords(ctx, eventID)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for i, expected := range []*storage.AuditRecord{created, deleted} {
+		require.Equal(t, expected.ID, records[i].ID)
+		require.Equal(t, expected.Action, records[i].Action)
+		require.True(t, expected.CreatedAt.Equal(records[i].CreatedAt))
+		require.Equal(t, expected.Before, records[i].Before)
+		require.Equal(t, expected.After, records[i].After)
+	}
+
+	records, err = s.ListAuditRecords(ctx, xid.New().String())
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+func testTransactions(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+
+	existing := newEvent("existing", user, time.Now(), time.Hour)
+	require.NoError(t, s.AddEvent(ctx, existing))
+
+	created := newEvent("created", user, time.Now(), time.Hour)
+	err := s.WithTx(ctx, func(tx app.Storage) error {
+		if err := tx.AddEvent(ctx, created); err != nil {
+			return err
+		}
+		// changes are visible inside the transaction
+		if _, err := tx.GetEvent(ctx, created.ID); err != nil {
+			return err
+		}
+		existing.Title = "modified in tx"
+		if err := tx.ModifyEvent(ctx, existing); err != nil {
+			return err
+		}
+		return tx.WithTx(ctx, func(nested app.Storage) error {
+			return nested.AddAuditRecord(ctx, &storage.AuditRecord{
+				EventID: created.ID, Action: storage.AuditActionCreate, CreatedAt: time.Now().UTC(),
+			})
+		})
+	})
+	require.NoError(t, err)
+	got, err := s.GetEvent(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+	got, err = s.GetEvent(ctx, existing.ID)
+	require.NoError(t, err)
+	require.Equal(t, "modified in tx", got.Title)
+	records, err := s.ListAuditRecords(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	errRollback := errors.New("rollback")
+	discarded := newEvent("discarded", user, time.Now(), time.Hour)
+	err = s.WithTx(ctx, func(tx app.Storage) error {
+		if err := tx.AddEvent(ctx, discarded); err != nil {
+			return err
+		}
+		if err := tx.DeleteEvent(ctx, existing.ID); err != nil {
+			return err
+		}
+		if err := tx.AddAuditRecord(ctx, &storage.AuditRecord{
+			EventID: created.ID, Action: storage.AuditActionUpdate, CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	_, err = s.GetEvent(ctx, discarded.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundEvent{})
+	_, err = s.GetEvent(ctx, existing.ID)
+	require.NoError(t, err)
+	records, err = s.ListAuditRecords(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+}
+
+func testWebhooks(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	alice, bob := xid.New().String(), xid.New().String()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	webhook := &storage.Webhook{UserID: alice, URL: "https://example.com/hook", Secret: "secret", CreatedAt: now}
+	other := &storage.Webhook{UserID: bob, URL: "https://example.com/other", Secret: "secret", CreatedAt: now}
+	require.NoError(t, s.AddWebhook(ctx, webhook))
+	require.NoError(t, s.AddWebhook(ctx, other))
+	require.NotEmpty(t, webhook.ID)
+
+	got, err := s.GetWebhook(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Equal(t, webhook, got)
+	_, err = s.GetWebhook(ctx, xid.New().String())
+	require.ErrorAs(t, err, &errs.ErrNotFoundWebhook{})
+
+	webhooks, err := s.ListUsersWebhooks(ctx, []string{alice})
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Webhook{webhook}, webhooks)
+	webhooks, err = s.ListUsersWebhooks(ctx, []string{alice, bob})
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+
+	newDelivery := func(webhookID string, nextAttemptAt time.Time) *storage.WebhookDelivery {
+		return &storage.WebhookDelivery{
+			WebhookID:     webhookID,
+			EventID:       xid.New().String(),
+			Type:          "event.created",
+			Payload:       `{"type":"event.created"}`,
+			Status:        storage.DeliveryStatusPending,
+			NextAttemptAt: nextAttemptAt,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+	}
+	due := newDelivery(webhook.ID, now.Add(-time.Minute))
+	later := newDelivery(webhook.ID, now.Add(time.Hour))
+	otherDue := newDelivery(other.ID, now.Add(-time.Hour))
+	for _, delivery := range []*storage.WebhookDelivery{due, later, otherDue} {
+		require.NoError(t, s.AddWebhookDelivery(ctx, delivery))
+	}
+	require.ErrorAs(t, s.AddWebhookDelivery(ctx, newDelivery(xid.New().String(), now)), &errs.ErrNotFoundWebhook{})
+
+	deliveries, err := s.ListWebhookDeliveries(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Equal(t, []*storage.WebhookDelivery{due, later}, deliveries)
+
+	// the storage may be shared, so only the order of known deliveries is checked
+	deliveries, err = s.ListDueWebhookDeliveries(ctx, now, 1000)
+	require.NoError(t, err)
+	dueIDs := make([]string, 0)
+	for _, delivery := range deliveries {
+		if delivery.ID == due.ID || delivery.ID == later.ID || delivery.ID == otherDue.ID {
+			dueIDs = append(dueIDs, delivery.ID)
+		}
+	}
+	require.Equal(t, []string{otherDue.ID, due.ID}, dueIDs)
+	deliveries, err = s.ListDueWebhookDeliveries(ctx, now, 1)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	due.Status = storage.DeliveryStatusDead
+	due.Attempts = 3
+	due.LastError = "connection refused"
+	due.UpdatedAt = now.Add(time.Second)
+	require.NoError(t, s.UpdateWebhookDelivery(ctx, due))
+	deliveries, err = s.ListWebhookDeliveries(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Equal(t, due, deliveries[0])
+
+	require.NoError(t, s.DeleteWebhook(ctx, webhook.ID))
+	require.ErrorAs(t, s.DeleteWebhook(ctx, webhook.ID), &errs.ErrNotFoundWebhook{})
+	deliveries, err = s.ListWebhookDeliveries(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+	// update of a removed delivery is ignored
+	require.NoError(t, s.UpdateWebhookDelivery(ctx, later))
+	webhooks, err = s.ListUsersWebhooks(ctx, []string{alice, bob})
+	require.NoError(t, err)
+	require.Equal(t, []*storage.Webhook{other}, webhooks)
+}
+
+func testUserProfiles(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := s.GetUserProfile(ctx, user)
+	require.ErrorAs(t, err, &errs.ErrNotFoundUserProfile{})
+
+	profile := &storage.UserProfile{
+		UserID:    user,
+		Email:     "user@example.com",
+		Channels:  []storage.NotificationChannel{storage.NotificationChannelEmail, storage.NotificationChannelStdout},
+		UpdatedAt: now,
+	}
+	require.NoError(t, s.SaveUserProfile(ctx, profile))
+	got, err := s.GetUserProfile(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, profile, got)
+
+	profile.Email = ""
+	profile.Channels = []storage.NotificationChannel{}
+	profile.UpdatedAt = now.Add(time.Minute)
+	require.NoError(t, s.SaveUserProfile(ctx, profile))
+	got, err = s.GetUserProfile(ctx, user)
+	require.NoError(t, err)
+	require.Equal(t, profile, got)
+}
+
+func testReminders(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	user := xid.New().String()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	soon := newEvent("soon", user, now.Add(time.Hour), time.Hour)
+	soon.NotifyBefore = 30 * time.Minute
+	later := newEvent("later", user, now.Add(3*time.Hour), time.Hour)
+	later.NotifyBefore = time.Hour
+	silent := newEvent("silent", user, now.Add(time.Hour), time.Hour)
+	deleted := newEvent("deleted", user, now.Add(time.Hour), time.Hour)
+	deleted.NotifyBefore = 30 * time.Minute
+	for _, event := range []*storage.Event{soon, later, silent, deleted} {
+		require.NoError(t, s.AddEvent(ctx, event))
+	}
+	require.NoError(t, s.DeleteEvent(ctx, deleted.ID))
+
+	events, err := s.ListEventsToNotify(ctx, now, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	got := findEvent(events, soon.ID)
+	require.NotNil(t, got)
+	require.Equal(t, 30*time.Minute, got.NotifyBefore)
+	require.Nil(t, findEvent(events, later.ID))
+	require.Nil(t, findEvent(events, silent.ID))
+	require.Nil(t, findEvent(events, deleted.ID))
+	// the window is open at the start, so the reminder is not listed by adjacent windows twice
+	events, err = s.ListEventsToNotify(ctx, now.Add(30*time.Minute), now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, findEvent(events, soon.ID))
+	require.NotNil(t, findEvent(events, later.ID))
+
+	reminder := &storage.Reminder{
+		ID:           storage.ReminderID(soon.ID, soon.StartAt, soon.NotifyAt()),
+		EventID:      soon.ID,
+		OccurrenceAt: soon.StartAt,
+		NotifyAt:     soon.NotifyAt(),
+		HandledAt:    time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	_, err = s.GetReminder(ctx, reminder.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundReminder{})
+	require.NoError(t, s.AddReminder(ctx, reminder))
+	require.ErrorAs(t, s.AddReminder(ctx, reminder), &errs.ErrReminderExists{})
+	stored, err := s.GetReminder(ctx, reminder.ID)
+	require.NoError(t, err)
+	require.Equal(t, reminder, stored)
+
+	delivered := reminder.Clone()
+	delivered.ID += "/" + user
+	delivered.UserID = user
+	delivered.HandledAt = now
+	require.NoError(t, s.AddReminder(ctx, delivered))
+
+	deletedCount, err := s.DeleteReminders(ctx, reminder.HandledAt.Add(time.Second))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deletedCount, 1)
+	_, err = s.GetReminder(ctx, reminder.ID)
+	require.ErrorAs(t, err, &errs.ErrNotFoundReminder{})
+	_, err = s.GetReminder(ctx, delivered.ID)
+	require.NoError(t, err)
+}
+
+func testLeases(t *testing.T, s app.Storage) {
+	ctx := context.Background()
+	name := "lease-" + xid.New().String()
+
+	acquired, err := s.AcquireLease(ctx, name, "first", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	// renewal
+	acquired, err = s.AcquireLease(ctx, name, "first", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	acquired, err = s.AcquireLease(ctx, name, "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	// releasing a lease of another holder does nothing
+	require.NoError(t, s.ReleaseLease(ctx, name, "second"))
+	acquired, err = s.AcquireLease(ctx, name, "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	require.NoError(t, s.ReleaseLease(ctx, name, "first"))
+	acquired, err = s.AcquireLease(ctx, name, "second", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, s.ReleaseLease(ctx, name, "second"))
+}
@@ -0,0 +1,64 @@
+package storage
+
+import "sort"
+
+// Tag is a label of events of the user, such as "1:1" or "travel". A tag with a color
+// is a category, clients paint events of the category with it.
+type Tag struct {
+	UserID string `db:"user_id" json:"user_id"`
+	Name   string `db:"name" json:"name"`
+	// Color is #rrggbb, it is empty for tags which are not categories.
+	Color string `db:"color" json:"color,omitempty"`
+}
+
+// HasTags reports whether the event is labeled with all of the tags.
+func (e *Event) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, eventTag := range e.Tags {
+			if eventTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CollectTags returns tags of the user: categories from saved tags and tags of events owned
+// by the user which are not in the trash, sorted by name. It is used by storages keeping
+// tags inside events.
+func CollectTags(userID string, saved []*Tag, events []*Event) []*Tag {
+	colors := make(map[string]string)
+	byName := make(map[string]*Tag)
+	for _, tag := range saved {
+		if tag.UserID != userID {
+			continue
+		}
+		colors[tag.Name] = tag.Color
+		if tag.Color != "" {
+			byName[tag.Name] = &Tag{UserID: userID, Name: tag.Name, Color: tag.Color}
+		}
+	}
+	for _, event := range events {
+		if event.UserID != userID || event.DeletedAt != nil {
+			continue
+		}
+		for _, name := range event.Tags {
+			if _, ok := byName[name]; !ok {
+				byName[name] = &Tag{UserID: userID, Name: name, Color: colors[name]}
+			}
+		}
+	}
+	tags := make([]*Tag, 0, len(byName))
+	for _, tag := range byName {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
@@ -0,0 +1,51 @@
+package storage
+
+import "time"
+
+// Webhook is a URL registered by the user, changes of events in the user's calendar
+// are posted to it. Payloads are signed with Secret.
+type Webhook struct {
+	ID        string    `db:"id" json:"id"`
+	UserID    string    `db:"user_id" json:"user_id"`
+	URL       string    `db:"url" json:"url"`
+	Secret    string    `db:"secret" json:"secret,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	// DeliveryStatusDead marks deliveries which failed too many times, they are not retried.
+	DeliveryStatusDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is a single payload to be posted to the webhook.
+// Pending deliveries are attempted when NextAttemptAt comes.
+type WebhookDelivery struct {
+	ID        string `db:"id" json:"id"`
+	WebhookID string `db:"webhook_id" json:"webhook_id"`
+	EventID   string `db:"event_id" json:"event_id"`
+	Type      string `db:"type" json:"type"`
+	// Payload is the JSON body of the request.
+	Payload       string         `db:"payload" json:"payload"`
+	Status        DeliveryStatus `db:"status" json:"status"`
+	Attempts      int            `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time      `db:"next_attempt_at" json:"next_attempt_at"`
+	// LastError and ResponseCode describe the last attempt, ResponseCode is 0 if no response was received.
+	LastError    string    `db:"last_error" json:"last_error,omitempty"`
+	ResponseCode int       `db:"response_code" json:"response_code,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Clone returns a copy of the webhook.
+func (w Webhook) Clone() *Webhook {
+	return &w
+}
+
+// Clone returns a copy of the delivery.
+func (d WebhookDelivery) Clone() *WebhookDelivery {
+	return &d
+}
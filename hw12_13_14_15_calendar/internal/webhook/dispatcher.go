@@ -0,0 +1,376 @@
+// Package webhook delivers changes of events to webhooks registered by users.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	errs "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/pkg/storage_errors"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Headers of the delivery request besides SignatureHeader.
+const (
+	DeliveryHeader = "X-Calendar-Delivery"
+	TypeHeader     = "X-Calendar-Event"
+)
+
+// Types of payloads, one per audit action.
+const (
+	TypeEventCreated  = "event.created"
+	TypeEventUpdated  = "event.updated"
+	TypeEventDeleted  = "event.deleted"
+	TypeEventRestored = "event.restored"
+)
+
+var payloadTypes = map[storage.AuditAction]string{
+	storage.AuditActionCreate:  TypeEventCreated,
+	storage.AuditActionUpdate:  TypeEventUpdated,
+	storage.AuditActionDelete:  TypeEventDeleted,
+	storage.AuditActionRestore: TypeEventRestored,
+}
+
+// Payload is the body posted to webhooks. ID identifies the change, it is the same
+// in payloads sent to all webhooks and in retries, so receivers can deduplicate them.
+type Payload struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Actor     string    `json:"actor,omitempty"`
+	// Event is the state after the change, for deleted events the state before it.
+	Event *storage.Event `json:"event"`
+}
+
+var errAddressNotAllowed = errors.New("address is not allowed")
+
+// Options of the Dispatcher. Attempts of a delivery are delayed by Backoff doubled after
+// every failure up to MaxBackoff, after MaxAttempts failures the delivery is dead.
+// Not positive values are replaced with defaults, except MaxBackoff which is no cap then.
+type Options struct {
+	Workers     int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Timeout limits a single attempt.
+	Timeout time.Duration
+	// SweepInterval is how often the storage is checked for due deliveries.
+	SweepInterval time.Duration
+	QueueSize     int
+	// AllowPrivate allows delivery to loopback, private and link-local addresses.
+	AllowPrivate bool
+}
+
+// Defaults of Options.
+const (
+	DefaultWorkers       = 1
+	DefaultMaxAttempts   = 8
+	DefaultBackoff       = 30 * time.Second
+	DefaultTimeout       = 10 * time.Second
+	DefaultSweepInterval = 30 * time.Second
+	DefaultQueueSize     = 100
+)
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = DefaultWorkers
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultMaxAttempts
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultBackoff
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.SweepInterval <= 0 {
+		o.SweepInterval = DefaultSweepInterval
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = DefaultQueueSize
+	}
+	return o
+}
+
+func (o Options) backoff(attempts int) time.Duration {
+	backoff := o.Backoff
+	// without the cap doubling stops before the duration overflows
+	for i := 1; i < attempts && (o.MaxBackoff <= 0 || backoff < o.MaxBackoff) && backoff <= math.MaxInt64/2; i++ {
+		backoff *= 2
+	}
+	if o.MaxBackoff > 0 && backoff > o.MaxBackoff {
+		return o.MaxBackoff
+	}
+	return backoff
+}
+
+// Dispatcher listens to changes of events, stores a delivery for every webhook of the users
+// who see the event and posts them asynchronously. Deliveries survive restarts, pending ones
+// are picked up from the storage.
+type Dispatcher struct {
+	log     app.Logger
+	store   app.Storage
+	options Options
+	client  *http.Client
+	now     func() time.Time
+
+	queue chan *storage.WebhookDelivery
+	mu    sync.Mutex
+	// queued contains IDs of deliveries in the queue or being attempted
+	queued map[string]struct{}
+	// finished contains IDs of deliveries attempted since the current sweep started,
+	// the sweep may have read them before the attempt was saved
+	finished map[string]struct{}
+}
+
+// New returns the dispatcher, options left unset get defaults.
+func New(log app.Logger, store app.Storage, options Options) *Dispatcher {
+	options = options.withDefaults()
+	return &Dispatcher{
+		log:      log,
+		store:    store,
+		options:  options,
+		client:   NewClient(options.Timeout, options.AllowPrivate),
+		now:      time.Now,
+		queue:    make(chan *storage.WebhookDelivery, options.QueueSize),
+		queued:   make(map[string]struct{}),
+		finished: make(map[string]struct{}),
+	}
+}
+
+// NewClient returns client for posting to webhooks. Unless allowPrivate is set, it refuses
+// to connect to loopback, private and link-local addresses, so users can't reach internal services.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = denyPrivateAddresses
+	}
+	return &http.Client{
+		Timeout: timeout,
+		// proxy would hide the address from the dialer
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		// redirects are not followed, as they may lead to a not allowed address
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// denyPrivateAddresses is called with the resolved address, so names resolving
+// to private addresses are denied too.
+func denyPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("%w: %s", errAddressNotAllowed, host)
+	}
+	return nil
+}
+
+// EventChanged stores deliveries of the change for webhooks of the event owner and attendees.
+func (d *Dispatcher) EventChanged(ctx context.Context, record *storage.AuditRecord) {
+	payloadType, ok := payloadTypes[record.Action]
+	if !ok {
+		return
+	}
+	payload := Payload{
+		ID:        record.ID,
+		Type:      payloadType,
+		CreatedAt: record.CreatedAt,
+		Actor:     record.Actor,
+		Event:     record.After,
+	}
+	if payload.Event == nil {
+		payload.Event = record.Before
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.log.Error().Err(err).Msgf("Failed to marshal webhook payload of event %s", record.EventID)
+		return
+	}
+	// webhooks may be registered right before the change
+	ctx = app.ContextWithReadYourWrites(ctx)
+	webhooks, err := d.store.ListUsersWebhooks(ctx, record.Users())
+	if err != nil {
+		d.log.Error().Err(err).Msgf("Failed to list webhooks for event %s", record.EventID)
+		return
+	}
+	now := d.now().UTC()
+	for _, webhook := range webhooks {
+		delivery := &storage.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       record.EventID,
+			Type:          payloadType,
+			Payload:       string(body),
+			Status:        storage.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err = d.store.AddWebhookDelivery(ctx, delivery); err != nil {
+			d.log.Error().Err(err).Msgf("Failed to add delivery of event %s to webhook %s", record.EventID, webhook.ID)
+			continue
+		}
+		d.enqueue(delivery)
+	}
+}
+
+// enqueue passes delivery to workers unless it is already queued. If the queue is full
+// the delivery is left for the next sweep.
+func (d *Dispatcher) enqueue(delivery *storage.WebhookDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.enqueueLocked(delivery)
+}
+
+func (d *Dispatcher) enqueueLocked(delivery *storage.WebhookDelivery) {
+	if _, ok := d.queued[delivery.ID]; ok {
+		return
+	}
+	select {
+	case d.queue <- delivery:
+		d.queued[delivery.ID] = struct{}{}
+	default:
+	}
+}
+
+func (d *Dispatcher) done(delivery *storage.WebhookDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.queued, delivery.ID)
+	d.finished[delivery.ID] = struct{}{}
+}
+
+// Run delivers queued deliveries and sweeps the storage for due ones until ctx is done.
+// Attempts in progress are finished before Run returns.
+func (d *Dispatcher) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < d.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	d.sweep(ctx)
+	ticker := time.NewTicker(d.options.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.sweep(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) sweep(ctx context.Context) {
+	d.mu.Lock()
+	d.finished = make(map[string]struct{})
+	d.mu.Unlock()
+	deliveries, err := d.store.ListDueWebhookDeliveries(
+		app.ContextWithReadYourWrites(ctx), d.now().UTC(), d.options.QueueSize)
+	if err != nil {
+		d.log.Error().Err(err).Msg("Failed to list due webhook deliveries")
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, delivery := range deliveries {
+		if _, ok := d.finished[delivery.ID]; !ok {
+			d.enqueueLocked(delivery)
+		}
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-d.queue:
+			// the attempt is not interrupted by shutdown, it is limited by the timeout
+			d.attempt(context.Background(), delivery)
+			d.done(delivery)
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *storage.WebhookDelivery) {
+	ctx = app.ContextWithReadYourWrites(ctx)
+	webhook, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		if !errors.As(err, &errs.ErrNotFoundWebhook{}) {
+			d.log.Error().Err(err).Msgf("Failed to get webhook %s", delivery.WebhookID)
+		}
+		return
+	}
+	code, err := d.post(ctx, webhook, delivery)
+	now := d.now().UTC()
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil:
+		delivery.Status = storage.DeliveryStatusDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= d.options.MaxAttempts:
+		delivery.Status = storage.DeliveryStatusDead
+		delivery.LastError = err.Error()
+		d.log.Warn().Err(err).Msgf("Delivery %s to webhook %s is dead after %d attempts",
+			delivery.ID, webhook.ID, delivery.Attempts)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.options.backoff(delivery.Attempts))
+		d.log.Debug().Err(err).Msgf("Delivery %s to webhook %s failed, next attempt at %v",
+			delivery.ID, webhook.ID, delivery.NextAttemptAt)
+	}
+	if err = d.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		d.log.Error().Err(err).Msgf("Failed to save delivery %s", delivery.ID)
+	}
+}
+
+// post sends the delivery and returns the response code, responses other than 2xx are errors.
+func (d *Dispatcher) post(
+	ctx context.Context, webhook *storage.Webhook, delivery *storage.WebhookDelivery,
+) (int, error) {
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(TypeHeader, delivery.Type)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, d.now(), body))
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// the body is drained, so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected response status %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
@@ -0,0 +1,335 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver records requests and answers them with codes in order, the last code is repeated.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	codes    []int
+	requests []receivedRequest
+}
+
+func newReceiver(t *testing.T, codes ...int) *receiver {
+	t.Helper()
+	r := &receiver{codes: codes}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		r.mu.Lock()
+		code := r.codes[0]
+		if len(r.codes) > 1 {
+			r.codes = r.codes[1:]
+		}
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		r.mu.Unlock()
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+// newEvent returns an hour long event, its time doesn't matter for the test.
+func newEvent(title string) *storage.Event {
+	start := time.Date(2022, time.October, 24, 10, 0, 0, 0, time.UTC)
+	return &storage.Event{Title: title, StartAt: start, EndAt: start.Add(time.Hour)}
+}
+
+func testOptions() Options {
+	return Options{
+		Workers:       2,
+		MaxAttempts:   3,
+		Backoff:       10 * time.Millisecond,
+		MaxBackoff:    20 * time.Millisecond,
+		Timeout:       time.Second,
+		SweepInterval: 5 * time.Millisecond,
+		QueueSize:     16,
+		AllowPrivate:  true,
+	}
+}
+
+// setup returns the app with the dispatcher running until the end of the test.
+func setup(t *testing.T, options Options) *app.App {
+	t.Helper()
+	logg := logger.New("error")
+	store := memorystorage.New(logg)
+	calendar := app.New(logg, store)
+	dispatcher := New(logg, store, options)
+	calendar.AddChangeListener(dispatcher)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		dispatcher.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	return calendar
+}
+
+// waitDeliveries waits until count deliveries of the webhook of userID have the status.
+func waitDeliveries(
+	t *testing.T, calendar *app.App, userID, webhookID string, status storage.DeliveryStatus, count int,
+) []*storage.WebhookDelivery {
+	t.Helper()
+	ctx := app.ContextWithUserID(context.Background(), userID)
+	var deliveries []*storage.WebhookDelivery
+	require.Eventually(t, func() bool {
+		var err error
+		deliveries, err = calendar.ListWebhookDeliveries(ctx, webhookID)
+		require.NoError(t, err)
+		finished := 0
+		for _, delivery := range deliveries {
+			if delivery.Status == status {
+				finished++
+			}
+		}
+		return finished == count
+	}, 5*time.Second, 5*time.Millisecond)
+	return deliveries
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("delivers signed payloads to webhooks of owner and attendees", func(t *testing.T) {
+		calendar := setup(t, testOptions())
+		alice := app.ContextWithUserID(context.Background(), "alice")
+		bob := app.ContextWithUserID(context.Background(), "bob")
+		aliceReceiver, bobReceiver := newReceiver(t, http.StatusOK), newReceiver(t, http.StatusNoContent)
+		aliceHook := &storage.Webhook{URL: aliceReceiver.URL, Secret: "alice secret"}
+		bobHook := &storage.Webhook{URL: bobReceiver.URL}
+		require.NoError(t, calendar.RegisterWebhook(alice, aliceHook))
+		require.NoError(t, calendar.RegisterWebhook(bob, bobHook))
+		require.NotEmpty(t, bobHook.Secret)
+
+		event := newEvent("meeting")
+		require.NoError(t, calendar.CreateEvent(alice, event))
+		require.NoError(t, calendar.InviteAttendees(alice, event.ID, []string{"bob"}))
+		require.NoError(t, calendar.DeleteEvent(alice, event.ID))
+
+		deliveries := waitDeliveries(t, calendar, "alice", aliceHook.ID, storage.DeliveryStatusDelivered, 3)
+		require.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+		require.Equal(t, 1, deliveries[0].Attempts)
+		// the event is in the calendar of bob, as bob is invited
+		waitDeliveries(t, calendar, "bob", bobHook.ID, storage.DeliveryStatusDelivered, 2)
+
+		requests := aliceReceiver.received()
+		require.Len(t, requests, 3)
+		types := make([]string, 0, len(requests))
+		for _, request := range requests {
+			require.True(t, Verify("alice secret", request.header.Get(SignatureHeader), request.body,
+				time.Now(), time.Minute))
+			require.False(t, Verify("wrong secret", request.header.Get(SignatureHeader), request.body,
+				time.Now(), time.Minute))
+			require.Equal(t, "application/json", request.header.Get("Content-Type"))
+			require.NotEmpty(t, request.header.Get(DeliveryHeader))
+
+			var payload Payload
+			require.NoError(t, json.Unmarshal(request.body, &payload))
+			require.Equal(t, request.header.Get(TypeHeader), payload.Type)
+			require.Equal(t, event.ID, payload.Event.ID)
+			require.Equal(t, "alice", payload.Actor)
+			types = append(types, payload.Type)
+		}
+		require.ElementsMatch(t, []string{TypeEventCreated, TypeEventUpdated, TypeEventDeleted}, types)
+	})
+
+	t.Run("retries failed deliveries", func(t *testing.T) {
+		calendar := setup(t, testOptions())
+		alice := app.ContextWithUserID(context.Background(), "alice")
+		r := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+		webhook := &storage.Webhook{URL: r.URL}
+		require.NoError(t, calendar.RegisterWebhook(alice, webhook))
+
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("meeting")))
+
+		deliveries := waitDeliveries(t, calendar, "alice", webhook.ID, storage.DeliveryStatusDelivered, 1)
+		require.Equal(t, 3, deliveries[0].Attempts)
+		require.Empty(t, deliveries[0].LastError)
+		requests := r.received()
+		require.Len(t, requests, 3)
+		// retries send the same delivery
+		for _, request := range requests[1:] {
+			require.Equal(t, requests[0].header.Get(DeliveryHeader), request.header.Get(DeliveryHeader))
+			require.Equal(t, requests[0].body, request.body)
+		}
+	})
+
+	t.Run("dead letter after max attempts", func(t *testing.T) {
+		calendar := setup(t, testOptions())
+		alice := app.ContextWithUserID(context.Background(), "alice")
+		r := newReceiver(t, http.StatusServiceUnavailable)
+		webhook := &storage.Webhook{URL: r.URL}
+		require.NoError(t, calendar.RegisterWebhook(alice, webhook))
+
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("meeting")))
+
+		deliveries := waitDeliveries(t, calendar, "alice", webhook.ID, storage.DeliveryStatusDead, 1)
+		require.Equal(t, 3, deliveries[0].Attempts)
+		require.Equal(t, http.StatusServiceUnavailable, deliveries[0].ResponseCode)
+		require.Contains(t, deliveries[0].LastError, "503")
+		// dead deliveries are not retried
+		time.Sleep(50 * time.Millisecond)
+		require.Len(t, r.received(), 3)
+	})
+
+	t.Run("private addresses are not allowed by default", func(t *testing.T) {
+		options := testOptions()
+		options.AllowPrivate = false
+		options.MaxAttempts = 1
+		calendar := setup(t, options)
+		alice := app.ContextWithUserID(context.Background(), "alice")
+		r := newReceiver(t, http.StatusOK)
+		webhook := &storage.Webhook{URL: r.URL}
+		require.NoError(t, calendar.RegisterWebhook(alice, webhook))
+
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("meeting")))
+
+		deliveries := waitDeliveries(t, calendar, "alice", webhook.ID, storage.DeliveryStatusDead, 1)
+		require.Contains(t, deliveries[0].LastError, errAddressNotAllowed.Error())
+		require.Empty(t, r.received())
+	})
+
+	t.Run("batch is delivered after commit", func(t *testing.T) {
+		calendar := setup(t, testOptions())
+		alice := app.ContextWithUserID(context.Background(), "alice")
+		r := newReceiver(t, http.StatusOK)
+		webhook := &storage.Webhook{URL: r.URL}
+		require.NoError(t, calendar.RegisterWebhook(alice, webhook))
+
+		_, err := calendar.ApplyBatch(alice, []app.BatchOperation{
+			{Op: app.BatchOpCreate, Event: newEvent("first")},
+			{Op: app.BatchOpDelete, ID: "missing"},
+		})
+		require.Error(t, err)
+		_, err = calendar.ApplyBatch(alice, []app.BatchOperation{
+			{Op: app.BatchOpCreate, Event: newEvent("first")},
+			{Op: app.BatchOpCreate, Event: newEvent("second")},
+		})
+		require.NoError(t, err)
+
+		waitDeliveries(t, calendar, "alice", webhook.ID, storage.DeliveryStatusDelivered, 2)
+		require.Len(t, r.received(), 2)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		options  Options
+		attempts int
+		expected time.Duration
+	}{
+		{"first", Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}, 1, time.Second},
+		{"doubled", Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}, 2, 2 * time.Second},
+		{"doubled twice", Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}, 3, 4 * time.Second},
+		{"capped", Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}, 4, 5 * time.Second},
+		{"capped later", Options{Backoff: time.Second, MaxBackoff: 5 * time.Second}, 10, 5 * time.Second},
+		{"first without cap", Options{Backoff: time.Second}, 1, time.Second},
+		{"doubled without cap", Options{Backoff: time.Second}, 10, 512 * time.Second},
+		{"negative cap", Options{Backoff: time.Second, MaxBackoff: -time.Second}, 3, 4 * time.Second},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.options.backoff(tc.attempts))
+		})
+	}
+
+	t.Run("does not overflow without cap", func(t *testing.T) {
+		require.Greater(t, Options{Backoff: time.Second}.backoff(100), time.Duration(math.MaxInt64/4))
+	})
+}
+
+func TestDefaultOptions(t *testing.T) {
+	logg := logger.New("error")
+	store := memorystorage.New(logg)
+	calendar := app.New(logg, store)
+	dispatcher := New(logg, store, Options{Workers: 1, AllowPrivate: true})
+	now := time.Now().UTC()
+	dispatcher.now = func() time.Time { return now }
+	require.Equal(t, Options{
+		Workers:       1,
+		MaxAttempts:   DefaultMaxAttempts,
+		Backoff:       DefaultBackoff,
+		Timeout:       DefaultTimeout,
+		SweepInterval: DefaultSweepInterval,
+		QueueSize:     DefaultQueueSize,
+		AllowPrivate:  true,
+	}, dispatcher.options)
+	require.Equal(t, DefaultTimeout, dispatcher.client.Timeout)
+
+	t.Run("run does not fail", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		dispatcher.Run(ctx)
+	})
+
+	t.Run("failed delivery is retried after backoff", func(t *testing.T) {
+		alice := app.ContextWithUserID(context.Background(), "alice")
+		r := newReceiver(t, http.StatusInternalServerError, http.StatusOK)
+		webhook := &storage.Webhook{URL: r.URL}
+		require.NoError(t, calendar.RegisterWebhook(alice, webhook))
+		calendar.AddChangeListener(dispatcher)
+		require.NoError(t, calendar.CreateEvent(alice, newEvent("meeting")))
+
+		// the delivery is queued as the queue is buffered
+		require.Len(t, dispatcher.queue, 1)
+		delivery := <-dispatcher.queue
+		dispatcher.attempt(context.Background(), delivery)
+		dispatcher.done(delivery)
+		require.Equal(t, storage.DeliveryStatusPending, delivery.Status)
+		require.Equal(t, now.Add(DefaultBackoff), delivery.NextAttemptAt)
+
+		dispatcher.sweep(context.Background())
+		require.Empty(t, dispatcher.queue, "the delivery is not due yet")
+
+		now = now.Add(DefaultBackoff)
+		dispatcher.sweep(context.Background())
+		require.Len(t, dispatcher.queue, 1)
+		delivery = <-dispatcher.queue
+		dispatcher.attempt(context.Background(), delivery)
+		require.Equal(t, storage.DeliveryStatusDelivered, delivery.Status)
+		require.Equal(t, 2, delivery.Attempts)
+		require.Len(t, r.received(), 2)
+	})
+}
+
+func TestSign(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", now, body)
+
+	require.True(t, Verify("secret", signature, body, now, time.Minute))
+	require.False(t, Verify("secret", signature, []byte(`{"id":"2"}`), now, time.Minute))
+	require.False(t, Verify("secret", signature, body, now.Add(2*time.Minute), time.Minute))
+	require.False(t, Verify("secret", "v1=abc", body, now, time.Minute))
+}
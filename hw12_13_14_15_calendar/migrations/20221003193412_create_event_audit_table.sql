@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS event_audit
+(
+    id         varchar(128) primary key NOT NULL,
+    event_id   varchar(128)             NOT NULL,
+    actor      varchar(128)             NOT NULL,
+    action     varchar(16)              NOT NULL,
+    created_at timestamptz              NOT NULL,
+    before     jsonb,
+    after      jsonb
+);
+CREATE INDEX IF NOT EXISTS event_audit_event_id_idx ON event_audit (event_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_audit;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS user_id varchar(128) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS events_user_id_idx ON events (user_id);
+
+CREATE TABLE IF NOT EXISTS event_attendees
+(
+    event_id varchar(128) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  varchar(128) NOT NULL,
+    status   varchar(16)  NOT NULL,
+    PRIMARY KEY (event_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS event_attendees_user_id_idx ON event_attendees (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_attendees;
+DROP INDEX IF EXISTS events_user_id_idx;
+ALTER TABLE events DROP COLUMN IF EXISTS user_id;
+-- +goose StatementEnd
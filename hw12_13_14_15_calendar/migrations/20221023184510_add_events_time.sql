@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN IF NOT EXISTS start_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE events ADD COLUMN IF NOT EXISTS end_at timestamptz NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS events_start_at_end_at_idx ON events (start_at, end_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS events_start_at_end_at_idx;
+ALTER TABLE events DROP COLUMN IF EXISTS end_at;
+ALTER TABLE events DROP COLUMN IF EXISTS start_at;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         varchar(128) primary key NOT NULL,
+    user_id    varchar(128)             NOT NULL,
+    url        text                     NOT NULL,
+    secret     varchar(128)             NOT NULL,
+    created_at timestamptz              NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              varchar(128) primary key NOT NULL,
+    webhook_id      varchar(128)             NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        varchar(128)             NOT NULL,
+    type            varchar(32)              NOT NULL,
+    payload         jsonb                    NOT NULL,
+    status          varchar(16)              NOT NULL,
+    attempts        integer                  NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz              NOT NULL,
+    last_error      text                     NOT NULL DEFAULT '',
+    response_code   integer                  NOT NULL DEFAULT 0,
+    created_at      timestamptz              NOT NULL,
+    updated_at      timestamptz              NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+-- +goose StatementEnd
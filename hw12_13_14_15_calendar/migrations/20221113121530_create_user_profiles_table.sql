@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_profiles
+(
+    user_id    varchar(128) primary key NOT NULL,
+    email      varchar(320)             NOT NULL DEFAULT '',
+    channels   varchar(16)[]            NOT NULL DEFAULT '{}',
+    updated_at timestamptz              NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_profiles;
+-- +goose StatementEnd
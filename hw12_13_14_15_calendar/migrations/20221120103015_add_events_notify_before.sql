@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- notify_before is in nanoseconds, 0 means no reminder
+ALTER TABLE events ADD COLUMN IF NOT EXISTS notify_before bigint NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN IF EXISTS notify_before;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reminders
+(
+    id            varchar(320) primary key NOT NULL,
+    event_id      varchar(128)             NOT NULL,
+    user_id       varchar(128)             NOT NULL DEFAULT '',
+    occurrence_at timestamptz              NOT NULL,
+    notify_at     timestamptz              NOT NULL,
+    handled_at    timestamptz              NOT NULL
+);
+CREATE INDEX IF NOT EXISTS reminders_handled_at_idx ON reminders (handled_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reminders;
+-- +goose StatementEnd
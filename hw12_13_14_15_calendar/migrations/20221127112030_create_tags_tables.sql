@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tags
+(
+    user_id varchar(128) NOT NULL,
+    name    varchar(64)  NOT NULL,
+    color   varchar(7)   NOT NULL DEFAULT '',
+    PRIMARY KEY (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS event_tags
+(
+    event_id varchar(128) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  varchar(128) NOT NULL,
+    name     varchar(64)  NOT NULL,
+    PRIMARY KEY (event_id, name),
+    FOREIGN KEY (user_id, name) REFERENCES tags (user_id, name) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS event_tags_user_id_name_idx ON event_tags (user_id, name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_tags;
+DROP TABLE IF EXISTS tags;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- event_id has no foreign key: attachments of purged events are kept until their blobs are removed
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id           varchar(128) primary key NOT NULL,
+    event_id     varchar(128)             NOT NULL,
+    user_id      varchar(128)             NOT NULL DEFAULT '',
+    name         varchar(255)             NOT NULL,
+    url          text                     NOT NULL DEFAULT '',
+    content_type varchar(255)             NOT NULL DEFAULT '',
+    size         bigint                   NOT NULL DEFAULT 0,
+    blob_key     varchar(128)             NOT NULL DEFAULT '',
+    created_at   timestamptz              NOT NULL
+);
+CREATE INDEX IF NOT EXISTS attachments_event_id_idx ON attachments (event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS attachments;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS resources
+(
+    id       varchar(128) primary key NOT NULL,
+    name     varchar(255)             NOT NULL,
+    kind     varchar(32)              NOT NULL,
+    capacity integer                  NOT NULL DEFAULT 0
+);
+
+-- bookings are checked for overlaps under a lock of the resource row, see setEventResources
+CREATE TABLE IF NOT EXISTS event_resources
+(
+    event_id    varchar(128) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    resource_id varchar(128) NOT NULL REFERENCES resources (id),
+    PRIMARY KEY (event_id, resource_id)
+);
+CREATE INDEX IF NOT EXISTS event_resources_resource_id_idx ON event_resources (resource_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_resources;
+DROP TABLE IF EXISTS resources;
+-- +goose StatementEnd
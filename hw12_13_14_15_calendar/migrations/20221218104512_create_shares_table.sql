@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS shares
+(
+    owner_id   varchar(128) NOT NULL,
+    user_id    varchar(128) NOT NULL,
+    access     varchar(16)  NOT NULL,
+    created_at timestamptz  NOT NULL,
+    PRIMARY KEY (owner_id, user_id)
+);
+-- every request of a user loads calendars shared with the user
+CREATE INDEX IF NOT EXISTS shares_user_id_idx ON shares (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shares;
+-- +goose StatementEnd
@@ -0,0 +1,305 @@
+//go:build integration
+// +build integration
+
+// Package integration runs the calendar with its dependencies in process and checks it through
+// its APIs. Environment variables point tests to a calendar running elsewhere instead.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/app"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/logger"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/notifier"
+	memoryqueue "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/queue/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/sender"
+	internalgrpc "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	internalhttp "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	memorystorage "github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Environment variables of an external calendar. The calendar has to allow private webhooks
+// and reach the receiver of reminders on WebhookHostEnv, which tests listen to on WebhookListenEnv.
+const (
+	HTTPURLEnv         = "CALENDAR_HTTP_URL"
+	GRPCAddrEnv        = "CALENDAR_GRPC_ADDR"
+	WebhookListenEnv   = "CALENDAR_WEBHOOK_LISTEN"
+	WebhookHostEnv     = "CALENDAR_WEBHOOK_HOST"
+	ReminderTimeoutEnv = "CALENDAR_REMINDER_TIMEOUT"
+)
+
+// in process reminders are scheduled often, so scenarios do not wait for them long.
+const (
+	schedulerInterval       = 100 * time.Millisecond
+	defaultReminderTimeout  = 10 * time.Second
+	externalReminderTimeout = 3 * time.Minute
+)
+
+// Calendar is the calendar under test.
+type Calendar struct {
+	HTTPURL  string
+	GRPCAddr string
+	// ReminderTimeout is how long a due reminder may take to be delivered.
+	ReminderTimeout time.Duration
+
+	webhookListen string
+	webhookHost   string
+}
+
+// Start runs the calendar in process, unless HTTPURLEnv and GRPCAddrEnv are set, and stops it
+// when the test ends.
+func Start(t *testing.T) *Calendar {
+	t.Helper()
+	if httpURL, grpcAddr := os.Getenv(HTTPURLEnv), os.Getenv(GRPCAddrEnv); httpURL != "" && grpcAddr != "" {
+		timeout := externalReminderTimeout
+		if value := os.Getenv(ReminderTimeoutEnv); value != "" {
+			var err error
+			timeout, err = time.ParseDuration(value)
+			require.NoError(t, err, ReminderTimeoutEnv)
+		}
+		return &Calendar{
+			HTTPURL:         httpURL,
+			GRPCAddr:        grpcAddr,
+			ReminderTimeout: timeout,
+			webhookListen:   os.Getenv(WebhookListenEnv),
+			webhookHost:     os.Getenv(WebhookHostEnv),
+		}
+	}
+	return startInProcess(t)
+}
+
+// startInProcess wires the calendar as cmd/calendar does with memory storage and the memory queue.
+func startInProcess(t *testing.T) *Calendar {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	logg := logger.New("error")
+	st := memorystorage.New(logg)
+	calendar := app.New(logg, st)
+
+	dispatcher := webhook.New(logg, st, webhook.Options{
+		Workers:       2,
+		MaxAttempts:   1,
+		Backoff:       time.Second,
+		MaxBackoff:    time.Second,
+		Timeout:       time.Second,
+		SweepInterval: time.Second,
+		QueueSize:     100,
+		AllowPrivate:  true,
+	})
+	calendar.AddChangeListener(dispatcher)
+
+	httpServer := internalhttp.NewServer(logg, calendar, "", "", time.Minute, time.Minute, time.Second)
+	gateway, err := internalgrpc.NewGateway(logg, calendar)
+	require.NoError(t, err)
+	httpServer.HandleDocumented("/v1/", gateway, gateway.WriteInvalidRequest)
+	httpTestServer := httptest.NewServer(httpServer.Handler())
+
+	grpcServer := internalgrpc.NewServer(logg, calendar, "", "", time.Second)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	templates := notifier.DefaultTemplates()
+	queue := memoryqueue.New()
+	router := notifier.NewRouter(st, map[storage.NotificationChannel]notifier.Notifier{
+		storage.NotificationChannelStdout:  notifier.NewStdout(ioutil.Discard, templates),
+		storage.NotificationChannelWebhook: notifier.NewWebhook(st, templates, time.Second, true),
+	}, []storage.NotificationChannel{storage.NotificationChannelStdout})
+	reminderScheduler := scheduler.New(logg, st, queue, scheduler.Options{
+		Interval:          schedulerInterval,
+		Lookback:          time.Hour,
+		ReminderRetention: 24 * time.Hour,
+	})
+	reminderSender := sender.New(logg, st, queue, router, schedulerInterval)
+
+	done := make(chan struct{}, 4)
+	for _, run := range []func(){
+		func() { _ = grpcServer.Server.Serve(listener) },
+		func() { dispatcher.Run(ctx) },
+		func() { reminderScheduler.Run(ctx) },
+		func() { _ = reminderSender.Run(ctx) },
+	} {
+		run := run
+		go func() {
+			defer func() { done <- struct{}{} }()
+			run()
+		}()
+	}
+	t.Cleanup(func() {
+		cancel()
+		grpcServer.Server.Stop()
+		httpTestServer.Close()
+		for i := 0; i < cap(done); i++ {
+			<-done
+		}
+	})
+
+	return &Calendar{
+		HTTPURL:         httpTestServer.URL,
+		GRPCAddr:        listener.Addr().String(),
+		ReminderTimeout: defaultReminderTimeout,
+		webhookListen:   "127.0.0.1:0",
+	}
+}
+
+// Do sends the request to the HTTP API as the user and decodes the JSON response to out
+// if it is not nil. It returns the status of the response. Messages of the gateway are
+// encoded as their protobuf JSON.
+func (c *Calendar) Do(t *testing.T, method, path, userID string, body, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		var (
+			data []byte
+			err  error
+		)
+		if message, ok := body.(proto.Message); ok {
+			data, err = protojson.Marshal(message)
+		} else {
+			data, err = json.Marshal(body)
+		}
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.HTTPURL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	// scenarios read what they have just written, replicas of an external calendar may lag
+	req.Header.Set("X-Consistency", "read-your-writes")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	if out != nil && len(data) > 0 {
+		if message, ok := out.(proto.Message); ok {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, message)
+		} else {
+			err = json.Unmarshal(data, out)
+		}
+		require.NoError(t, err, "%s %s: %s", method, path, data)
+	}
+	return resp.StatusCode
+}
+
+// GRPC returns a client of the gRPC API, it is closed when the test ends.
+func (c *Calendar) GRPC(t *testing.T) pb.EventServiceClient {
+	t.Helper()
+	conn, err := grpc.Dial(c.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewEventServiceClient(conn)
+}
+
+// AsUser returns ctx of gRPC calls made by the user.
+func AsUser(ctx context.Context, userID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		internalgrpc.UserIDKey, userID,
+		internalgrpc.ConsistencyKey, internalgrpc.ReadYourWritesValue)
+}
+
+// Reminder is a reminder posted to the receiver.
+type Reminder struct {
+	Header  http.Header
+	Body    []byte
+	Payload notifier.ReminderPayload
+}
+
+// Receiver is a webhook which collects reminders posted to it, other deliveries are ignored.
+type Receiver struct {
+	URL       string
+	reminders chan Reminder
+}
+
+// NewReceiver starts the webhook, it is stopped when the test ends.
+func (c *Calendar) NewReceiver(t *testing.T) *Receiver {
+	t.Helper()
+	listener, err := net.Listen("tcp", c.webhookListen)
+	require.NoError(t, err)
+	receiver := &Receiver{reminders: make(chan Reminder, 100)}
+	server := &httptest.Server{
+		Listener: listener,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.Header.Get(webhook.TypeHeader) == notifier.ReminderType {
+				reminder := Reminder{Header: r.Header.Clone(), Body: body}
+				if err := json.Unmarshal(body, &reminder.Payload); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				receiver.reminders <- reminder
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})},
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	host := c.webhookHost
+	if host == "" {
+		host = listener.Addr().String()
+	} else if _, port, err := net.SplitHostPort(listener.Addr().String()); err == nil {
+		host = net.JoinHostPort(host, port)
+	}
+	receiver.URL = fmt.Sprintf("http://%s/reminders", host)
+	return receiver
+}
+
+// Wait returns the next reminder about the event, reminders about other events are skipped.
+func (r *Receiver) Wait(t *testing.T, eventID string, timeout time.Duration) Reminder {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case reminder := <-r.reminders:
+			if reminder.Payload.Notification != nil && reminder.Payload.Notification.EventID == eventID {
+				return reminder
+			}
+		case <-deadline:
+			require.FailNow(t, "reminder is not delivered", "event %s, timeout %v", eventID, timeout)
+		}
+	}
+}
+
+// Quiet fails if a reminder about the event is delivered during d.
+func (r *Receiver) Quiet(t *testing.T, eventID string, d time.Duration) {
+	t.Helper()
+	deadline := time.After(d)
+	for {
+		select {
+		case reminder := <-r.reminders:
+			if reminder.Payload.Notification != nil && reminder.Payload.Notification.EventID == eventID {
+				require.FailNow(t, "unexpected reminder", "%s", reminder.Body)
+			}
+		case <-deadline:
+			return
+		}
+	}
+}
@@ -0,0 +1,230 @@
+//go:build integration
+// +build integration
+
+package integration_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/notifier"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/storage"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/hihoak/otus-course-hws/hw12_13_14_15_calendar/test/integration"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// user returns a new user, so scenarios do not see data of previous runs on an external calendar.
+func user(name string) string {
+	return name + "-" + xid.New().String()
+}
+
+// apiError is google.rpc.Status the gateway reports errors with.
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func TestEvents(t *testing.T) {
+	calendar := integration.Start(t)
+
+	integration.Run(t, "user creates an event and gets it back", func(s *integration.Scenario) {
+		alice := user("alice")
+		start := time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC)
+		created := &pb.Event{}
+
+		s.When("alice creates an event", func(t *testing.T) {
+			code := calendar.Do(t, http.MethodPost, "/v1/events", alice, &pb.Event{
+				Title:    "planning",
+				StartAt:  timestamppb.New(start),
+				EndAt:    timestamppb.New(start.Add(time.Hour)),
+				TimeZone: "Europe/Moscow",
+			}, created)
+			require.Equal(t, http.StatusOK, code)
+		})
+		s.Then("the event gets an id and belongs to her", func(t *testing.T) {
+			require.NotEmpty(t, created.GetId())
+			require.Equal(t, alice, created.GetUserId())
+		})
+		s.And("she gets the event by the id", func(t *testing.T) {
+			got := &pb.Event{}
+			code := calendar.Do(t, http.MethodGet, "/v1/events/"+created.GetId(), alice, nil, got)
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, "planning", got.GetTitle())
+			require.True(t, start.Equal(got.GetStartAt().AsTime()))
+		})
+		s.And("the gRPC API returns the same event", func(t *testing.T) {
+			got, err := calendar.GRPC(t).GetEvent(integration.AsUser(context.Background(), alice),
+				&pb.GetEventRequest{Id: created.GetId()})
+			require.NoError(t, err)
+			require.Equal(t, "planning", got.GetTitle())
+		})
+	})
+
+	integration.Run(t, "business errors are reported to the user", func(s *integration.Scenario) {
+		bob := user("bob")
+		start := time.Now().Add(time.Hour).UTC()
+		for _, tc := range []struct {
+			name  string
+			event *pb.Event
+		}{
+			{"with a negative reminder offset", &pb.Event{
+				Title:        "negative",
+				StartAt:      timestamppb.New(start),
+				EndAt:        timestamppb.New(start.Add(time.Hour)),
+				NotifyBefore: durationpb.New(-time.Minute),
+			}},
+			{"in an unknown time zone", &pb.Event{
+				Title:    "mars",
+				StartAt:  timestamppb.New(start),
+				EndAt:    timestamppb.New(start.Add(time.Hour)),
+				TimeZone: "Mars/Olympus",
+			}},
+			{"ending before it starts", &pb.Event{
+				Title: "backwards", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(-time.Hour)),
+			}},
+		} {
+			event := tc.event
+			s.When("bob creates an event "+tc.name, func(t *testing.T) {
+				var resp apiError
+				code := calendar.Do(t, http.MethodPost, "/v1/events", bob, event, &resp)
+				require.Equal(t, http.StatusBadRequest, code)
+				require.NotEmpty(t, resp.Message)
+			})
+		}
+		s.Then("no events are created", func(t *testing.T) {
+			events := &pb.ListEventsResponse{}
+			require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodGet, "/v1/events", bob, nil, events))
+			require.Empty(t, events.GetEvents())
+		})
+		s.And("a missing event is not found by both APIs", func(t *testing.T) {
+			var resp apiError
+			require.Equal(t, http.StatusNotFound, calendar.Do(t, http.MethodGet, "/v1/events/missing", bob, nil, &resp))
+			require.NotEmpty(t, resp.Message)
+
+			_, err := calendar.GRPC(t).GetEvent(integration.AsUser(context.Background(), bob),
+				&pb.GetEventRequest{Id: "missing"})
+			require.Equal(t, codes.NotFound, status.Code(err))
+		})
+		s.And("a deleted event is not found", func(t *testing.T) {
+			created := &pb.Event{}
+			require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodPost, "/v1/events", bob, &pb.Event{
+				Title: "cancelled", StartAt: timestamppb.New(start), EndAt: timestamppb.New(start.Add(time.Hour)),
+			}, created))
+			require.Equal(t, http.StatusOK,
+				calendar.Do(t, http.MethodDelete, "/v1/events/"+created.GetId(), bob, nil, nil))
+			require.Equal(t, http.StatusNotFound,
+				calendar.Do(t, http.MethodGet, "/v1/events/"+created.GetId(), bob, nil, nil))
+		})
+	})
+}
+
+func TestListing(t *testing.T) {
+	calendar := integration.Start(t)
+
+	integration.Run(t, "user lists events of a day, a week and a month", func(s *integration.Scenario) {
+		carol := user("carol")
+		s.Given("carol has events on Monday, on Sunday and in the next month", func(t *testing.T) {
+			for _, start := range []time.Time{
+				time.Date(2022, 10, 24, 10, 0, 0, 0, time.UTC),
+				time.Date(2022, 10, 30, 10, 0, 0, 0, time.UTC),
+				time.Date(2022, 11, 2, 10, 0, 0, 0, time.UTC),
+			} {
+				require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodPost, "/v1/events", carol, &pb.Event{
+					Title:   start.Weekday().String(),
+					StartAt: timestamppb.New(start),
+					EndAt:   timestamppb.New(start.Add(time.Hour)),
+				}, nil))
+			}
+		})
+		for _, tc := range []struct {
+			period, path string
+			count        int
+		}{
+			{"the day", "/v1/days/2022-10-24/events", 1},
+			{"the week", "/v1/weeks/2022-10-24/events", 2},
+			{"the month", "/v1/months/2022-10-01/events", 2},
+			{"the next week", "/v1/weeks/2022-10-31/events", 1},
+		} {
+			tc := tc
+			s.Then("she sees "+tc.period+" by the HTTP API", func(t *testing.T) {
+				events := &pb.ListEventsResponse{}
+				require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodGet, tc.path, carol, nil, events))
+				require.Len(t, events.GetEvents(), tc.count)
+			})
+		}
+		s.And("the gRPC API lists the same periods", func(t *testing.T) {
+			client := calendar.GRPC(t)
+			ctx := integration.AsUser(context.Background(), carol)
+			day, err := client.ListDayEvents(ctx, &pb.ListPeriodEventsRequest{Date: "2022-10-24"})
+			require.NoError(t, err)
+			require.Len(t, day.GetEvents(), 1)
+			week, err := client.ListWeekEvents(ctx, &pb.ListPeriodEventsRequest{Date: "2022-10-24"})
+			require.NoError(t, err)
+			require.Len(t, week.GetEvents(), 2)
+			month, err := client.ListMonthEvents(ctx, &pb.ListPeriodEventsRequest{Date: "2022-10-01"})
+			require.NoError(t, err)
+			require.Len(t, month.GetEvents(), 2)
+		})
+		s.And("other users do not see her events", func(t *testing.T) {
+			events := &pb.ListEventsResponse{}
+			require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodGet, "/v1/months/2022-10-01/events",
+				user("dave"), nil, events))
+			require.Empty(t, events.GetEvents())
+		})
+	})
+}
+
+func TestReminders(t *testing.T) {
+	calendar := integration.Start(t)
+
+	integration.Run(t, "reminder about an event is delivered to the webhook of the user", func(s *integration.Scenario) {
+		erin := user("erin")
+		receiver := calendar.NewReceiver(t)
+		var (
+			hook     = &pb.Webhook{}
+			event    = &pb.Event{}
+			reminder integration.Reminder
+		)
+
+		s.Given("erin receives reminders by her webhook", func(t *testing.T) {
+			require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodPost, "/v1/webhooks", erin,
+				&pb.Webhook{Url: receiver.URL}, hook))
+			require.NotEmpty(t, hook.GetSecret())
+			require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodPut, "/v1/profile", erin,
+				&pb.UserProfile{Channels: []string{string(storage.NotificationChannelWebhook)}}, nil))
+		})
+		s.When("she creates an event whose reminder is due", func(t *testing.T) {
+			start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			require.Equal(t, http.StatusOK, calendar.Do(t, http.MethodPost, "/v1/events", erin, &pb.Event{
+				Title:        "dentist",
+				StartAt:      timestamppb.New(start),
+				EndAt:        timestamppb.New(start.Add(time.Hour)),
+				NotifyBefore: durationpb.New(time.Hour + time.Minute),
+			}, event))
+		})
+		s.Then("the reminder is posted to her webhook", func(t *testing.T) {
+			reminder = receiver.Wait(t, event.GetId(), calendar.ReminderTimeout)
+			require.Equal(t, notifier.ReminderType, reminder.Payload.Type)
+			require.Equal(t, erin, reminder.Payload.Notification.UserID)
+			require.Equal(t, "dentist", reminder.Payload.Notification.Title)
+			require.Contains(t, reminder.Payload.Subject, "dentist")
+		})
+		s.And("it is signed with the secret of the webhook", func(t *testing.T) {
+			require.True(t, webhook.Verify(hook.GetSecret(), reminder.Header.Get(webhook.SignatureHeader),
+				reminder.Body, time.Now(), time.Minute))
+			require.Equal(t, reminder.Payload.ID, reminder.Header.Get(webhook.DeliveryHeader))
+		})
+		s.And("it is not delivered again", func(t *testing.T) {
+			// a few cycles of the in process scheduler, an external one is trusted with its interval
+			receiver.Quiet(t, event.GetId(), time.Second)
+		})
+	})
+}
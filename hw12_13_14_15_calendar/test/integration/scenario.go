@@ -0,0 +1,35 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import "testing"
+
+// Scenario runs steps of a business scenario as subtests, so the output reads as the scenario.
+// A failed step stops the scenario, the next steps depend on it.
+type Scenario struct {
+	t *testing.T
+}
+
+// Run runs the scenario as a subtest of t.
+func Run(t *testing.T, name string, steps func(s *Scenario)) {
+	t.Helper()
+	t.Run(name, func(t *testing.T) {
+		steps(&Scenario{t: t})
+	})
+}
+
+func (s *Scenario) Given(text string, step func(t *testing.T)) { s.step("Given "+text, step) }
+
+func (s *Scenario) When(text string, step func(t *testing.T)) { s.step("When "+text, step) }
+
+func (s *Scenario) Then(text string, step func(t *testing.T)) { s.step("Then "+text, step) }
+
+func (s *Scenario) And(text string, step func(t *testing.T)) { s.step("And "+text, step) }
+
+func (s *Scenario) step(name string, step func(t *testing.T)) {
+	s.t.Helper()
+	if !s.t.Run(name, step) {
+		s.t.FailNow()
+	}
+}